@@ -61,6 +61,29 @@ type Config struct {
 	SMTPUser     string
 	SMTPPassword string
 	SMTPFrom     string
+
+	// Push 离线推送（未配置的通道不启用）
+	APNsKeyFile         string // APNs .p8 密钥文件
+	APNsKeyID           string
+	APNsTeamID          string
+	APNsTopic           string // App Bundle ID
+	APNsProduction      bool
+	FCMCredentialsFile  string // FCM 服务账号 JSON 文件
+	XiaomiPushAppSecret string
+	XiaomiPushPackage   string
+	HuaweiPushAppID     string
+	HuaweiPushAppSecret string
+	PushMemoryProvider  bool // 启用内存推送通道（仅用于测试）
+
+	// 未读消息邮件摘要
+	EmailDigestEnabled      bool
+	EmailDigestOfflineHours int    // 离线超过该小时数才发送摘要
+	EmailDigestAppURL       string // 邮件中的应用链接（可选）
+
+	// 加急消息
+	UrgentEscalationMinutes int    // 加急消息未读多少分钟后通过短信/邮件提醒
+	UrgentEscalationChannel string // 优先使用的提醒方式：sms 或 email（无对应联系方式时使用另一种）
+	UrgentDailyQuota        int    // 每个用户每天可发送的加急消息数
 }
 
 var AppConfig *Config
@@ -92,6 +115,15 @@ func LoadConfig(debugMode bool) {
 	verifyExpire, _ := strconv.Atoi(getEnvViper("VERIFY_CODE_EXPIRE_MINUTES", "5"))
 	redisDB, _ := strconv.Atoi(getEnvViper("REDIS_DB", "0"))
 	smtpPort, _ := strconv.Atoi(getEnvViper("SMTP_PORT", "465"))
+	digestOfflineHours, _ := strconv.Atoi(getEnvViper("EMAIL_DIGEST_OFFLINE_HOURS", "24"))
+	if digestOfflineHours <= 0 {
+		digestOfflineHours = 24
+	}
+	urgentEscalationMinutes, _ := strconv.Atoi(getEnvViper("URGENT_ESCALATION_MINUTES", "10"))
+	if urgentEscalationMinutes <= 0 {
+		urgentEscalationMinutes = 10
+	}
+	urgentDailyQuota, _ := strconv.Atoi(getEnvViper("URGENT_DAILY_QUOTA", "20"))
 
 	// 获取应用环境
 	appEnv := getEnvViper("APP_ENV", "development")
@@ -157,6 +189,23 @@ func LoadConfig(debugMode bool) {
 		SMTPUser:                getEnvViper("SMTP_USER", ""),
 		SMTPPassword:            getEnvViper("SMTP_PASSWORD", ""),
 		SMTPFrom:                getEnvViper("SMTP_FROM", ""),
+		APNsKeyFile:             getEnvViper("APNS_KEY_FILE", ""),
+		APNsKeyID:               getEnvViper("APNS_KEY_ID", ""),
+		APNsTeamID:              getEnvViper("APNS_TEAM_ID", ""),
+		APNsTopic:               getEnvViper("APNS_TOPIC", ""),
+		APNsProduction:          getEnvViper("APNS_PRODUCTION", "false") == "true",
+		FCMCredentialsFile:      getEnvViper("FCM_CREDENTIALS_FILE", ""),
+		XiaomiPushAppSecret:     getEnvViper("XIAOMI_PUSH_APP_SECRET", ""),
+		XiaomiPushPackage:       getEnvViper("XIAOMI_PUSH_PACKAGE", ""),
+		HuaweiPushAppID:         getEnvViper("HUAWEI_PUSH_APP_ID", ""),
+		HuaweiPushAppSecret:     getEnvViper("HUAWEI_PUSH_APP_SECRET", ""),
+		PushMemoryProvider:      getEnvViper("PUSH_MEMORY_PROVIDER", "false") == "true",
+		EmailDigestEnabled:      getEnvViper("EMAIL_DIGEST_ENABLED", "true") == "true",
+		EmailDigestOfflineHours: digestOfflineHours,
+		EmailDigestAppURL:       getEnvViper("EMAIL_DIGEST_APP_URL", ""),
+		UrgentEscalationMinutes: urgentEscalationMinutes,
+		UrgentEscalationChannel: getEnvViper("URGENT_ESCALATION_CHANNEL", "sms"),
+		UrgentDailyQuota:        urgentDailyQuota,
 	}
 }
 
@@ -16,6 +16,7 @@ import (
 type AuthController struct {
 	userRepo *models.UserRepository
 	codeRepo *models.VerificationCodeRepository
+	botRepo  *models.BotRepository
 }
 
 // NewAuthController 创建认证控制器
@@ -23,6 +24,7 @@ func NewAuthController() *AuthController {
 	return &AuthController{
 		userRepo: models.NewUserRepository(db.DB),
 		codeRepo: models.NewVerificationCodeRepository(db.DB),
+		botRepo:  models.NewBotRepository(db.DB),
 	}
 }
 
@@ -148,6 +150,11 @@ func (ctrl *AuthController) Login(c *gin.Context) {
 		return
 	}
 
+	// 机器人账号只能通过 Webhook 发消息，不能交互式登录（放在密码校验之后，且不暴露账号是否为机器人）
+	if !ctrl.checkNotBot(c, user.ID, "用户名或密码错误") {
+		return
+	}
+
 	// 注释掉在线状态检查，允许直接登录
 	// WebSocket连接时会自动踢掉旧设备的连接
 	// if user.Status == "online" || user.Status == "busy" || user.Status == "away" {
@@ -384,6 +391,11 @@ func (ctrl *AuthController) VerifyCodeLogin(c *gin.Context) {
 		return
 	}
 
+	// 机器人账号只能通过 Webhook 发消息，不能交互式登录（放在验证码校验之后，且不暴露账号是否为机器人）
+	if !ctrl.checkNotBot(c, user.ID, "验证码错误或已过期") {
+		return
+	}
+
 	// 验证成功，删除数据库中的验证码
 	ctrl.codeRepo.DeleteByAccount(req.Account, "login")
 
@@ -472,3 +484,20 @@ func (ctrl *AuthController) ForgotPassword(c *gin.Context) {
 
 	utils.SuccessWithMessage(c, "密码重置成功", nil)
 }
+
+// checkNotBot 拒绝机器人账号登录，返回 false 表示已写入错误响应
+// rejectMessage 与凭据错误时的提示相同，避免通过登录接口探测哪些账号是机器人
+func (ctrl *AuthController) checkNotBot(c *gin.Context, userID int, rejectMessage string) bool {
+	isBot, err := ctrl.botRepo.IsBot(userID)
+	if err != nil {
+		utils.LogDebug("查询机器人标记失败: %v", err)
+		utils.InternalServerError(c, "服务器错误")
+		return false
+	}
+	if isBot {
+		utils.LogDebug("机器人账号 %d 尝试交互式登录，已拒绝", userID)
+		utils.BadRequest(c, rejectMessage)
+		return false
+	}
+	return true
+}
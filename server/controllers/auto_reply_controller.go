@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AutoReplyController 自动回复（外出/忙碌）控制器
+type AutoReplyController struct {
+	Hub           *ws.Hub
+	autoReplyRepo *models.AutoReplyRepository
+}
+
+// NewAutoReplyController 创建自动回复控制器
+func NewAutoReplyController(hub *ws.Hub) *AutoReplyController {
+	return &AutoReplyController{
+		Hub:           hub,
+		autoReplyRepo: models.NewAutoReplyRepository(db.DB),
+	}
+}
+
+// GetAutoReply 获取当前用户的自动回复设置
+// GET /api/user/auto-reply
+func (ac *AutoReplyController) GetAutoReply(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	rule, err := ac.autoReplyRepo.GetRule(userID.(int))
+	if err != nil {
+		utils.LogError("❌ [自动回复] 获取用户 %d 的自动回复设置失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "获取自动回复设置失败")
+		return
+	}
+
+	utils.Success(c, rule)
+}
+
+// UpdateAutoReply 更新当前用户的自动回复设置
+// PUT /api/user/auto-reply
+func (ac *AutoReplyController) UpdateAutoReply(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	var req models.UpdateAutoReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	rule, err := ac.autoReplyRepo.GetRule(userID.(int))
+	if err != nil {
+		utils.LogError("❌ [自动回复] 获取用户 %d 的自动回复设置失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "更新自动回复设置失败")
+		return
+	}
+
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.Message != nil {
+		rule.Message = strings.TrimSpace(*req.Message)
+		if utf8.RuneCountInString(rule.Message) > models.MaxAutoReplyLength {
+			utils.BadRequest(c, "自动回复内容不能超过 "+strconv.Itoa(models.MaxAutoReplyLength)+" 个字符")
+			return
+		}
+		// 内容审核：命中 block 规则拒绝保存，mask 规则命中部分打码后保存
+		moderation := moderateMessageContent("text", rule.Message)
+		if moderation.Blocked {
+			respondContentBlocked(c)
+			return
+		}
+		rule.Message = moderation.Content
+	}
+	if req.StartAt != nil {
+		startAt, errMsg := parseAutoReplyTime(*req.StartAt, "开始时间")
+		if errMsg != "" {
+			utils.BadRequest(c, errMsg)
+			return
+		}
+		rule.StartAt = startAt
+	}
+	if req.EndAt != nil {
+		endAt, errMsg := parseAutoReplyTime(*req.EndAt, "结束时间")
+		if errMsg != "" {
+			utils.BadRequest(c, errMsg)
+			return
+		}
+		rule.EndAt = endAt
+	}
+
+	if rule.Enabled {
+		if rule.Message == "" {
+			utils.BadRequest(c, "请填写自动回复内容")
+			return
+		}
+		if rule.StartAt != nil && rule.EndAt != nil && !rule.EndAt.After(*rule.StartAt) {
+			utils.BadRequest(c, "结束时间必须晚于开始时间")
+			return
+		}
+		if rule.EndAt != nil && !rule.EndAt.After(time.Now().UTC()) {
+			utils.BadRequest(c, "结束时间必须晚于当前时间")
+			return
+		}
+	}
+
+	if err := ac.autoReplyRepo.SaveRule(rule); err != nil {
+		utils.LogError("❌ [自动回复] 保存用户 %d 的自动回复设置失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "更新自动回复设置失败")
+		return
+	}
+
+	notifyAutoReplyUpdated(ac.Hub, rule)
+	utils.LogDebug("🏖️ [自动回复] 用户 %d 更新自动回复设置 - 开启: %v, 当前生效: %v", rule.UserID, rule.Enabled, rule.Active)
+	utils.SuccessWithMessage(c, "自动回复设置已更新", rule)
+}
+
+// parseAutoReplyTime 解析生效时间（RFC3339），空字符串表示不限
+func parseAutoReplyTime(value, field string) (*time.Time, string) {
+	if value == "" {
+		return nil, ""
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, field + "格式错误，请使用 RFC3339 格式"
+	}
+	t = t.UTC()
+	return &t, ""
+}
+
+// syncAutoReplyStatus 用户状态变更时同步自动回复：切换为离开/忙碌时开启新的生效周期，切换回在线时停止
+// 断线（offline）不影响自动回复，离开后关闭应用的用户仍会自动回复
+func syncAutoReplyStatus(hub *ws.Hub, userID int, status string) {
+	if status == "offline" {
+		return
+	}
+
+	rule, err := models.NewAutoReplyRepository(db.DB).SetStatusTriggered(userID, models.IsStatusTriggering(status))
+	if err != nil {
+		utils.LogError("❌ [自动回复] 同步用户 %d 的状态失败: %v", userID, err)
+		return
+	}
+	if rule == nil || !rule.Enabled {
+		return
+	}
+
+	notifyAutoReplyUpdated(hub, rule)
+	utils.LogDebug("🏖️ [自动回复] 用户 %d 状态变更为 %s - 自动回复生效: %v", userID, status, rule.Active)
+}
+
+// notifyAutoReplyUpdated 向用户的所有设备推送自动回复设置变更
+func notifyAutoReplyUpdated(hub *ws.Hub, rule *models.AutoReplyRule) {
+	msgBytes, err := json.Marshal(models.WSMessage{Type: "auto_reply_updated", Data: rule})
+	if err != nil {
+		utils.LogDebug("序列化自动回复设置失败: %v", err)
+		return
+	}
+	hub.SendToUser(rule.UserID, msgBytes)
+}
+
+// sendAutoReply 接收者的自动回复生效时，向私聊消息的发送者回复一次（同一生效周期内每个发送者只回复一次）
+func sendAutoReply(mc *MessageController, msg *models.Message) {
+	// 系统消息、通话记录和自动回复本身不触发自动回复，避免双方互相自动回复
+	if msg.MessageType == "system" || msg.MessageType == models.MessageTypeAutoReply || strings.HasPrefix(msg.MessageType, "call_") {
+		return
+	}
+
+	repo := models.NewAutoReplyRepository(db.DB)
+	rule, err := repo.GetRule(msg.ReceiverID)
+	if err != nil {
+		utils.LogError("❌ [自动回复] 获取用户 %d 的自动回复设置失败: %v", msg.ReceiverID, err)
+		return
+	}
+	if !rule.IsActive(time.Now().UTC()) {
+		return
+	}
+
+	// 机器人发来的消息不回复
+	if isBot, err := models.NewBotRepository(db.DB).IsBot(msg.SenderID); err != nil || isBot {
+		return
+	}
+
+	claimed, err := repo.ClaimReply(msg.ReceiverID, msg.SenderID, rule.PeriodStart())
+	if err != nil {
+		utils.LogError("❌ [自动回复] 记录用户 %d 对 %d 的自动回复失败: %v", msg.ReceiverID, msg.SenderID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	// 审核规则可能在设置自动回复之后才新增，发送时按当前规则再审核一次
+	moderation := moderateMessageContent("text", rule.Message)
+	if moderation.Blocked {
+		utils.LogInfo("🛡️ [自动回复] 用户 %d 的自动回复内容命中拦截规则，已跳过", msg.ReceiverID)
+		return
+	}
+
+	reply, err := mc.saveMessage(msg.ReceiverID, msg.SenderID, moderation.Content, models.MessageTypeAutoReply, "", 0, "", "", 0)
+	if err != nil {
+		utils.LogError("❌ [自动回复] 保存用户 %d 的自动回复失败: %v", msg.ReceiverID, err)
+		return
+	}
+	recordModerationFlag(moderation, models.ModerationSourceMessage, reply.ID, reply.SenderID, reply.ReceiverID, reply.Content)
+
+	wsMsg := models.WSMessage{
+		Type: "message",
+		Data: models.WSMessageData{
+			ID:             reply.ID,
+			SenderID:       reply.SenderID,
+			ReceiverID:     reply.ReceiverID,
+			SenderName:     reply.SenderName,
+			ReceiverName:   reply.ReceiverName,
+			SenderAvatar:   reply.SenderAvatar,
+			ReceiverAvatar: reply.ReceiverAvatar,
+			Content:        reply.Content,
+			MessageType:    reply.MessageType,
+			IsRead:         reply.IsRead,
+			CreatedAt:      reply.CreatedAt.UTC(),
+		},
+	}
+	msgBytes, _ := json.Marshal(wsMsg)
+	if !mc.Hub.SendToUser(reply.ReceiverID, msgBytes) {
+		pushPrivateMessage(mc.Hub, reply)
+	}
+	// 同步到自动回复设置者的其他设备
+	mc.Hub.SendToUser(reply.SenderID, msgBytes)
+
+	utils.LogDebug("🏖️ [自动回复] 用户 %d 已自动回复 %d - MessageID: %d", reply.SenderID, reply.ReceiverID, reply.ID)
+}
+
+// ExpireAutoReplyRules 关闭生效时间段已结束的自动回复并通知用户（由定时任务调用）
+func ExpireAutoReplyRules(hub *ws.Hub) {
+	repo := models.NewAutoReplyRepository(db.DB)
+	userIDs, err := repo.DisableExpired(time.Now().UTC())
+	if err != nil {
+		utils.LogError("❌ [自动回复] 关闭已到期的自动回复失败: %v", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		if rule, err := repo.GetRule(userID); err == nil {
+			notifyAutoReplyUpdated(hub, rule)
+		}
+		utils.LogInfo("🏖️ [自动回复] 用户 %d 的自动回复已到期，自动关闭", userID)
+	}
+}
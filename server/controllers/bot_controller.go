@@ -0,0 +1,409 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Webhook 相关常量
+const (
+	webhookRateLimit     = 20          // 每个 Webhook 每个窗口最多发送的消息数
+	webhookRateWindow    = time.Minute // 限流窗口
+	maxWebhookTextLength = 4000        // 纯文本消息最大长度（字符）
+)
+
+// webhookLimiter Webhook 发消息限流器（按 Webhook ID 计数）
+var webhookLimiter = utils.NewRateLimiter(webhookRateLimit, webhookRateWindow)
+
+// BotController 机器人与 Incoming Webhook 控制器
+// Webhook 消息复用 GroupController 的群消息保存与广播逻辑
+type BotController struct {
+	groupCtrl *GroupController
+	botRepo   *models.BotRepository
+}
+
+// NewBotController 创建机器人控制器
+func NewBotController(groupCtrl *GroupController) *BotController {
+	return &BotController{
+		groupCtrl: groupCtrl,
+		botRepo:   models.NewBotRepository(db.DB),
+	}
+}
+
+// webhookURL 根据请求地址拼接 Webhook 完整地址
+func webhookURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + "/api/webhooks/" + token
+}
+
+// AdminCreateBot 创建机器人账号（机器人不能登录，只能通过 Webhook 发消息）
+func (bc *BotController) AdminCreateBot(c *gin.Context) {
+	var req models.CreateBotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	existUser, err := bc.groupCtrl.userRepo.FindByUsername(req.Username)
+	if err != nil && err != sql.ErrNoRows {
+		utils.LogError("❌ [机器人] 查询用户失败: %v", err)
+		utils.InternalServerError(c, "服务器错误")
+		return
+	}
+	if existUser != nil {
+		utils.BadRequest(c, "用户名已存在")
+		return
+	}
+
+	// 随机密码，不对外公开，且登录接口会拒绝机器人账号
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		utils.InternalServerError(c, "创建机器人失败")
+		return
+	}
+	hashedPassword, err := utils.HashPassword(hex.EncodeToString(buf))
+	if err != nil {
+		utils.InternalServerError(c, "创建机器人失败")
+		return
+	}
+
+	bot, err := bc.botRepo.CreateBot(&req, hashedPassword)
+	if err != nil {
+		utils.LogError("❌ [机器人] 创建机器人失败: %v", err)
+		utils.InternalServerError(c, "创建机器人失败")
+		return
+	}
+
+	utils.LogInfo("🤖 [机器人] 已创建机器人 %d (%s)", bot.ID, bot.Username)
+	utils.Success(c, gin.H{"bot": bot})
+}
+
+// AdminGetBots 获取所有机器人
+func (bc *BotController) AdminGetBots(c *gin.Context) {
+	bots, err := bc.botRepo.ListBots()
+	if err != nil {
+		utils.LogError("❌ [机器人] 获取机器人列表失败: %v", err)
+		utils.InternalServerError(c, "获取机器人列表失败")
+		return
+	}
+	utils.Success(c, gin.H{"bots": bots})
+}
+
+// AdminGetWebhooks 获取机器人的 Webhook 列表
+func (bc *BotController) AdminGetWebhooks(c *gin.Context) {
+	botID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的机器人ID")
+		return
+	}
+
+	webhooks, err := bc.botRepo.ListWebhooks(botID)
+	if err != nil {
+		utils.LogError("❌ [机器人] 获取 Webhook 列表失败: %v", err)
+		utils.InternalServerError(c, "获取 Webhook 列表失败")
+		return
+	}
+	utils.Success(c, gin.H{"webhooks": webhooks})
+}
+
+// AdminCreateWebhook 为机器人创建群组 Webhook，机器人不在群内时自动加入
+// 密钥只在创建时返回一次
+func (bc *BotController) AdminCreateWebhook(c *gin.Context) {
+	botID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的机器人ID")
+		return
+	}
+
+	var req models.CreateBotWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	bot, err := bc.botRepo.GetBot(botID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "机器人不存在")
+			return
+		}
+		utils.LogError("❌ [机器人] 获取机器人失败: %v", err)
+		utils.InternalServerError(c, "获取机器人失败")
+		return
+	}
+
+	group, err := bc.groupCtrl.groupRepo.GetGroupByID(req.GroupID)
+	if err != nil || group.DeletedAt != nil || models.GetDisbandedGroupsManager().IsGroupDisbanded(req.GroupID) {
+		utils.NotFound(c, "群组不存在或已解散")
+		return
+	}
+
+	isMember, err := bc.groupCtrl.groupRepo.IsGroupMember(req.GroupID, bot.ID)
+	if err != nil {
+		utils.LogError("❌ [机器人] 检查群成员失败: %v", err)
+		utils.InternalServerError(c, "创建 Webhook 失败")
+		return
+	}
+	if !isMember {
+		if err := bc.groupCtrl.groupRepo.AddGroupMember(req.GroupID, bot.ID, nil, nil, "member"); err != nil {
+			utils.LogError("❌ [机器人] 机器人 %d 加入群组 %d 失败: %v", bot.ID, req.GroupID, err)
+			utils.InternalServerError(c, "机器人加入群组失败")
+			return
+		}
+	}
+
+	webhook, token, err := bc.botRepo.CreateWebhook(bot.ID, req.GroupID)
+	if err != nil {
+		utils.LogError("❌ [机器人] 创建 Webhook 失败: %v", err)
+		utils.BadRequest(c, "创建 Webhook 失败，该机器人在此群组可能已有 Webhook")
+		return
+	}
+	webhook.GroupName = group.Name
+
+	utils.LogInfo("🤖 [机器人] 机器人 %d 在群组 %d 创建 Webhook %d", bot.ID, req.GroupID, webhook.ID)
+	utils.Success(c, gin.H{
+		"webhook": webhook,
+		"token":   token,
+		"url":     webhookURL(c, token),
+	})
+}
+
+// AdminRotateWebhook 轮换 Webhook 密钥，旧密钥立即失效
+func (bc *BotController) AdminRotateWebhook(c *gin.Context) {
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的 Webhook ID")
+		return
+	}
+
+	webhook, token, err := bc.botRepo.RotateWebhook(webhookID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "Webhook 不存在或已吊销")
+			return
+		}
+		utils.LogError("❌ [机器人] 轮换 Webhook 密钥失败: %v", err)
+		utils.InternalServerError(c, "轮换 Webhook 密钥失败")
+		return
+	}
+
+	utils.LogInfo("🤖 [机器人] Webhook %d 密钥已轮换", webhookID)
+	utils.Success(c, gin.H{
+		"webhook": webhook,
+		"token":   token,
+		"url":     webhookURL(c, token),
+	})
+}
+
+// AdminRevokeWebhook 吊销 Webhook
+func (bc *BotController) AdminRevokeWebhook(c *gin.Context) {
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的 Webhook ID")
+		return
+	}
+
+	revoked, err := bc.botRepo.RevokeWebhook(webhookID)
+	if err != nil {
+		utils.LogError("❌ [机器人] 吊销 Webhook 失败: %v", err)
+		utils.InternalServerError(c, "吊销 Webhook 失败")
+		return
+	}
+	if !revoked {
+		utils.NotFound(c, "Webhook 不存在或已吊销")
+		return
+	}
+
+	utils.LogInfo("🤖 [机器人] Webhook %d 已吊销", webhookID)
+	utils.SuccessWithMessage(c, "Webhook 已吊销", nil)
+}
+
+// PostWebhookMessage 外部系统通过 Incoming Webhook 以机器人身份向群组发消息
+func (bc *BotController) PostWebhookMessage(c *gin.Context) {
+	webhook, err := bc.botRepo.GetWebhookByToken(c.Param("token"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.Unauthorized(c, "无效的 Webhook")
+			return
+		}
+		utils.LogError("❌ [Webhook] 查询 Webhook 失败: %v", err)
+		utils.InternalServerError(c, "服务器错误")
+		return
+	}
+
+	if ok, retryAfter := webhookLimiter.Allow(strconv.Itoa(webhook.ID)); !ok {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		utils.ErrorWithData(c, http.StatusTooManyRequests, "发送过于频繁，请稍后再试", gin.H{"retry_after": seconds})
+		return
+	}
+
+	var payload models.WebhookPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	messageType, content, errMsg := buildWebhookContent(&payload)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	if models.GetDisbandedGroupsManager().IsGroupDisbanded(webhook.GroupID) {
+		utils.NotFound(c, "该群组已被群主解散")
+		return
+	}
+
+	// 机器人被移出群组后 Webhook 不再可用
+	role, err := bc.groupCtrl.groupRepo.GetUserGroupRole(webhook.GroupID, webhook.BotID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.Forbidden(c, "机器人不是该群组成员")
+			return
+		}
+		utils.InternalServerError(c, "验证群组成员失败")
+		return
+	}
+
+	group, err := bc.groupCtrl.groupRepo.GetGroupByID(webhook.GroupID)
+	if err != nil {
+		utils.InternalServerError(c, "获取群组信息失败")
+		return
+	}
+	if group.AllMuted && role != "owner" && role != "admin" {
+		utils.Forbidden(c, "群组已开启全体禁言")
+		return
+	}
+	if isMuted, err := bc.groupCtrl.groupRepo.IsGroupMemberMuted(webhook.GroupID, webhook.BotID); err == nil && isMuted {
+		utils.Forbidden(c, "机器人已被禁言")
+		return
+	}
+
+	message, code, errMsg := sendBotGroupMessage(bc.groupCtrl, webhook.BotID, webhook.GroupID, messageType, content)
+	if errMsg != "" {
+		if errMsg == contentBlockedMessage {
+			respondContentBlocked(c)
+			return
+		}
+		utils.Error(c, code, errMsg)
+		return
+	}
+
+	if err := bc.botRepo.TouchWebhook(webhook.ID); err != nil {
+		utils.LogDebug("更新 Webhook 使用时间失败: %v", err)
+	}
+
+	utils.LogInfo("🤖 [Webhook] Webhook %d 向群组 %d 发送消息 %d", webhook.ID, webhook.GroupID, message.ID)
+	utils.Success(c, gin.H{"message_id": message.ID})
+}
+
+// sendBotGroupMessage 以机器人身份发送群消息：规范化、内容审核、保存并广播（调用方已完成成员与禁言检查）
+// 失败时返回错误码和错误信息，内容被拦截时错误信息为 contentBlockedMessage
+func sendBotGroupMessage(gc *GroupController, botID, groupID int, messageType, content string) (*models.GroupMessage, int, string) {
+	normalizedContent, err := normalizeMessageContent(botID, 0, groupID, messageType, content)
+	if err != nil {
+		return nil, http.StatusBadRequest, err.Error()
+	}
+
+	req := models.CreateGroupMessageRequest{
+		GroupID:     groupID,
+		Content:     normalizedContent,
+		MessageType: messageType,
+	}
+	req.MentionedUserIds = mergeRichTextMentions(req.MessageType, req.Content, nil)
+
+	moderation := moderateMessageContent(req.MessageType, req.Content)
+	if moderation.Blocked {
+		return nil, http.StatusBadRequest, contentBlockedMessage
+	}
+	req.Content = moderation.Content
+
+	bot, err := gc.userRepo.FindByID(botID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "获取机器人信息失败"
+	}
+	senderName := bot.Username
+	if bot.FullName != nil && *bot.FullName != "" {
+		senderName = *bot.FullName
+	}
+	var avatar *string
+	if bot.Avatar != "" {
+		avatar = &bot.Avatar
+	}
+
+	message, err := gc.groupRepo.CreateGroupMessage(&req, bot.ID, senderName, nil, bot.FullName, avatar)
+	if err != nil {
+		utils.LogError("❌ [机器人] 创建群组消息失败: %v", err)
+		return nil, http.StatusInternalServerError, "发送消息失败"
+	}
+	recordModerationFlag(moderation, models.ModerationSourceGroupMessage, message.ID, bot.ID, message.GroupID, message.Content)
+
+	go gc.broadcastGroupMessage(message)
+	return message, 0, ""
+}
+
+// buildWebhookContent 将 Webhook 请求体转换为消息类型和内容，卡片转换为富文本
+func buildWebhookContent(payload *models.WebhookPayload) (string, string, string) {
+	switch payload.Type {
+	case "", "text":
+		text := strings.TrimSpace(payload.Text)
+		if text == "" {
+			return "", "", "text 不能为空"
+		}
+		if utf8.RuneCountInString(text) > maxWebhookTextLength {
+			return "", "", "text 不能超过" + strconv.Itoa(maxWebhookTextLength) + "个字符"
+		}
+		return "text", text, ""
+	case models.MessageTypeRichText:
+		if strings.TrimSpace(payload.Text) == "" {
+			return "", "", "text 不能为空"
+		}
+		return models.MessageTypeRichText, payload.Text, ""
+	case "card":
+		if payload.Card == nil || strings.TrimSpace(payload.Card.Title) == "" {
+			return "", "", "card.title 不能为空"
+		}
+		return models.MessageTypeRichText, webhookCardMarkdown(payload.Card), ""
+	default:
+		return "", "", "不支持的消息类型: " + payload.Type
+	}
+}
+
+// webhookCardMarkdown 将卡片转换为受限 Markdown：加粗标题、正文、字段列表和详情链接
+func webhookCardMarkdown(card *models.WebhookCard) string {
+	var sb strings.Builder
+	sb.WriteString("**" + strings.TrimSpace(card.Title) + "**\n")
+	if text := strings.TrimSpace(card.Text); text != "" {
+		sb.WriteString(text + "\n")
+	}
+	for _, field := range card.Fields {
+		if field.Title == "" && field.Value == "" {
+			continue
+		}
+		sb.WriteString("- **" + strings.TrimSpace(field.Title) + "**: " + strings.TrimSpace(field.Value) + "\n")
+	}
+	if url := strings.TrimSpace(card.URL); url != "" {
+		sb.WriteString("[查看详情](" + url + ")\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,424 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBroadcastListNameLen 群发列表名称最大长度（字符）
+const maxBroadcastListNameLen = 100
+
+// BroadcastListController 群发列表控制器
+// 群发消息复用 MessageController 的私聊消息保存逻辑
+type BroadcastListController struct {
+	messageCtrl *MessageController
+	listRepo    *models.BroadcastListRepository
+}
+
+// NewBroadcastListController 创建群发列表控制器
+func NewBroadcastListController(messageCtrl *MessageController) *BroadcastListController {
+	return &BroadcastListController{
+		messageCtrl: messageCtrl,
+		listRepo:    models.NewBroadcastListRepository(db.DB),
+	}
+}
+
+// GetBroadcastLists 获取当前用户的群发列表
+func (bc *BroadcastListController) GetBroadcastLists(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	lists, err := bc.listRepo.ListByOwner(userID.(int))
+	if err != nil {
+		utils.LogError("❌ [群发] 获取用户 %d 的群发列表失败: %v", userID, err)
+		utils.InternalServerError(c, "获取群发列表失败")
+		return
+	}
+
+	utils.Success(c, gin.H{"lists": lists})
+}
+
+// GetBroadcastList 获取群发列表详情（含成员）
+func (bc *BroadcastListController) GetBroadcastList(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	listID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的群发列表ID")
+		return
+	}
+
+	list, err := bc.listRepo.GetByID(listID, userID.(int))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "群发列表不存在")
+			return
+		}
+		utils.LogError("❌ [群发] 获取群发列表 %d 失败: %v", listID, err)
+		utils.InternalServerError(c, "获取群发列表失败")
+		return
+	}
+
+	utils.Success(c, list)
+}
+
+// CreateBroadcastList 创建群发列表
+func (bc *BroadcastListController) CreateBroadcastList(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	var req models.SaveBroadcastListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "无效的请求参数")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > maxBroadcastListNameLen {
+		utils.BadRequest(c, "列表名称不能为空且不能超过100个字符")
+		return
+	}
+
+	memberIDs, errMsg := bc.validateMembers(userID.(int), req.MemberIDs)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	listID, err := bc.listRepo.Create(userID.(int), req.Name, memberIDs)
+	if err != nil {
+		if err == models.ErrBroadcastListLimitReached {
+			utils.BadRequest(c, "群发列表数量已达上限（50个）")
+			return
+		}
+		utils.LogError("❌ [群发] 创建群发列表失败: %v", err)
+		utils.InternalServerError(c, "创建群发列表失败")
+		return
+	}
+
+	list, err := bc.listRepo.GetByID(listID, userID.(int))
+	if err != nil {
+		utils.LogError("❌ [群发] 获取群发列表 %d 失败: %v", listID, err)
+		utils.InternalServerError(c, "创建群发列表失败")
+		return
+	}
+
+	utils.LogInfo("📢 [群发] 用户 %d 创建群发列表 %d（%d 名成员）", userID, listID, list.MemberCount)
+	utils.SuccessWithMessage(c, "创建成功", list)
+}
+
+// UpdateBroadcastList 更新群发列表名称或成员（member_ids 会整体替换原成员）
+func (bc *BroadcastListController) UpdateBroadcastList(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	listID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的群发列表ID")
+		return
+	}
+
+	var req models.SaveBroadcastListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "无效的请求参数")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if utf8.RuneCountInString(req.Name) > maxBroadcastListNameLen {
+		utils.BadRequest(c, "列表名称不能超过100个字符")
+		return
+	}
+
+	var memberIDs []int
+	if req.MemberIDs != nil {
+		var errMsg string
+		memberIDs, errMsg = bc.validateMembers(userID.(int), req.MemberIDs)
+		if errMsg != "" {
+			utils.BadRequest(c, errMsg)
+			return
+		}
+	}
+
+	if err := bc.listRepo.Update(listID, userID.(int), req.Name, memberIDs); err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "群发列表不存在")
+			return
+		}
+		utils.LogError("❌ [群发] 更新群发列表 %d 失败: %v", listID, err)
+		utils.InternalServerError(c, "更新群发列表失败")
+		return
+	}
+
+	list, err := bc.listRepo.GetByID(listID, userID.(int))
+	if err != nil {
+		utils.LogError("❌ [群发] 获取群发列表 %d 失败: %v", listID, err)
+		utils.InternalServerError(c, "更新群发列表失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "更新成功", list)
+}
+
+// DeleteBroadcastList 删除群发列表
+func (bc *BroadcastListController) DeleteBroadcastList(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	listID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的群发列表ID")
+		return
+	}
+
+	deleted, err := bc.listRepo.Delete(listID, userID.(int))
+	if err != nil {
+		utils.LogError("❌ [群发] 删除群发列表 %d 失败: %v", listID, err)
+		utils.InternalServerError(c, "删除群发列表失败")
+		return
+	}
+	if !deleted {
+		utils.NotFound(c, "群发列表不存在")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// SendBroadcast 向群发列表中的每个成员分别发送一条私聊消息
+// 每个接收者独立校验好友关系、拉黑和删除状态，返回逐个接收者的投递结果
+func (bc *BroadcastListController) SendBroadcast(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	senderID := userID.(int)
+
+	listID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的群发列表ID")
+		return
+	}
+
+	var req models.SendBroadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "消息内容不能为空")
+		return
+	}
+	if req.MessageType == "" {
+		req.MessageType = "text"
+	}
+	// 加密消息的密文按接收者设备分别生成，无法群发同一份内容
+	if strings.HasPrefix(req.MessageType, "call_") || req.MessageType == models.MessageTypePoll || req.MessageType == models.MessageTypeEncrypted {
+		utils.BadRequest(c, "该消息类型不支持群发")
+		return
+	}
+
+	list, err := bc.listRepo.GetByID(listID, senderID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "群发列表不存在")
+			return
+		}
+		utils.LogError("❌ [群发] 获取群发列表 %d 失败: %v", listID, err)
+		utils.InternalServerError(c, "群发失败")
+		return
+	}
+
+	// 内容审核：群发内容对所有接收者相同，只需审核一次
+	moderation := moderateMessageContent(req.MessageType, req.Content)
+	if moderation.Blocked {
+		respondContentBlocked(c)
+		return
+	}
+	req.Content = moderation.Content
+	if len(list.Members) == 0 {
+		utils.BadRequest(c, "群发列表中没有成员")
+		return
+	}
+
+	mc := bc.messageCtrl
+	deliveries := make([]models.BroadcastDelivery, 0, len(list.Members))
+	sentCount := 0
+	// 链接预览未命中缓存时，发送完成后在后台只抓取一次，再更新所有接收者的消息
+	var previewURL string
+	var pendingPreviews []pendingLinkPreview
+	for _, member := range list.Members {
+		delivery := models.BroadcastDelivery{
+			UserID: member.UserID,
+			Name:   member.Username,
+			Status: "failed",
+		}
+		if member.FullName != nil && *member.FullName != "" {
+			delivery.Name = *member.FullName
+		}
+
+		if reason := mc.privateMessageBlockReason(senderID, member.UserID); reason != "" {
+			delivery.Reason = reason
+			deliveries = append(deliveries, delivery)
+			continue
+		}
+
+		content, err := normalizeMessageContent(senderID, member.UserID, 0, req.MessageType, req.Content)
+		if err != nil {
+			delivery.Reason = err.Error()
+			deliveries = append(deliveries, delivery)
+			continue
+		}
+
+		msg, err := mc.saveMessage(senderID, member.UserID, content, req.MessageType, req.FileName, 0, "", "", req.VoiceDuration)
+		if err != nil {
+			utils.LogError("❌ [群发] 保存发往用户 %d 的消息失败: %v", member.UserID, err)
+			delivery.Reason = "消息保存失败"
+			deliveries = append(deliveries, delivery)
+			continue
+		}
+		recordModerationFlag(moderation, models.ModerationSourceMessage, msg.ID, senderID, member.UserID, msg.Content)
+		linkPreview, pendingURL := lookupLinkPreview("messages", msg.ID, msg.MessageType, msg.Content)
+		msg.LinkPreview = linkPreview
+		if pendingURL != "" {
+			previewURL = pendingURL
+			pendingPreviews = append(pendingPreviews, pendingLinkPreview{messageID: msg.ID, recipientIDs: []int{senderID, member.UserID}})
+		}
+
+		receiverMsg := models.WSMessage{
+			Type: "message",
+			Data: models.WSMessageData{
+				ID:             msg.ID,
+				SenderID:       msg.SenderID,
+				ReceiverID:     msg.ReceiverID,
+				SenderName:     msg.SenderName,
+				ReceiverName:   msg.ReceiverName,
+				SenderAvatar:   msg.SenderAvatar,
+				ReceiverAvatar: msg.ReceiverAvatar,
+				Content:        msg.Content,
+				MessageType:    msg.MessageType,
+				FileName:       msg.FileName,
+				VoiceDuration:  msg.VoiceDuration,
+				LinkPreview:    msg.LinkPreview,
+				IsRead:         msg.IsRead,
+				CreatedAt:      msg.CreatedAt.UTC(),
+			},
+		}
+		receiverMsgBytes, _ := json.Marshal(receiverMsg)
+		if !mc.Hub.SendToUser(member.UserID, receiverMsgBytes) {
+			pushPrivateMessage(mc.Hub, msg)
+		}
+
+		delivery.Status = "sent"
+		delivery.Message = msg
+		deliveries = append(deliveries, delivery)
+		sentCount++
+	}
+
+	if len(pendingPreviews) > 0 {
+		go fetchLinkPreviewAsync(mc.Hub, "messages", 0, previewURL, pendingPreviews)
+	}
+
+	utils.LogInfo("📢 [群发] 用户 %d 通过列表 %d 群发消息 - 成功: %d, 失败: %d", senderID, listID, sentCount, len(deliveries)-sentCount)
+
+	utils.Success(c, gin.H{
+		"list_id":      listID,
+		"sent_count":   sentCount,
+		"failed_count": len(deliveries) - sentCount,
+		"deliveries":   deliveries,
+	})
+}
+
+// validateMembers 去重并校验群发列表成员，返回错误提示（为空表示通过）
+func (bc *BroadcastListController) validateMembers(ownerID int, memberIDs []int) ([]int, string) {
+	seen := make(map[int]bool)
+	ids := []int{}
+	for _, id := range memberIDs {
+		if id <= 0 || id == ownerID || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) > models.MaxBroadcastRecipients {
+		return nil, "群发列表成员不能超过200人"
+	}
+
+	existing, err := bc.listRepo.FilterExistingUsers(ids)
+	if err != nil {
+		utils.LogError("❌ [群发] 校验群发列表成员失败: %v", err)
+		return nil, "校验群发列表成员失败"
+	}
+	if len(existing) != len(ids) {
+		return nil, "群发列表中包含不存在的用户"
+	}
+	return ids, ""
+}
+
+// privateMessageBlockReason 检查发送者能否向接收者发送私聊消息，返回拦截原因（为空表示允许）
+// 与 handleSendMessage 的检查一致，但群发时检查失败按拦截处理
+func (mc *MessageController) privateMessageBlockReason(senderID, receiverID int) string {
+	approvalStatus, err := mc.contactRepo.CheckContactApprovalStatus(senderID, receiverID)
+	if err != nil {
+		return "检查好友关系失败"
+	}
+	switch approvalStatus {
+	case "rejected":
+		return "您的好友申请已被拒绝，无法发送消息"
+	case "pending":
+		return "您的好友申请待对方审核，暂时无法发送消息"
+	}
+
+	if blocked, err := mc.contactRepo.CheckContactBlocked(receiverID, senderID); err != nil {
+		return "检查拉黑状态失败"
+	} else if blocked {
+		return "该联系人已将您加入黑名单，无法发送消息"
+	}
+	if blocked, err := mc.contactRepo.CheckContactBlocked(senderID, receiverID); err != nil {
+		return "检查拉黑状态失败"
+	} else if blocked {
+		return "您已将该联系人加入黑名单，无法发送消息"
+	}
+
+	if exists, err := mc.contactRepo.CheckRelationExists(senderID, receiverID); err != nil {
+		return "检查好友关系失败"
+	} else if !exists {
+		return "您与该联系人不是好友关系，无法发送消息"
+	}
+
+	if deleted, err := mc.contactRepo.CheckContactDeleted(receiverID, senderID); err != nil {
+		return "检查删除状态失败"
+	} else if deleted {
+		return "该联系人已将您删除，无法发送消息"
+	}
+	if deleted, err := mc.contactRepo.CheckContactDeleted(senderID, receiverID); err != nil {
+		return "检查删除状态失败"
+	} else if deleted {
+		return "您已删除该联系人，无法发送消息"
+	}
+
+	return ""
+}
@@ -916,6 +916,13 @@ func (cc *CallController) EndCall(c *gin.Context) {
 
 // notifyCallEnded 通知对方通话已结束
 func (cc *CallController) notifyCallEnded(peerID int, channelName string, userID int) {
+	// 发布通话结束事件（与对方是否在线无关）
+	emitEvent(models.EventCallEnded, 0, gin.H{
+		"channel_name": channelName,
+		"user_id":      userID,
+		"peer_id":      peerID,
+	})
+
 	// 检查对方是否在线
 	if !cc.Hub.IsUserOnline(peerID) {
 		return
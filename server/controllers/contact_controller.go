@@ -525,6 +525,13 @@ func (ctrl *ContactController) UpdateContactApprovalStatus(c *gin.Context) {
 
 		// 向双方发送联系人状态变更通知，触发APP端更新通讯录缓存
 		ctrl.sendContactStatusChangeNotification(relation.UserID, currentUserID.(int), "approved", initiator, currentUser)
+
+		// 发布联系人通过事件
+		emitEvent(models.EventContactApproved, 0, gin.H{
+			"relation_id": relationID,
+			"user_id":     relation.UserID,
+			"friend_id":   currentUserID.(int),
+		})
 	} else if req.ApprovalStatus == "rejected" {
 		utils.LogDebug("审核拒绝，准备发送拒绝消息")
 		utils.LogDebug("关系信息: relationID=%d, userID=%d, friendID=%d", relationID, relation.UserID, relation.FriendID)
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConversationDraftController 会话草稿控制器
+type ConversationDraftController struct {
+	Hub       *ws.Hub
+	draftRepo *models.ConversationDraftRepository
+	groupRepo *models.GroupRepository
+}
+
+// NewConversationDraftController 创建会话草稿控制器
+func NewConversationDraftController(hub *ws.Hub) *ConversationDraftController {
+	return &ConversationDraftController{
+		Hub:       hub,
+		draftRepo: models.NewConversationDraftRepository(db.DB),
+		groupRepo: models.NewGroupRepository(db.DB),
+	}
+}
+
+// GetDrafts 获取当前用户的所有草稿
+// GET /api/drafts
+func (dc *ConversationDraftController) GetDrafts(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	drafts, err := dc.draftRepo.GetByUserID(userID.(int))
+	if err != nil {
+		utils.LogDebug("获取草稿失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "获取草稿失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"drafts": drafts,
+	})
+}
+
+// SaveDraft 保存草稿（内容和引用都为空时视为清除草稿）
+// PUT /api/drafts
+func (dc *ConversationDraftController) SaveDraft(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	uid := userID.(int)
+
+	var req models.SaveConversationDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	targetID, status, errMsg := resolveConversationTarget(dc.groupRepo, uid, req.ConversationType, req.TargetID)
+	if errMsg != "" {
+		utils.Error(c, status, errMsg)
+		return
+	}
+	req.TargetID = targetID
+
+	// 空草稿直接删除
+	if req.Content == "" && req.QuotedMessageID == 0 {
+		if _, err := dc.draftRepo.Delete(uid, req.ConversationType, req.TargetID); err != nil {
+			utils.LogDebug("删除草稿失败: %v", err)
+			utils.Error(c, http.StatusInternalServerError, "保存草稿失败")
+			return
+		}
+		notifyDraftUpdated(dc.Hub, uid, req.ConversationType, req.TargetID, nil)
+		utils.Success(c, gin.H{
+			"draft": nil,
+		})
+		return
+	}
+
+	draft, err := dc.draftRepo.Save(uid, &req)
+	if err != nil {
+		utils.LogDebug("保存草稿失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "保存草稿失败")
+		return
+	}
+
+	utils.LogDebug("✅ 用户 %d 保存草稿: type=%s, target_id=%d", uid, req.ConversationType, req.TargetID)
+
+	// 推送给用户的其他在线会话
+	notifyDraftUpdated(dc.Hub, uid, req.ConversationType, req.TargetID, draft)
+
+	utils.Success(c, gin.H{
+		"draft": draft,
+	})
+}
+
+// DeleteDraft 删除草稿
+// DELETE /api/drafts?conversation_type=user&target_id=2
+func (dc *ConversationDraftController) DeleteDraft(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	uid := userID.(int)
+
+	conversationType := c.Query("conversation_type")
+	targetID, _ := strconv.Atoi(c.DefaultQuery("target_id", "0"))
+	if !models.IsValidConversationType(conversationType) {
+		utils.Error(c, http.StatusBadRequest, "无效的会话类型")
+		return
+	}
+	if conversationType == models.ConversationTypeFileAssistant {
+		targetID = 0
+	}
+
+	deleted, err := dc.draftRepo.Delete(uid, conversationType, targetID)
+	if err != nil {
+		utils.LogDebug("删除草稿失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "删除草稿失败")
+		return
+	}
+
+	if deleted {
+		notifyDraftUpdated(dc.Hub, uid, conversationType, targetID, nil)
+	}
+
+	utils.Success(c, gin.H{
+		"message": "删除成功",
+	})
+}
+
+// clearDraftAfterSend 用户在会话中发送消息后自动清除草稿并通知其他在线会话
+func clearDraftAfterSend(hub *ws.Hub, userID int, conversationType string, targetID int) {
+	draftRepo := models.NewConversationDraftRepository(db.DB)
+	deleted, err := draftRepo.Delete(userID, conversationType, targetID)
+	if err != nil {
+		utils.LogDebug("⚠️ 发送消息后清除草稿失败: %v", err)
+		return
+	}
+	if deleted {
+		notifyDraftUpdated(hub, userID, conversationType, targetID, nil)
+	}
+}
+
+// notifyDraftUpdated 推送草稿变更通知（draft 为 nil 表示草稿已清除）
+func notifyDraftUpdated(hub *ws.Hub, userID int, conversationType string, targetID int, draft *models.ConversationDraft) {
+	if hub == nil {
+		return
+	}
+
+	wsMsg := models.WSMessage{
+		Type: "draft_updated",
+		Data: gin.H{
+			"conversation_type": conversationType,
+			"target_id":         targetID,
+			"draft":             draft,
+		},
+	}
+
+	messageBytes, err := json.Marshal(wsMsg)
+	if err != nil {
+		utils.LogDebug("序列化草稿通知失败: %v", err)
+		return
+	}
+
+	hub.SendToUser(userID, messageBytes)
+}
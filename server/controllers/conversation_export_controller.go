@@ -0,0 +1,738 @@
+package controllers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// 导出相关限制
+const (
+	exportMaxMessages       = 100000            // 单次导出的最大消息数
+	exportMaxAttachmentSize = 200 * 1024 * 1024 // 单个附件最大字节数，超过则只保留链接
+	exportListLimit         = 20                // 任务列表返回条数
+)
+
+// ConversationExportController 会话导出控制器
+type ConversationExportController struct {
+	Hub        *ws.Hub
+	exportRepo *models.ConversationExportRepository
+	groupRepo  *models.GroupRepository
+	userRepo   *models.UserRepository
+}
+
+// NewConversationExportController 创建会话导出控制器
+func NewConversationExportController(hub *ws.Hub) *ConversationExportController {
+	return &ConversationExportController{
+		Hub:        hub,
+		exportRepo: models.NewConversationExportRepository(db.DB),
+		groupRepo:  models.NewGroupRepository(db.DB),
+		userRepo:   models.NewUserRepository(db.DB),
+	}
+}
+
+// exportedMessage 导出文件中的单条消息
+type exportedMessage struct {
+	ID                   int       `json:"id"`
+	SenderID             int       `json:"sender_id"`
+	SenderName           string    `json:"sender_name"`
+	Content              string    `json:"content"`
+	MessageType          string    `json:"message_type"`
+	FileName             string    `json:"file_name,omitempty"`
+	QuotedMessageContent string    `json:"quoted_message_content,omitempty"`
+	Status               string    `json:"status"`
+	Attachment           string    `json:"attachment,omitempty"` // ZIP 包内的附件路径
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+// exportArchive 导出文件的 JSON 结构
+type exportArchive struct {
+	ConversationType string            `json:"conversation_type"`
+	TargetID         int               `json:"target_id"`
+	Title            string            `json:"title"`
+	ExportedBy       int               `json:"exported_by"`
+	ExportedAt       time.Time         `json:"exported_at"`
+	StartTime        *time.Time        `json:"start_time,omitempty"`
+	EndTime          *time.Time        `json:"end_time,omitempty"`
+	MessageCount     int               `json:"message_count"`
+	Messages         []exportedMessage `json:"messages"`
+}
+
+// CreateExport 创建会话导出任务
+// POST /api/exports
+func (ec *ConversationExportController) CreateExport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	uid := userID.(int)
+
+	var req models.CreateConversationExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	targetID, status, errMsg := resolveConversationTarget(ec.groupRepo, uid, req.ConversationType, req.TargetID)
+	if errMsg != "" {
+		utils.Error(c, status, errMsg)
+		return
+	}
+	req.TargetID = targetID
+
+	export, errMsg := ec.startExport(uid, &req, false)
+	if errMsg != "" {
+		utils.Error(c, http.StatusBadRequest, errMsg)
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"export": export,
+	})
+}
+
+// AdminCreateExport 管理后台发起的合规导出（可导出指定用户的任意会话）
+// POST /api/admin/exports
+func (ec *ConversationExportController) AdminCreateExport(c *gin.Context) {
+	var req struct {
+		UserID int `json:"user_id" binding:"required"`
+		models.CreateConversationExportRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if !models.IsValidConversationType(req.ConversationType) {
+		utils.BadRequest(c, "无效的会话类型")
+		return
+	}
+	if req.ConversationType == models.ConversationTypeFileAssistant {
+		req.TargetID = 0
+	}
+	if _, err := ec.userRepo.FindByID(req.UserID); err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	export, errMsg := ec.startExport(req.UserID, &req.CreateConversationExportRequest, true)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"export": export,
+	})
+}
+
+// ListExports 获取当前用户的导出任务列表
+// GET /api/exports
+func (ec *ConversationExportController) ListExports(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	exports, err := ec.exportRepo.ListByUserID(userID.(int), exportListLimit)
+	if err != nil {
+		utils.LogDebug("获取导出任务列表失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "获取导出任务失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"exports": exports,
+	})
+}
+
+// GetExport 获取导出任务详情（完成后返回签名下载链接）
+// GET /api/exports/:id
+func (ec *ConversationExportController) GetExport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	exportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的任务ID")
+		return
+	}
+
+	export, err := ec.exportRepo.GetByID(exportID)
+	if err != nil || export.UserID != userID.(int) || export.RequestedByAdmin {
+		utils.Error(c, http.StatusNotFound, "导出任务不存在")
+		return
+	}
+
+	ec.respondExport(c, export)
+}
+
+// AdminGetExport 管理后台获取导出任务详情
+// GET /api/admin/exports/:id
+func (ec *ConversationExportController) AdminGetExport(c *gin.Context) {
+	exportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	export, err := ec.exportRepo.GetByID(exportID)
+	if err != nil {
+		utils.NotFound(c, "导出任务不存在")
+		return
+	}
+
+	ec.respondExport(c, export)
+}
+
+// respondExport 返回导出任务，已完成时附带签名下载链接
+func (ec *ConversationExportController) respondExport(c *gin.Context, export *models.ConversationExport) {
+	result := gin.H{
+		"export": export,
+	}
+
+	if export.Status == models.ExportStatusCompleted && export.ObjectKey != nil {
+		downloadURL, err := signExportDownloadURL(*export.ObjectKey)
+		if err != nil {
+			utils.LogDebug("生成导出下载链接失败: %v", err)
+			utils.Error(c, http.StatusInternalServerError, "生成下载链接失败")
+			return
+		}
+		result["download_url"] = downloadURL
+		result["expires_in"] = defaultSignedURLExpiry
+	}
+
+	utils.Success(c, result)
+}
+
+// startExport 校验时间范围、创建任务并在后台执行
+func (ec *ConversationExportController) startExport(userID int, req *models.CreateConversationExportRequest, byAdmin bool) (*models.ConversationExport, string) {
+	startTime, err := parseExportTime(req.StartTime, false)
+	if err != nil {
+		return nil, "起始时间格式错误"
+	}
+	endTime, err := parseExportTime(req.EndTime, true)
+	if err != nil {
+		return nil, "结束时间格式错误"
+	}
+	if startTime != nil && endTime != nil && !startTime.Before(*endTime) {
+		return nil, "起始时间必须早于结束时间"
+	}
+
+	export := &models.ConversationExport{
+		UserID:             userID,
+		ConversationType:   req.ConversationType,
+		TargetID:           req.TargetID,
+		StartTime:          startTime,
+		EndTime:            endTime,
+		IncludeAttachments: req.IncludeAttachments,
+		RequestedByAdmin:   byAdmin,
+	}
+	if err := ec.exportRepo.Create(export); err != nil {
+		utils.LogDebug("创建导出任务失败: %v", err)
+		return nil, "创建导出任务失败"
+	}
+
+	utils.LogInfo("📦 创建会话导出任务 %d - 用户: %d, 类型: %s, 目标: %d, 管理后台: %v", export.ID, userID, req.ConversationType, req.TargetID, byAdmin)
+
+	// 后台任务使用副本，避免与响应序列化并发读写
+	job := *export
+	go ec.runExport(&job)
+
+	return export, ""
+}
+
+// runExport 执行导出任务：读取消息 -> 生成 ZIP -> 上传 OSS
+func (ec *ConversationExportController) runExport(export *models.ConversationExport) {
+	defer func() {
+		if r := recover(); r != nil {
+			utils.LogError("❌ 会话导出任务 %d 异常: %v", export.ID, r)
+			ec.failExport(export, "导出任务异常")
+		}
+	}()
+
+	ec.reportProgress(export, 0, 0)
+
+	title, err := ec.exportTitle(export)
+	if err != nil {
+		ec.failExport(export, "会话不存在")
+		return
+	}
+
+	messages, err := loadExportMessages(export)
+	if err != nil {
+		utils.LogError("❌ 会话导出任务 %d 读取消息失败: %v", export.ID, err)
+		ec.failExport(export, "读取消息失败")
+		return
+	}
+	ec.reportProgress(export, 10, len(messages))
+
+	tmpFile, err := os.CreateTemp("", fmt.Sprintf("export_%d_*.zip", export.ID))
+	if err != nil {
+		ec.failExport(export, "创建临时文件失败")
+		return
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	// 附件下载和 ZIP 上传都需要 OSS
+	ossCtx, err := NewOSSController().getOSSContext()
+	if err != nil {
+		utils.LogError("❌ 会话导出任务 %d 获取OSS失败: %v", export.ID, err)
+		ec.failExport(export, "存储服务不可用")
+		return
+	}
+
+	zipWriter := zip.NewWriter(tmpFile)
+
+	// 附件（可选）
+	if export.IncludeAttachments {
+		ec.writeExportAttachments(zipWriter, ossCtx, export, messages)
+	}
+	ec.reportProgress(export, 70, len(messages))
+
+	archive := exportArchive{
+		ConversationType: export.ConversationType,
+		TargetID:         export.TargetID,
+		Title:            title,
+		ExportedBy:       export.UserID,
+		ExportedAt:       time.Now().UTC(),
+		StartTime:        export.StartTime,
+		EndTime:          export.EndTime,
+		MessageCount:     len(messages),
+		Messages:         messages,
+	}
+	if err := writeExportFiles(zipWriter, &archive); err != nil {
+		utils.LogError("❌ 会话导出任务 %d 生成文件失败: %v", export.ID, err)
+		ec.failExport(export, "生成导出文件失败")
+		return
+	}
+	if err := zipWriter.Close(); err != nil {
+		ec.failExport(export, "生成导出文件失败")
+		return
+	}
+	ec.reportProgress(export, 85, len(messages))
+
+	// 上传到 OSS
+	info, err := tmpFile.Stat()
+	if err != nil {
+		ec.failExport(export, "生成导出文件失败")
+		return
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		ec.failExport(export, "生成导出文件失败")
+		return
+	}
+	objectKey := fmt.Sprintf("exports/%d/%d_%d.zip", export.UserID, export.ID, time.Now().Unix())
+	if err := ossCtx.bucket.PutObject(objectKey, tmpFile, oss.ContentType("application/zip"),
+		oss.ContentDisposition(fmt.Sprintf("attachment; filename=\"chat_export_%d.zip\"", export.ID))); err != nil {
+		utils.LogError("❌ 会话导出任务 %d 上传失败: %v", export.ID, err)
+		ec.failExport(export, "上传导出文件失败")
+		return
+	}
+
+	if err := ec.exportRepo.MarkCompleted(export.ID, objectKey, info.Size(), len(messages)); err != nil {
+		utils.LogError("❌ 会话导出任务 %d 更新状态失败: %v", export.ID, err)
+		return
+	}
+	export.Status = models.ExportStatusCompleted
+	export.Progress = 100
+	export.MessageCount = len(messages)
+	export.FileSize = info.Size()
+
+	utils.LogInfo("✅ 会话导出任务 %d 完成 - 消息数: %d, 大小: %d 字节", export.ID, len(messages), info.Size())
+	ec.notifyExport(export, "conversation_export_completed")
+}
+
+// writeExportAttachments 下载消息中引用的 OSS 附件并写入 ZIP，失败时仅保留原链接
+func (ec *ConversationExportController) writeExportAttachments(zipWriter *zip.Writer, ossCtx *ossContext, export *models.ConversationExport, messages []exportedMessage) {
+	total := 0
+	for _, msg := range messages {
+		if isAttachmentMessageType(msg.MessageType) && msg.Status != "recalled" {
+			total++
+		}
+	}
+
+	done := 0
+	for i := range messages {
+		msg := &messages[i]
+		if !isAttachmentMessageType(msg.MessageType) || msg.Status == "recalled" {
+			continue
+		}
+		done++
+
+		objectKey := ossObjectKeyFromURL(ossCtx, msg.Content)
+		if objectKey == "" {
+			continue
+		}
+
+		meta, err := ossCtx.bucket.GetObjectMeta(objectKey)
+		if err != nil {
+			utils.LogDebug("⚠️ 导出附件不存在: %s, %v", objectKey, err)
+			continue
+		}
+		if size, _ := strconv.ParseInt(meta.Get("Content-Length"), 10, 64); size > exportMaxAttachmentSize {
+			utils.LogDebug("⚠️ 导出附件过大，仅保留链接: %s (%d 字节)", objectKey, size)
+			continue
+		}
+
+		name := path.Base(objectKey)
+		if msg.FileName != "" {
+			name = msg.FileName
+		}
+		entryName := fmt.Sprintf("attachments/%d_%s", msg.ID, sanitizeExportFileName(name))
+
+		body, err := ossCtx.bucket.GetObject(objectKey)
+		if err != nil {
+			utils.LogDebug("⚠️ 下载导出附件失败: %s, %v", objectKey, err)
+			continue
+		}
+		writer, err := zipWriter.Create(entryName)
+		if err == nil {
+			_, err = io.Copy(writer, body)
+		}
+		body.Close()
+		if err != nil {
+			utils.LogDebug("⚠️ 写入导出附件失败: %s, %v", objectKey, err)
+			continue
+		}
+		msg.Attachment = entryName
+
+		// 附件阶段占 10%-70%
+		if total > 0 {
+			ec.reportProgress(export, 10+done*60/total, len(messages))
+		}
+	}
+}
+
+// exportTitle 生成导出标题（对方名称、群名称或文件助手）
+func (ec *ConversationExportController) exportTitle(export *models.ConversationExport) (string, error) {
+	switch export.ConversationType {
+	case models.ConversationTypeGroup:
+		group, err := ec.groupRepo.GetGroupByID(export.TargetID)
+		if err != nil {
+			return "", err
+		}
+		return group.Name, nil
+	case models.ConversationTypeUser:
+		user, err := ec.userRepo.FindByID(export.TargetID)
+		if err != nil {
+			return "", err
+		}
+		if user.FullName != nil && *user.FullName != "" {
+			return *user.FullName, nil
+		}
+		return user.Username, nil
+	}
+	return "文件传输助手", nil
+}
+
+// reportProgress 更新任务进度并推送给用户（管理后台任务不推送）
+func (ec *ConversationExportController) reportProgress(export *models.ConversationExport, progress, messageCount int) {
+	if progress <= export.Progress && export.Status == models.ExportStatusRunning {
+		return
+	}
+	// 小幅变化不推送，避免频繁写库
+	if export.Status == models.ExportStatusRunning && progress-export.Progress < 5 && progress < 100 {
+		return
+	}
+
+	export.Status = models.ExportStatusRunning
+	export.Progress = progress
+	export.MessageCount = messageCount
+	if err := ec.exportRepo.UpdateProgress(export.ID, export.Status, progress, messageCount); err != nil {
+		utils.LogDebug("更新导出进度失败: %v", err)
+	}
+	ec.notifyExport(export, "conversation_export_progress")
+}
+
+// failExport 标记任务失败并通知用户
+func (ec *ConversationExportController) failExport(export *models.ConversationExport, reason string) {
+	if err := ec.exportRepo.MarkFailed(export.ID, reason); err != nil {
+		utils.LogDebug("更新导出任务状态失败: %v", err)
+	}
+	export.Status = models.ExportStatusFailed
+	export.ErrorMessage = &reason
+	ec.notifyExport(export, "conversation_export_failed")
+}
+
+// notifyExport 推送导出任务状态
+func (ec *ConversationExportController) notifyExport(export *models.ConversationExport, msgType string) {
+	if ec.Hub == nil || export.RequestedByAdmin {
+		return
+	}
+
+	wsMsg := models.WSMessage{
+		Type: msgType,
+		Data: export,
+	}
+	msgBytes, err := json.Marshal(wsMsg)
+	if err != nil {
+		return
+	}
+	ec.Hub.SendToUser(export.UserID, msgBytes)
+}
+
+// loadExportMessages 读取待导出的消息（已被该用户删除的消息不导出）
+func loadExportMessages(export *models.ConversationExport) ([]exportedMessage, error) {
+	userIDStr := strconv.Itoa(export.UserID)
+
+	var query string
+	var args []interface{}
+	switch export.ConversationType {
+	case models.ConversationTypeUser:
+		query = `
+			SELECT id, sender_id, sender_name, content, message_type, COALESCE(file_name, ''), COALESCE(quoted_message_content, ''), status, created_at
+			FROM messages
+			WHERE ((sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1))
+				AND (deleted_by_users = '' OR deleted_by_users NOT LIKE '%' || $3 || '%')
+				AND ($4::timestamp IS NULL OR created_at >= $4)
+				AND ($5::timestamp IS NULL OR created_at < $5)
+			ORDER BY created_at ASC
+			LIMIT $6
+		`
+		args = []interface{}{export.UserID, export.TargetID, userIDStr, export.StartTime, export.EndTime, exportMaxMessages}
+	case models.ConversationTypeGroup:
+		query = `
+			SELECT id, COALESCE(sender_id, 0), sender_name, content, message_type, COALESCE(file_name, ''), COALESCE(quoted_message_content, ''), status, created_at
+			FROM group_messages
+			WHERE group_id = $1
+				AND (deleted_by_users = '' OR deleted_by_users NOT LIKE '%' || $2 || '%')
+				AND ($3::timestamp IS NULL OR created_at >= $3)
+				AND ($4::timestamp IS NULL OR created_at < $4)
+			ORDER BY created_at ASC
+			LIMIT $5
+		`
+		args = []interface{}{export.TargetID, userIDStr, export.StartTime, export.EndTime, exportMaxMessages}
+	default:
+		query = `
+			SELECT fam.id, fam.user_id, COALESCE(NULLIF(u.full_name, ''), u.username), fam.content, fam.message_type,
+				COALESCE(fam.file_name, ''), COALESCE(fam.quoted_message_content, ''), fam.status, fam.created_at
+			FROM file_assistant_messages fam
+			JOIN users u ON u.id = fam.user_id
+			WHERE fam.user_id = $1
+				AND ($2::timestamp IS NULL OR fam.created_at >= $2)
+				AND ($3::timestamp IS NULL OR fam.created_at < $3)
+			ORDER BY fam.created_at ASC
+			LIMIT $4
+		`
+		args = []interface{}{export.UserID, export.StartTime, export.EndTime, exportMaxMessages}
+	}
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []exportedMessage{}
+	for rows.Next() {
+		var msg exportedMessage
+		if err := rows.Scan(&msg.ID, &msg.SenderID, &msg.SenderName, &msg.Content, &msg.MessageType, &msg.FileName, &msg.QuotedMessageContent, &msg.Status, &msg.CreatedAt); err != nil {
+			return nil, err
+		}
+		if msg.Status == "recalled" {
+			msg.Content = "此消息已被撤销"
+			msg.FileName = ""
+		} else if msg.MessageType == models.MessageTypeEncrypted {
+			// 服务端无法解密，导出时只保留占位文本
+			msg.Content = models.EncryptedMessagePreview
+		}
+		msg.CreatedAt = msg.CreatedAt.UTC()
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+// writeExportFiles 写入 messages.json、transcript.html 和 transcript.txt
+func writeExportFiles(zipWriter *zip.Writer, archive *exportArchive) error {
+	jsonWriter, err := zipWriter.Create("messages.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(jsonWriter)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(archive); err != nil {
+		return err
+	}
+
+	htmlWriter, err := zipWriter.Create("transcript.html")
+	if err != nil {
+		return err
+	}
+	if err := exportHTMLTemplate.Execute(htmlWriter, archive); err != nil {
+		return err
+	}
+
+	textWriter, err := zipWriter.Create("transcript.txt")
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s\n导出时间: %s\n消息数量: %d\n\n", archive.Title, archive.ExportedAt.Format("2006-01-02 15:04:05 UTC"), archive.MessageCount))
+	for _, msg := range archive.Messages {
+		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n", msg.CreatedAt.Format("2006-01-02 15:04:05"), msg.SenderName, exportDisplayContent(msg)))
+	}
+	_, err = io.WriteString(textWriter, sb.String())
+	return err
+}
+
+// exportDisplayContent 纯文本中的消息展示内容
+func exportDisplayContent(msg exportedMessage) string {
+	content := msg.Content
+	switch msg.MessageType {
+	case "image":
+		content = "[图片] " + content
+	case "video":
+		content = "[视频] " + content
+	case "audio":
+		content = "[语音] " + content
+	case "file":
+		content = fmt.Sprintf("[文件] %s %s", msg.FileName, content)
+	case models.MessageTypeLocation, models.MessageTypeContactCard, models.MessageTypePoll, models.MessageTypeRichText, models.MessageTypeSticker, models.MessageTypeEncrypted:
+		content = models.MessagePreviewText(msg.MessageType, content)
+	}
+	if msg.Attachment != "" {
+		content += " (" + msg.Attachment + ")"
+	}
+	if msg.QuotedMessageContent != "" {
+		content = fmt.Sprintf("「%s」\n    %s", msg.QuotedMessageContent, content)
+	}
+	return content
+}
+
+var exportHTMLTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"formatTime": func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+	"isImage":    func(messageType string) bool { return messageType == "image" },
+	"isMedia":    isAttachmentMessageType,
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} - 聊天记录</title>
+<style>
+body{font-family:-apple-system,"PingFang SC","Microsoft YaHei",sans-serif;background:#f5f5f5;margin:0;padding:24px;color:#333}
+.header{max-width:800px;margin:0 auto 16px}
+.header h1{font-size:20px;margin:0 0 4px}
+.header p{margin:0;color:#888;font-size:13px}
+.msg{max-width:800px;margin:0 auto 8px;background:#fff;border-radius:6px;padding:10px 14px}
+.meta{font-size:12px;color:#999;margin-bottom:4px}
+.meta b{color:#576b95;font-weight:normal;margin-right:8px}
+.content{white-space:pre-wrap;word-break:break-word}
+.quote{border-left:3px solid #ddd;padding-left:8px;color:#888;font-size:13px;margin-bottom:4px}
+.recalled{color:#aaa;font-style:italic}
+img{max-width:320px;border-radius:4px}
+</style>
+</head>
+<body>
+<div class="header">
+<h1>{{.Title}}</h1>
+<p>导出时间 {{formatTime .ExportedAt}} UTC · 共 {{.MessageCount}} 条消息</p>
+</div>
+{{range .Messages}}<div class="msg">
+<div class="meta"><b>{{.SenderName}}</b>{{formatTime .CreatedAt}}</div>
+{{if .QuotedMessageContent}}<div class="quote">{{.QuotedMessageContent}}</div>{{end}}
+{{if eq .Status "recalled"}}<div class="content recalled">{{.Content}}</div>
+{{else if and (isImage .MessageType) .Attachment}}<div class="content"><img src="{{.Attachment}}" alt="图片"></div>
+{{else if .Attachment}}<div class="content"><a href="{{.Attachment}}">{{if .FileName}}{{.FileName}}{{else}}{{.Attachment}}{{end}}</a></div>
+{{else if isMedia .MessageType}}<div class="content"><a href="{{.Content}}">{{if .FileName}}{{.FileName}}{{else}}{{.Content}}{{end}}</a></div>
+{{else}}<div class="content">{{.Content}}</div>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
+
+// isAttachmentMessageType 是否为引用OSS文件的消息类型
+func isAttachmentMessageType(messageType string) bool {
+	switch messageType {
+	case "image", "video", "file", "audio":
+		return true
+	}
+	return false
+}
+
+// ossObjectKeyFromURL 从消息中的文件URL解析OSS对象键（仅识别本系统的CDN域名和Bucket域名）
+func ossObjectKeyFromURL(ossCtx *ossContext, rawURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+
+	cdnDomain := os.Getenv("S3_CDN_DOMAIN")
+	if cdnDomain == "" {
+		cdnDomain = viper.GetString("S3_CDN_DOMAIN")
+	}
+	bucketHost := fmt.Sprintf("%s.%s", ossCtx.bucketName, strings.TrimPrefix(strings.TrimPrefix(ossCtx.endpoint, "https://"), "http://"))
+
+	if !strings.EqualFold(parsed.Host, cdnDomain) && !strings.EqualFold(parsed.Host, bucketHost) {
+		return ""
+	}
+
+	objectKey, err := url.PathUnescape(strings.TrimPrefix(parsed.Path, "/"))
+	if err != nil {
+		return ""
+	}
+	return objectKey
+}
+
+// sanitizeExportFileName 去除文件名中的路径分隔符等特殊字符
+func sanitizeExportFileName(name string) string {
+	replacer := strings.NewReplacer("/", "_", "\\", "_", "..", "_", ":", "_")
+	name = replacer.Replace(name)
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// parseExportTime 解析导出时间范围，支持 RFC3339 和 2006-01-02；按天的结束时间包含当天
+func parseExportTime(value string, isEnd bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if isEnd {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// signExportDownloadURL 生成导出文件的签名下载链接
+func signExportDownloadURL(objectKey string) (string, error) {
+	ossCtx, err := NewOSSController().getOSSContext()
+	if err != nil {
+		return "", err
+	}
+	return ossCtx.bucket.SignURL(objectKey, oss.HTTPGet, defaultSignedURLExpiry)
+}
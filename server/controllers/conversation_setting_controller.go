@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConversationSettingController 会话设置控制器
+type ConversationSettingController struct {
+	Hub         *ws.Hub
+	settingRepo *models.ConversationSettingRepository
+	groupRepo   *models.GroupRepository
+}
+
+// NewConversationSettingController 创建会话设置控制器
+func NewConversationSettingController(hub *ws.Hub) *ConversationSettingController {
+	return &ConversationSettingController{
+		Hub:         hub,
+		settingRepo: models.NewConversationSettingRepository(db.DB),
+		groupRepo:   models.NewGroupRepository(db.DB),
+	}
+}
+
+// GetConversationSettings 获取当前用户的所有会话设置
+// GET /api/conversation-settings
+func (csc *ConversationSettingController) GetConversationSettings(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	settings, err := csc.settingRepo.GetByUserID(userID.(int))
+	if err != nil {
+		utils.LogDebug("获取会话设置失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "获取会话设置失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"settings": settings,
+	})
+}
+
+// UpdateConversationSetting 更新会话设置（置顶、免打扰、归档、标记未读、隐藏）
+// PUT /api/conversation-settings
+func (csc *ConversationSettingController) UpdateConversationSetting(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	uid := userID.(int)
+
+	var req models.UpdateConversationSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+
+	targetID, status, errMsg := resolveConversationTarget(csc.groupRepo, uid, req.ConversationType, req.TargetID)
+	if errMsg != "" {
+		utils.Error(c, status, errMsg)
+		return
+	}
+	req.TargetID = targetID
+
+	setting, err := csc.settingRepo.Update(uid, &req)
+	if err != nil {
+		utils.LogDebug("更新会话设置失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "更新会话设置失败")
+		return
+	}
+
+	utils.LogDebug("✅ 用户 %d 更新会话设置: type=%s, target_id=%d", uid, req.ConversationType, req.TargetID)
+
+	// 通知当前用户的在线会话同步设置
+	csc.notifyConversationSettingUpdated(uid, setting)
+
+	utils.Success(c, gin.H{
+		"setting": setting,
+	})
+}
+
+// notifyConversationSettingUpdated 推送会话设置变更通知
+func (csc *ConversationSettingController) notifyConversationSettingUpdated(userID int, setting *models.ConversationSetting) {
+	if csc.Hub == nil {
+		return
+	}
+
+	wsMsg := models.WSMessage{
+		Type: "conversation_settings_updated",
+		Data: gin.H{
+			"setting":  setting,
+			"is_muted": setting.IsMuted(),
+		},
+	}
+
+	messageBytes, err := json.Marshal(wsMsg)
+	if err != nil {
+		utils.LogDebug("序列化会话设置通知失败: %v", err)
+		return
+	}
+
+	csc.Hub.SendToUser(userID, messageBytes)
+}
+
+// resolveConversationTarget 校验会话类型与目标ID，返回规范化后的目标ID、错误状态码和错误提示（为空表示校验通过）
+func resolveConversationTarget(groupRepo *models.GroupRepository, userID int, conversationType string, targetID int) (int, int, string) {
+	switch conversationType {
+	case models.ConversationTypeFileAssistant:
+		// 文件助手会话的目标ID固定为0
+		return 0, 0, ""
+	case models.ConversationTypeGroup:
+		if targetID <= 0 {
+			return 0, http.StatusBadRequest, "无效的群组ID"
+		}
+		isMember, err := groupRepo.IsGroupMember(targetID, userID)
+		if err != nil {
+			utils.LogDebug("检查群组成员失败: %v", err)
+			return 0, http.StatusInternalServerError, "检查群组成员失败"
+		}
+		if !isMember {
+			return 0, http.StatusForbidden, "您不是该群组成员"
+		}
+		return targetID, 0, ""
+	case models.ConversationTypeUser:
+		if targetID <= 0 || targetID == userID {
+			return 0, http.StatusBadRequest, "无效的用户ID"
+		}
+		return targetID, 0, ""
+	}
+	return 0, http.StatusBadRequest, "无效的会话类型"
+}
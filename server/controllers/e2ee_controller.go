@@ -0,0 +1,393 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 密钥包获取限流：每个用户每个窗口最多获取的次数，防止恶意耗尽他人的一次性预共享公钥
+const (
+	e2eeBundleRateLimit  = 60
+	e2eeBundleRateWindow = time.Minute
+)
+
+// e2eeBundleLimiter 密钥包获取限流器（按请求者计数）
+var e2eeBundleLimiter = utils.NewRateLimiter(e2eeBundleRateLimit, e2eeBundleRateWindow)
+
+// E2EEController 端到端加密公钥目录控制器
+// 服务端只保存和分发公钥，加密消息通过普通私聊通道原样转发
+type E2EEController struct {
+	Hub         *ws.Hub
+	e2eeRepo    *models.E2EERepository
+	contactRepo *models.ContactRepository
+	userRepo    *models.UserRepository
+	botRepo     *models.BotRepository
+}
+
+// NewE2EEController 创建端到端加密控制器
+func NewE2EEController(hub *ws.Hub) *E2EEController {
+	return &E2EEController{
+		Hub:         hub,
+		e2eeRepo:    models.NewE2EERepository(db.DB),
+		contactRepo: models.NewContactRepository(db.DB),
+		userRepo:    models.NewUserRepository(db.DB),
+		botRepo:     models.NewBotRepository(db.DB),
+	}
+}
+
+// PublishDevice 发布或更新当前设备的密钥包
+// PUT /api/e2ee/devices/:device_id
+func (ec *E2EEController) PublishDevice(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	deviceID := c.Param("device_id")
+	if !models.ValidE2EEDeviceID(deviceID) {
+		utils.BadRequest(c, "无效的设备标识")
+		return
+	}
+
+	var req models.PublishE2EEDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if err := req.Validate(); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	device := &models.E2EEDevice{
+		UserID:                userID.(int),
+		DeviceID:              deviceID,
+		RegistrationID:        req.RegistrationID,
+		IdentityKey:           req.IdentityKey,
+		SignedPrekeyID:        req.SignedPrekeyID,
+		SignedPrekey:          req.SignedPrekey,
+		SignedPrekeySignature: req.SignedPrekeySignature,
+	}
+	previousIdentityKey, err := ec.e2eeRepo.PublishDevice(device, req.OneTimePrekeys)
+	if err != nil {
+		if err == models.ErrE2EEDeviceLimitReached {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+		utils.LogError("❌ [端到端加密] 发布设备密钥包失败 - 用户: %d, 设备: %s, 错误: %v", userID.(int), deviceID, err)
+		utils.InternalServerError(c, "发布密钥失败")
+		return
+	}
+
+	switch {
+	case previousIdentityKey == "":
+		ec.notifyKeyChanged(userID.(int), deviceID, models.E2EEKeyChangeDeviceAdded, device.IdentityKey)
+	case previousIdentityKey != device.IdentityKey:
+		ec.notifyKeyChanged(userID.(int), deviceID, models.E2EEKeyChangeIdentityChanged, device.IdentityKey)
+	}
+
+	remaining, err := ec.e2eeRepo.CountOneTimePrekeys(userID.(int), deviceID)
+	if err != nil {
+		utils.LogDebug("⚠️ [端到端加密] 统计一次性预共享公钥失败: %v", err)
+	}
+
+	utils.LogDebug("🔐 [端到端加密] 用户 %d 发布设备 %s 密钥包，剩余一次性预共享公钥 %d 个", userID.(int), deviceID, remaining)
+	utils.SuccessWithMessage(c, "密钥已发布", gin.H{
+		"device":            device,
+		"prekeys_remaining": remaining,
+	})
+}
+
+// DeleteDevice 移除当前用户的加密设备（退出登录或重置加密时调用）
+// DELETE /api/e2ee/devices/:device_id
+func (ec *E2EEController) DeleteDevice(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	deviceID := c.Param("device_id")
+	deleted, err := ec.e2eeRepo.DeleteDevice(userID.(int), deviceID)
+	if err != nil {
+		utils.LogError("❌ [端到端加密] 移除设备失败 - 用户: %d, 设备: %s, 错误: %v", userID.(int), deviceID, err)
+		utils.InternalServerError(c, "移除设备失败")
+		return
+	}
+	if !deleted {
+		utils.NotFound(c, "设备不存在")
+		return
+	}
+
+	ec.notifyKeyChanged(userID.(int), deviceID, models.E2EEKeyChangeDeviceRemoved, "")
+	utils.SuccessWithMessage(c, "设备已移除", nil)
+}
+
+// UploadPrekeys 补充一次性预共享公钥
+// POST /api/e2ee/devices/:device_id/prekeys
+func (ec *E2EEController) UploadPrekeys(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	deviceID := c.Param("device_id")
+	var req models.UploadE2EEPrekeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if err := models.ValidateE2EEPrekeys(req.OneTimePrekeys); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	if _, err := ec.e2eeRepo.GetDevice(userID.(int), deviceID); err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "设备未发布密钥")
+			return
+		}
+		utils.InternalServerError(c, "上传失败")
+		return
+	}
+
+	if err := ec.e2eeRepo.AddOneTimePrekeys(userID.(int), deviceID, req.OneTimePrekeys); err != nil {
+		utils.LogError("❌ [端到端加密] 上传一次性预共享公钥失败 - 用户: %d, 设备: %s, 错误: %v", userID.(int), deviceID, err)
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	remaining, err := ec.e2eeRepo.CountOneTimePrekeys(userID.(int), deviceID)
+	if err != nil {
+		utils.InternalServerError(c, "统计失败")
+		return
+	}
+	utils.Success(c, gin.H{"prekeys_remaining": remaining})
+}
+
+// GetPrekeyCount 获取当前设备剩余的一次性预共享公钥数量
+// GET /api/e2ee/devices/:device_id/prekeys/count
+func (ec *E2EEController) GetPrekeyCount(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	remaining, err := ec.e2eeRepo.CountOneTimePrekeys(userID.(int), c.Param("device_id"))
+	if err != nil {
+		utils.InternalServerError(c, "统计失败")
+		return
+	}
+	utils.Success(c, gin.H{
+		"prekeys_remaining": remaining,
+		"low_threshold":     models.E2EEPrekeyLowThreshold,
+	})
+}
+
+// GetUserDevices 获取用户的加密设备及身份公钥（用于核对安全码，不消费预共享公钥）
+// GET /api/e2ee/users/:id/devices
+func (ec *E2EEController) GetUserDevices(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的用户ID")
+		return
+	}
+	if errMsg := ec.checkKeyAccess(userID.(int), targetID); errMsg != "" {
+		utils.Forbidden(c, errMsg)
+		return
+	}
+
+	devices, err := ec.e2eeRepo.ListDevices(targetID)
+	if err != nil {
+		utils.LogError("❌ [端到端加密] 获取用户 %d 的加密设备失败: %v", targetID, err)
+		utils.InternalServerError(c, "获取设备失败")
+		return
+	}
+	utils.Success(c, devices)
+}
+
+// GetUserBundles 获取用户各设备的密钥包，用于发起 X3DH 会话
+// 每个设备消费一个一次性预共享公钥；可通过 device_id 只获取指定设备
+// GET /api/e2ee/users/:id/bundles?device_id=xxx
+func (ec *E2EEController) GetUserBundles(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的用户ID")
+		return
+	}
+	if errMsg := ec.checkKeyAccess(userID.(int), targetID); errMsg != "" {
+		utils.Forbidden(c, errMsg)
+		return
+	}
+
+	if ok, retryAfter := e2eeBundleLimiter.Allow(strconv.Itoa(userID.(int))); !ok {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		utils.ErrorWithData(c, http.StatusTooManyRequests, "请求过于频繁，请稍后再试", gin.H{"retry_after": seconds})
+		return
+	}
+
+	devices, err := ec.e2eeRepo.ListDevices(targetID)
+	if err != nil {
+		utils.LogError("❌ [端到端加密] 获取用户 %d 的加密设备失败: %v", targetID, err)
+		utils.InternalServerError(c, "获取密钥失败")
+		return
+	}
+
+	deviceFilter := c.Query("device_id")
+	bundles := []models.E2EEPrekeyBundle{}
+	for _, device := range devices {
+		if deviceFilter != "" && device.DeviceID != deviceFilter {
+			continue
+		}
+		prekey, err := ec.e2eeRepo.ClaimOneTimePrekey(targetID, device.DeviceID)
+		if err != nil {
+			utils.LogError("❌ [端到端加密] 获取一次性预共享公钥失败 - 用户: %d, 设备: %s, 错误: %v", targetID, device.DeviceID, err)
+			utils.InternalServerError(c, "获取密钥失败")
+			return
+		}
+		bundles = append(bundles, models.E2EEPrekeyBundle{E2EEDevice: device, OneTimePrekey: prekey})
+		if prekey != nil {
+			ec.checkPrekeysLow(targetID, device.DeviceID)
+		}
+	}
+
+	if deviceFilter != "" && len(bundles) == 0 {
+		utils.NotFound(c, "设备不存在")
+		return
+	}
+	utils.Success(c, bundles)
+}
+
+// checkKeyAccess 检查是否可以获取目标用户的公钥，返回错误提示（为空表示允许）
+// 机器人不参与端到端加密；存在拉黑关系时不提供公钥
+func (ec *E2EEController) checkKeyAccess(userID, targetID int) string {
+	if userID == targetID {
+		return ""
+	}
+	if _, err := ec.userRepo.FindByID(targetID); err != nil {
+		return "用户不存在"
+	}
+	if isBot, err := ec.botRepo.IsBot(targetID); err == nil && isBot {
+		return "机器人账号不支持端到端加密"
+	}
+	if blocked, err := ec.contactRepo.CheckContactBlocked(targetID, userID); err == nil && blocked {
+		return "无法获取该用户的密钥"
+	}
+	return ""
+}
+
+// checkPrekeysLow 一次性预共享公钥不足时提醒设备补充
+func (ec *E2EEController) checkPrekeysLow(userID int, deviceID string) {
+	remaining, err := ec.e2eeRepo.CountOneTimePrekeys(userID, deviceID)
+	if err != nil || remaining >= models.E2EEPrekeyLowThreshold {
+		return
+	}
+
+	msg := models.WSMessage{
+		Type: "e2ee_prekeys_low",
+		Data: gin.H{
+			"device_id": deviceID,
+			"remaining": remaining,
+		},
+	}
+	msgBytes, _ := json.Marshal(msg)
+	ec.Hub.SendToUser(userID, msgBytes)
+}
+
+// notifyKeyChanged 通知用户的联系人其加密设备发生变化，联系人客户端据此提示安全码变更并重建会话
+func (ec *E2EEController) notifyKeyChanged(userID int, deviceID, reason, identityKey string) {
+	contacts, err := ec.contactRepo.GetContactsByUserID(userID)
+	if err != nil {
+		utils.LogDebug("⚠️ [端到端加密] 获取联系人列表失败，无法发送密钥变更通知: %v", err)
+		return
+	}
+
+	msg := models.WSMessage{
+		Type: "e2ee_key_changed",
+		Data: gin.H{
+			"user_id":      userID,
+			"device_id":    deviceID,
+			"reason":       reason,
+			"identity_key": identityKey,
+			"changed_at":   time.Now().UTC(),
+		},
+	}
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	notifiedCount := 0
+	for _, contact := range contacts {
+		if ec.Hub.SendToUser(contact.FriendID, msgBytes) {
+			notifiedCount++
+		}
+	}
+	utils.LogDebug("🔐 [端到端加密] 用户 %d 设备 %s 密钥变更（%s），已通知 %d/%d 个联系人", userID, deviceID, reason, notifiedCount, len(contacts))
+}
+
+// validateEncryptedEnvelope 校验私聊加密消息信封
+// 发送设备必须已发布密钥；密文只能发给接收者或发送者本人的已登记设备，且必须覆盖接收者的全部设备
+func validateEncryptedEnvelope(senderID, receiverID int, content string) error {
+	envelope, err := models.ParseEncryptedEnvelope(content)
+	if err != nil {
+		return err
+	}
+
+	devices, err := models.NewE2EERepository(db.DB).DeviceIDSet(senderID, receiverID)
+	if err != nil {
+		utils.LogError("❌ [端到端加密] 查询加密设备失败: %v", err)
+		return errors.New("校验加密消息失败")
+	}
+	if !devices[senderID][envelope.SenderDevice] {
+		return errors.New("发送设备未发布加密密钥")
+	}
+
+	covered := map[string]bool{}
+	for _, payload := range envelope.Messages {
+		if payload.UserID != senderID && payload.UserID != receiverID {
+			return errors.New("密文目标设备无效")
+		}
+		if !devices[payload.UserID][payload.DeviceID] {
+			return errors.New("接收设备已变化，请刷新密钥后重试")
+		}
+		if payload.UserID == receiverID {
+			covered[payload.DeviceID] = true
+		}
+	}
+	if len(devices[receiverID]) == 0 {
+		return errors.New("对方尚未开启端到端加密")
+	}
+	if len(covered) != len(devices[receiverID]) {
+		return errors.New("接收设备已变化，请刷新密钥后重试")
+	}
+	return nil
+}
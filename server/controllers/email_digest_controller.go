@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"youdu-server/config"
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 邮件摘要相关常量
+const (
+	emailDigestPageSize         = 200 // 每页查询的候选用户数
+	emailDigestMaxConversations = 10  // 邮件中最多展示的会话数
+	emailDigestMaxSnippets      = 3   // 每个会话最多展示的消息数
+	emailDigestSnippetMaxRunes  = 80  // 单条消息预览最大长度
+)
+
+// emailDigestMu 防止同一实例上的摘要任务重叠运行
+var emailDigestMu sync.Mutex
+
+// sendDigestEmail 摘要邮件发送函数（测试时可替换，无需真实 SMTP 服务）
+var sendDigestEmail = utils.SendEmail
+
+// EmailDigestController 未读消息邮件摘要控制器
+type EmailDigestController struct {
+	digestRepo *models.EmailDigestRepository
+}
+
+// NewEmailDigestController 创建邮件摘要控制器
+func NewEmailDigestController() *EmailDigestController {
+	return &EmailDigestController{
+		digestRepo: models.NewEmailDigestRepository(db.DB),
+	}
+}
+
+// GetSettings 获取当前用户的邮件摘要设置
+// GET /api/user/email-digest
+func (ec *EmailDigestController) GetSettings(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	setting, err := ec.digestRepo.GetSetting(userID.(int))
+	if err != nil {
+		utils.LogError("❌ [邮件摘要] 获取用户 %d 的摘要设置失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "获取邮件摘要设置失败")
+		return
+	}
+
+	utils.Success(c, setting)
+}
+
+// UpdateSettings 更新当前用户的邮件摘要设置（退订或调整发送频率）
+// PUT /api/user/email-digest
+func (ec *EmailDigestController) UpdateSettings(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	var req models.UpdateEmailDigestSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if req.Frequency != nil && !models.IsValidEmailDigestFrequency(*req.Frequency) {
+		utils.BadRequest(c, "发送频率只能是 daily 或 weekly")
+		return
+	}
+
+	setting, err := ec.digestRepo.GetSetting(userID.(int))
+	if err != nil {
+		utils.LogError("❌ [邮件摘要] 获取用户 %d 的摘要设置失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "更新邮件摘要设置失败")
+		return
+	}
+	if req.Enabled != nil {
+		setting.Enabled = *req.Enabled
+	}
+	if req.Frequency != nil {
+		setting.Frequency = *req.Frequency
+	}
+
+	if err := ec.digestRepo.SaveSetting(setting); err != nil {
+		utils.LogError("❌ [邮件摘要] 保存用户 %d 的摘要设置失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "更新邮件摘要设置失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "邮件摘要设置已更新", setting)
+}
+
+// SendEmailDigests 向长时间离线且有未读私聊或@消息的用户发送摘要邮件（由定时任务调用）
+func SendEmailDigests(hub *ws.Hub) {
+	cfg := config.AppConfig
+	if !cfg.EmailDigestEnabled || cfg.SMTPHost == "" {
+		return
+	}
+	if !emailDigestMu.TryLock() {
+		return
+	}
+	defer emailDigestMu.Unlock()
+
+	repo := models.NewEmailDigestRepository(db.DB)
+	now := time.Now().UTC()
+	offlineBefore := now.Add(-time.Duration(cfg.EmailDigestOfflineHours) * time.Hour)
+
+	sentCount := 0
+	afterID := 0
+	for {
+		candidates, err := repo.ListCandidates(offlineBefore, now, afterID, emailDigestPageSize)
+		if err != nil {
+			utils.LogError("❌ [邮件摘要] 查询待发送用户失败: %v", err)
+			return
+		}
+
+		for i := range candidates {
+			candidate := &candidates[i]
+			afterID = candidate.UserID
+
+			// 任务运行期间重新上线的用户不再发送
+			if hub != nil && hub.IsUserOnline(candidate.UserID) {
+				continue
+			}
+
+			digest, err := buildEmailDigest(repo, candidate, now)
+			if err != nil {
+				utils.LogError("❌ [邮件摘要] 生成用户 %d 的摘要失败: %v", candidate.UserID, err)
+				continue
+			}
+			if digest == nil {
+				continue
+			}
+
+			if err := deliverEmailDigest(candidate.Email, digest); err != nil {
+				utils.LogError("❌ [邮件摘要] 向用户 %d 发送摘要邮件失败: %v", candidate.UserID, err)
+				continue
+			}
+			if err := repo.MarkSent(candidate.UserID, now); err != nil {
+				utils.LogError("❌ [邮件摘要] 记录用户 %d 的摘要发送时间失败: %v", candidate.UserID, err)
+			}
+			sentCount++
+		}
+
+		if len(candidates) < emailDigestPageSize {
+			break
+		}
+	}
+
+	if sentCount > 0 {
+		utils.LogInfo("📧 [邮件摘要] 本次发送摘要邮件 %d 封", sentCount)
+	}
+}
+
+// deliverEmailDigest 渲染并发送摘要邮件
+func deliverEmailDigest(email string, digest *utils.EmailDigest) error {
+	subject, body, err := utils.RenderEmailDigest(digest)
+	if err != nil {
+		return fmt.Errorf("渲染摘要邮件失败: %w", err)
+	}
+	return sendDigestEmail(email, subject, body)
+}
+
+// buildEmailDigest 汇总用户的未读私聊和@我的消息，没有可展示的内容时返回 nil
+func buildEmailDigest(repo *models.EmailDigestRepository, candidate *models.EmailDigestCandidate, now time.Time) (*utils.EmailDigest, error) {
+	since := candidate.Since(now)
+
+	privateSummaries, err := repo.PrivateSummaries(candidate.UserID, since)
+	if err != nil {
+		return nil, err
+	}
+	mentionSummaries, err := repo.MentionSummaries(candidate.UserID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	// 合并私聊和群聊，最近的会话在前
+	summaries := make([]models.EmailDigestSummary, 0, len(privateSummaries)+len(mentionSummaries))
+	i, j := 0, 0
+	for i < len(privateSummaries) || j < len(mentionSummaries) {
+		if j >= len(mentionSummaries) || (i < len(privateSummaries) && privateSummaries[i].LatestAt.After(mentionSummaries[j].LatestAt)) {
+			summaries = append(summaries, privateSummaries[i])
+			i++
+		} else {
+			summaries = append(summaries, mentionSummaries[j])
+			j++
+		}
+	}
+	if len(summaries) == 0 {
+		return nil, nil
+	}
+
+	digest := &utils.EmailDigest{
+		UserName:       candidate.DisplayName(),
+		AppURL:         config.AppConfig.EmailDigestAppURL,
+		UnsubscribeTip: "如需退订或调整发送频率，请在应用的设置中修改邮件摘要选项。",
+	}
+	for idx, summary := range summaries {
+		digest.TotalUnread += summary.Count
+		if idx >= emailDigestMaxConversations {
+			digest.MoreCount++
+			continue
+		}
+
+		var snippets []models.EmailDigestSnippet
+		if summary.ConversationType == models.ConversationTypeGroup {
+			snippets, err = repo.MentionSnippets(candidate.UserID, summary.TargetID, since, emailDigestMaxSnippets)
+		} else {
+			snippets, err = repo.PrivateSnippets(candidate.UserID, summary.TargetID, since, emailDigestMaxSnippets)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		digest.Conversations = append(digest.Conversations, utils.EmailDigestConversation{
+			Title:    summary.Title,
+			IsGroup:  summary.ConversationType == models.ConversationTypeGroup,
+			Count:    summary.Count,
+			Snippets: emailDigestSnippetTexts(summary.ConversationType, snippets),
+		})
+	}
+
+	return digest, nil
+}
+
+// emailDigestSnippetTexts 生成消息预览文本（群聊带发送者名称）
+func emailDigestSnippetTexts(conversationType string, snippets []models.EmailDigestSnippet) []string {
+	texts := make([]string, 0, len(snippets))
+	for _, snippet := range snippets {
+		text := models.MessagePreviewText(snippet.MessageType, snippet.Content)
+		if conversationType == models.ConversationTypeGroup && snippet.SenderName != "" {
+			text = snippet.SenderName + ": " + text
+		}
+		if utf8.RuneCountInString(text) > emailDigestSnippetMaxRunes {
+			text = string([]rune(text)[:emailDigestSnippetMaxRunes]) + "…"
+		}
+		texts = append(texts, text)
+	}
+	return texts
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"youdu-server/models"
+	"youdu-server/utils"
+)
+
+func TestEmailDigestSnippetTexts(t *testing.T) {
+	longText := strings.Repeat("长", emailDigestSnippetMaxRunes+5)
+
+	tests := []struct {
+		name             string
+		conversationType string
+		snippets         []models.EmailDigestSnippet
+		want             []string
+	}{
+		{
+			name:             "私聊不带发送者",
+			conversationType: models.ConversationTypeUser,
+			snippets:         []models.EmailDigestSnippet{{SenderName: "bob", MessageType: "text", Content: "在吗"}},
+			want:             []string{"在吗"},
+		},
+		{
+			name:             "群聊带发送者",
+			conversationType: models.ConversationTypeGroup,
+			snippets:         []models.EmailDigestSnippet{{SenderName: "bob", MessageType: "text", Content: "@alice 看一下"}},
+			want:             []string{"bob: @alice 看一下"},
+		},
+		{
+			name:             "非文本消息使用占位文案",
+			conversationType: models.ConversationTypeUser,
+			snippets: []models.EmailDigestSnippet{
+				{MessageType: "image", Content: "https://example.com/a.png"},
+				{MessageType: "audio", Content: "https://example.com/a.m4a"},
+				{MessageType: "file", Content: "https://example.com/a.pdf"},
+			},
+			want: []string{"[图片]", "[语音]", "[文件]"},
+		},
+		{
+			name:             "超长预览截断",
+			conversationType: models.ConversationTypeUser,
+			snippets:         []models.EmailDigestSnippet{{MessageType: "text", Content: longText}},
+			want:             []string{strings.Repeat("长", emailDigestSnippetMaxRunes) + "…"},
+		},
+		{
+			name:             "没有消息",
+			conversationType: models.ConversationTypeUser,
+			want:             []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := emailDigestSnippetTexts(tt.conversationType, tt.snippets)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("emailDigestSnippetTexts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverEmailDigest(t *testing.T) {
+	orig := sendDigestEmail
+	t.Cleanup(func() { sendDigestEmail = orig })
+
+	var gotTo, gotSubject, gotBody string
+	sendDigestEmail = func(to, subject, body string) error {
+		gotTo, gotSubject, gotBody = to, subject, body
+		return nil
+	}
+
+	digest := &utils.EmailDigest{
+		UserName:    "alice",
+		TotalUnread: 2,
+		Conversations: []utils.EmailDigestConversation{{
+			Title:    "<bob>",
+			Count:    2,
+			Snippets: emailDigestSnippetTexts(models.ConversationTypeUser, []models.EmailDigestSnippet{{MessageType: "audio"}}),
+		}},
+	}
+	if err := deliverEmailDigest("alice@example.com", digest); err != nil {
+		t.Fatalf("deliverEmailDigest() error = %v", err)
+	}
+	if gotTo != "alice@example.com" {
+		t.Errorf("to = %q", gotTo)
+	}
+	if gotSubject != "您有 2 条未读消息" {
+		t.Errorf("subject = %q", gotSubject)
+	}
+	for _, s := range []string{"&lt;bob&gt;", "[语音]", "（2 条未读）"} {
+		if !strings.Contains(gotBody, s) {
+			t.Errorf("正文缺少 %q", s)
+		}
+	}
+
+	sendErr := errors.New("smtp unavailable")
+	sendDigestEmail = func(to, subject, body string) error { return sendErr }
+	if err := deliverEmailDigest("alice@example.com", digest); !errors.Is(err, sendErr) {
+		t.Errorf("deliverEmailDigest() error = %v, want %v", err, sendErr)
+	}
+}
@@ -0,0 +1,544 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 事件投递相关常量
+const (
+	eventSubscriptionsTTL    = time.Minute      // 订阅缓存时间
+	eventDeliveryTimeout     = 10 * time.Second // 单次回调超时
+	eventDeliveryLease       = 2 * time.Minute  // 投递进行中时记录的锁定时间，期间定时任务不会重复领取
+	eventRetryBaseDelay      = 30 * time.Second // 首次重试间隔，之后每次翻倍
+	maxEventDeliveryAttempts = 8                // 最多投递次数（含首次）
+	eventRetryBatchSize      = 50               // 定时任务每次领取的记录数
+	maxEventErrorLength      = 500              // 记录的失败原因最大长度
+)
+
+// 回调请求头
+const (
+	eventHeaderEvent     = "X-Youdu-Event"
+	eventHeaderEventID   = "X-Youdu-Event-Id"
+	eventHeaderDelivery  = "X-Youdu-Delivery"
+	eventHeaderTimestamp = "X-Youdu-Timestamp"
+	eventHeaderSignature = "X-Youdu-Signature" // sha256=HEX(HMAC-SHA256(secret, timestamp + "." + body))
+)
+
+var eventDeliveryClient = &http.Client{Timeout: eventDeliveryTimeout}
+
+var (
+	eventSubsMu       sync.RWMutex
+	eventSubs         []models.EventSubscription
+	eventSubsLoadedAt time.Time
+)
+
+// eventPayload 回调请求体
+type eventPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	GroupID   int         `json:"group_id,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// invalidateEventSubscriptions 使订阅缓存失效（订阅变更后调用）
+func invalidateEventSubscriptions() {
+	eventSubsMu.Lock()
+	eventSubs = nil
+	eventSubsMu.Unlock()
+}
+
+// loadEventSubscriptions 获取启用的订阅，缓存过期时从数据库重新加载
+func loadEventSubscriptions() []models.EventSubscription {
+	eventSubsMu.RLock()
+	subs := eventSubs
+	fresh := time.Since(eventSubsLoadedAt) < eventSubscriptionsTTL
+	eventSubsMu.RUnlock()
+	if subs != nil && fresh {
+		return subs
+	}
+
+	eventSubsMu.Lock()
+	defer eventSubsMu.Unlock()
+	if eventSubs != nil && time.Since(eventSubsLoadedAt) < eventSubscriptionsTTL {
+		return eventSubs
+	}
+
+	list, err := models.NewEventSubscriptionRepository(db.DB).ListSubscriptions(true)
+	if err != nil {
+		utils.LogError("❌ [事件订阅] 加载订阅失败: %v", err)
+		// 加载失败时沿用旧订阅
+		if eventSubs != nil {
+			return eventSubs
+		}
+		return []models.EventSubscription{}
+	}
+
+	eventSubs = list
+	eventSubsLoadedAt = time.Now()
+	return list
+}
+
+// emitEvent 向匹配的订阅投递事件（groupID 为 0 表示非群组事件，只投递给全局订阅）
+// 没有匹配的订阅时立即返回；否则在后台保存投递记录并发起回调，失败的投递由定时任务按指数退避重试
+func emitEvent(eventType string, groupID int, data interface{}) {
+	var matched []models.EventSubscription
+	for _, sub := range loadEventSubscriptions() {
+		if sub.Matches(eventType, groupID) {
+			matched = append(matched, sub)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	go func() {
+		idBytes := make([]byte, 16)
+		if _, err := rand.Read(idBytes); err != nil {
+			utils.LogError("❌ [事件订阅] 生成事件ID失败: %v", err)
+			return
+		}
+		payload := eventPayload{
+			ID:        hex.EncodeToString(idBytes),
+			Event:     eventType,
+			GroupID:   groupID,
+			CreatedAt: time.Now().UTC(),
+			Data:      data,
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			utils.LogError("❌ [事件订阅] 序列化事件 %s 失败: %v", eventType, err)
+			return
+		}
+
+		repo := models.NewEventSubscriptionRepository(db.DB)
+		for i := range matched {
+			sub := &matched[i]
+			delivery := &models.EventDelivery{
+				SubscriptionID: sub.ID,
+				EventID:        payload.ID,
+				EventType:      eventType,
+				Payload:        string(body),
+			}
+			// 首次投递由当前协程发起，锁定期内定时任务不会领取该记录
+			if err := repo.CreateDelivery(delivery, time.Now().UTC().Add(eventDeliveryLease)); err != nil {
+				utils.LogError("❌ [事件订阅] 保存投递记录失败 (订阅 %d, 事件 %s): %v", sub.ID, eventType, err)
+				continue
+			}
+			deliverEvent(repo, sub, delivery)
+		}
+	}()
+}
+
+// signEventPayload 计算回调签名
+func signEventPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// eventRetryDelay 第 attempts 次失败后的重试间隔（指数退避）
+func eventRetryDelay(attempts int) time.Duration {
+	return eventRetryBaseDelay << uint(attempts-1)
+}
+
+// deliverEvent 发起一次回调并记录结果，2xx 视为成功
+func deliverEvent(repo *models.EventSubscriptionRepository, sub *models.EventSubscription, delivery *models.EventDelivery) {
+	delivery.Attempts++
+	statusCode, err := postEvent(sub, delivery)
+
+	delivery.ResponseStatus = nil
+	if statusCode > 0 {
+		delivery.ResponseStatus = &statusCode
+	}
+	delivery.LastError = nil
+	delivery.NextAttemptAt = nil
+
+	if err == nil {
+		delivery.Status = models.EventDeliverySuccess
+		utils.LogDebug("✅ [事件订阅] 事件 %s 已投递给订阅 %d (第 %d 次)", delivery.EventType, sub.ID, delivery.Attempts)
+	} else {
+		errMsg := err.Error()
+		if len(errMsg) > maxEventErrorLength {
+			errMsg = errMsg[:maxEventErrorLength]
+		}
+		delivery.LastError = &errMsg
+		if delivery.Attempts >= maxEventDeliveryAttempts {
+			delivery.Status = models.EventDeliveryFailed
+			utils.LogError("❌ [事件订阅] 事件 %s 投递给订阅 %d 失败，已重试 %d 次: %v", delivery.EventType, sub.ID, delivery.Attempts, err)
+		} else {
+			delivery.Status = models.EventDeliveryPending
+			next := time.Now().UTC().Add(eventRetryDelay(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+			utils.LogDebug("⚠️ [事件订阅] 事件 %s 投递给订阅 %d 失败 (第 %d 次)，%v 后重试: %v", delivery.EventType, sub.ID, delivery.Attempts, eventRetryDelay(delivery.Attempts), err)
+		}
+	}
+
+	if err := repo.RecordAttempt(delivery); err != nil {
+		utils.LogError("❌ [事件订阅] 更新投递记录 %d 失败: %v", delivery.ID, err)
+	}
+}
+
+// postEvent 发送签名的回调请求，返回响应状态码
+func postEvent(sub *models.EventSubscription, delivery *models.EventDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Youdu-Webhook/1.0")
+	req.Header.Set(eventHeaderEvent, delivery.EventType)
+	req.Header.Set(eventHeaderEventID, delivery.EventID)
+	req.Header.Set(eventHeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(eventHeaderTimestamp, timestamp)
+	req.Header.Set(eventHeaderSignature, signEventPayload(sub.Secret, timestamp, body))
+
+	resp, err := eventDeliveryClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, &eventStatusError{status: resp.Status}
+	}
+	return resp.StatusCode, nil
+}
+
+// eventStatusError 回调返回非 2xx 状态码
+type eventStatusError struct {
+	status string
+}
+
+func (e *eventStatusError) Error() string {
+	return "回调返回 " + e.status
+}
+
+// RetryEventDeliveries 重试到期的失败投递（由定时任务调用）
+func RetryEventDeliveries() {
+	repo := models.NewEventSubscriptionRepository(db.DB)
+	deliveries, err := repo.ClaimDueDeliveries(eventRetryBatchSize, eventDeliveryLease)
+	if err != nil {
+		utils.LogError("❌ [事件订阅] 领取待重试投递失败: %v", err)
+		return
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	subs := make(map[int]*models.EventSubscription)
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		sub, ok := subs[delivery.SubscriptionID]
+		if !ok {
+			sub, err = repo.GetSubscription(delivery.SubscriptionID)
+			if err != nil {
+				utils.LogError("❌ [事件订阅] 获取订阅 %d 失败: %v", delivery.SubscriptionID, err)
+				continue
+			}
+			subs[delivery.SubscriptionID] = sub
+		}
+
+		// 订阅已停用：不再投递
+		if !sub.Enabled {
+			errMsg := "订阅已停用"
+			delivery.Status = models.EventDeliveryFailed
+			delivery.LastError = &errMsg
+			delivery.NextAttemptAt = nil
+			if err := repo.RecordAttempt(delivery); err != nil {
+				utils.LogError("❌ [事件订阅] 更新投递记录 %d 失败: %v", delivery.ID, err)
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(sub *models.EventSubscription, delivery *models.EventDelivery) {
+			defer wg.Done()
+			deliverEvent(repo, sub, delivery)
+		}(sub, delivery)
+	}
+	wg.Wait()
+	utils.LogDebug("🔁 [事件订阅] 已重试 %d 条投递", len(deliveries))
+}
+
+// emitGroupMessageSent 发布群消息事件（系统消息不发布）
+func emitGroupMessageSent(message *models.GroupMessage) {
+	if message.MessageType == "system" {
+		return
+	}
+	emitEvent(models.EventGroupMessageSent, message.GroupID, gin.H{
+		"message": gin.H{
+			"id":           message.ID,
+			"group_id":     message.GroupID,
+			"sender_id":    message.SenderID,
+			"sender_name":  message.SenderName,
+			"message_type": message.MessageType,
+			"content":      message.Content,
+			"created_at":   message.CreatedAt.UTC(),
+		},
+	})
+}
+
+// emitGroupMemberEvent 发布成员进出群事件，reason 为 join、invite、approve、leave 或 remove
+func emitGroupMemberEvent(eventType string, groupID, userID, operatorID int, reason string) {
+	data := gin.H{
+		"group_id": groupID,
+		"user_id":  userID,
+		"reason":   reason,
+	}
+	if operatorID > 0 {
+		data["operator_id"] = operatorID
+	}
+	emitEvent(eventType, groupID, data)
+}
+
+// EventSubscriptionController 事件订阅管理控制器（管理后台使用）
+type EventSubscriptionController struct {
+	eventRepo *models.EventSubscriptionRepository
+	groupRepo *models.GroupRepository
+}
+
+// NewEventSubscriptionController 创建事件订阅管理控制器
+func NewEventSubscriptionController() *EventSubscriptionController {
+	return &EventSubscriptionController{
+		eventRepo: models.NewEventSubscriptionRepository(db.DB),
+		groupRepo: models.NewGroupRepository(db.DB),
+	}
+}
+
+// AdminGetSubscriptions 获取所有事件订阅
+func (ec *EventSubscriptionController) AdminGetSubscriptions(c *gin.Context) {
+	subs, err := ec.eventRepo.ListSubscriptions(false)
+	if err != nil {
+		utils.LogError("❌ [事件订阅] 获取订阅列表失败: %v", err)
+		utils.InternalServerError(c, "获取订阅列表失败")
+		return
+	}
+	utils.Success(c, gin.H{"subscriptions": subs})
+}
+
+// AdminCreateSubscription 创建事件订阅，签名密钥只在创建时返回
+func (ec *EventSubscriptionController) AdminCreateSubscription(c *gin.Context) {
+	sub, errMsg := ec.buildSubscription(c, nil)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	created, err := ec.eventRepo.CreateSubscription(sub)
+	if err != nil {
+		utils.LogError("❌ [事件订阅] 创建订阅失败: %v", err)
+		utils.InternalServerError(c, "创建订阅失败")
+		return
+	}
+	invalidateEventSubscriptions()
+
+	utils.LogInfo("🔔 [事件订阅] 已创建订阅 %d -> %s", created.ID, created.URL)
+	utils.Success(c, gin.H{
+		"subscription": created,
+		"secret":       created.Secret,
+	})
+}
+
+// AdminUpdateSubscription 更新事件订阅（secret 为空时保留原密钥）
+func (ec *EventSubscriptionController) AdminUpdateSubscription(c *gin.Context) {
+	subID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的订阅ID")
+		return
+	}
+
+	existing, err := ec.eventRepo.GetSubscription(subID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "订阅不存在")
+			return
+		}
+		utils.LogError("❌ [事件订阅] 获取订阅失败: %v", err)
+		utils.InternalServerError(c, "获取订阅失败")
+		return
+	}
+
+	sub, errMsg := ec.buildSubscription(c, existing)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+	sub.ID = subID
+
+	updated, err := ec.eventRepo.UpdateSubscription(sub)
+	if err != nil {
+		utils.LogError("❌ [事件订阅] 更新订阅失败: %v", err)
+		utils.InternalServerError(c, "更新订阅失败")
+		return
+	}
+	invalidateEventSubscriptions()
+
+	utils.Success(c, gin.H{"subscription": updated})
+}
+
+// AdminDeleteSubscription 删除事件订阅
+func (ec *EventSubscriptionController) AdminDeleteSubscription(c *gin.Context) {
+	subID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的订阅ID")
+		return
+	}
+
+	deleted, err := ec.eventRepo.DeleteSubscription(subID)
+	if err != nil {
+		utils.LogError("❌ [事件订阅] 删除订阅失败: %v", err)
+		utils.InternalServerError(c, "删除订阅失败")
+		return
+	}
+	if !deleted {
+		utils.NotFound(c, "订阅不存在")
+		return
+	}
+	invalidateEventSubscriptions()
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// AdminGetDeliveries 查询投递记录（分页）
+// GET /api/admin/event-deliveries?subscription_id=1&status=failed&event=call.ended&page=1&page_size=20
+func (ec *EventSubscriptionController) AdminGetDeliveries(c *gin.Context) {
+	subscriptionID, _ := strconv.Atoi(c.Query("subscription_id"))
+	status := c.Query("status")
+	eventType := c.Query("event")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	deliveries, total, err := ec.eventRepo.ListDeliveries(subscriptionID, status, eventType, pageSize, (page-1)*pageSize)
+	if err != nil {
+		utils.LogError("❌ [事件订阅] 获取投递记录失败: %v", err)
+		utils.InternalServerError(c, "获取投递记录失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"deliveries": deliveries,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+	})
+}
+
+// AdminRetryDelivery 手动重新投递（重置为待投递，由定时任务发送）
+func (ec *EventSubscriptionController) AdminRetryDelivery(c *gin.Context) {
+	deliveryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的投递记录ID")
+		return
+	}
+
+	reset, err := ec.eventRepo.ResetDelivery(deliveryID)
+	if err != nil {
+		utils.LogError("❌ [事件订阅] 重置投递记录失败: %v", err)
+		utils.InternalServerError(c, "重新投递失败")
+		return
+	}
+	if !reset {
+		utils.NotFound(c, "投递记录不存在")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已加入重新投递队列", nil)
+}
+
+// buildSubscription 校验请求并构建订阅，existing 不为空时为更新
+func (ec *EventSubscriptionController) buildSubscription(c *gin.Context, existing *models.EventSubscription) (*models.EventSubscription, string) {
+	var req models.SaveEventSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, "请求参数错误: " + err.Error()
+	}
+
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, "回调地址必须是有效的 http/https 地址"
+	}
+
+	events := []string{}
+	for _, e := range req.Events {
+		e = strings.TrimSpace(e)
+		if e == models.EventTypeAll {
+			events = []string{models.EventTypeAll}
+			break
+		}
+		if !models.ValidEventTypes[e] {
+			return nil, "不支持的事件类型: " + e
+		}
+		events = append(events, e)
+	}
+	if len(events) == 0 {
+		events = []string{models.EventTypeAll}
+	}
+
+	if req.GroupID != nil {
+		if _, err := ec.groupRepo.GetGroupByID(*req.GroupID); err != nil {
+			return nil, "群组不存在"
+		}
+	}
+
+	sub := &models.EventSubscription{
+		Name:    strings.TrimSpace(req.Name),
+		URL:     u.String(),
+		Secret:  strings.TrimSpace(req.Secret),
+		Events:  events,
+		GroupID: req.GroupID,
+		Enabled: true,
+	}
+	if existing != nil {
+		sub.Enabled = existing.Enabled
+		if sub.Secret == "" {
+			sub.Secret = existing.Secret
+		}
+	}
+	if req.Enabled != nil {
+		sub.Enabled = *req.Enabled
+	}
+	if sub.Secret == "" {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, "生成签名密钥失败"
+		}
+		sub.Secret = hex.EncodeToString(buf)
+	}
+	if len(sub.Secret) > 128 {
+		return nil, "签名密钥不能超过128个字符"
+	}
+	return sub, ""
+}
@@ -19,6 +19,9 @@ type FavoriteController struct {
 	favoriteRepo *models.FavoriteRepository
 }
 
+// encryptedFavoriteMessage 收藏加密消息时的提示：客户端解密后以普通消息类型收藏
+const encryptedFavoriteMessage = "加密消息请解密后以文本形式收藏"
+
 // NewFavoriteController 创建收藏控制器实例
 func NewFavoriteController() *FavoriteController {
 	favoriteRepo := models.NewFavoriteRepository(db.DB)
@@ -131,6 +134,12 @@ func (ctrl *FavoriteController) CreateFavorite(c *gin.Context) {
 		return
 	}
 
+	// 加密消息的密文只能由收发双方设备解密，服务端保存密文没有意义
+	if messageType == models.MessageTypeEncrypted {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": encryptedFavoriteMessage})
+		return
+	}
+
 	// 判断是否为群组消息
 	// 如果是群组消息，message_id 设置为 NULL，因为外键约束 favorites_message_id_fkey 只指向 messages 表
 	// 这样可以避免违反外键约束
@@ -320,6 +329,11 @@ func (ctrl *FavoriteController) CreateDirectFavorite(c *gin.Context) {
 		return
 	}
 
+	if req.MessageType == models.MessageTypeEncrypted {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": encryptedFavoriteMessage})
+		return
+	}
+
 	// 检查是否已收藏（通过内容、发送者ID和用户ID）
 	exists2, favoriteID, checkErr := ctrl.favoriteRepo.CheckExistsByContent(userID.(int), req.Content, req.SenderID)
 	if checkErr != nil {
@@ -493,6 +507,8 @@ func (ctrl *FavoriteController) CreateBatchFavorite(c *gin.Context) {
 			contentText = msg.Content
 		case "image":
 			contentText = "[图片]"
+		case models.MessageTypeLocation, models.MessageTypeContactCard, models.MessageTypePoll, models.MessageTypeRichText, models.MessageTypeSticker, models.MessageTypeEncrypted:
+			contentText = models.MessagePreviewText(msg.MessageType, msg.Content)
 		case "file":
 			if msg.FileName != nil && *msg.FileName != "" {
 				contentText = fmt.Sprintf("[文件: %s]", *msg.FileName)
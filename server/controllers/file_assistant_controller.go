@@ -7,16 +7,21 @@ import (
 	"youdu-server/db"
 	"youdu-server/models"
 	"youdu-server/utils"
+	ws "youdu-server/websocket"
 
 	"github.com/gin-gonic/gin"
 )
 
 // FileAssistantController 文件传输助手控制器
-type FileAssistantController struct{}
+type FileAssistantController struct {
+	Hub *ws.Hub
+}
 
 // NewFileAssistantController 创建文件传输助手控制器
-func NewFileAssistantController() *FileAssistantController {
-	return &FileAssistantController{}
+func NewFileAssistantController(hub *ws.Hub) *FileAssistantController {
+	return &FileAssistantController{
+		Hub: hub,
+	}
 }
 
 // CreateMessage 创建文件助手消息
@@ -40,6 +45,14 @@ func (fac *FileAssistantController) CreateMessage(c *gin.Context) {
 		req.MessageType = "text"
 	}
 
+	// 内容审核：命中拦截规则时拒绝保存，命中打码规则时替换敏感内容
+	moderation := moderateMessageContent(req.MessageType, req.Content)
+	if moderation.Blocked {
+		respondContentBlocked(c)
+		return
+	}
+	req.Content = moderation.Content
+
 	// 插入消息到数据库
 	query := `
 		INSERT INTO file_assistant_messages (user_id, content, message_type, file_name, quoted_message_id, quoted_message_content, status, created_at)
@@ -88,6 +101,10 @@ func (fac *FileAssistantController) CreateMessage(c *gin.Context) {
 	if quotedContent.Valid {
 		message.QuotedMessageContent = &quotedContent.String
 	}
+	recordModerationFlag(moderation, models.ModerationSourceFileAssistant, message.ID, message.UserID, 0, message.Content)
+
+	// 发送消息后清除文件助手会话的草稿
+	clearDraftAfterSend(fac.Hub, message.UserID, models.ConversationTypeFileAssistant, 0)
 
 	utils.Success(c, message)
 }
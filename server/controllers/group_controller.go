@@ -300,26 +300,7 @@ func (gc *GroupController) UpdateGroup(c *gin.Context) {
 		}
 
 		// 通知所有群组成员群组信息已更新
-		members, err := gc.groupRepo.GetGroupMembers(groupID)
-		if err == nil {
-			// 获取更新后的群组信息
-			updatedGroup, err := gc.groupRepo.GetGroupByID(groupID)
-			if err == nil {
-				// 向所有群组成员广播更新通知
-				for _, member := range members {
-					notificationData := gin.H{
-						"type": "group_info_updated",
-						"data": gin.H{
-							"group_id": groupID,
-							"group":    updatedGroup,
-						},
-					}
-					notificationJSON, _ := json.Marshal(notificationData)
-					gc.Hub.SendToUser(member.UserID, notificationJSON)
-				}
-				utils.LogDebug("✅ 已向 %d 个群组成员广播群组信息更新", len(members))
-			}
-		}
+		gc.notifyGroupInfoUpdated(groupID)
 	}
 
 	// 添加群组成员（所有群成员都可以添加）
@@ -414,6 +395,7 @@ func (gc *GroupController) UpdateGroup(c *gin.Context) {
 				utils.LogDebug("移除群组成员失败 (user_id=%d): %v", memberID, err)
 			} else {
 				utils.LogDebug("✅ 群组成员已移除 (user_id=%d)", memberID)
+				emitGroupMemberEvent(models.EventGroupMemberLeft, groupID, memberID, userID.(int), "remove")
 			}
 		}
 	}
@@ -431,6 +413,32 @@ func (gc *GroupController) UpdateGroup(c *gin.Context) {
 	})
 }
 
+// notifyGroupInfoUpdated 向所有群组成员广播群组信息已更新
+func (gc *GroupController) notifyGroupInfoUpdated(groupID int) {
+	members, err := gc.groupRepo.GetGroupMembers(groupID)
+	if err != nil {
+		return
+	}
+	// 获取更新后的群组信息
+	updatedGroup, err := gc.groupRepo.GetGroupByID(groupID)
+	if err != nil {
+		return
+	}
+	// 向所有群组成员广播更新通知
+	for _, member := range members {
+		notificationData := gin.H{
+			"type": "group_info_updated",
+			"data": gin.H{
+				"group_id": groupID,
+				"group":    updatedGroup,
+			},
+		}
+		notificationJSON, _ := json.Marshal(notificationData)
+		gc.Hub.SendToUser(member.UserID, notificationJSON)
+	}
+	utils.LogDebug("✅ 已向 %d 个群组成员广播群组信息更新", len(members))
+}
+
 // GetUserGroups 获取用户的所有群组
 func (gc *GroupController) GetUserGroups(c *gin.Context) {
 	// 获取当前用户ID
@@ -514,6 +522,50 @@ func (gc *GroupController) CreateGroupMessage(c *gin.Context) {
 		return
 	}
 
+	// 斜杠命令：由命令处理，回复仅调用者可见，不作为群消息发送
+	if reply, handled := dispatchSlashCommand(gc.Hub, req.GroupID, userID.(int), userRole, req.MessageType, req.Content, req.MentionedUserIds, false); handled {
+		utils.Success(c, gin.H{
+			"command_response": reply,
+		})
+		return
+	}
+
+	// 位置、名片消息：校验内容并由服务端生成最终内容
+	normalizedContent, err := normalizeMessageContent(userID.(int), 0, req.GroupID, req.MessageType, req.Content)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Content = normalizedContent
+	req.MentionedUserIds = mergeRichTextMentions(req.MessageType, req.Content, req.MentionedUserIds)
+
+	// 内容审核：命中拦截规则时拒绝发送，命中打码规则时替换敏感内容
+	moderation := moderateMessageContent(req.MessageType, req.Content)
+	if moderation.Blocked {
+		respondContentBlocked(c)
+		return
+	}
+	req.Content = moderation.Content
+
+	// 加急消息：发送前确定接收者并校验配额
+	var urgentRecipients []int
+	if req.Urgent {
+		urgentRecipients, err = urgentGroupRecipients(gc.groupRepo, req.GroupID, userID.(int), req.MentionedUserIds, req.Mentions)
+		if err != nil {
+			utils.LogDebug("获取群组成员ID列表失败: %v", err)
+			utils.Error(c, http.StatusInternalServerError, "发送消息失败")
+			return
+		}
+		if err := validateUrgentMessage(userID.(int), req.MessageType, len(urgentRecipients)); err != nil {
+			if err == models.ErrUrgentQuotaExceeded {
+				utils.Error(c, http.StatusTooManyRequests, err.Error())
+				return
+			}
+			utils.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
 	// 获取发送者信息
 	user, err := gc.userRepo.FindByID(userID.(int))
 	if err != nil {
@@ -537,13 +589,38 @@ func (gc *GroupController) CreateGroupMessage(c *gin.Context) {
 		utils.Error(c, http.StatusInternalServerError, "发送消息失败")
 		return
 	}
+	recordModerationFlag(moderation, models.ModerationSourceGroupMessage, message.ID, user.ID, message.GroupID, message.Content)
+
+	// 加急消息：广播前记录加急并占用配额，失败时按普通消息发送
+	response := gin.H{"message": message}
+	var urgent *models.UrgentMessage
+	if req.Urgent {
+		urgent = &models.UrgentMessage{
+			ConversationType: models.ConversationTypeGroup,
+			MessageID:        message.ID,
+			GroupID:          message.GroupID,
+			SenderID:         user.ID,
+			SenderName:       message.SenderName,
+		}
+		if err := reserveUrgent(urgent, message.MessageType, message.Content, urgentRecipients); err != nil {
+			urgent = nil
+			response["urgent_error"] = err.Error()
+		}
+	}
 
-	// 通过WebSocket发送消息给群组所有成员
-	go gc.broadcastGroupMessage(message)
+	// 发送消息后清除该群组会话的草稿
+	clearDraftAfterSend(gc.Hub, user.ID, models.ConversationTypeGroup, req.GroupID)
 
-	utils.Success(c, gin.H{
-		"message": message,
-	})
+	// 通过WebSocket发送消息给群组所有成员（加急消息在广播后提醒接收者确认，随后匹配关键词自动回复）
+	go func() {
+		gc.broadcastGroupMessage(message)
+		if urgent != nil {
+			markUrgent(gc.Hub, urgent, message.MessageType, message.Content, urgentRecipients)
+		}
+		replyGroupKeywords(gc.Hub, message)
+	}()
+
+	utils.Success(c, response)
 }
 
 // GetGroupMessages 获取群组消息列表
@@ -604,13 +681,22 @@ func (gc *GroupController) GetGroupMessages(c *gin.Context) {
 			gm.mentions,
 			gm.call_type,
 			gm.channel_name,
+			gm.link_preview,
 			gm.status, 
+			gm.recalled_by,
 			gm.created_at,
 			CASE 
 				WHEN gm.sender_id = $4 THEN true
 				WHEN EXISTS (SELECT 1 FROM group_message_reads gmr WHERE gmr.group_message_id = gm.id AND gmr.user_id = $4) THEN true
 				ELSE false
-			END as is_read
+			END as is_read,
+			CASE
+				WHEN gm.sender_id = $4 THEN (
+					SELECT COUNT(*) FROM group_message_reads gmr
+					JOIN group_members rmem ON rmem.group_id = gm.group_id AND rmem.user_id = gmr.user_id AND rmem.approval_status = 'approved'
+					WHERE gmr.group_message_id = gm.id AND gmr.user_id != gm.sender_id
+				)
+			END as read_count
 		FROM group_messages gm
 		LEFT JOIN group_members gmem ON gmem.group_id = gm.group_id AND gmem.user_id = gm.sender_id
 		WHERE gm.group_id = $1
@@ -631,6 +717,7 @@ func (gc *GroupController) GetGroupMessages(c *gin.Context) {
 	for rows.Next() {
 		var msg models.GroupMessage
 		var isRead bool
+		var linkPreview []byte // link_preview 可能为 NULL，不能直接扫描到 json.RawMessage
 		err := rows.Scan(
 			&msg.ID,
 			&msg.GroupID,
@@ -647,14 +734,20 @@ func (gc *GroupController) GetGroupMessages(c *gin.Context) {
 			&msg.Mentions,
 			&msg.CallType,
 			&msg.ChannelName,
+			&linkPreview,
 			&msg.Status,
+			&msg.RecalledBy,
 			&msg.CreatedAt,
 			&isRead,
+			&msg.ReadCount,
 		)
 		if err != nil {
 			utils.LogDebug("扫描群组消息失败: %v", err)
 			continue
 		}
+		if len(linkPreview) > 0 {
+			msg.LinkPreview = linkPreview
+		}
 		msg.IsRead = isRead // 🔴 设置已读状态
 		messages = append(messages, msg)
 	}
@@ -682,6 +775,12 @@ func (gc *GroupController) broadcastGroupMessage(message *models.GroupMessage) {
 		return
 	}
 
+	// 记录@并推送提醒（不受免打扰影响）
+	mentionTargets := recordGroupMentions(gc.Hub, gc.groupRepo, message, memberIDs)
+
+	// 发布群消息事件
+	emitGroupMessageSent(message)
+
 	// 构建WebSocket消息
 	wsMsg := models.WSGroupMessage{
 		Type:    "group_message",
@@ -691,6 +790,7 @@ func (gc *GroupController) broadcastGroupMessage(message *models.GroupMessage) {
 			GroupID:              message.GroupID,
 			SenderID:             message.SenderID,
 			SenderName:           message.SenderName,
+			SenderAvatar:         message.SenderAvatar,
 			Content:              message.Content,
 			MessageType:          message.MessageType,
 			FileName:             message.FileName,
@@ -708,13 +808,19 @@ func (gc *GroupController) broadcastGroupMessage(message *models.GroupMessage) {
 
 	// 向所有群组成员发送消息（不包括发送者自己）
 	sentCount := 0
+	var offlineIDs []int
 	for _, memberID := range memberIDs {
 		if memberID != message.SenderID {
-			gc.Hub.SendToUser(memberID, msgBytes)
+			if !gc.Hub.SendToUser(memberID, msgBytes) {
+				offlineIDs = append(offlineIDs, memberID)
+			}
 			sentCount++
 		}
 	}
 
+	// 离线成员走离线推送
+	pushGroupMessage(gc.Hub, gc.groupRepo, message, offlineIDs, mentionTargets)
+
 	utils.LogDebug("群组消息已广播 - GroupID: %d, MessageID: %d, 发送者: %d, 接收者数量: %d",
 		message.GroupID, message.ID, message.SenderID, sentCount)
 }
@@ -1289,6 +1395,7 @@ func (gc *GroupController) LeaveGroup(c *gin.Context) {
 	}
 
 	utils.LogDebug("✅ 用户退出群组成功: 群组ID=%d, 用户ID=%v", groupID, currentUserID)
+	emitGroupMemberEvent(models.EventGroupMemberLeft, groupID, currentUserID.(int), 0, "leave")
 
 	// 通知其他群成员用户已退出（可选，如果需要实时通知的话）
 	// 这里可以通过 WebSocket 发送通知
@@ -1695,6 +1802,63 @@ func (gc *GroupController) UpdateGroupMemberViewPermission(c *gin.Context) {
 	})
 }
 
+// UpdateGroupRecallWindow 设置群组的消息撤回时限（仅群主和管理员），minutes 为 null 时恢复使用全局配置
+// PUT /api/groups/:id/recall-window
+func (gc *GroupController) UpdateGroupRecallWindow(c *gin.Context) {
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的群组ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	var req struct {
+		Minutes *int `json:"minutes"` // 撤回时限（分钟），null 表示使用全局配置
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+	if req.Minutes != nil && (*req.Minutes < 1 || *req.Minutes > models.MaxGroupRecallWindow) {
+		utils.Error(c, http.StatusBadRequest, "撤回时限必须在 1 到 "+strconv.Itoa(models.MaxGroupRecallWindow)+" 分钟之间")
+		return
+	}
+
+	// 验证用户是否是群主或管理员
+	role, err := gc.groupRepo.GetUserGroupRole(groupID, userID.(int))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.Error(c, http.StatusForbidden, "您不是该群组成员")
+			return
+		}
+		utils.Error(c, http.StatusInternalServerError, "验证群组成员失败")
+		return
+	}
+	if role != "owner" && role != "admin" {
+		utils.Error(c, http.StatusForbidden, "只有群主和管理员可以设置撤回时限")
+		return
+	}
+
+	settingRepo := models.NewServerSettingRepository(db.DB)
+	if err := settingRepo.SetGroupRecallWindowMinutes(groupID, req.Minutes); err != nil {
+		utils.LogDebug("更新群组撤回时限失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "更新撤回时限失败")
+		return
+	}
+
+	minutes := settingRepo.GetRecallWindowMinutes(groupID)
+	utils.LogDebug("✅ 群组撤回时限更新成功: 群组ID=%d, 生效时限=%d 分钟", groupID, minutes)
+	utils.Success(c, gin.H{
+		"message":               "撤回时限已更新",
+		"recall_window_minutes": minutes,
+	})
+}
+
 // ApproveGroupMember 通过群成员审核
 func (gc *GroupController) ApproveGroupMember(c *gin.Context) {
 	// 获取当前用户ID
@@ -1758,6 +1922,8 @@ func (gc *GroupController) ApproveGroupMember(c *gin.Context) {
 		return
 	}
 
+	emitGroupMemberEvent(models.EventGroupMemberJoined, groupID, req.UserID, currentUserID.(int), "approve")
+
 	utils.Success(c, gin.H{
 		"message": "审核通过",
 	})
@@ -1833,6 +1999,8 @@ func (gc *GroupController) RejectGroupMember(c *gin.Context) {
 
 // sendMemberAddedNotification 向新添加的成员发送系统消息
 func (gc *GroupController) sendMemberAddedNotification(groupID int, memberID int, operatorName string) {
+	// 发布成员加入事件
+	emitGroupMemberEvent(models.EventGroupMemberJoined, groupID, memberID, 0, "invite")
 
 	// 创建系统消息：您已被添加到群组
 	createMsg := &models.CreateGroupMessageRequest{
@@ -2119,6 +2287,9 @@ func (gc *GroupController) sendMuteNotificationToUser(groupID int, targetUserID
 
 // sendMemberJoinedNotification 向用户发送主动加入群组的系统消息
 func (gc *GroupController) sendMemberJoinedNotification(groupID int, memberID int, memberName string) {
+	// 发布成员加入事件
+	emitGroupMemberEvent(models.EventGroupMemberJoined, groupID, memberID, 0, "join")
+
 	// 获取群组信息
 	group, err := gc.groupRepo.GetGroupByID(groupID)
 	if err != nil {
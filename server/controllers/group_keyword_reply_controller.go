@@ -0,0 +1,431 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keywordReplyRulesTTL 规则缓存时间（多实例部署时其他实例的规则变更在此时间内生效）
+const keywordReplyRulesTTL = time.Minute
+
+// keywordReplyRuleSet 群组已启用的规则（正则在加载时编译一次）
+type keywordReplyRuleSet struct {
+	rules    []models.GroupKeywordReply
+	regexes  []*regexp.Regexp // 与 rules 一一对应，关键词规则为 nil
+	loadedAt time.Time
+}
+
+var (
+	keywordReplyMu    sync.RWMutex
+	keywordReplyRules = map[int]*keywordReplyRuleSet{}
+)
+
+// GroupKeywordReplyController 群关键词自动回复控制器
+type GroupKeywordReplyController struct {
+	Hub       *ws.Hub
+	replyRepo *models.GroupKeywordReplyRepository
+	groupRepo *models.GroupRepository
+}
+
+// NewGroupKeywordReplyController 创建群关键词自动回复控制器
+func NewGroupKeywordReplyController(hub *ws.Hub) *GroupKeywordReplyController {
+	return &GroupKeywordReplyController{
+		Hub:       hub,
+		replyRepo: models.NewGroupKeywordReplyRepository(db.DB),
+		groupRepo: models.NewGroupRepository(db.DB),
+	}
+}
+
+// requireGroupAdmin 解析群组ID并校验当前用户是群主或管理员，失败时已写入响应
+func (kc *GroupKeywordReplyController) requireGroupAdmin(c *gin.Context) (int, int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return 0, 0, false
+	}
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的群组ID")
+		return 0, 0, false
+	}
+
+	role, err := kc.groupRepo.GetUserGroupRole(groupID, userID.(int))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.Forbidden(c, "您不是该群组成员")
+			return 0, 0, false
+		}
+		utils.InternalServerError(c, "验证群组成员失败")
+		return 0, 0, false
+	}
+	if role != "owner" && role != "admin" {
+		utils.Forbidden(c, "只有群主和管理员可以管理关键词自动回复")
+		return 0, 0, false
+	}
+	return userID.(int), groupID, true
+}
+
+// GetKeywordReplies 获取群组的关键词自动回复规则
+// GET /api/groups/:id/keyword-replies
+func (kc *GroupKeywordReplyController) GetKeywordReplies(c *gin.Context) {
+	_, groupID, ok := kc.requireGroupAdmin(c)
+	if !ok {
+		return
+	}
+
+	rules, err := kc.replyRepo.ListByGroup(groupID, false)
+	if err != nil {
+		utils.LogError("❌ [关键词回复] 获取群组 %d 的规则失败: %v", groupID, err)
+		utils.InternalServerError(c, "获取关键词自动回复失败")
+		return
+	}
+	utils.Success(c, gin.H{"rules": rules})
+}
+
+// CreateKeywordReply 创建关键词自动回复规则
+// POST /api/groups/:id/keyword-replies
+func (kc *GroupKeywordReplyController) CreateKeywordReply(c *gin.Context) {
+	currentUserID, groupID, ok := kc.requireGroupAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req models.SaveGroupKeywordReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	count, err := kc.replyRepo.CountByGroup(groupID)
+	if err != nil {
+		utils.LogError("❌ [关键词回复] 统计群组 %d 的规则失败: %v", groupID, err)
+		utils.InternalServerError(c, "创建关键词自动回复失败")
+		return
+	}
+	if count >= models.MaxKeywordRepliesPerGroup {
+		utils.BadRequest(c, "每个群最多设置 "+strconv.Itoa(models.MaxKeywordRepliesPerGroup)+" 条关键词自动回复")
+		return
+	}
+
+	rule := &models.GroupKeywordReply{
+		GroupID:         groupID,
+		MatchType:       models.KeywordReplyMatchKeyword,
+		CooldownSeconds: models.DefaultKeywordReplyCooldown,
+		Enabled:         true,
+		CreatedBy:       currentUserID,
+	}
+	if errMsg := applyKeywordReplyRequest(rule, &req); errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	created, err := kc.replyRepo.Create(rule)
+	if err != nil {
+		utils.LogError("❌ [关键词回复] 创建群组 %d 的规则失败: %v", groupID, err)
+		utils.InternalServerError(c, "创建关键词自动回复失败")
+		return
+	}
+
+	invalidateKeywordReplies(groupID)
+
+	utils.LogInfo("💬 [关键词回复] 用户 %d 在群组 %d 创建规则 %d", currentUserID, groupID, created.ID)
+	utils.SuccessWithMessage(c, "关键词自动回复已创建", created)
+}
+
+// UpdateKeywordReply 更新关键词自动回复规则（包括启用/停用）
+// PUT /api/groups/:id/keyword-replies/:rule_id
+func (kc *GroupKeywordReplyController) UpdateKeywordReply(c *gin.Context) {
+	currentUserID, groupID, ok := kc.requireGroupAdmin(c)
+	if !ok {
+		return
+	}
+
+	ruleID, err := strconv.Atoi(c.Param("rule_id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的规则ID")
+		return
+	}
+
+	var req models.SaveGroupKeywordReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	rule, err := kc.replyRepo.GetByID(groupID, ruleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "规则不存在")
+			return
+		}
+		utils.LogError("❌ [关键词回复] 获取规则 %d 失败: %v", ruleID, err)
+		utils.InternalServerError(c, "更新关键词自动回复失败")
+		return
+	}
+
+	if errMsg := applyKeywordReplyRequest(rule, &req); errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	updated, err := kc.replyRepo.Update(rule)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "规则不存在")
+			return
+		}
+		utils.LogError("❌ [关键词回复] 更新规则 %d 失败: %v", ruleID, err)
+		utils.InternalServerError(c, "更新关键词自动回复失败")
+		return
+	}
+
+	invalidateKeywordReplies(groupID)
+
+	utils.LogInfo("💬 [关键词回复] 用户 %d 更新群组 %d 的规则 %d - 启用: %v", currentUserID, groupID, ruleID, updated.Enabled)
+	utils.SuccessWithMessage(c, "关键词自动回复已更新", updated)
+}
+
+// DeleteKeywordReply 删除关键词自动回复规则
+// DELETE /api/groups/:id/keyword-replies/:rule_id
+func (kc *GroupKeywordReplyController) DeleteKeywordReply(c *gin.Context) {
+	currentUserID, groupID, ok := kc.requireGroupAdmin(c)
+	if !ok {
+		return
+	}
+
+	ruleID, err := strconv.Atoi(c.Param("rule_id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的规则ID")
+		return
+	}
+
+	deleted, err := kc.replyRepo.Delete(groupID, ruleID)
+	if err != nil {
+		utils.LogError("❌ [关键词回复] 删除规则 %d 失败: %v", ruleID, err)
+		utils.InternalServerError(c, "删除关键词自动回复失败")
+		return
+	}
+	if !deleted {
+		utils.NotFound(c, "规则不存在")
+		return
+	}
+
+	invalidateKeywordReplies(groupID)
+
+	utils.LogInfo("💬 [关键词回复] 用户 %d 删除群组 %d 的规则 %d", currentUserID, groupID, ruleID)
+	utils.SuccessWithMessage(c, "关键词自动回复已删除", nil)
+}
+
+// applyKeywordReplyRequest 将请求字段写入规则并校验，返回错误信息
+func applyKeywordReplyRequest(rule *models.GroupKeywordReply, req *models.SaveGroupKeywordReplyRequest) string {
+	if req.Pattern != nil {
+		rule.Pattern = *req.Pattern
+	}
+	if req.MatchType != nil && *req.MatchType != "" {
+		rule.MatchType = *req.MatchType
+	}
+	if req.Response != nil {
+		rule.Response = strings.TrimSpace(*req.Response)
+	}
+	if req.CooldownSeconds != nil {
+		rule.CooldownSeconds = *req.CooldownSeconds
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+
+	switch rule.MatchType {
+	case models.KeywordReplyMatchKeyword:
+		rule.Pattern = strings.TrimSpace(rule.Pattern)
+		if rule.Pattern == "" {
+			return "关键词不能为空"
+		}
+	case models.KeywordReplyMatchRegex:
+		if rule.Pattern == "" {
+			return "正则表达式不能为空"
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return "正则表达式无效: " + err.Error()
+		}
+	default:
+		return "匹配方式必须是 keyword 或 regex"
+	}
+	if utf8.RuneCountInString(rule.Pattern) > models.MaxKeywordReplyPatternLen {
+		return "关键词不能超过 " + strconv.Itoa(models.MaxKeywordReplyPatternLen) + " 个字符"
+	}
+
+	if rule.Response == "" {
+		return "回复内容不能为空"
+	}
+	if utf8.RuneCountInString(rule.Response) > models.MaxKeywordReplyResponseLen {
+		return "回复内容不能超过 " + strconv.Itoa(models.MaxKeywordReplyResponseLen) + " 个字符"
+	}
+
+	if rule.CooldownSeconds < 0 || rule.CooldownSeconds > models.MaxKeywordReplyCooldown {
+		return "冷却时间必须在 0 到 " + strconv.Itoa(models.MaxKeywordReplyCooldown) + " 秒之间"
+	}
+	return ""
+}
+
+// invalidateKeywordReplies 使群组的规则缓存失效（规则变更后调用）
+func invalidateKeywordReplies(groupID int) {
+	keywordReplyMu.Lock()
+	delete(keywordReplyRules, groupID)
+	keywordReplyMu.Unlock()
+}
+
+// loadKeywordReplies 获取群组已编译的启用规则，缓存过期时从数据库重新加载
+func loadKeywordReplies(groupID int) (*keywordReplyRuleSet, error) {
+	keywordReplyMu.RLock()
+	set := keywordReplyRules[groupID]
+	keywordReplyMu.RUnlock()
+	if set != nil && time.Since(set.loadedAt) < keywordReplyRulesTTL {
+		return set, nil
+	}
+
+	rules, err := models.NewGroupKeywordReplyRepository(db.DB).ListByGroup(groupID, true)
+	if err != nil {
+		return nil, err
+	}
+	set = compileKeywordReplies(rules)
+
+	keywordReplyMu.Lock()
+	keywordReplyRules[groupID] = set
+	// 顺带清理过期的缓存，避免不活跃群组的规则一直占用内存
+	for id, cached := range keywordReplyRules {
+		if time.Since(cached.loadedAt) >= keywordReplyRulesTTL {
+			delete(keywordReplyRules, id)
+		}
+	}
+	keywordReplyMu.Unlock()
+	return set, nil
+}
+
+// compileKeywordReplies 编译规则中的正则表达式（正则无效的规则跳过）
+func compileKeywordReplies(rules []models.GroupKeywordReply) *keywordReplyRuleSet {
+	set := &keywordReplyRuleSet{loadedAt: time.Now()}
+	for _, rule := range rules {
+		var re *regexp.Regexp
+		if rule.MatchType == models.KeywordReplyMatchRegex {
+			compiled, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				utils.LogError("❌ [关键词回复] 规则 %d 正则表达式无效: %v", rule.ID, err)
+				continue
+			}
+			re = compiled
+		}
+		set.rules = append(set.rules, rule)
+		set.regexes = append(set.regexes, re)
+	}
+	return set
+}
+
+// matchKeywordReply 判断文本是否命中规则（re 为规则编译后的正则，关键词规则为 nil）
+func matchKeywordReply(rule *models.GroupKeywordReply, re *regexp.Regexp, text string) bool {
+	if rule.MatchType == models.KeywordReplyMatchRegex {
+		return re != nil && re.MatchString(text)
+	}
+	return strings.Contains(strings.ToLower(text), strings.ToLower(rule.Pattern))
+}
+
+// replyGroupKeywords 群消息保存并广播后匹配关键词自动回复规则，命中时以系统消息发送预设回复
+// 每条消息最多触发一条规则（按规则创建顺序取第一条不在冷却期内的命中规则）
+func replyGroupKeywords(hub *ws.Hub, message *models.GroupMessage) {
+	// 只匹配成员发送的文本消息，系统消息（包括自动回复本身）不触发
+	if message.MessageType != "text" && message.MessageType != models.MessageTypeRichText {
+		return
+	}
+
+	text := message.Content
+	if message.MessageType == models.MessageTypeRichText {
+		doc, err := models.ParseRichTextContent(message.Content)
+		if err != nil {
+			return
+		}
+		text = doc.PlainText()
+	}
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	set, err := loadKeywordReplies(message.GroupID)
+	if err != nil {
+		utils.LogError("❌ [关键词回复] 获取群组 %d 的规则失败: %v", message.GroupID, err)
+		return
+	}
+	if len(set.rules) == 0 {
+		return
+	}
+
+	// 机器人发送的消息不触发，避免与机器人互相回复
+	if isBot, err := models.NewBotRepository(db.DB).IsBot(message.SenderID); err != nil || isBot {
+		return
+	}
+
+	repo := models.NewGroupKeywordReplyRepository(db.DB)
+	for i := range set.rules {
+		rule := &set.rules[i]
+		if !matchKeywordReply(rule, set.regexes[i], text) {
+			continue
+		}
+
+		claimed, err := repo.ClaimTrigger(rule.ID)
+		if err != nil {
+			utils.LogError("❌ [关键词回复] 记录规则 %d 触发失败: %v", rule.ID, err)
+			return
+		}
+		if !claimed {
+			// 冷却期内，继续尝试后面的规则
+			continue
+		}
+
+		postKeywordReply(hub, repo, rule, message)
+		return
+	}
+}
+
+// postKeywordReply 以自动回复账号发送规则回复（系统消息），并通过群消息广播路径下发给全体成员
+func postKeywordReply(hub *ws.Hub, repo *models.GroupKeywordReplyRepository, rule *models.GroupKeywordReply, trigger *models.GroupMessage) {
+	senderID, err := repo.SenderID()
+	if err != nil {
+		utils.LogError("❌ [关键词回复] 获取自动回复发送者账号失败: %v", err)
+		return
+	}
+
+	// 回复内容由群管理员配置，审核规则可能在配置之后才新增，发送时按当前规则审核
+	moderation := moderateMessageContent("text", rule.Response)
+	if moderation.Blocked {
+		utils.LogInfo("🛡️ [关键词回复] 规则 %d 的回复内容命中拦截规则，已跳过", rule.ID)
+		return
+	}
+
+	gc := NewGroupController(hub)
+	reply, err := gc.groupRepo.CreateGroupMessage(&models.CreateGroupMessageRequest{
+		GroupID:     trigger.GroupID,
+		Content:     moderation.Content,
+		MessageType: "system",
+	}, senderID, models.KeywordReplySenderName, nil, nil, nil)
+	if err != nil {
+		utils.LogError("❌ [关键词回复] 保存规则 %d 的回复失败: %v", rule.ID, err)
+		return
+	}
+	recordModerationFlag(moderation, models.ModerationSourceGroupMessage, reply.ID, senderID, reply.GroupID, reply.Content)
+
+	gc.broadcastGroupMessage(reply)
+
+	utils.LogDebug("💬 [关键词回复] 群组 %d 消息 %d 命中规则 %d，已发送自动回复 %d", trigger.GroupID, trigger.ID, rule.ID, reply.ID)
+}
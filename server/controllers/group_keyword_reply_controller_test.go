@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"youdu-server/models"
+)
+
+func TestMatchKeywordReply(t *testing.T) {
+	rules := []models.GroupKeywordReply{
+		{ID: 1, MatchType: models.KeywordReplyMatchKeyword, Pattern: "VPN"},
+		{ID: 2, MatchType: models.KeywordReplyMatchRegex, Pattern: `^报销(流程|标准)`},
+		{ID: 3, MatchType: models.KeywordReplyMatchRegex, Pattern: `(`},
+	}
+	set := compileKeywordReplies(rules)
+	if len(set.rules) != 2 || len(set.regexes) != 2 {
+		t.Fatalf("无效正则的规则应被跳过，实际规则数 %d，正则数 %d", len(set.rules), len(set.regexes))
+	}
+	if set.regexes[0] != nil || set.regexes[1] == nil {
+		t.Fatalf("关键词规则不应编译正则，正则规则应已编译: %v", set.regexes)
+	}
+
+	tests := []struct {
+		name string
+		rule int
+		text string
+		want bool
+	}{
+		{name: "关键词忽略大小写", rule: 0, text: "vpn 连不上了", want: true},
+		{name: "关键词包含匹配", rule: 0, text: "公司的VPN地址是多少", want: true},
+		{name: "关键词未命中", rule: 0, text: "内网打不开", want: false},
+		{name: "正则命中", rule: 1, text: "报销流程是什么", want: true},
+		{name: "正则锚定未命中", rule: 1, text: "请问报销标准", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchKeywordReply(&set.rules[tt.rule], set.regexes[tt.rule], tt.text); got != tt.want {
+				t.Errorf("matchKeywordReply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 正则规则缺少编译结果时视为不命中
+	if matchKeywordReply(&rules[1], nil, "报销流程") {
+		t.Error("未编译的正则规则不应命中")
+	}
+}
+
+func TestInvalidateKeywordReplies(t *testing.T) {
+	keywordReplyMu.Lock()
+	keywordReplyRules[42] = compileKeywordReplies(nil)
+	keywordReplyMu.Unlock()
+
+	invalidateKeywordReplies(42)
+
+	keywordReplyMu.RLock()
+	_, ok := keywordReplyRules[42]
+	keywordReplyMu.RUnlock()
+	if ok {
+		t.Fatal("规则变更后群组缓存应失效")
+	}
+}
+
+func TestApplyKeywordReplyRequest(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(n int) *int { return &n }
+
+	tests := []struct {
+		name        string
+		req         models.SaveGroupKeywordReplyRequest
+		wantErr     string
+		wantPattern string
+	}{
+		{
+			name:        "关键词去除首尾空白",
+			req:         models.SaveGroupKeywordReplyRequest{Pattern: str("  VPN "), Response: str("见群公告")},
+			wantPattern: "VPN",
+		},
+		{
+			name:        "正则规则",
+			req:         models.SaveGroupKeywordReplyRequest{Pattern: str(`^报销\s*流程`), MatchType: str(models.KeywordReplyMatchRegex), Response: str("见财务制度")},
+			wantPattern: `^报销\s*流程`,
+		},
+		{
+			name:    "关键词为空",
+			req:     models.SaveGroupKeywordReplyRequest{Pattern: str("   "), Response: str("回复")},
+			wantErr: "关键词不能为空",
+		},
+		{
+			name:    "正则无效",
+			req:     models.SaveGroupKeywordReplyRequest{Pattern: str("("), MatchType: str(models.KeywordReplyMatchRegex), Response: str("回复")},
+			wantErr: "正则表达式无效",
+		},
+		{
+			name:    "匹配方式无效",
+			req:     models.SaveGroupKeywordReplyRequest{Pattern: str("a"), MatchType: str("glob"), Response: str("回复")},
+			wantErr: "匹配方式必须是 keyword 或 regex",
+		},
+		{
+			name:    "关键词过长",
+			req:     models.SaveGroupKeywordReplyRequest{Pattern: str(strings.Repeat("a", models.MaxKeywordReplyPatternLen+1)), Response: str("回复")},
+			wantErr: "关键词不能超过",
+		},
+		{
+			name:    "回复为空",
+			req:     models.SaveGroupKeywordReplyRequest{Pattern: str("VPN"), Response: str("  ")},
+			wantErr: "回复内容不能为空",
+		},
+		{
+			name:    "回复过长",
+			req:     models.SaveGroupKeywordReplyRequest{Pattern: str("VPN"), Response: str(strings.Repeat("长", models.MaxKeywordReplyResponseLen+1))},
+			wantErr: "回复内容不能超过",
+		},
+		{
+			name:    "冷却时间为负",
+			req:     models.SaveGroupKeywordReplyRequest{Pattern: str("VPN"), Response: str("回复"), CooldownSeconds: num(-1)},
+			wantErr: "冷却时间必须在",
+		},
+		{
+			name:    "冷却时间过长",
+			req:     models.SaveGroupKeywordReplyRequest{Pattern: str("VPN"), Response: str("回复"), CooldownSeconds: num(models.MaxKeywordReplyCooldown + 1)},
+			wantErr: "冷却时间必须在",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &models.GroupKeywordReply{
+				MatchType:       models.KeywordReplyMatchKeyword,
+				CooldownSeconds: models.DefaultKeywordReplyCooldown,
+				Enabled:         true,
+			}
+			errMsg := applyKeywordReplyRequest(rule, &tt.req)
+			if tt.wantErr == "" {
+				if errMsg != "" {
+					t.Fatalf("applyKeywordReplyRequest() = %q, want no error", errMsg)
+				}
+				if rule.Pattern != tt.wantPattern {
+					t.Errorf("Pattern = %q, want %q", rule.Pattern, tt.wantPattern)
+				}
+				return
+			}
+			if !strings.Contains(errMsg, tt.wantErr) {
+				t.Errorf("applyKeywordReplyRequest() = %q, want %q", errMsg, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GroupMentionController "@我的"消息控制器
+type GroupMentionController struct {
+	Hub         *ws.Hub
+	mentionRepo *models.GroupMentionRepository
+}
+
+// NewGroupMentionController 创建"@我的"消息控制器
+func NewGroupMentionController(hub *ws.Hub) *GroupMentionController {
+	return &GroupMentionController{
+		Hub:         hub,
+		mentionRepo: models.NewGroupMentionRepository(db.DB),
+	}
+}
+
+// GetMentions 获取跨群组的"@我的"消息列表（分页）
+// GET /api/mentions?page=1&page_size=20&unread_only=true
+func (mc *GroupMentionController) GetMentions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	unreadOnly := c.Query("unread_only") == "true"
+
+	mentions, total, err := mc.mentionRepo.ListByUser(userID.(int), unreadOnly, pageSize, (page-1)*pageSize)
+	if err != nil {
+		utils.LogError("❌ [@我的] 获取用户 %d 的@消息失败: %v", userID, err)
+		utils.InternalServerError(c, "获取@消息失败")
+		return
+	}
+
+	unreadCount, err := mc.mentionRepo.CountUnread(userID.(int))
+	if err != nil {
+		utils.LogError("❌ [@我的] 获取用户 %d 的未读@数量失败: %v", userID, err)
+		utils.InternalServerError(c, "获取@消息失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"mentions":     mentions,
+		"total":        total,
+		"unread_count": unreadCount,
+		"page":         page,
+		"page_size":    pageSize,
+	})
+}
+
+// MarkMentionsRead 标记"@我的"消息为已读
+// POST /api/mentions/read，可按 ids、group_id 标记，或 all=true 全部标记
+func (mc *GroupMentionController) MarkMentionsRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	var req models.MarkMentionsReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "无效的请求参数")
+		return
+	}
+
+	var affected int64
+	var err error
+	switch {
+	case req.All:
+		affected, err = mc.mentionRepo.MarkAllRead(userID.(int))
+	case req.GroupID > 0:
+		affected, err = mc.mentionRepo.MarkGroupRead(userID.(int), req.GroupID)
+	case len(req.IDs) > 0:
+		affected, err = mc.mentionRepo.MarkRead(userID.(int), req.IDs)
+	default:
+		utils.BadRequest(c, "请指定要标记的@消息")
+		return
+	}
+	if err != nil {
+		utils.LogError("❌ [@我的] 标记用户 %d 的@消息已读失败: %v", userID, err)
+		utils.InternalServerError(c, "标记已读失败")
+		return
+	}
+
+	unreadCount, err := mc.mentionRepo.CountUnread(userID.(int))
+	if err != nil {
+		utils.LogError("❌ [@我的] 获取用户 %d 的未读@数量失败: %v", userID, err)
+		utils.InternalServerError(c, "标记已读失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"rows_affected": affected,
+		"unread_count":  unreadCount,
+	})
+}
+
+// recordGroupMentions 记录群消息中的@并向被@的成员推送 mentioned 事件
+// 被@的提醒不受消息免打扰影响，开启免打扰的成员同样会收到
+func recordGroupMentions(hub *ws.Hub, groupRepo *models.GroupRepository, message *models.GroupMessage, memberIDs []int) []int {
+	if message.Mentions == nil && message.MentionedUserIDs == nil {
+		return nil
+	}
+
+	mentionRepo := models.NewGroupMentionRepository(db.DB)
+	targets, err := mentionRepo.CreateForMessage(message, memberIDs)
+	if err != nil {
+		utils.LogError("❌ [@我的] 保存群消息 %d 的@记录失败: %v", message.ID, err)
+		return nil
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	groupName := ""
+	if group, err := groupRepo.GetGroupByID(message.GroupID); err == nil {
+		groupName = group.Name
+	}
+
+	wsMsg := models.WSMessage{
+		Type: "mentioned",
+		Data: gin.H{
+			"group_id":       message.GroupID,
+			"group_name":     groupName,
+			"message_id":     message.ID,
+			"sender_id":      message.SenderID,
+			"sender_name":    message.SenderName,
+			"preview":        models.MessagePreviewText(message.MessageType, message.Content),
+			"is_mention_all": message.IsMentionAll(),
+			"created_at":     message.CreatedAt.UTC(),
+		},
+	}
+	msgBytes, err := json.Marshal(wsMsg)
+	if err != nil {
+		utils.LogDebug("序列化@提醒失败: %v", err)
+		return targets
+	}
+
+	hub.BroadcastToUsers(targets, msgBytes, 0)
+	utils.LogDebug("📣 [@我的] 群消息 %d 已向 %d 名成员推送@提醒", message.ID, len(targets))
+	return targets
+}
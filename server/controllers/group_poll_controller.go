@@ -0,0 +1,565 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 投票内容长度限制（字符）
+const (
+	maxPollQuestionLen = 200
+	maxPollOptionLen   = 100
+)
+
+// 发起投票的业务错误
+var (
+	errPollGroupDisbanded = errors.New("该群组已被群主解散")
+	errPollContentBlocked = errors.New(contentBlockedMessage)
+)
+
+// GroupPollController 群投票控制器
+type GroupPollController struct {
+	Hub       *ws.Hub
+	pollRepo  *models.GroupPollRepository
+	groupRepo *models.GroupRepository
+	userRepo  *models.UserRepository
+}
+
+// NewGroupPollController 创建群投票控制器
+func NewGroupPollController(hub *ws.Hub) *GroupPollController {
+	return &GroupPollController{
+		Hub:       hub,
+		pollRepo:  models.NewGroupPollRepository(db.DB),
+		groupRepo: models.NewGroupRepository(db.DB),
+		userRepo:  models.NewUserRepository(db.DB),
+	}
+}
+
+// pollMessageContent 投票消息的 content 内容
+type pollMessageContent struct {
+	PollID         int                      `json:"poll_id"`
+	Question       string                   `json:"question"`
+	Options        []models.GroupPollOption `json:"options"`
+	MultipleChoice bool                     `json:"multiple_choice"`
+	Anonymous      bool                     `json:"anonymous"`
+	Deadline       *time.Time               `json:"deadline,omitempty"`
+}
+
+// CreatePoll 在群组中发起投票
+func (pc *GroupPollController) CreatePoll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	currentUserID := userID.(int)
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的群组ID")
+		return
+	}
+
+	var req models.CreateGroupPollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// 校验投票主题与选项
+	question, options, errMsg := normalizePollInput(req.Question, req.Options)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	var deadline *time.Time
+	if req.Deadline != "" {
+		t, err := time.Parse(time.RFC3339, req.Deadline)
+		if err != nil {
+			utils.BadRequest(c, "截止时间格式错误")
+			return
+		}
+		t = t.UTC()
+		if !t.After(time.Now().UTC()) {
+			utils.BadRequest(c, "截止时间必须晚于当前时间")
+			return
+		}
+		deadline = &t
+	}
+
+	// 群组状态与发言权限检查（与发送群消息一致）
+	if models.GetDisbandedGroupsManager().IsGroupDisbanded(groupID) {
+		utils.Error(c, http.StatusNotFound, "该群组已被群主解散")
+		return
+	}
+
+	userRole, err := pc.groupRepo.GetUserGroupRole(groupID, currentUserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.Error(c, http.StatusForbidden, "您不是该群组成员")
+			return
+		}
+		utils.Error(c, http.StatusInternalServerError, "验证群组成员失败")
+		return
+	}
+
+	group, err := pc.groupRepo.GetGroupByID(groupID)
+	if err != nil {
+		utils.LogDebug("获取群组信息失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "获取群组信息失败")
+		return
+	}
+	if group.AllMuted && userRole != "owner" && userRole != "admin" {
+		utils.Error(c, http.StatusForbidden, "群组已开启全体禁言，只有群主和管理员可以发送消息")
+		return
+	}
+
+	isMuted, err := pc.groupRepo.IsGroupMemberMuted(groupID, currentUserID)
+	if err != nil {
+		utils.LogDebug("检查禁言状态失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, "检查禁言状态失败")
+		return
+	}
+	if isMuted {
+		utils.Error(c, http.StatusForbidden, "你已被群主禁言")
+		return
+	}
+
+	poll, message, err := pc.createPoll(groupID, currentUserID, question, options, req.MultipleChoice, req.Anonymous, deadline)
+	if err == errPollGroupDisbanded {
+		utils.Error(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err == errPollContentBlocked {
+		respondContentBlocked(c)
+		return
+	}
+	if err != nil {
+		utils.LogError("创建投票失败: %v", err)
+		utils.InternalServerError(c, "创建投票失败")
+		return
+	}
+
+	result, err := pc.pollRepo.GetResult(poll.ID, currentUserID)
+	if err != nil {
+		utils.LogError("获取投票结果失败: %v", err)
+		utils.InternalServerError(c, "获取投票结果失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"poll":    result,
+		"message": message,
+	})
+}
+
+// normalizePollInput 校验并清洗投票主题与选项，返回错误信息为空表示校验通过
+func normalizePollInput(question string, rawOptions []string) (string, []string, string) {
+	question = strings.TrimSpace(question)
+	if question == "" || utf8.RuneCountInString(question) > maxPollQuestionLen {
+		return "", nil, "投票主题不能为空且不能超过200个字符"
+	}
+
+	options := make([]string, 0, len(rawOptions))
+	seen := make(map[string]bool)
+	for _, option := range rawOptions {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		if utf8.RuneCountInString(option) > maxPollOptionLen {
+			return "", nil, "投票选项不能超过100个字符"
+		}
+		if seen[option] {
+			return "", nil, "投票选项不能重复"
+		}
+		seen[option] = true
+		options = append(options, option)
+	}
+	if len(options) < models.MinPollOptions || len(options) > models.MaxPollOptions {
+		return "", nil, "投票选项数量需在2到20个之间"
+	}
+	return question, options, ""
+}
+
+// createPoll 创建投票并以 poll 类型的群消息发出（调用方已完成参数校验和发言权限检查）
+func (pc *GroupPollController) createPoll(groupID, userID int, question string, options []string, multipleChoice, anonymous bool, deadline *time.Time) (*models.GroupPoll, *models.GroupMessage, error) {
+	// 群组已解散时不再发起投票（斜杠命令等入口同样经过这里）
+	if models.GetDisbandedGroupsManager().IsGroupDisbanded(groupID) {
+		return nil, nil, errPollGroupDisbanded
+	}
+
+	// 内容审核：主题和选项逐项审核，任一项命中 block 规则则拒绝发起
+	question, options, moderation := moderatePollContent(question, options)
+	if moderation.Blocked {
+		return nil, nil, errPollContentBlocked
+	}
+
+	poll := &models.GroupPoll{
+		GroupID:        groupID,
+		CreatorID:      userID,
+		Question:       question,
+		MultipleChoice: multipleChoice,
+		Anonymous:      anonymous,
+		Deadline:       deadline,
+	}
+	pollOptions, err := pc.pollRepo.Create(poll, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 以 poll 类型的群消息发出
+	content, _ := json.Marshal(pollMessageContent{
+		PollID:         poll.ID,
+		Question:       poll.Question,
+		Options:        pollOptions,
+		MultipleChoice: poll.MultipleChoice,
+		Anonymous:      poll.Anonymous,
+		Deadline:       poll.Deadline,
+	})
+
+	nickname, fullName, username, avatar, err := pc.groupRepo.GetGroupMemberInfo(groupID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	senderName := pollVoterName(nickname, fullName, username)
+
+	message, err := pc.groupRepo.CreateGroupMessage(&models.CreateGroupMessageRequest{
+		GroupID:     groupID,
+		Content:     string(content),
+		MessageType: models.MessageTypePoll,
+	}, userID, senderName, nickname, fullName, avatar)
+	if err != nil {
+		return nil, nil, err
+	}
+	recordModerationFlag(moderation, models.ModerationSourceGroupMessage, message.ID, userID, groupID, message.Content)
+
+	if err := pc.pollRepo.SetMessageID(poll.ID, message.ID); err != nil {
+		utils.LogError("关联投票消息失败: %v", err)
+	}
+	poll.MessageID = &message.ID
+
+	// 与普通群消息一致：发布事件、实时推送在线成员、离线成员走离线推送
+	go NewGroupController(pc.Hub).broadcastGroupMessage(message)
+
+	utils.LogInfo("📊 [投票] 用户 %d 在群组 %d 发起投票 %d（%d 个选项）", userID, groupID, poll.ID, len(pollOptions))
+	return poll, message, nil
+}
+
+// moderatePollContent 审核投票主题和选项，返回打码后的内容和合并后的审核结果
+// 结果中 Flag 为第一条命中的 flag 规则，Matched 汇总所有 flag 命中文本
+func moderatePollContent(question string, options []string) (string, []string, *moderationOutcome) {
+	merged := &moderationOutcome{}
+	check := func(text string) string {
+		outcome := moderateMessageContent("text", text)
+		if outcome.Blocked {
+			merged.Blocked = true
+			return text
+		}
+		if merged.Flag == nil {
+			merged.Flag = outcome.Flag
+		}
+		merged.Matched = append(merged.Matched, outcome.Matched...)
+		return outcome.Content
+	}
+
+	question = check(question)
+	moderated := make([]string, 0, len(options))
+	for _, option := range options {
+		moderated = append(moderated, check(option))
+	}
+	return question, moderated, merged
+}
+
+// GetPoll 获取投票详情及统计结果
+func (pc *GroupPollController) GetPoll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	pollID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的投票ID")
+		return
+	}
+
+	result, err := pc.pollRepo.GetResult(pollID, userID.(int))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "投票不存在")
+			return
+		}
+		utils.LogError("获取投票结果失败: %v", err)
+		utils.InternalServerError(c, "获取投票结果失败")
+		return
+	}
+
+	isMember, err := pc.groupRepo.IsGroupMember(result.GroupID, userID.(int))
+	if err != nil {
+		utils.InternalServerError(c, "验证群组成员失败")
+		return
+	}
+	if !isMember {
+		utils.Forbidden(c, "您不是该群组成员")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"poll": result,
+	})
+}
+
+// VotePoll 投票（重复投票会覆盖之前的选择）
+func (pc *GroupPollController) VotePoll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	pollID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的投票ID")
+		return
+	}
+
+	var req models.CastPollVoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	result, errMsg := castPollVote(pc.Hub, userID.(int), pollID, req.OptionIDs)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"poll": result,
+	})
+}
+
+// ClosePoll 提前结束投票（发起人、群主或管理员）
+func (pc *GroupPollController) ClosePoll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	currentUserID := userID.(int)
+
+	pollID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的投票ID")
+		return
+	}
+
+	poll, err := pc.pollRepo.GetByID(pollID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "投票不存在")
+			return
+		}
+		utils.InternalServerError(c, "获取投票失败")
+		return
+	}
+
+	role, err := pc.groupRepo.GetUserGroupRole(poll.GroupID, currentUserID)
+	if err != nil {
+		utils.Forbidden(c, "您不是该群组成员")
+		return
+	}
+	if poll.CreatorID != currentUserID && role != "owner" && role != "admin" {
+		utils.Forbidden(c, "只有发起人、群主或管理员可以结束投票")
+		return
+	}
+
+	closed, err := pc.pollRepo.Close(pollID, &currentUserID)
+	if err != nil {
+		utils.LogError("结束投票失败: %v", err)
+		utils.InternalServerError(c, "结束投票失败")
+		return
+	}
+	if !closed {
+		utils.BadRequest(c, models.ErrPollClosed.Error())
+		return
+	}
+
+	result, err := pc.pollRepo.GetResult(pollID, currentUserID)
+	if err != nil {
+		utils.InternalServerError(c, "获取投票结果失败")
+		return
+	}
+	broadcastPollUpdate(pc.Hub, pc.groupRepo, result)
+
+	utils.LogInfo("📊 [投票] 用户 %d 结束了群组 %d 的投票 %d", currentUserID, poll.GroupID, pollID)
+	utils.Success(c, gin.H{
+		"poll": result,
+	})
+}
+
+// castPollVote 校验并记录投票，成功后向群成员推送最新统计
+// 返回投票结果和错误提示（为空表示成功）
+func castPollVote(hub *ws.Hub, userID, pollID int, optionIDs []int) (*models.PollResult, string) {
+	pollRepo := models.NewGroupPollRepository(db.DB)
+	groupRepo := models.NewGroupRepository(db.DB)
+
+	poll, err := pollRepo.GetByID(pollID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "投票不存在"
+		}
+		utils.LogError("获取投票失败: %v", err)
+		return nil, "获取投票失败"
+	}
+
+	// 只有当前已通过审核的群成员可以投票
+	memberIDs, err := groupRepo.GetGroupMemberIDs(poll.GroupID)
+	if err != nil {
+		utils.LogError("获取群组成员ID列表失败: %v", err)
+		return nil, "验证群组成员失败"
+	}
+	isMember := false
+	for _, memberID := range memberIDs {
+		if memberID == userID {
+			isMember = true
+			break
+		}
+	}
+	if !isMember {
+		return nil, "您不是该群组成员"
+	}
+
+	// 校验选项
+	options, err := pollRepo.GetOptions(pollID)
+	if err != nil {
+		utils.LogError("获取投票选项失败: %v", err)
+		return nil, "获取投票选项失败"
+	}
+	validOptions := make(map[int]bool, len(options))
+	for _, option := range options {
+		validOptions[option.ID] = true
+	}
+
+	selected := make([]int, 0, len(optionIDs))
+	seen := make(map[int]bool)
+	for _, optionID := range optionIDs {
+		if !validOptions[optionID] {
+			return nil, "无效的投票选项"
+		}
+		if !seen[optionID] {
+			seen[optionID] = true
+			selected = append(selected, optionID)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, "请选择投票选项"
+	}
+	if !poll.MultipleChoice && len(selected) > 1 {
+		return nil, "该投票为单选"
+	}
+
+	nickname, fullName, username, _, err := groupRepo.GetGroupMemberInfo(poll.GroupID, userID)
+	if err != nil {
+		utils.LogDebug("获取用户群组信息失败: %v", err)
+	}
+
+	if err := pollRepo.Vote(pollID, userID, pollVoterName(nickname, fullName, username), selected); err != nil {
+		if err == models.ErrPollClosed {
+			// 已过截止时间但尚未被定时任务结束的投票，顺带结束并推送
+			if closed, closeErr := pollRepo.Close(pollID, nil); closeErr == nil && closed {
+				if result, resultErr := pollRepo.GetResult(pollID, 0); resultErr == nil {
+					broadcastPollUpdate(hub, groupRepo, result)
+				}
+			}
+			return nil, err.Error()
+		}
+		utils.LogError("保存投票失败: %v", err)
+		return nil, "投票失败"
+	}
+
+	result, err := pollRepo.GetResult(pollID, userID)
+	if err != nil {
+		utils.LogError("获取投票结果失败: %v", err)
+		return nil, "获取投票结果失败"
+	}
+
+	broadcastPollUpdate(hub, groupRepo, result)
+	utils.LogDebug("📊 [投票] 用户 %d 在投票 %d 中选择了 %v", userID, pollID, selected)
+	return result, ""
+}
+
+// broadcastPollUpdate 向群成员推送投票最新统计（poll_updated）
+func broadcastPollUpdate(hub *ws.Hub, groupRepo *models.GroupRepository, result *models.PollResult) {
+	memberIDs, err := groupRepo.GetGroupMemberIDs(result.GroupID)
+	if err != nil {
+		utils.LogDebug("获取群组成员ID列表失败: %v", err)
+		return
+	}
+
+	// 推送内容对所有成员相同，不包含个人选择
+	tally := *result
+	tally.MyOptionIDs = nil
+
+	wsMsg := models.WSMessage{
+		Type: "poll_updated",
+		Data: tally,
+	}
+	msgBytes, err := json.Marshal(wsMsg)
+	if err != nil {
+		utils.LogDebug("序列化投票结果失败: %v", err)
+		return
+	}
+
+	hub.BroadcastToUsers(memberIDs, msgBytes, 0)
+}
+
+// CloseExpiredPolls 结束所有已到截止时间的投票并推送最终结果（由定时任务调用）
+func CloseExpiredPolls(hub *ws.Hub) {
+	pollRepo := models.NewGroupPollRepository(db.DB)
+	groupRepo := models.NewGroupRepository(db.DB)
+
+	pollIDs, err := pollRepo.CloseExpired()
+	if err != nil {
+		utils.LogError("❌ [投票] 结束到期投票失败: %v", err)
+		return
+	}
+
+	for _, pollID := range pollIDs {
+		result, err := pollRepo.GetResult(pollID, 0)
+		if err != nil {
+			utils.LogError("❌ [投票] 获取投票 %d 结果失败: %v", pollID, err)
+			continue
+		}
+		broadcastPollUpdate(hub, groupRepo, result)
+		utils.LogInfo("📊 [投票] 投票 %d 已到截止时间，自动结束", pollID)
+	}
+}
+
+// pollVoterName 确定投票人显示名称（群昵称 > 全名 > 用户名）
+func pollVoterName(nickname, fullName *string, username string) string {
+	if nickname != nil && *nickname != "" {
+		return *nickname
+	}
+	if fullName != nil && *fullName != "" {
+		return *fullName
+	}
+	return username
+}
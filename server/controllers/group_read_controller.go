@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GroupReadController 群消息已读详情控制器
+type GroupReadController struct {
+	Hub       *ws.Hub
+	readRepo  *models.GroupMessageReadRepository
+	groupRepo *models.GroupRepository
+}
+
+// NewGroupReadController 创建群消息已读详情控制器
+func NewGroupReadController(hub *ws.Hub) *GroupReadController {
+	return &GroupReadController{
+		Hub:       hub,
+		readRepo:  models.NewGroupMessageReadRepository(db.DB),
+		groupRepo: models.NewGroupRepository(db.DB),
+	}
+}
+
+// GetMessageReads 获取群消息的已读/未读成员列表（分页）
+// GET /api/groups/:id/messages/:message_id/reads?page=1&page_size=50
+// 仅消息发送者和群主/管理员可查看
+func (rc *GroupReadController) GetMessageReads(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的群组ID")
+		return
+	}
+	messageID, err := strconv.Atoi(c.Param("message_id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的消息ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	messageGroupID, senderID, err := rc.readRepo.GetMessageSender(messageID)
+	if err != nil || messageGroupID != groupID {
+		if err != nil && err != sql.ErrNoRows {
+			utils.LogError("❌ [已读详情] 获取群消息 %d 失败: %v", messageID, err)
+			utils.InternalServerError(c, "获取已读详情失败")
+			return
+		}
+		utils.NotFound(c, "消息不存在")
+		return
+	}
+
+	role, err := rc.groupRepo.GetUserGroupRole(groupID, userID.(int))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.Forbidden(c, "您不是该群组成员")
+			return
+		}
+		utils.InternalServerError(c, "验证群组成员失败")
+		return
+	}
+	if senderID != userID.(int) && role != "owner" && role != "admin" {
+		utils.Forbidden(c, "只有消息发送者和群主/管理员可以查看已读详情")
+		return
+	}
+
+	progress, err := rc.readRepo.GetProgress([]int{messageID})
+	if err != nil || len(progress) == 0 {
+		utils.LogError("❌ [已读详情] 统计群消息 %d 已读进度失败: %v", messageID, err)
+		utils.InternalServerError(c, "获取已读详情失败")
+		return
+	}
+
+	offset := (page - 1) * pageSize
+	readMembers, err := rc.readRepo.GetReadMembers(messageID, groupID, senderID, pageSize, offset)
+	if err != nil {
+		utils.LogError("❌ [已读详情] 获取群消息 %d 已读成员失败: %v", messageID, err)
+		utils.InternalServerError(c, "获取已读详情失败")
+		return
+	}
+	unreadMembers, err := rc.readRepo.GetUnreadMembers(messageID, groupID, senderID, pageSize, offset)
+	if err != nil {
+		utils.LogError("❌ [已读详情] 获取群消息 %d 未读成员失败: %v", messageID, err)
+		utils.InternalServerError(c, "获取已读详情失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"message_id":   messageID,
+		"group_id":     groupID,
+		"read_count":   progress[0].ReadCount,
+		"unread_count": progress[0].UnreadCount,
+		"read":         readMembers,
+		"unread":       unreadMembers,
+		"page":         page,
+		"page_size":    pageSize,
+	})
+}
+
+// pushGroupReadProgress 向消息发送者推送群消息已读进度（group_read_progress）
+// 同一发送者的多条消息合并为一次推送
+func pushGroupReadProgress(hub *ws.Hub, groupID, readerID int, messageIDs []int) {
+	if len(messageIDs) == 0 {
+		return
+	}
+
+	progress, err := models.NewGroupMessageReadRepository(db.DB).GetProgress(messageIDs)
+	if err != nil {
+		utils.LogError("❌ [已读详情] 统计群组 %d 已读进度失败: %v", groupID, err)
+		return
+	}
+
+	bySender := make(map[int][]models.GroupReadProgress)
+	for _, p := range progress {
+		if p.SenderID == readerID {
+			continue
+		}
+		bySender[p.SenderID] = append(bySender[p.SenderID], p)
+	}
+
+	for senderID, items := range bySender {
+		wsMsg := models.WSMessage{
+			Type: "group_read_progress",
+			Data: gin.H{
+				"group_id":  groupID,
+				"reader_id": readerID,
+				"messages":  items,
+			},
+		}
+		msgBytes, err := json.Marshal(wsMsg)
+		if err != nil {
+			utils.LogDebug("序列化已读进度失败: %v", err)
+			continue
+		}
+		hub.SendToUser(senderID, msgBytes)
+	}
+}
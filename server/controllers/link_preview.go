@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resolveLinkPreview 为文本消息解析链接预览
+// 缓存命中时直接写入消息并返回预览数据，随消息一起推送；
+// 未命中时在后台抓取，完成后写入消息并向 recipientIDs 推送 link_preview_updated
+func resolveLinkPreview(hub *ws.Hub, table string, messageID, groupID int, messageType, content string, recipientIDs []int) json.RawMessage {
+	data, rawURL := lookupLinkPreview(table, messageID, messageType, content)
+	if rawURL != "" {
+		go fetchLinkPreviewAsync(hub, table, groupID, rawURL, []pendingLinkPreview{{messageID: messageID, recipientIDs: recipientIDs}})
+	}
+	return data
+}
+
+// pendingLinkPreview 等待后台抓取链接预览的消息及需要通知的用户
+type pendingLinkPreview struct {
+	messageID    int
+	recipientIDs []int
+}
+
+// lookupLinkPreview 只读取缓存：命中时写入消息并返回预览数据；
+// 未命中时返回需要后台抓取的链接（无链接或缓存为失败结果时两者都为空）
+func lookupLinkPreview(table string, messageID int, messageType, content string) (json.RawMessage, string) {
+	if messageType != "text" {
+		return nil, ""
+	}
+
+	rawURL := utils.ExtractFirstURL(content)
+	if rawURL == "" {
+		return nil, ""
+	}
+
+	preview, found := utils.GetCachedLinkPreview(rawURL)
+	if !found {
+		return nil, rawURL
+	}
+	if preview == nil {
+		return nil, ""
+	}
+	data, err := saveLinkPreview(table, messageID, preview)
+	if err != nil {
+		utils.LogDebug("⚠️ [链接预览] 保存链接预览失败: %v", err)
+		return nil, ""
+	}
+	return data, ""
+}
+
+// fetchLinkPreviewAsync 抓取一次链接预览，写入所有等待的消息并分别推送 link_preview_updated
+func fetchLinkPreviewAsync(hub *ws.Hub, table string, groupID int, rawURL string, pending []pendingLinkPreview) {
+	preview, err := utils.FetchLinkPreview(rawURL)
+	if err != nil {
+		utils.LogDebug("⚠️ [链接预览] 抓取失败 - URL: %s, 错误: %v", rawURL, err)
+		return
+	}
+
+	for _, item := range pending {
+		data, err := saveLinkPreview(table, item.messageID, preview)
+		if err != nil {
+			utils.LogDebug("⚠️ [链接预览] 保存链接预览失败: %v", err)
+			continue
+		}
+
+		payload := gin.H{
+			"message_id":   item.messageID,
+			"link_preview": data,
+		}
+		if groupID > 0 {
+			payload["group_id"] = groupID
+		}
+		wsMsg := models.WSMessage{
+			Type: "link_preview_updated",
+			Data: payload,
+		}
+		msgBytes, err := json.Marshal(wsMsg)
+		if err != nil {
+			continue
+		}
+		for _, userID := range item.recipientIDs {
+			hub.SendToUser(userID, msgBytes)
+		}
+		utils.LogDebug("✅ [链接预览] 已更新消息 %d 的链接预览并推送给 %d 个用户", item.messageID, len(item.recipientIDs))
+	}
+}
+
+// saveLinkPreview 将链接预览写入消息记录
+func saveLinkPreview(table string, messageID int, preview *utils.LinkPreview) (json.RawMessage, error) {
+	data, err := json.Marshal(preview)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET link_preview = $1 WHERE id = $2`, table)
+	if _, err := db.DB.Exec(query, string(data), messageID); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+)
+
+// normalizeMessageContent 校验并规范化结构化消息（位置、名片、富文本、表情、投票）的内容
+// receiverID 为私聊接收者，groupID 为群组ID（私聊时为0）
+// 其他类型的消息原样返回
+func normalizeMessageContent(senderID, receiverID, groupID int, messageType, content string) (string, error) {
+	switch messageType {
+	case models.MessageTypeLocation:
+		loc, err := models.ParseLocationContent(content)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(loc)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case models.MessageTypeContactCard:
+		card, err := resolveContactCard(senderID, receiverID, groupID, content)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(card)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case models.MessageTypeRichText:
+		doc, err := models.ParseRichText(content)
+		if err != nil {
+			return "", err
+		}
+		resolveRichTextMentions(doc, senderID, receiverID, groupID)
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case models.MessageTypeSticker:
+		sticker, err := resolveSticker(senderID, content)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(sticker)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case models.MessageTypePoll:
+		// 投票消息只能通过投票接口创建
+		return "", errors.New("请通过投票接口发起投票")
+	case models.MessageTypeEncrypted:
+		// 加密消息原样存储和转发，服务端只校验信封
+		if groupID > 0 {
+			return "", errors.New("群聊暂不支持端到端加密消息")
+		}
+		if err := validateEncryptedEnvelope(senderID, receiverID, content); err != nil {
+			return "", err
+		}
+		return content, nil
+	default:
+		return content, nil
+	}
+}
+
+// resolveContactCard 将名片解析为被分享用户的当前资料，并校验可见性
+// 1. 发送者自己能看到该用户（本人、好友或通过共同群组可见）
+// 2. 该用户与发送者、私聊接收者之间没有拉黑关系
+// 3. 群聊中关闭了"群成员查看权限"时，普通成员不能分享本群其他成员的名片
+func resolveContactCard(senderID, receiverID, groupID int, content string) (*models.ContactCardContent, error) {
+	cardUserID, err := models.ParseContactCardUserID(content)
+	if err != nil {
+		return nil, err
+	}
+
+	userRepo := models.NewUserRepository(db.DB)
+	contactRepo := models.NewContactRepository(db.DB)
+	groupRepo := models.NewGroupRepository(db.DB)
+
+	cardUser, err := userRepo.FindByID(cardUserID)
+	if err != nil {
+		return nil, errors.New("名片用户不存在")
+	}
+
+	if cardUserID != senderID {
+		visible, err := canSeeUser(contactRepo, groupRepo, senderID, cardUserID)
+		if err != nil {
+			utils.LogDebug("⚠️ [名片] 检查发送者可见性失败: %v", err)
+			return nil, errors.New("校验名片失败")
+		}
+		if !visible {
+			return nil, errors.New("无权分享该用户的名片")
+		}
+
+		blocked, err := contactRepo.CheckContactBlocked(cardUserID, senderID)
+		if err != nil {
+			utils.LogDebug("⚠️ [名片] 检查拉黑状态失败: %v", err)
+			return nil, errors.New("校验名片失败")
+		}
+		if blocked {
+			return nil, errors.New("无权分享该用户的名片")
+		}
+	}
+
+	if receiverID > 0 && receiverID != cardUserID {
+		blocked, err := contactRepo.CheckContactBlocked(cardUserID, receiverID)
+		if err != nil {
+			utils.LogDebug("⚠️ [名片] 检查接收者拉黑状态失败: %v", err)
+			return nil, errors.New("校验名片失败")
+		}
+		if blocked {
+			return nil, errors.New("对方无法查看该用户的名片")
+		}
+	}
+
+	if groupID > 0 && cardUserID != senderID {
+		group, err := groupRepo.GetGroupByID(groupID)
+		if err != nil {
+			return nil, errors.New("获取群组信息失败")
+		}
+		if !group.MemberViewPermission {
+			role, err := groupRepo.GetUserGroupRole(groupID, senderID)
+			if err != nil {
+				return nil, errors.New("您不是该群组成员")
+			}
+			if role != "owner" && role != "admin" {
+				isMember, err := groupRepo.IsGroupMember(groupID, cardUserID)
+				if err != nil {
+					return nil, errors.New("校验名片失败")
+				}
+				if isMember {
+					return nil, errors.New("群主已关闭群成员查看权限，无法分享群成员名片")
+				}
+			}
+		}
+	}
+
+	return models.NewContactCardContent(cardUser), nil
+}
+
+// resolveSticker 将表情消息解析为表情图片快照
+// 只能发送全局表情包中的表情或自己个人表情中的表情
+func resolveSticker(senderID int, content string) (*models.StickerMessageContent, error) {
+	var ref struct {
+		StickerID int `json:"sticker_id"`
+	}
+	if err := json.Unmarshal([]byte(content), &ref); err != nil || ref.StickerID <= 0 {
+		return nil, errors.New("表情消息格式错误")
+	}
+
+	stickerRepo := models.NewStickerRepository(db.DB)
+	sticker, err := stickerRepo.GetByID(ref.StickerID)
+	if err != nil || sticker.DeletedAt != nil {
+		return nil, errors.New("该表情已被删除")
+	}
+
+	if sticker.PackID == nil {
+		owned, err := stickerRepo.HasUserSticker(senderID, sticker.ID)
+		if err != nil {
+			utils.LogDebug("⚠️ [表情] 检查个人表情失败: %v", err)
+			return nil, errors.New("校验表情失败")
+		}
+		if !owned {
+			return nil, errors.New("请先将该表情添加到个人表情")
+		}
+	}
+
+	return models.NewStickerMessageContent(sticker), nil
+}
+
+// canSeeUser 判断 viewerID 能否查看 targetID 的资料：已通过的好友，或通过共同群组可见
+func canSeeUser(contactRepo *models.ContactRepository, groupRepo *models.GroupRepository, viewerID, targetID int) (bool, error) {
+	relation, err := contactRepo.GetRelationByUsers(viewerID, targetID)
+	if err != nil {
+		return false, err
+	}
+	if relation != nil && relation.ApprovalStatus == "approved" && !relation.IsDeleted {
+		return true, nil
+	}
+
+	return groupRepo.CanViewMemberProfile(viewerID, targetID)
+}
+
+// resolveRichTextMentions 按服务端资料填写 @提及 的显示名称（群聊优先群昵称），
+// 群聊中提及非成员、私聊中提及会话双方以外的用户时降级为普通文本
+func resolveRichTextMentions(doc *models.RichTextDocument, senderID, receiverID, groupID int) {
+	groupRepo := models.NewGroupRepository(db.DB)
+	userRepo := models.NewUserRepository(db.DB)
+
+	type mentionName struct {
+		name string
+		ok   bool
+	}
+	resolved := make(map[int]mentionName)
+	doc.ResolveMentions(func(userID int) (string, bool) {
+		if cached, exists := resolved[userID]; exists {
+			return cached.name, cached.ok
+		}
+
+		var result mentionName
+		if groupID > 0 {
+			if nickname, fullName, username, _, err := groupRepo.GetGroupMemberInfo(groupID, userID); err == nil {
+				result = mentionName{name: pollVoterName(nickname, fullName, username), ok: true}
+			}
+		} else if userID == senderID || userID == receiverID {
+			if user, err := userRepo.FindByID(userID); err == nil {
+				result = mentionName{name: pollVoterName(nil, user.FullName, user.Username), ok: true}
+			}
+		}
+		resolved[userID] = result
+		return result.name, result.ok
+	})
+}
+
+// mergeRichTextMentions 将富文本中的 @提及 合并到群消息的 mentioned_user_ids 中
+func mergeRichTextMentions(messageType, content string, mentionedUserIDs []int) []int {
+	if messageType != models.MessageTypeRichText {
+		return mentionedUserIDs
+	}
+	doc, err := models.ParseRichTextContent(content)
+	if err != nil {
+		return mentionedUserIDs
+	}
+
+	seen := make(map[int]bool, len(mentionedUserIDs))
+	for _, id := range mentionedUserIDs {
+		seen[id] = true
+	}
+	for _, id := range doc.MentionedUserIDs() {
+		if !seen[id] {
+			seen[id] = true
+			mentionedUserIDs = append(mentionedUserIDs, id)
+		}
+	}
+	return mentionedUserIDs
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -27,19 +28,23 @@ var upgrader = websocket.Upgrader{
 
 // MessageController 消息控制器
 type MessageController struct {
-	Hub         *ws.Hub
-	userRepo    *models.UserRepository
-	contactRepo *models.ContactRepository
-	groupRepo   *models.GroupRepository
+	Hub                     *ws.Hub
+	userRepo                *models.UserRepository
+	contactRepo             *models.ContactRepository
+	groupRepo               *models.GroupRepository
+	conversationSettingRepo *models.ConversationSettingRepository
+	settingRepo             *models.ServerSettingRepository
 }
 
 // NewMessageController 创建消息控制器
 func NewMessageController(hub *ws.Hub) *MessageController {
 	mc := &MessageController{
-		Hub:         hub,
-		userRepo:    models.NewUserRepository(db.DB),
-		contactRepo: models.NewContactRepository(db.DB),
-		groupRepo:   models.NewGroupRepository(db.DB),
+		Hub:                     hub,
+		userRepo:                models.NewUserRepository(db.DB),
+		contactRepo:             models.NewContactRepository(db.DB),
+		groupRepo:               models.NewGroupRepository(db.DB),
+		conversationSettingRepo: models.NewConversationSettingRepository(db.DB),
+		settingRepo:             models.NewServerSettingRepository(db.DB),
 	}
 
 	// 设置离线通知回调
@@ -136,6 +141,9 @@ func (mc *MessageController) handleMessage(client *ws.Client, message []byte) {
 	case "message_recall":
 		// 处理消息撤回（通过WebSocket）
 		mc.handleMessageRecall(client, wsMsg)
+	case "poll_vote":
+		// 处理群投票（通过WebSocket）
+		mc.handlePollVote(client, wsMsg)
 	default:
 		utils.LogDebug("未知消息类型: %s", wsMsg.Type)
 	}
@@ -173,7 +181,7 @@ func (mc *MessageController) handleSendGroupMessage(client *ws.Client, wsMsg mod
 	}
 
 	// 验证用户是否是群组成员
-	_, err = mc.groupRepo.GetUserGroupRole(msgData.GroupID, client.UserID)
+	role, err := mc.groupRepo.GetUserGroupRole(msgData.GroupID, client.UserID)
 	if err != nil {
 		utils.LogDebug("用户 %d 不是群组 %d 的成员或验证失败: %v", client.UserID, msgData.GroupID, err)
 		// 发送错误响应给发送者
@@ -208,6 +216,60 @@ func (mc *MessageController) handleSendGroupMessage(client *ws.Client, wsMsg mod
 		return
 	}
 
+	// 斜杠命令：由命令处理，回复仅调用者可见，不作为群消息发送
+	if reply, handled := dispatchSlashCommand(mc.Hub, msgData.GroupID, client.UserID, role, msgData.MessageType, msgData.Content, msgData.MentionedUserIds, true); handled {
+		sendSlashCommandReply(mc.Hub, client.UserID, msgData.GroupID, reply)
+		return
+	}
+
+	// 位置、名片消息：校验内容并由服务端生成最终内容
+	normalizedContent, err := normalizeMessageContent(client.UserID, 0, msgData.GroupID, msgData.MessageType, msgData.Content)
+	if err != nil {
+		utils.LogDebug("🚫 用户 %d 在群组 %d 发送的%s消息校验失败: %v", client.UserID, msgData.GroupID, msgData.MessageType, err)
+		errorMsg := models.WSMessage{
+			Type: "group_message_error",
+			Data: gin.H{
+				"error": err.Error(),
+			},
+		}
+		errorMsgBytes, _ := json.Marshal(errorMsg)
+		client.Send <- errorMsgBytes
+		return
+	}
+	msgData.Content = normalizedContent
+	msgData.MentionedUserIds = mergeRichTextMentions(msgData.MessageType, msgData.Content, msgData.MentionedUserIds)
+
+	// 内容审核：命中拦截规则时拒绝发送，命中打码规则时替换敏感内容
+	moderation := moderateMessageContent(msgData.MessageType, msgData.Content)
+	if moderation.Blocked {
+		utils.LogDebug("🚫 用户 %d 在群组 %d 发送的消息包含违规内容，已拦截", client.UserID, msgData.GroupID)
+		sendContentBlockedError(client, "group_message_error", gin.H{"group_id": msgData.GroupID})
+		return
+	}
+	msgData.Content = moderation.Content
+
+	// 加急消息：发送前确定接收者并校验配额
+	var urgentRecipients []int
+	if msgData.Urgent {
+		urgentRecipients, err = urgentGroupRecipients(mc.groupRepo, msgData.GroupID, client.UserID, msgData.MentionedUserIds, msgData.Mentions)
+		if err == nil {
+			err = validateUrgentMessage(client.UserID, msgData.MessageType, len(urgentRecipients))
+		}
+		if err != nil {
+			utils.LogDebug("🚫 用户 %d 在群组 %d 发送加急消息失败: %v", client.UserID, msgData.GroupID, err)
+			errorMsg := models.WSMessage{
+				Type: "group_message_error",
+				Data: gin.H{
+					"error":    err.Error(),
+					"group_id": msgData.GroupID,
+				},
+			}
+			errorMsgBytes, _ := json.Marshal(errorMsg)
+			client.Send <- errorMsgBytes
+			return
+		}
+	}
+
 	// 获取发送者在群组中的完整信息（群昵称、全名、用户名、头像）
 	nickname, fullName, username, avatar, err := mc.groupRepo.GetGroupMemberInfo(msgData.GroupID, client.UserID)
 	if err != nil {
@@ -242,6 +304,36 @@ func (mc *MessageController) handleSendGroupMessage(client *ws.Client, wsMsg mod
 		utils.LogDebug("保存群组消息失败: %v", err)
 		return
 	}
+	recordModerationFlag(moderation, models.ModerationSourceGroupMessage, message.ID, client.UserID, message.GroupID, message.Content)
+
+	// 加急消息：投递前记录加急并占用配额，失败时按普通消息投递并告知发送者
+	var urgent *models.UrgentMessage
+	if msgData.Urgent {
+		urgent = &models.UrgentMessage{
+			ConversationType: models.ConversationTypeGroup,
+			MessageID:        message.ID,
+			GroupID:          message.GroupID,
+			SenderID:         client.UserID,
+			SenderName:       message.SenderName,
+		}
+		if err := reserveUrgent(urgent, message.MessageType, message.Content, urgentRecipients); err != nil {
+			urgent = nil
+			utils.LogDebug("🚫 用户 %d 在群组 %d 的消息 %d 加急失败: %v", client.UserID, msgData.GroupID, message.ID, err)
+			errorMsg := models.WSMessage{
+				Type: "group_message_error",
+				Data: gin.H{
+					"error":      err.Error(),
+					"group_id":   msgData.GroupID,
+					"message_id": message.ID,
+				},
+			}
+			errorMsgBytes, _ := json.Marshal(errorMsg)
+			client.Send <- errorMsgBytes
+		}
+	}
+
+	// 发送消息后清除该群组会话的草稿
+	clearDraftAfterSend(mc.Hub, client.UserID, models.ConversationTypeGroup, msgData.GroupID)
 
 	// 获取群组所有成员ID
 	memberIDs, err := mc.groupRepo.GetGroupMemberIDs(msgData.GroupID)
@@ -250,6 +342,15 @@ func (mc *MessageController) handleSendGroupMessage(client *ws.Client, wsMsg mod
 		return
 	}
 
+	// 链接预览（缓存命中时随消息推送，否则后台抓取后单独推送）
+	message.LinkPreview = resolveLinkPreview(mc.Hub, "group_messages", message.ID, message.GroupID, message.MessageType, message.Content, memberIDs)
+
+	// 记录@并推送提醒（不受免打扰影响）
+	mentionTargets := recordGroupMentions(mc.Hub, mc.groupRepo, message, memberIDs)
+
+	// 发布群消息事件
+	emitGroupMessageSent(message)
+
 	// 将字符串格式的 mentioned_user_ids 转换为整数数组
 	var mentionedUserIds []int
 	if message.MentionedUserIDs != nil && *message.MentionedUserIDs != "" {
@@ -282,6 +383,7 @@ func (mc *MessageController) handleSendGroupMessage(client *ws.Client, wsMsg mod
 			MentionedUserIds:     mentionedUserIds,
 			Mentions:             message.Mentions,
 			VoiceDuration:        message.VoiceDuration,
+			LinkPreview:          message.LinkPreview,
 			CreatedAt:            message.CreatedAt.UTC(), // 🔴 确保使用 UTC 时间
 		},
 	}
@@ -294,13 +396,19 @@ func (mc *MessageController) handleSendGroupMessage(client *ws.Client, wsMsg mod
 
 	// 向所有群组成员发送消息（不包括发送者自己）
 	sentCount := 0
+	var offlineIDs []int
 	for _, memberID := range memberIDs {
 		if memberID != client.UserID {
-			mc.Hub.SendToUser(memberID, msgBytes)
+			if !mc.Hub.SendToUser(memberID, msgBytes) {
+				offlineIDs = append(offlineIDs, memberID)
+			}
 			sentCount++
 		}
 	}
 
+	// 离线成员走离线推送
+	pushGroupMessage(mc.Hub, mc.groupRepo, message, offlineIDs, mentionTargets)
+
 	utils.LogDebug("群组消息已通过WebSocket广播 - GroupID: %d, MessageID: %d, 发送者: %d, 接收者数量: %d",
 		message.GroupID, message.ID, client.UserID, sentCount)
 
@@ -316,6 +424,14 @@ func (mc *MessageController) handleSendGroupMessage(client *ws.Client, wsMsg mod
 	confirmMsgBytes, _ := json.Marshal(confirmMsg)
 	client.Send <- confirmMsgBytes
 	utils.LogDebug("✅ [群组消息] 发送确认已发送给发送者 - 发送者ID: %d, MessageID: %d, GroupID: %d (发送者不会收到group_message推送)", client.UserID, message.ID, message.GroupID)
+
+	// 加急消息：提醒接收者确认，超时未读时升级为短信或邮件提醒
+	if urgent != nil {
+		go markUrgent(mc.Hub, urgent, message.MessageType, message.Content, urgentRecipients)
+	}
+
+	// 匹配群关键词自动回复
+	go replyGroupKeywords(mc.Hub, message)
 }
 
 // handleSendMessage 处理发送私聊消息
@@ -348,6 +464,8 @@ func (mc *MessageController) handleSendMessage(client *ws.Client, wsMsg models.W
 		}
 	case "audio":
 		contentLog = "[语音]"
+	case models.MessageTypeLocation, models.MessageTypeContactCard, models.MessageTypePoll, models.MessageTypeRichText, models.MessageTypeSticker, models.MessageTypeEncrypted:
+		contentLog = models.MessagePreviewText(msgData.MessageType, msgData.Content)
 	default:
 		// 对于文本消息，限制打印长度
 		if len(msgData.Content) > 100 {
@@ -494,6 +612,50 @@ func (mc *MessageController) handleSendMessage(client *ws.Client, wsMsg models.W
 		return
 	}
 
+	// 位置、名片消息：校验内容并由服务端生成最终内容
+	normalizedContent, err := normalizeMessageContent(client.UserID, msgData.ReceiverID, 0, msgData.MessageType, msgData.Content)
+	if err != nil {
+		errorMsg := models.WSMessage{
+			Type: "message_error",
+			Data: gin.H{
+				"error":   "消息内容无效",
+				"message": err.Error(),
+			},
+		}
+		errorMsgBytes, _ := json.Marshal(errorMsg)
+		client.Send <- errorMsgBytes
+		utils.LogDebug("🚫 [消息拦截] %s消息校验失败 - 发送者 %d -> 接收者 %d: %v", msgData.MessageType, client.UserID, msgData.ReceiverID, err)
+		return
+	}
+	msgData.Content = normalizedContent
+
+	// 内容审核：命中拦截规则时拒绝发送，命中打码规则时替换敏感内容
+	moderation := moderateMessageContent(msgData.MessageType, msgData.Content)
+	if moderation.Blocked {
+		utils.LogDebug("🚫 [消息拦截] 消息包含违规内容 - 发送者 %d -> 接收者 %d，消息被拦截", client.UserID, msgData.ReceiverID)
+		sendContentBlockedError(client, "message_error", gin.H{"receiver_id": msgData.ReceiverID})
+		return
+	}
+	msgData.Content = moderation.Content
+
+	// 加急消息：发送前校验配额
+	if msgData.Urgent {
+		if err := validateUrgentMessage(client.UserID, msgData.MessageType, 1); err != nil {
+			errorMsg := models.WSMessage{
+				Type: "message_error",
+				Data: gin.H{
+					"error":       "加急失败",
+					"message":     err.Error(),
+					"receiver_id": msgData.ReceiverID,
+				},
+			}
+			errorMsgBytes, _ := json.Marshal(errorMsg)
+			client.Send <- errorMsgBytes
+			utils.LogDebug("🚫 [消息拦截] 加急消息校验失败 - 发送者 %d -> 接收者 %d: %v", client.UserID, msgData.ReceiverID, err)
+			return
+		}
+	}
+
 	// 通话结束消息专用去重：如果最近已存在相同的 call_ended/call_ended_video，则复用已有记录
 	if msgData.MessageType == "call_ended" || msgData.MessageType == "call_ended_video" {
 		cutoff := time.Now().UTC().Add(-10 * time.Second)
@@ -537,6 +699,41 @@ func (mc *MessageController) handleSendMessage(client *ws.Client, wsMsg models.W
 		return
 	}
 	utils.LogDebug("💾 [消息路由] 消息已保存到数据库 - MessageID: %d, VoiceDuration: %v", msg.ID, msg.VoiceDuration)
+	recordModerationFlag(moderation, models.ModerationSourceMessage, msg.ID, client.UserID, msgData.ReceiverID, msg.Content)
+
+	// 加急消息：投递前记录加急并占用配额，失败时按普通消息投递并告知发送者
+	var urgent *models.UrgentMessage
+	if msgData.Urgent {
+		urgent = &models.UrgentMessage{
+			ConversationType: models.ConversationTypeUser,
+			MessageID:        msg.ID,
+			SenderID:         client.UserID,
+			SenderName:       msg.SenderName,
+		}
+		if err := reserveUrgent(urgent, msg.MessageType, msg.Content, []int{msg.ReceiverID}); err != nil {
+			urgent = nil
+			errorMsg := models.WSMessage{
+				Type: "message_error",
+				Data: gin.H{
+					"error":       "加急失败",
+					"message":     err.Error(),
+					"receiver_id": msgData.ReceiverID,
+					"message_id":  msg.ID,
+				},
+			}
+			errorMsgBytes, _ := json.Marshal(errorMsg)
+			client.Send <- errorMsgBytes
+			utils.LogDebug("🚫 [消息路由] 消息 %d 加急失败 - 发送者 %d -> 接收者 %d: %v", msg.ID, client.UserID, msgData.ReceiverID, err)
+		}
+	}
+
+	// 用户主动发送消息后清除该会话的草稿（通话记录消息除外）
+	if !strings.HasPrefix(msg.MessageType, "call_") {
+		clearDraftAfterSend(mc.Hub, client.UserID, models.ConversationTypeUser, msgData.ReceiverID)
+	}
+
+	// 链接预览（缓存命中时随消息推送，否则后台抓取后单独推送）
+	msg.LinkPreview = resolveLinkPreview(mc.Hub, "messages", msg.ID, 0, msg.MessageType, msg.Content, []int{client.UserID, msgData.ReceiverID})
 
 	// 构造发送给接收者的消息
 	receiverMsg := models.WSMessage{
@@ -555,6 +752,7 @@ func (mc *MessageController) handleSendMessage(client *ws.Client, wsMsg models.W
 			QuotedMessageID:      msg.QuotedMessageID,
 			QuotedMessageContent: msg.QuotedMessageContent,
 			VoiceDuration:        msg.VoiceDuration,
+			LinkPreview:          msg.LinkPreview,
 			IsRead:               msg.IsRead,                // 包含已读状态（新消息默认为false）
 			CreatedAt:            msg.CreatedAt.UTC(), // 🔴 确保使用 UTC 时间
 		},
@@ -569,6 +767,7 @@ func (mc *MessageController) handleSendMessage(client *ws.Client, wsMsg models.W
 		utils.LogDebug("✅ [消息路由] 消息已发送给在线用户 %d", msgData.ReceiverID)
 	} else {
 		utils.LogDebug("⚠️ [消息路由] 用户 %d 离线，消息已保存到数据库", msgData.ReceiverID)
+		pushPrivateMessage(mc.Hub, msg)
 	}
 
 	// 给发送者发送确认
@@ -583,6 +782,14 @@ func (mc *MessageController) handleSendMessage(client *ws.Client, wsMsg models.W
 	client.Send <- confirmMsgBytes
 	utils.LogDebug("✉️ [消息路由] 发送确认已发送给发送者 - 发送者ID: %d, MessageID: %d", client.UserID, msg.ID)
 
+	// 加急消息：提醒接收者确认，超时未读时升级为短信或邮件提醒
+	if urgent != nil {
+		go markUrgent(mc.Hub, urgent, msg.MessageType, msg.Content, []int{msg.ReceiverID})
+	}
+
+	// 接收者开启了自动回复（外出/忙碌）时回复发送者
+	go sendAutoReply(mc, msg)
+
 	// 🔴 已移除：不再向发送者回显完整消息（APP端发送时已保存到本地数据库）
 	// 发送者只需要收到 message_sent 确认即可
 }
@@ -699,6 +906,9 @@ func (mc *MessageController) handleStatusChange(client *ws.Client, wsMsg models.
 
 	utils.LogDebug("✅ 用户 %d 状态通过WebSocket更新为: %s", client.UserID, status)
 
+	// 离开/忙碌状态联动自动回复
+	syncAutoReplyStatus(mc.Hub, client.UserID, status)
+
 	// 获取当前用户信息（用于发送通知）
 	user, err := mc.userRepo.FindByID(client.UserID)
 	if err != nil {
@@ -871,6 +1081,11 @@ func (mc *MessageController) saveMessage(senderID, receiverID int, content, mess
 		messageType = "text"
 	}
 
+	// 加密消息的引用原文由客户端放在密文中，服务端不保存明文
+	if messageType == models.MessageTypeEncrypted && quotedMessageContent != "" {
+		quotedMessageContent = models.EncryptedMessagePreview
+	}
+
 	// 通话结束系统消息去重：避免同一次通话在极短时间内多次写入相同的 call_ended / call_ended_video
 	// 这里按「用户对 + 消息类型 + 内容」在最近 10 秒内去重
 	if messageType == "call_ended" || messageType == "call_ended_video" {
@@ -940,8 +1155,8 @@ func (mc *MessageController) saveMessage(senderID, receiverID int, content, mess
 	}
 
 	query := `
-		INSERT INTO messages (sender_id, receiver_id, sender_name, receiver_name, sender_avatar, receiver_avatar, content, message_type, file_name, quoted_message_id, quoted_message_content, call_type, voice_duration, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		INSERT INTO messages (sender_id, receiver_id, sender_name, receiver_name, sender_avatar, receiver_avatar, content, message_type, file_name, quoted_message_id, quoted_message_content, call_type, voice_duration, created_at, plain_text)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 		RETURNING id, sender_id, receiver_id, sender_name, receiver_name, sender_avatar, receiver_avatar, content, message_type, file_name, quoted_message_id, quoted_message_content, call_type, voice_duration, is_read, created_at
 	`
 
@@ -983,7 +1198,7 @@ func (mc *MessageController) saveMessage(senderID, receiverID int, content, mess
 		receiverAvatarPtr = &receiverAvatar.String
 	}
 
-	err = db.DB.QueryRow(query, senderID, receiverID, senderName, receiverName, senderAvatarPtr, receiverAvatarPtr, content, messageType, fileNamePtr, quotedIDPtr, quotedContentPtr, callTypePtr, voiceDurationPtr, now).Scan(
+	err = db.DB.QueryRow(query, senderID, receiverID, senderName, receiverName, senderAvatarPtr, receiverAvatarPtr, content, messageType, fileNamePtr, quotedIDPtr, quotedContentPtr, callTypePtr, voiceDurationPtr, now, models.StoredPlainText(messageType, content)).Scan(
 		&msg.ID,
 		&msg.SenderID,
 		&msg.ReceiverID,
@@ -1063,6 +1278,9 @@ func (mc *MessageController) sendOfflineNotification(userID int) {
 		utils.LogDebug("⚠️ 更新用户 %d 离线状态失败: %v", userID, err)
 		// 即使更新失败，仍然继续发送离线通知
 	}
+	if err := mc.userRepo.UpdateLastSeenAt(userID); err != nil {
+		utils.LogDebug("⚠️ 更新用户 %d 最近在线时间失败: %v", userID, err)
+	}
 
 	// 获取用户信息
 	user, err := mc.userRepo.FindByID(userID)
@@ -1414,6 +1632,11 @@ func (mc *MessageController) MarkMessagesAsRead(c *gin.Context) {
 	rowsAffected, _ := result.RowsAffected()
 	utils.LogDebug("✅ 已标记 %d 条消息为已读", rowsAffected)
 
+	// 阅读会话后清除手动标记的未读状态
+	if err := mc.conversationSettingRepo.ClearMarkedUnread(userID.(int), models.ConversationTypeUser, req.SenderID); err != nil {
+		utils.LogDebug("⚠️ 清除会话标记未读状态失败: %v", err)
+	}
+
 	utils.Success(c, gin.H{
 		"message":       "标记成功",
 		"rows_affected": rowsAffected,
@@ -1453,18 +1676,46 @@ func (mc *MessageController) MarkGroupMessagesAsRead(c *gin.Context) {
 				FROM group_message_reads 
 				WHERE user_id = $1
 			)
+		RETURNING group_message_id
 	`
 
-	result, err := db.DB.Exec(query, userID, time.Now(), req.GroupID)
+	rows, err := db.DB.Query(query, userID, time.Now(), req.GroupID)
 	if err != nil {
 		utils.LogDebug("❌ 标记群组消息已读失败: %v", err)
 		utils.InternalServerError(c, "标记群组消息已读失败")
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
+	var readMessageIDs []int
+	for rows.Next() {
+		var messageID int
+		if err := rows.Scan(&messageID); err == nil {
+			readMessageIDs = append(readMessageIDs, messageID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		utils.LogDebug("❌ 标记群组消息已读失败: %v", err)
+		utils.InternalServerError(c, "标记群组消息已读失败")
+		return
+	}
+
+	rowsAffected := int64(len(readMessageIDs))
 	utils.LogDebug("✅ 已标记群组 %d 的 %d 条消息为已读", req.GroupID, rowsAffected)
 
+	// 向消息发送者推送已读进度
+	go pushGroupReadProgress(mc.Hub, req.GroupID, userID.(int), readMessageIDs)
+
+	// 阅读会话后清除手动标记的未读状态
+	if err := mc.conversationSettingRepo.ClearMarkedUnread(userID.(int), models.ConversationTypeGroup, req.GroupID); err != nil {
+		utils.LogDebug("⚠️ 清除会话标记未读状态失败: %v", err)
+	}
+
+	// 阅读群组会话后，该群组中的@消息同样视为已读
+	if _, err := models.NewGroupMentionRepository(db.DB).MarkGroupRead(userID.(int), req.GroupID); err != nil {
+		utils.LogDebug("⚠️ 标记群组@消息已读失败: %v", err)
+	}
+
 	utils.Success(c, gin.H{
 		"message":       "标记成功",
 		"rows_affected": rowsAffected,
@@ -1500,7 +1751,7 @@ func (mc *MessageController) GetMessageHistory(c *gin.Context) {
 
 	// 查询两个用户之间的消息，排除已被当前用户删除的消息
 	query := `
-		SELECT id, sender_id, receiver_id, sender_name, receiver_name, sender_avatar, receiver_avatar, content, message_type, file_name, quoted_message_id, quoted_message_content, call_type, voice_duration, status, is_read, created_at, read_at
+		SELECT id, sender_id, receiver_id, sender_name, receiver_name, sender_avatar, receiver_avatar, content, message_type, file_name, quoted_message_id, quoted_message_content, call_type, voice_duration, link_preview, status, is_read, created_at, read_at
 		FROM messages
 		WHERE ((sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1))
 			AND (deleted_by_users = '' OR deleted_by_users NOT LIKE '%' || $5 || '%')
@@ -1518,6 +1769,7 @@ func (mc *MessageController) GetMessageHistory(c *gin.Context) {
 	var messages []models.Message
 	for rows.Next() {
 		var msg models.Message
+		var linkPreview []byte // link_preview 可能为 NULL，不能直接扫描到 json.RawMessage
 		err := rows.Scan(
 			&msg.ID,
 			&msg.SenderID,
@@ -1533,6 +1785,7 @@ func (mc *MessageController) GetMessageHistory(c *gin.Context) {
 			&msg.QuotedMessageContent,
 			&msg.CallType,
 			&msg.VoiceDuration,
+			&linkPreview,
 			&msg.Status,
 			&msg.IsRead,
 			&msg.CreatedAt,
@@ -1541,6 +1794,9 @@ func (mc *MessageController) GetMessageHistory(c *gin.Context) {
 		if err != nil {
 			continue
 		}
+		if len(linkPreview) > 0 {
+			msg.LinkPreview = linkPreview
+		}
 		messages = append(messages, msg)
 	}
 
@@ -1685,19 +1941,26 @@ func formatFullMessageTime(t time.Time) string {
 
 // RecentContact 最近联系人结构
 type RecentContact struct {
-	Type            string  `json:"type"`                 // 类型：user 或 group
-	UserID          int     `json:"user_id"`              // 用户ID或群组ID
-	Username        string  `json:"username"`             // 用户名
-	FullName        string  `json:"full_name"`            // 全名或群组名
-	Avatar          string  `json:"avatar,omitempty"`     // 用户头像URL
-	LastMessageTime string  `json:"last_message_time"`    // 最后消息时间
-	LastMessage     string  `json:"last_message"`         // 最后消息内容
-	UnreadCount     int     `json:"unread_count"`         // 未读消息数量
-	Status          string  `json:"status"`               // 用户状态：online, busy, away, offline（群组固定为online）
-	GroupID         int     `json:"group_id,omitempty"`   // 群组ID（仅群组类型）
-	GroupName       string  `json:"group_name,omitempty"` // 群组名称（仅群组类型）
-	Remark          *string `json:"remark,omitempty"`     // 用户对群组的备注（仅群组类型）
-	DoNotDisturb    bool    `json:"do_not_disturb"`       // 消息免打扰（仅群组类型）
+	Type            string                    `json:"type"`                 // 类型：user、group 或 file_assistant
+	UserID          int                       `json:"user_id"`              // 用户ID或群组ID
+	Username        string                    `json:"username"`             // 用户名
+	FullName        string                    `json:"full_name"`            // 全名或群组名
+	Avatar          string                    `json:"avatar,omitempty"`     // 用户头像URL
+	LastMessageTime string                    `json:"last_message_time"`    // 最后消息时间
+	LastMessage     string                    `json:"last_message"`         // 最后消息内容
+	UnreadCount     int                       `json:"unread_count"`         // 未读消息数量
+	Status          string                    `json:"status"`               // 用户状态：online, busy, away, offline（群组固定为online）
+	GroupID         int                       `json:"group_id,omitempty"`   // 群组ID（仅群组类型）
+	GroupName       string                    `json:"group_name,omitempty"` // 群组名称（仅群组类型）
+	Remark          *string                   `json:"remark,omitempty"`     // 用户对群组的备注（仅群组类型）
+	DoNotDisturb    bool                      `json:"do_not_disturb"`       // 消息免打扰（仅群组类型）
+	IsPinned        bool                      `json:"is_pinned"`            // 是否置顶
+	PinOrder        int                       `json:"pin_order"`            // 置顶排序（数值越大越靠前）
+	MutedUntil      *string                   `json:"muted_until"`          // 会话免打扰截止时间（UTC）
+	IsMuted         bool                      `json:"is_muted"`             // 当前是否处于会话免打扰
+	IsArchived      bool                      `json:"is_archived"`          // 是否已归档
+	MarkedUnread    bool                      `json:"marked_unread"`        // 是否手动标记为未读
+	Draft           *models.ConversationDraft `json:"draft"`                // 未发送的草稿（含引用）
 }
 
 // GetRecentContacts 获取最近30个联系人列表
@@ -1951,16 +2214,68 @@ func (mc *MessageController) GetRecentContacts(c *gin.Context) {
 				AND gm2.created_at = glm.last_time
 				AND (gm2.deleted_by_users = '' OR gm2.deleted_by_users NOT LIKE '%' || $2 || '%')
 			LEFT JOIN group_members gmem ON gmem.group_id = g.id AND gmem.user_id = $1
+		),
+		file_assistant_contacts AS (
+			-- 文件传输助手（目标ID固定为0，取最后一条消息）
+			SELECT
+				'file_assistant' as type,
+				0 as id,
+				'' as username,
+				'文件传输助手' as full_name,
+				'' as avatar,
+				fam.created_at as last_time,
+				fam.content,
+				fam.message_type,
+				fam.status as message_status,
+				0 as unread_count,
+				'online' as status,
+				NULL::integer as group_id,
+				NULL::text as group_name,
+				NULL::text as remark,
+				false as do_not_disturb
+			FROM file_assistant_messages fam
+			WHERE fam.user_id = $1
+			ORDER BY fam.created_at DESC, fam.id DESC
+			LIMIT 1
+		),
+		all_contacts AS (
+			-- 合并私聊、群聊和文件传输助手
+			SELECT * FROM private_contacts
+			UNION ALL
+			SELECT * FROM group_contacts
+			UNION ALL
+			SELECT * FROM file_assistant_contacts
 		)
-		-- 合并私聊和群聊，并按时间排序
-		SELECT * FROM private_contacts
-		UNION ALL
-		SELECT * FROM group_contacts
-		ORDER BY last_time DESC
+		-- 关联用户的会话设置（置顶、免打扰、归档、标记未读、隐藏），置顶会话优先，其余按时间排序
+		SELECT
+			ac.*,
+			COALESCE(cs.is_pinned, false) as is_pinned,
+			COALESCE(cs.pin_order, 0) as pin_order,
+			cs.muted_until,
+			COALESCE(cs.is_archived, false) as is_archived,
+			COALESCE(cs.marked_unread, false) as marked_unread,
+			cd.id as draft_id,
+			cd.content as draft_content,
+			cd.quoted_message_id as draft_quoted_message_id,
+			cd.quoted_message_content as draft_quoted_message_content,
+			cd.updated_at as draft_updated_at
+		FROM all_contacts ac
+		LEFT JOIN conversation_settings cs ON cs.user_id = $1
+			AND cs.conversation_type = ac.type
+			AND cs.target_id = ac.id
+		LEFT JOIN conversation_drafts cd ON cd.user_id = $1
+			AND cd.conversation_type = ac.type
+			AND cd.target_id = ac.id
+		WHERE (cs.hidden_at IS NULL OR ac.last_time > cs.hidden_at)
+			AND ($3 OR COALESCE(cs.is_archived, false) = false)
+		ORDER BY is_pinned DESC, pin_order DESC, ac.last_time DESC
 		LIMIT 30
 	`
 
-	rows, err := db.DB.Query(finalQuery, currentUserID, userIDStr)
+	// 默认不返回已归档的会话，传 include_archived=true 时一并返回
+	includeArchived := c.Query("include_archived") == "true"
+
+	rows, err := db.DB.Query(finalQuery, currentUserID, userIDStr, includeArchived)
 	if err != nil {
 		utils.LogDebug("查询最近联系人失败: %v", err)
 		utils.InternalServerError(c, "查询联系人列表失败")
@@ -1979,13 +2294,51 @@ func (mc *MessageController) GetRecentContacts(c *gin.Context) {
 		var groupName sql.NullString
 		var remark sql.NullString
 		var doNotDisturb bool
-
-		err := rows.Scan(&contactType, &id, &username, &fullName, &avatar, &createdAt, &content, &messageType, &messageStatus, &unreadCount, &status, &groupID, &groupName, &remark, &doNotDisturb)
+		var isPinned, isArchived, markedUnread bool
+		var pinOrder int
+		var mutedUntil sql.NullTime
+		var draftID, draftQuotedID sql.NullInt64
+		var draftContent, draftQuotedContent sql.NullString
+		var draftUpdatedAt sql.NullTime
+
+		err := rows.Scan(&contactType, &id, &username, &fullName, &avatar, &createdAt, &content, &messageType, &messageStatus, &unreadCount, &status, &groupID, &groupName, &remark, &doNotDisturb,
+			&isPinned, &pinOrder, &mutedUntil, &isArchived, &markedUnread,
+			&draftID, &draftContent, &draftQuotedID, &draftQuotedContent, &draftUpdatedAt)
 		if err != nil {
 			utils.LogDebug("扫描数据失败: %v", err)
 			continue
 		}
 
+		// 会话草稿
+		var draft *models.ConversationDraft
+		if draftID.Valid {
+			draft = &models.ConversationDraft{
+				ID:               int(draftID.Int64),
+				UserID:           currentUserID,
+				ConversationType: contactType,
+				TargetID:         id,
+				Content:          draftContent.String,
+				UpdatedAt:        draftUpdatedAt.Time,
+			}
+			if draftQuotedID.Valid {
+				quotedID := int(draftQuotedID.Int64)
+				draft.QuotedMessageID = &quotedID
+			}
+			if draftQuotedContent.Valid {
+				quotedContent := draftQuotedContent.String
+				draft.QuotedMessageContent = &quotedContent
+			}
+		}
+
+		// 会话设置
+		var mutedUntilStr *string
+		isMuted := false
+		if mutedUntil.Valid {
+			formatted := mutedUntil.Time.UTC().Format(time.RFC3339)
+			mutedUntilStr = &formatted
+			isMuted = mutedUntil.Time.After(time.Now().UTC())
+		}
+
 		// 如果消息已被撤回，显示"此消息已被撤销"
 		if messageStatus == "recalled" {
 			contact := RecentContact{
@@ -1999,6 +2352,13 @@ func (mc *MessageController) GetRecentContacts(c *gin.Context) {
 				UnreadCount:     unreadCount,
 				Status:          status,
 				DoNotDisturb:    doNotDisturb,
+				IsPinned:        isPinned,
+				PinOrder:        pinOrder,
+				MutedUntil:      mutedUntilStr,
+				IsMuted:         isMuted,
+				IsArchived:      isArchived,
+				MarkedUnread:    markedUnread,
+				Draft:           draft,
 			}
 
 			// 如果是群组类型，设置群组相关字段
@@ -2019,17 +2379,7 @@ func (mc *MessageController) GetRecentContacts(c *gin.Context) {
 		}
 
 		// 根据消息类型格式化显示内容
-		var displayContent string
-		switch messageType {
-		case "image":
-			displayContent = "[图片]"
-		case "video":
-			displayContent = "[视频]"
-		case "file":
-			displayContent = "[文件]"
-		default:
-			displayContent = content
-		}
+		displayContent := models.MessagePreviewText(messageType, content)
 
 		contact := RecentContact{
 			Type:            contactType,
@@ -2042,6 +2392,13 @@ func (mc *MessageController) GetRecentContacts(c *gin.Context) {
 			UnreadCount:     unreadCount,
 			Status:          status,
 			DoNotDisturb:    doNotDisturb,
+			IsPinned:        isPinned,
+			PinOrder:        pinOrder,
+			MutedUntil:      mutedUntilStr,
+			IsMuted:         isMuted,
+			IsArchived:      isArchived,
+			MarkedUnread:    markedUnread,
+			Draft:           draft,
 		}
 
 		// 如果是群组类型，设置群组相关字段
@@ -2065,9 +2422,7 @@ func (mc *MessageController) GetRecentContacts(c *gin.Context) {
 		contacts = []RecentContact{}
 	}
 
-	// 注意：文件助手由前端固定显示，不在后端返回的联系人列表中
-
-	utils.LogDebug("返回最近联系人列表，共 %d 个联系人（包含私聊和群聊）", len(contacts))
+	utils.LogDebug("返回最近联系人列表，共 %d 个联系人（包含私聊、群聊和文件传输助手）", len(contacts))
 	utils.Success(c, gin.H{
 		"contacts": contacts,
 	})
@@ -2253,67 +2608,20 @@ func (mc *MessageController) handleGroupMessageRecall(client *ws.Client, message
 		return
 	}
 
-	// 检查是否在3分钟内
-	now := time.Now()
-	diff := now.Sub(groupMessage.CreatedAt)
-	if diff.Minutes() > 3 {
-		utils.LogDebug("⚠️ [群组消息撤回] 超过3分钟，无法撤回")
-		mc.sendRecallError(client, "超过3分钟，无法撤回")
-		return
-	}
-
-	// 检查当前用户是否是群主或管理员
-	role, err := mc.groupRepo.GetUserGroupRole(groupMessage.GroupID, currentUserID)
-	if err != nil {
-		utils.LogDebug("❌ [群组消息撤回] 用户不是群组成员: %v", err)
-		mc.sendRecallError(client, "您不是该群组成员")
-		return
-	}
-
-	// 如果是发送者本人，或者群主/管理员，允许撤回
-	if groupMessage.SenderID != currentUserID && role != "owner" && role != "admin" {
-		utils.LogDebug("❌ [群组消息撤回] 无权撤回此消息")
-		mc.sendRecallError(client, "只能撤回自己发送的消息，或需要群主/管理员权限")
+	// 检查撤回权限和撤回时限
+	role, _, errMsg := mc.authorizeGroupRecall(&groupMessage, currentUserID)
+	if errMsg != "" {
+		utils.LogDebug("❌ [群组消息撤回] %s", errMsg)
+		mc.sendRecallError(client, errMsg)
 		return
 	}
 
-	// 更新群组消息状态为已撤回
-	updateQuery := `UPDATE group_messages SET status = 'recalled' WHERE id = $1`
-	_, err = db.DB.Exec(updateQuery, messageID)
-	if err != nil {
+	if err := mc.recallGroupMessage(&groupMessage, currentUserID, role); err != nil {
 		utils.LogDebug("❌ [群组消息撤回] 更新数据库失败: %v", err)
 		mc.sendRecallError(client, "撤回消息失败")
 		return
 	}
 
-	utils.LogDebug("✅ [群组消息撤回] 用户 %d 撤回了群组消息 %d (群组ID: %d)", currentUserID, messageID, groupMessage.GroupID)
-
-	// 获取群组所有成员ID
-	memberIDs, err := mc.groupRepo.GetGroupMemberIDs(groupMessage.GroupID)
-	if err != nil {
-		utils.LogDebug("⚠️ [群组消息撤回] 获取群组成员ID列表失败: %v", err)
-	} else {
-		// 通过WebSocket实时通知所有群组成员消息被撤回
-		recallNotification := models.WSMessage{
-			Type: "message_recalled",
-			Data: gin.H{
-				"message_id": messageID,
-				"sender_id":  currentUserID,
-				"group_id":   groupMessage.GroupID,
-			},
-		}
-		recallNotificationBytes, _ := json.Marshal(recallNotification)
-
-		// 发送给所有群组成员（包括发送者自己，用于确认撤回成功）
-		sentCount := 0
-		for _, memberID := range memberIDs {
-			if mc.Hub.SendToUser(memberID, recallNotificationBytes) {
-				sentCount++
-			}
-		}
-		utils.LogDebug("✅ [群组消息撤回] 撤回通知已发送给群组 %d 的 %d 个成员", groupMessage.GroupID, sentCount)
-	}
-
 	// 发送撤回成功确认给发送者
 	mc.sendRecallSuccess(client, messageID)
 }
@@ -2351,32 +2659,140 @@ func (mc *MessageController) handlePrivateMessageRecall(client *ws.Client, messa
 		return
 	}
 
-	// 检查是否在3分钟内
-	now := time.Now()
-	diff := now.Sub(message.CreatedAt)
-	if diff.Minutes() > 3 {
-		utils.LogDebug("⚠️ [私聊消息撤回] 超过3分钟，无法撤回")
-		mc.sendRecallError(client, "超过3分钟，无法撤回")
+	// 检查是否在撤回时限内
+	if ok, minutes := mc.settingRepo.IsWithinRecallWindow(message.CreatedAt, 0); !ok {
+		utils.LogDebug("⚠️ [私聊消息撤回] 超过%d分钟，无法撤回", minutes)
+		mc.sendRecallError(client, fmt.Sprintf("超过%d分钟，无法撤回", minutes))
 		return
 	}
 
-	// 更新消息状态为已撤回
-	updateQuery := `UPDATE messages SET status = 'recalled' WHERE id = $1`
-	_, err = db.DB.Exec(updateQuery, messageID)
-	if err != nil {
+	if err := mc.recallPrivateMessage(&message, currentUserID); err != nil {
 		utils.LogDebug("❌ [私聊消息撤回] 更新数据库失败: %v", err)
 		mc.sendRecallError(client, "撤回消息失败")
 		return
 	}
 
-	utils.LogDebug("✅ [私聊消息撤回] 用户 %d 撤回了消息 %d", currentUserID, messageID)
+	// 发送撤回成功确认给发送者
+	mc.sendRecallSuccess(client, messageID)
+}
+
+// authorizeGroupRecall 检查群组消息撤回权限，返回操作人角色、错误状态码和错误提示（为空表示允许撤回）
+// 发送者本人需在撤回时限内撤回；群主/管理员可随时撤回成员消息（管理员不能撤回群主的消息）
+// 无权限返回403，超过撤回时限返回400
+func (mc *MessageController) authorizeGroupRecall(groupMessage *models.GroupMessage, operatorID int) (string, int, string) {
+	role, err := mc.groupRepo.GetUserGroupRole(groupMessage.GroupID, operatorID)
+	if err != nil {
+		return "", http.StatusForbidden, "您不是该群组成员"
+	}
+
+	if role == "owner" || role == "admin" {
+		if role == "admin" && groupMessage.SenderID != operatorID {
+			// 发送者已退群时查询不到角色，按普通成员处理
+			senderRole, _ := mc.groupRepo.GetUserGroupRole(groupMessage.GroupID, groupMessage.SenderID)
+			if senderRole == "owner" {
+				return role, http.StatusForbidden, "管理员不能撤回群主的消息"
+			}
+		}
+		return role, 0, ""
+	}
+
+	if groupMessage.SenderID != operatorID {
+		return role, http.StatusForbidden, "只能撤回自己发送的消息，或需要群主/管理员权限"
+	}
+
+	if ok, minutes := mc.settingRepo.IsWithinRecallWindow(groupMessage.CreatedAt, groupMessage.GroupID); !ok {
+		return role, http.StatusBadRequest, fmt.Sprintf("超过%d分钟，无法撤回", minutes)
+	}
+
+	return role, 0, ""
+}
+
+// recallGroupMessage 撤回群组消息，记录操作人并通知所有群组成员
+func (mc *MessageController) recallGroupMessage(groupMessage *models.GroupMessage, operatorID int, operatorRole string) error {
+	updateQuery := `UPDATE group_messages SET status = 'recalled', recalled_by = $2, recalled_at = $3 WHERE id = $1`
+	if _, err := db.DB.Exec(updateQuery, groupMessage.ID, operatorID, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	isAdminRecall := groupMessage.SenderID != operatorID
+	if isAdminRecall {
+		utils.LogDebug("✅ [群组消息撤回] %s %d 撤回了成员 %d 的群组消息 %d (群组ID: %d)", operatorRole, operatorID, groupMessage.SenderID, groupMessage.ID, groupMessage.GroupID)
+	} else {
+		utils.LogDebug("✅ [群组消息撤回] 用户 %d 撤回了群组消息 %d (群组ID: %d)", operatorID, groupMessage.ID, groupMessage.GroupID)
+	}
+
+	// 撤回操作人的显示名称（优先群昵称）
+	operatorName := ""
+	if nickname, fullName, username, _, err := mc.groupRepo.GetGroupMemberInfo(groupMessage.GroupID, operatorID); err == nil {
+		operatorName = username
+		if nickname != nil {
+			operatorName = *nickname
+		} else if fullName != nil {
+			operatorName = *fullName
+		}
+	}
+
+	recallNotice := fmt.Sprintf("%s 撤回了一条消息", operatorName)
+	if isAdminRecall {
+		roleName := "管理员"
+		if operatorRole == "owner" {
+			roleName = "群主"
+		}
+		recallNotice = fmt.Sprintf("%s %s 撤回了一条成员消息", roleName, operatorName)
+	}
+
+	// 获取群组所有成员ID
+	memberIDs, err := mc.groupRepo.GetGroupMemberIDs(groupMessage.GroupID)
+	if err != nil {
+		utils.LogDebug("⚠️ [群组消息撤回] 获取群组成员ID列表失败: %v", err)
+		return nil
+	}
+
+	// 通过WebSocket实时通知所有群组成员消息被撤回
+	recallNotification := models.WSMessage{
+		Type: "message_recalled",
+		Data: gin.H{
+			"message_id":         groupMessage.ID,
+			"sender_id":          operatorID,
+			"group_id":           groupMessage.GroupID,
+			"original_sender_id": groupMessage.SenderID,
+			"recalled_by":        operatorID,
+			"recalled_by_name":   operatorName,
+			"recalled_by_role":   operatorRole,
+			"is_admin_recall":    isAdminRecall,
+			"recall_notice":      recallNotice,
+		},
+	}
+	recallNotificationBytes, _ := json.Marshal(recallNotification)
+
+	// 发送给所有群组成员（包括操作人自己，用于确认撤回成功）
+	sentCount := 0
+	for _, memberID := range memberIDs {
+		if mc.Hub.SendToUser(memberID, recallNotificationBytes) {
+			sentCount++
+		}
+	}
+	utils.LogDebug("✅ [群组消息撤回] 撤回通知已发送给群组 %d 的 %d 个成员", groupMessage.GroupID, sentCount)
+
+	return nil
+}
+
+// recallPrivateMessage 撤回私聊消息，记录操作人并通知接收者
+func (mc *MessageController) recallPrivateMessage(message *models.Message, operatorID int) error {
+	updateQuery := `UPDATE messages SET status = 'recalled', recalled_by = $2, recalled_at = $3 WHERE id = $1`
+	if _, err := db.DB.Exec(updateQuery, message.ID, operatorID, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	utils.LogDebug("✅ [私聊消息撤回] 用户 %d 撤回了消息 %d", operatorID, message.ID)
 
 	// 通过WebSocket实时通知接收者消息被撤回
 	recallNotification := models.WSMessage{
 		Type: "message_recalled",
 		Data: gin.H{
-			"message_id": messageID,
-			"sender_id":  currentUserID,
+			"message_id":  message.ID,
+			"sender_id":   operatorID,
+			"recalled_by": operatorID,
 		},
 	}
 	recallNotificationBytes, _ := json.Marshal(recallNotification)
@@ -2388,8 +2804,7 @@ func (mc *MessageController) handlePrivateMessageRecall(client *ws.Client, messa
 		utils.LogDebug("⚠️ [私聊消息撤回] 接收者 %d 离线，下次登录时将看到消息已撤回", message.ReceiverID)
 	}
 
-	// 发送撤回成功确认给发送者
-	mc.sendRecallSuccess(client, messageID)
+	return nil
 }
 
 // sendRecallError 发送撤回错误消息
@@ -2417,7 +2832,47 @@ func (mc *MessageController) sendRecallSuccess(client *ws.Client, messageID int)
 	client.Send <- responseBytes
 }
 
-// RecallMessage 撤回消息（3分钟内）
+// handlePollVote 处理群投票（通过WebSocket）
+func (mc *MessageController) handlePollVote(client *ws.Client, wsMsg models.WSMessage) {
+	dataBytes, err := json.Marshal(wsMsg.Data)
+	if err != nil {
+		utils.LogDebug("投票数据序列化失败: %v", err)
+		return
+	}
+
+	var voteData struct {
+		PollID    int   `json:"poll_id"`
+		OptionIDs []int `json:"option_ids"`
+	}
+	if err := json.Unmarshal(dataBytes, &voteData); err != nil {
+		utils.LogDebug("解析投票数据失败: %v", err)
+		return
+	}
+
+	result, errMsg := castPollVote(mc.Hub, client.UserID, voteData.PollID, voteData.OptionIDs)
+	if errMsg != "" {
+		errorMsg := models.WSMessage{
+			Type: "poll_vote_error",
+			Data: gin.H{
+				"poll_id": voteData.PollID,
+				"error":   errMsg,
+			},
+		}
+		errorMsgBytes, _ := json.Marshal(errorMsg)
+		client.Send <- errorMsgBytes
+		return
+	}
+
+	// 给投票人返回包含个人选择的结果
+	response := models.WSMessage{
+		Type: "poll_vote_success",
+		Data: result,
+	}
+	responseBytes, _ := json.Marshal(response)
+	client.Send <- responseBytes
+}
+
+// RecallMessage 撤回消息（撤回时限可通过服务器设置配置，群主/管理员可随时撤回群成员消息）
 func (mc *MessageController) RecallMessage(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -2455,63 +2910,19 @@ func (mc *MessageController) RecallMessage(c *gin.Context) {
 			return
 		}
 
-		// 检查是否在3分钟内
-		now := time.Now()
-		diff := now.Sub(groupMessage.CreatedAt)
-		if diff.Minutes() > 3 {
-			utils.Error(c, http.StatusBadRequest, "超过3分钟，无法撤回")
-			return
-		}
-
-		// 检查当前用户是否是群主或管理员
-		role, err := mc.groupRepo.GetUserGroupRole(groupMessage.GroupID, currentUserID)
-		if err != nil {
-			utils.Error(c, http.StatusForbidden, "您不是该群组成员")
-			return
-		}
-
-		// 如果是发送者本人，或者群主/管理员，允许撤回
-		if groupMessage.SenderID != currentUserID && role != "owner" && role != "admin" {
-			utils.Error(c, http.StatusForbidden, "只能撤回自己发送的消息，或需要群主/管理员权限")
+		// 检查撤回权限和撤回时限
+		role, status, errMsg := mc.authorizeGroupRecall(&groupMessage, currentUserID)
+		if errMsg != "" {
+			utils.Error(c, status, errMsg)
 			return
 		}
 
-		// 更新群组消息状态为已撤回
-		updateQuery := `UPDATE group_messages SET status = 'recalled' WHERE id = $1`
-		_, err = db.DB.Exec(updateQuery, req.MessageID)
-		if err != nil {
+		if err := mc.recallGroupMessage(&groupMessage, currentUserID, role); err != nil {
 			utils.LogDebug("❌ 撤回群组消息失败: %v", err)
 			utils.Error(c, http.StatusInternalServerError, "撤回消息失败")
 			return
 		}
 
-		utils.LogDebug("✅ 用户 %d 撤回了群组消息 %d (群组ID: %d)", currentUserID, req.MessageID, groupMessage.GroupID)
-
-		// 获取群组所有成员ID
-		memberIDs, err := mc.groupRepo.GetGroupMemberIDs(groupMessage.GroupID)
-		if err != nil {
-			utils.LogDebug("获取群组成员ID列表失败: %v", err)
-		} else {
-			// 通过WebSocket实时通知所有群组成员消息被撤回
-			recallNotification := models.WSMessage{
-				Type: "message_recalled",
-				Data: gin.H{
-					"message_id": req.MessageID,
-					"sender_id":  currentUserID,
-				},
-			}
-			recallNotificationBytes, _ := json.Marshal(recallNotification)
-
-			// 发送给所有群组成员
-			sentCount := 0
-			for _, memberID := range memberIDs {
-				if mc.Hub.SendToUser(memberID, recallNotificationBytes) {
-					sentCount++
-				}
-			}
-			utils.LogDebug("✅ 撤回通知已发送给群组 %d 的 %d 个成员", groupMessage.GroupID, sentCount)
-		}
-
 		utils.Success(c, gin.H{"message": "消息已撤回"})
 		return
 	}
@@ -2544,42 +2955,18 @@ func (mc *MessageController) RecallMessage(c *gin.Context) {
 		return
 	}
 
-	// 检查是否在3分钟内
-	now := time.Now()
-	diff := now.Sub(message.CreatedAt)
-	if diff.Minutes() > 3 {
-		utils.Error(c, http.StatusBadRequest, "超过3分钟，无法撤回")
+	// 检查是否在撤回时限内
+	if ok, minutes := mc.settingRepo.IsWithinRecallWindow(message.CreatedAt, 0); !ok {
+		utils.Error(c, http.StatusBadRequest, fmt.Sprintf("超过%d分钟，无法撤回", minutes))
 		return
 	}
 
-	// 更新消息状态为已撤回
-	updateQuery := `UPDATE messages SET status = 'recalled' WHERE id = $1`
-	_, err = db.DB.Exec(updateQuery, req.MessageID)
-	if err != nil {
+	if err := mc.recallPrivateMessage(&message, currentUserID); err != nil {
 		utils.LogDebug("❌ 撤回消息失败: %v", err)
 		utils.Error(c, http.StatusInternalServerError, "撤回消息失败")
 		return
 	}
 
-	utils.LogDebug("✅ 用户 %d 撤回了消息 %d", currentUserID, req.MessageID)
-
-	// 通过WebSocket实时通知接收者消息被撤回
-	recallNotification := models.WSMessage{
-		Type: "message_recalled",
-		Data: gin.H{
-			"message_id": req.MessageID,
-			"sender_id":  currentUserID,
-		},
-	}
-	recallNotificationBytes, _ := json.Marshal(recallNotification)
-
-	// 发送给接收者
-	if mc.Hub.SendToUser(message.ReceiverID, recallNotificationBytes) {
-		utils.LogDebug("✅ 撤回通知已发送给接收者 %d", message.ReceiverID)
-	} else {
-		utils.LogDebug("⚠️ 接收者 %d 离线，下次登录时将看到消息已撤回", message.ReceiverID)
-	}
-
 	utils.Success(c, gin.H{"message": "消息已撤回"})
 }
 
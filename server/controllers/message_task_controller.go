@@ -0,0 +1,691 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// taskReminderBatchSize 每批领取的到期任务数
+const taskReminderBatchSize = 100
+
+// MessageTaskController 消息任务控制器
+// 状态变更的系统消息复用 MessageController 的私聊消息保存逻辑
+type MessageTaskController struct {
+	messageCtrl *MessageController
+	taskRepo    *models.MessageTaskRepository
+}
+
+// NewMessageTaskController 创建消息任务控制器
+func NewMessageTaskController(messageCtrl *MessageController) *MessageTaskController {
+	return &MessageTaskController{
+		messageCtrl: messageCtrl,
+		taskRepo:    models.NewMessageTaskRepository(db.DB),
+	}
+}
+
+// CreateTask 将私聊或群聊消息转为任务
+// POST /api/tasks
+func (tc *MessageTaskController) CreateTask(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	currentUserID := userID.(int)
+
+	var req models.CreateMessageTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if req.ConversationType != models.ConversationTypeUser && req.ConversationType != models.ConversationTypeGroup {
+		utils.BadRequest(c, "会话类型只能是 user 或 group")
+		return
+	}
+
+	source, err := tc.taskRepo.GetSourceMessage(req.ConversationType, req.MessageID)
+	if err == sql.ErrNoRows {
+		utils.NotFound(c, "消息不存在")
+		return
+	}
+	if err != nil {
+		utils.LogError("❌ [任务] 获取消息 %s/%d 失败: %v", req.ConversationType, req.MessageID, err)
+		utils.InternalServerError(c, "创建任务失败")
+		return
+	}
+	if source.Status == "recalled" {
+		utils.BadRequest(c, "消息已撤回，无法转为任务")
+		return
+	}
+
+	// 确定会话并校验权限：私聊只能由双方创建，群聊只能由群成员创建
+	task := &models.MessageTask{
+		ConversationType: req.ConversationType,
+		MessageID:        req.MessageID,
+		CreatorID:        currentUserID,
+		Content:          taskMessagePreview(source.MessageType, source.Content),
+	}
+	if req.ConversationType == models.ConversationTypeGroup {
+		task.TargetID = source.GroupID
+		isMember, err := tc.messageCtrl.groupRepo.IsGroupMember(source.GroupID, currentUserID)
+		if err != nil {
+			utils.LogError("❌ [任务] 检查用户 %d 的群组 %d 成员身份失败: %v", currentUserID, source.GroupID, err)
+			utils.InternalServerError(c, "创建任务失败")
+			return
+		}
+		if !isMember {
+			utils.Forbidden(c, "您不是该群组成员")
+			return
+		}
+	} else {
+		switch currentUserID {
+		case source.SenderID:
+			task.TargetID = source.ReceiverID
+		case source.ReceiverID:
+			task.TargetID = source.SenderID
+		default:
+			utils.Forbidden(c, "无权将该消息转为任务")
+			return
+		}
+	}
+
+	title, errMsg := normalizeTaskTitle(req.Title, task.Content)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+	task.Title = title
+
+	if req.DueAt != "" {
+		dueAt, errMsg := parseTaskDueAt(req.DueAt)
+		if errMsg != "" {
+			utils.BadRequest(c, errMsg)
+			return
+		}
+		task.DueAt = dueAt
+	}
+
+	assigneeIDs := req.AssigneeIDs
+	if len(assigneeIDs) == 0 {
+		assigneeIDs = []int{currentUserID}
+	}
+	assigneeIDs, errMsg = tc.validateAssignees(task, assigneeIDs)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	if err := tc.taskRepo.Create(task, assigneeIDs); err != nil {
+		utils.LogError("❌ [任务] 用户 %d 创建任务失败: %v", currentUserID, err)
+		utils.InternalServerError(c, "创建任务失败")
+		return
+	}
+
+	created, err := tc.taskRepo.GetByID(task.ID)
+	if err != nil {
+		utils.LogError("❌ [任务] 获取任务 %d 失败: %v", task.ID, err)
+		utils.InternalServerError(c, "创建任务失败")
+		return
+	}
+
+	notifyTaskAssigned(tc.messageCtrl.Hub, created, excludeTaskUser(assigneeIDs, currentUserID))
+
+	utils.LogInfo("📋 [任务] 用户 %d 将消息 %s/%d 转为任务 %d - 负责人: %v", currentUserID, task.ConversationType, task.MessageID, task.ID, assigneeIDs)
+	utils.SuccessWithMessage(c, "任务已创建", created)
+}
+
+// GetTask 获取任务详情（创建者、负责人及所在会话的成员可查看）
+// GET /api/tasks/:id
+func (tc *MessageTaskController) GetTask(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	task, ok := tc.loadTask(c)
+	if !ok {
+		return
+	}
+	if !tc.canViewTask(task, userID.(int)) {
+		utils.Forbidden(c, "无权查看该任务")
+		return
+	}
+
+	utils.Success(c, task)
+}
+
+// UpdateTask 修改任务标题、负责人和截止时间（仅创建者）
+// PUT /api/tasks/:id
+func (tc *MessageTaskController) UpdateTask(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	currentUserID := userID.(int)
+
+	var req models.UpdateMessageTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	task, ok := tc.loadTask(c)
+	if !ok {
+		return
+	}
+	if task.CreatorID != currentUserID {
+		utils.Forbidden(c, "只有任务创建者可以修改任务")
+		return
+	}
+
+	if req.Title != nil {
+		title, errMsg := normalizeTaskTitle(*req.Title, "")
+		if errMsg != "" {
+			utils.BadRequest(c, errMsg)
+			return
+		}
+		task.Title = title
+	}
+
+	dueChanged := false
+	if req.DueAt != nil {
+		var dueAt *time.Time
+		if *req.DueAt != "" {
+			parsed, errMsg := parseTaskDueAt(*req.DueAt)
+			if errMsg != "" {
+				utils.BadRequest(c, errMsg)
+				return
+			}
+			dueAt = parsed
+		}
+		dueChanged = !sameTaskDueAt(task.DueAt, dueAt)
+		task.DueAt = dueAt
+	}
+
+	var assigneeIDs []int
+	var addedIDs []int
+	if req.AssigneeIDs != nil {
+		if len(*req.AssigneeIDs) == 0 {
+			utils.BadRequest(c, "请至少指定一名负责人")
+			return
+		}
+		var errMsg string
+		assigneeIDs, errMsg = tc.validateAssignees(task, *req.AssigneeIDs)
+		if errMsg != "" {
+			utils.BadRequest(c, errMsg)
+			return
+		}
+		for _, id := range assigneeIDs {
+			if !task.IsAssignee(id) && id != currentUserID {
+				addedIDs = append(addedIDs, id)
+			}
+		}
+	}
+	previousIDs := task.AssigneeIDs()
+
+	if err := tc.taskRepo.Update(task, assigneeIDs, dueChanged); err != nil {
+		utils.LogError("❌ [任务] 修改任务 %d 失败: %v", task.ID, err)
+		utils.InternalServerError(c, "修改任务失败")
+		return
+	}
+
+	updated, err := tc.taskRepo.GetByID(task.ID)
+	if err != nil {
+		utils.LogError("❌ [任务] 获取任务 %d 失败: %v", task.ID, err)
+		utils.InternalServerError(c, "修改任务失败")
+		return
+	}
+
+	// 新负责人收到分配通知，其余相关人员收到更新事件（包括被移除的负责人）
+	notifyTaskAssigned(tc.messageCtrl.Hub, updated, addedIDs)
+	broadcastTaskUpdate(tc.messageCtrl.Hub, updated, previousIDs...)
+
+	utils.SuccessWithMessage(c, "任务已更新", updated)
+}
+
+// UpdateTaskStatus 修改任务状态（创建者或负责人），并在原会话中发送状态变更的系统消息
+// PUT /api/tasks/:id/status
+func (tc *MessageTaskController) UpdateTaskStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	currentUserID := userID.(int)
+
+	var req models.UpdateMessageTaskStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if !models.IsValidTaskStatus(req.Status) {
+		utils.BadRequest(c, "任务状态只能是 todo、in_progress、done 或 cancelled")
+		return
+	}
+
+	task, ok := tc.loadTask(c)
+	if !ok {
+		return
+	}
+	if task.CreatorID != currentUserID && !task.IsAssignee(currentUserID) {
+		utils.Forbidden(c, "只有任务创建者或负责人可以修改任务状态")
+		return
+	}
+
+	changed, err := tc.taskRepo.UpdateStatus(task, req.Status)
+	if err != nil {
+		utils.LogError("❌ [任务] 修改任务 %d 的状态失败: %v", task.ID, err)
+		utils.InternalServerError(c, "修改任务状态失败")
+		return
+	}
+
+	if changed {
+		broadcastTaskUpdate(tc.messageCtrl.Hub, task)
+		tc.postTaskStatusMessage(task, currentUserID)
+		utils.LogInfo("📋 [任务] 用户 %d 将任务 %d 标记为 %s", currentUserID, task.ID, task.Status)
+	}
+
+	utils.SuccessWithMessage(c, "任务状态已更新", task)
+}
+
+// GetMyTasks 获取分配给我的任务
+// GET /api/tasks/mine?status=todo&page=1&page_size=20
+func (tc *MessageTaskController) GetMyTasks(c *gin.Context) {
+	tc.listTasks(c, func(userID int, status string, limit, offset int) ([]models.MessageTask, int, error) {
+		return tc.taskRepo.ListAssignedTo(userID, status, limit, offset)
+	})
+}
+
+// GetCreatedTasks 获取我分配出去的任务
+// GET /api/tasks/created?status=todo&page=1&page_size=20
+func (tc *MessageTaskController) GetCreatedTasks(c *gin.Context) {
+	tc.listTasks(c, func(userID int, status string, limit, offset int) ([]models.MessageTask, int, error) {
+		return tc.taskRepo.ListCreatedBy(userID, status, limit, offset)
+	})
+}
+
+// GetGroupTasks 获取群组中的任务（仅群成员）
+// GET /api/groups/:id/tasks?status=todo&page=1&page_size=20
+func (tc *MessageTaskController) GetGroupTasks(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的群组ID")
+		return
+	}
+
+	isMember, err := tc.messageCtrl.groupRepo.IsGroupMember(groupID, userID.(int))
+	if err != nil {
+		utils.LogError("❌ [任务] 检查用户 %d 的群组 %d 成员身份失败: %v", userID.(int), groupID, err)
+		utils.InternalServerError(c, "获取任务列表失败")
+		return
+	}
+	if !isMember {
+		utils.Forbidden(c, "您不是该群组成员")
+		return
+	}
+
+	tc.listTasks(c, func(_ int, status string, limit, offset int) ([]models.MessageTask, int, error) {
+		return tc.taskRepo.ListByGroup(groupID, status, limit, offset)
+	})
+}
+
+// listTasks 解析分页和状态过滤参数并返回任务列表
+func (tc *MessageTaskController) listTasks(c *gin.Context, query func(userID int, status string, limit, offset int) ([]models.MessageTask, int, error)) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	status := c.Query("status")
+	if status != "" && !models.IsValidTaskStatus(status) {
+		utils.BadRequest(c, "任务状态只能是 todo、in_progress、done 或 cancelled")
+		return
+	}
+
+	tasks, total, err := query(userID.(int), status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		utils.LogError("❌ [任务] 获取用户 %d 的任务列表失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "获取任务列表失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"tasks":     tasks,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// loadTask 根据路径参数加载任务，失败时已写入响应
+func (tc *MessageTaskController) loadTask(c *gin.Context) (*models.MessageTask, bool) {
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的任务ID")
+		return nil, false
+	}
+
+	task, err := tc.taskRepo.GetByID(taskID)
+	if err == sql.ErrNoRows {
+		utils.NotFound(c, "任务不存在")
+		return nil, false
+	}
+	if err != nil {
+		utils.LogError("❌ [任务] 获取任务 %d 失败: %v", taskID, err)
+		utils.InternalServerError(c, "获取任务失败")
+		return nil, false
+	}
+	return task, true
+}
+
+// canViewTask 创建者、负责人及任务所在会话的成员可查看任务
+func (tc *MessageTaskController) canViewTask(task *models.MessageTask, userID int) bool {
+	if task.CreatorID == userID || task.IsAssignee(userID) {
+		return true
+	}
+	if task.ConversationType == models.ConversationTypeGroup {
+		isMember, err := tc.messageCtrl.groupRepo.IsGroupMember(task.TargetID, userID)
+		return err == nil && isMember
+	}
+	return task.TargetID == userID
+}
+
+// validateAssignees 去重并校验负责人：私聊只能指派给会话双方，群聊只能指派给群成员
+func (tc *MessageTaskController) validateAssignees(task *models.MessageTask, assigneeIDs []int) ([]int, string) {
+	allowed := map[int]bool{}
+	if task.ConversationType == models.ConversationTypeGroup {
+		memberIDs, err := tc.messageCtrl.groupRepo.GetGroupMemberIDs(task.TargetID)
+		if err != nil {
+			utils.LogError("❌ [任务] 获取群组 %d 成员失败: %v", task.TargetID, err)
+			return nil, "获取群成员失败"
+		}
+		for _, id := range memberIDs {
+			allowed[id] = true
+		}
+	} else {
+		allowed[task.CreatorID] = true
+		allowed[task.TargetID] = true
+	}
+
+	seen := make(map[int]bool, len(assigneeIDs))
+	result := make([]int, 0, len(assigneeIDs))
+	for _, id := range assigneeIDs {
+		if seen[id] {
+			continue
+		}
+		if !allowed[id] {
+			if task.ConversationType == models.ConversationTypeGroup {
+				return nil, "负责人必须是群组成员"
+			}
+			return nil, "私聊任务只能指派给会话双方"
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	if len(result) > models.MaxTaskAssignees {
+		return nil, "负责人最多 " + strconv.Itoa(models.MaxTaskAssignees) + " 人"
+	}
+	return result, ""
+}
+
+// postTaskStatusMessage 在任务所在会话中发送状态变更的系统消息
+func (tc *MessageTaskController) postTaskStatusMessage(task *models.MessageTask, actorID int) {
+	actorName := "系统"
+	if user, err := tc.messageCtrl.userRepo.FindByID(actorID); err == nil {
+		actorName = user.Username
+		if user.FullName != nil && *user.FullName != "" {
+			actorName = *user.FullName
+		}
+	}
+	content := actorName + " 将任务「" + task.Title + "」标记为" + models.TaskStatusLabels[task.Status]
+
+	hub := tc.messageCtrl.Hub
+	if task.ConversationType == models.ConversationTypeGroup {
+		message, err := tc.messageCtrl.groupRepo.CreateGroupMessage(&models.CreateGroupMessageRequest{
+			GroupID:     task.TargetID,
+			Content:     content,
+			MessageType: "system",
+		}, actorID, actorName, nil, nil, nil)
+		if err != nil {
+			utils.LogError("❌ [任务] 保存任务 %d 的状态变更消息失败: %v", task.ID, err)
+			return
+		}
+
+		memberIDs, err := tc.messageCtrl.groupRepo.GetGroupMemberIDs(task.TargetID)
+		if err != nil {
+			utils.LogDebug("获取群组成员ID列表失败: %v", err)
+			return
+		}
+
+		wsMsg := models.WSGroupMessage{
+			Type:    "group_message",
+			GroupID: message.GroupID,
+			Data: models.WSGroupMessageData{
+				ID:          message.ID,
+				GroupID:     message.GroupID,
+				SenderID:    message.SenderID,
+				SenderName:  message.SenderName,
+				Content:     message.Content,
+				MessageType: message.MessageType,
+				CreatedAt:   message.CreatedAt.UTC(),
+			},
+		}
+		msgBytes, err := json.Marshal(wsMsg)
+		if err != nil {
+			utils.LogDebug("序列化任务状态变更消息失败: %v", err)
+			return
+		}
+		hub.BroadcastToUsers(memberIDs, msgBytes, 0)
+		return
+	}
+
+	// 私聊：系统消息由操作人发给会话另一方，双方都会收到
+	peerID := task.TargetID
+	if actorID != task.CreatorID {
+		peerID = task.CreatorID
+	}
+	msg, err := tc.messageCtrl.saveMessage(actorID, peerID, content, "system", "", 0, "", "", 0)
+	if err != nil {
+		utils.LogError("❌ [任务] 保存任务 %d 的状态变更消息失败: %v", task.ID, err)
+		return
+	}
+
+	wsMsg := models.WSMessage{
+		Type: "message",
+		Data: models.WSMessageData{
+			ID:             msg.ID,
+			SenderID:       msg.SenderID,
+			ReceiverID:     msg.ReceiverID,
+			SenderName:     msg.SenderName,
+			ReceiverName:   msg.ReceiverName,
+			SenderAvatar:   msg.SenderAvatar,
+			ReceiverAvatar: msg.ReceiverAvatar,
+			Content:        msg.Content,
+			MessageType:    msg.MessageType,
+			IsRead:         msg.IsRead,
+			CreatedAt:      msg.CreatedAt.UTC(),
+		},
+	}
+	msgBytes, _ := json.Marshal(wsMsg)
+	hub.BroadcastToUsers([]int{actorID, peerID}, msgBytes, 0)
+}
+
+// RemindDueTasks 向到期仍未完成的任务负责人发送提醒，离线的负责人通过离线推送提醒（由定时任务调用）
+func RemindDueTasks(hub *ws.Hub) {
+	repo := models.NewMessageTaskRepository(db.DB)
+	tasks, err := repo.ClaimDueReminders(time.Now().UTC(), taskReminderBatchSize)
+	if err != nil {
+		utils.LogError("❌ [任务] 领取到期任务失败: %v", err)
+		return
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		offlineIDs := sendTaskEvent(hub, "task_reminder", task, task.AssigneeIDs())
+		for _, userID := range offlineIDs {
+			pushTaskNotification(task, userID, "任务到期提醒", "Task due")
+		}
+		utils.LogDebug("⏰ [任务] 任务 %d 已到期，已提醒 %d 名负责人", task.ID, len(task.Assignees))
+	}
+}
+
+// notifyTaskAssigned 通知新的负责人（离线时发送推送）
+func notifyTaskAssigned(hub *ws.Hub, task *models.MessageTask, userIDs []int) {
+	offlineIDs := sendTaskEvent(hub, "task_assigned", task, userIDs)
+	for _, userID := range offlineIDs {
+		pushTaskNotification(task, userID, task.CreatorName+" 给您分配了任务", task.CreatorName+" assigned you a task")
+	}
+}
+
+// broadcastTaskUpdate 向任务创建者、负责人及额外指定的用户推送 task_updated 事件
+func broadcastTaskUpdate(hub *ws.Hub, task *models.MessageTask, extraIDs ...int) {
+	userIDs := append([]int{task.CreatorID}, task.AssigneeIDs()...)
+	userIDs = append(userIDs, extraIDs...)
+
+	seen := make(map[int]bool, len(userIDs))
+	unique := make([]int, 0, len(userIDs))
+	for _, id := range userIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	sendTaskEvent(hub, "task_updated", task, unique)
+}
+
+// sendTaskEvent 向用户推送任务事件，返回离线的用户
+func sendTaskEvent(hub *ws.Hub, eventType string, task *models.MessageTask, userIDs []int) []int {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	msgBytes, err := json.Marshal(models.WSMessage{Type: eventType, Data: task})
+	if err != nil {
+		utils.LogDebug("序列化任务事件失败: %v", err)
+		return nil
+	}
+
+	var offlineIDs []int
+	for _, userID := range userIDs {
+		if !hub.SendToUser(userID, msgBytes) {
+			offlineIDs = append(offlineIDs, userID)
+		}
+	}
+	return offlineIDs
+}
+
+// pushTaskNotification 通过离线推送发送任务通知（标题按令牌语言选择，正文为任务标题）
+func pushTaskNotification(task *models.MessageTask, userID int, titleZH, titleEN string) {
+	if !utils.PushEnabled() {
+		return
+	}
+
+	// 私聊任务对负责人而言的会话对方
+	targetID := task.TargetID
+	if task.ConversationType == models.ConversationTypeUser && userID == task.TargetID {
+		targetID = task.CreatorID
+	}
+
+	sendPushToUser(userID, func(locale string) *utils.PushNotification {
+		title := titleZH
+		if pushLanguage(locale) == "en" {
+			title = titleEN
+		}
+		return &utils.PushNotification{
+			Title:       title,
+			Body:        task.Title,
+			CollapseKey: "task_" + strconv.Itoa(task.ID),
+			Data: map[string]string{
+				"task_id":           strconv.Itoa(task.ID),
+				"conversation_type": task.ConversationType,
+				"target_id":         strconv.Itoa(targetID),
+				"message_id":        strconv.Itoa(task.MessageID),
+			},
+		}
+	})
+}
+
+// excludeTaskUser 从用户列表中排除指定用户
+func excludeTaskUser(userIDs []int, excludeID int) []int {
+	result := make([]int, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id != excludeID {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// taskMessagePreview 生成来源消息预览
+func taskMessagePreview(messageType, content string) string {
+	preview := pushPreview("zh", messageType, content)
+	if utf8.RuneCountInString(preview) > models.MaxTaskTitleLength {
+		preview = string([]rune(preview)[:models.MaxTaskTitleLength])
+	}
+	return preview
+}
+
+// normalizeTaskTitle 校验任务标题，为空时使用消息预览
+func normalizeTaskTitle(title, fallback string) (string, string) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = strings.TrimSpace(fallback)
+	}
+	if title == "" {
+		return "", "任务标题不能为空"
+	}
+	if utf8.RuneCountInString(title) > models.MaxTaskTitleLength {
+		return "", "任务标题不能超过 " + strconv.Itoa(models.MaxTaskTitleLength) + " 个字符"
+	}
+	return title, ""
+}
+
+// parseTaskDueAt 解析截止时间（RFC3339），必须晚于当前时间
+func parseTaskDueAt(value string) (*time.Time, string) {
+	dueAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, "截止时间格式错误，请使用 RFC3339 格式"
+	}
+	dueAt = dueAt.UTC()
+	if !dueAt.After(time.Now().UTC()) {
+		return nil, "截止时间必须晚于当前时间"
+	}
+	return &dueAt, ""
+}
+
+// sameTaskDueAt 判断两个截止时间是否相同
+func sameTaskDueAt(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,564 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 内容审核相关常量
+const (
+	moderationRulesTTL      = time.Minute // 规则缓存时间（多实例部署时其他实例的规则变更在此时间内生效）
+	maxModerationPatternLen = 500         // 规则内容最大长度（字符）
+	contentBlockedCode      = "content_blocked"
+	contentBlockedMessage   = "消息包含违规内容，无法发送"
+)
+
+// moderationHit 规则命中记录
+type moderationHit struct {
+	Rule  *models.ModerationRule
+	Start int // 命中文本起始字节位置
+	End   int // 命中文本结束字节位置（不含）
+}
+
+// moderationOutcome 内容审核结果
+type moderationOutcome struct {
+	Blocked bool           // 命中 block 规则，拒绝发送
+	Content string         // 审核后的内容（mask 规则命中部分已替换为*）
+	Flag    *moderationHit // 命中的第一条 flag 规则，发送后需加入审核队列
+	Matched []string       // 命中的 flag 规则文本
+}
+
+// moderationRuleSet 已编译的审核规则
+type moderationRuleSet struct {
+	words      []*models.ModerationRule
+	matcher    *utils.KeywordMatcher
+	regexRules []*models.ModerationRule
+	regexes    []*regexp.Regexp
+}
+
+var (
+	moderationMu       sync.RWMutex
+	moderationRules    *moderationRuleSet
+	moderationLoadedAt time.Time
+)
+
+// invalidateModerationRules 使规则缓存失效（规则变更后调用）
+func invalidateModerationRules() {
+	moderationMu.Lock()
+	moderationRules = nil
+	moderationMu.Unlock()
+}
+
+// loadModerationRules 获取已编译的审核规则，缓存过期时从数据库重新加载
+func loadModerationRules() *moderationRuleSet {
+	moderationMu.RLock()
+	rules := moderationRules
+	fresh := time.Since(moderationLoadedAt) < moderationRulesTTL
+	moderationMu.RUnlock()
+	if rules != nil && fresh {
+		return rules
+	}
+
+	moderationMu.Lock()
+	defer moderationMu.Unlock()
+	if moderationRules != nil && time.Since(moderationLoadedAt) < moderationRulesTTL {
+		return moderationRules
+	}
+
+	list, err := models.NewModerationRepository(db.DB).ListRules(true)
+	if err != nil {
+		utils.LogError("❌ [内容审核] 加载审核规则失败: %v", err)
+		// 加载失败时沿用旧规则，避免数据库抖动导致审核失效
+		if moderationRules != nil {
+			return moderationRules
+		}
+		return &moderationRuleSet{}
+	}
+
+	set := compileModerationRules(list)
+	moderationRules = set
+	moderationLoadedAt = time.Now()
+	utils.LogDebug("🛡️ [内容审核] 已加载 %d 条敏感词规则、%d 条正则规则", len(set.words), len(set.regexRules))
+	return set
+}
+
+// compileModerationRules 编译审核规则：敏感词构建关键词匹配器，正则规则逐条编译（无效正则跳过）
+func compileModerationRules(list []models.ModerationRule) *moderationRuleSet {
+	set := &moderationRuleSet{}
+	var keywords []string
+	for i := range list {
+		rule := &list[i]
+		switch rule.RuleType {
+		case models.ModerationRuleRegex:
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				utils.LogError("❌ [内容审核] 规则 %d 正则表达式无效: %v", rule.ID, err)
+				continue
+			}
+			set.regexRules = append(set.regexRules, rule)
+			set.regexes = append(set.regexes, re)
+		default:
+			set.words = append(set.words, rule)
+			keywords = append(keywords, rule.Pattern)
+		}
+	}
+	set.matcher = utils.NewKeywordMatcher(keywords)
+	return set
+}
+
+// find 返回文本命中的所有规则
+func (s *moderationRuleSet) find(text string) []moderationHit {
+	if text == "" {
+		return nil
+	}
+
+	var hits []moderationHit
+	for _, match := range s.matcher.FindAll(text) {
+		hits = append(hits, moderationHit{Rule: s.words[match.Keyword], Start: match.Start, End: match.End})
+	}
+	for i, re := range s.regexes {
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if loc[1] > loc[0] {
+				hits = append(hits, moderationHit{Rule: s.regexRules[i], Start: loc[0], End: loc[1]})
+			}
+		}
+	}
+	return hits
+}
+
+// mask 将 mask 规则命中的文本替换为*
+func (s *moderationRuleSet) mask(text string) string {
+	hits := s.find(text)
+	masked := make([]bool, len(text))
+	changed := false
+	for _, hit := range hits {
+		if hit.Rule.Action != models.ModerationActionMask {
+			continue
+		}
+		for i := hit.Start; i < hit.End; i++ {
+			masked[i] = true
+		}
+		changed = true
+	}
+	if !changed {
+		return text
+	}
+
+	var sb strings.Builder
+	for i, r := range text {
+		if masked[i] {
+			sb.WriteRune('*')
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// moderateMessageContent 在消息保存前审核文本内容（文本和富文本消息）
+// 任一 block 规则命中则拒绝发送；mask 规则命中部分替换为*；flag 规则命中则正常发送并在保存后加入审核队列
+func moderateMessageContent(messageType, content string) *moderationOutcome {
+	if messageType != "text" && messageType != models.MessageTypeRichText {
+		return &moderationOutcome{Content: content}
+	}
+	return loadModerationRules().moderate(messageType, content)
+}
+
+// moderate 按规则审核文本或富文本内容，优先级 block > mask > flag
+func (s *moderationRuleSet) moderate(messageType, content string) *moderationOutcome {
+	outcome := &moderationOutcome{Content: content}
+	if len(s.words) == 0 && len(s.regexRules) == 0 {
+		return outcome
+	}
+
+	var doc *models.RichTextDocument
+	text := content
+	if messageType == models.MessageTypeRichText {
+		parsed, err := models.ParseRichTextContent(content)
+		if err != nil {
+			return outcome
+		}
+		doc = parsed
+		text = doc.PlainText()
+	}
+
+	masked := false
+	for _, hit := range s.find(text) {
+		switch hit.Rule.Action {
+		case models.ModerationActionBlock:
+			outcome.Blocked = true
+			return outcome
+		case models.ModerationActionMask:
+			masked = true
+		case models.ModerationActionFlag:
+			if outcome.Flag == nil {
+				h := hit
+				outcome.Flag = &h
+			}
+			outcome.Matched = append(outcome.Matched, text[hit.Start:hit.End])
+		}
+	}
+
+	if masked {
+		if doc != nil {
+			doc.MapText(s.mask)
+			if data, err := json.Marshal(doc); err == nil {
+				outcome.Content = string(data)
+			}
+		} else {
+			outcome.Content = s.mask(content)
+		}
+	}
+	return outcome
+}
+
+// recordModerationFlag 将命中 flag 规则的已保存消息加入审核队列
+func recordModerationFlag(outcome *moderationOutcome, source string, messageID, senderID, targetID int, content string) {
+	if outcome == nil || outcome.Flag == nil {
+		return
+	}
+
+	ruleID := outcome.Flag.Rule.ID
+	flag := &models.ModerationFlag{
+		RuleID:    &ruleID,
+		Source:    source,
+		MessageID: messageID,
+		SenderID:  senderID,
+		TargetID:  targetID,
+		Content:   content,
+		Matched:   strings.Join(outcome.Matched, ","),
+	}
+	if err := models.NewModerationRepository(db.DB).CreateFlag(flag); err != nil {
+		utils.LogError("❌ [内容审核] 消息 %s/%d 加入审核队列失败: %v", source, messageID, err)
+		return
+	}
+	utils.LogInfo("🛡️ [内容审核] 消息 %s/%d 命中规则 %d，已加入审核队列", source, messageID, ruleID)
+}
+
+// sendContentBlockedError 向发送者推送内容被拦截的错误帧（frameType 为 message_error 或 group_message_error）
+func sendContentBlockedError(client *ws.Client, frameType string, data gin.H) {
+	if data == nil {
+		data = gin.H{}
+	}
+	data["error"] = contentBlockedMessage
+	data["message"] = contentBlockedMessage
+	data["code"] = contentBlockedCode
+
+	errorMsg := models.WSMessage{
+		Type: frameType,
+		Data: data,
+	}
+	errorMsgBytes, _ := json.Marshal(errorMsg)
+	client.Send <- errorMsgBytes
+}
+
+// respondContentBlocked HTTP 接口返回内容被拦截的错误（data.code 为 content_blocked）
+func respondContentBlocked(c *gin.Context) {
+	utils.ErrorWithData(c, http.StatusBadRequest, contentBlockedMessage, gin.H{"code": contentBlockedCode})
+}
+
+// ModerationController 内容审核管理控制器（管理后台使用）
+type ModerationController struct {
+	Hub            *ws.Hub
+	moderationRepo *models.ModerationRepository
+}
+
+// NewModerationController 创建内容审核管理控制器
+func NewModerationController(hub *ws.Hub) *ModerationController {
+	return &ModerationController{
+		Hub:            hub,
+		moderationRepo: models.NewModerationRepository(db.DB),
+	}
+}
+
+// AdminGetRules 获取所有审核规则
+func (mc *ModerationController) AdminGetRules(c *gin.Context) {
+	rules, err := mc.moderationRepo.ListRules(false)
+	if err != nil {
+		utils.LogError("❌ [内容审核] 获取审核规则失败: %v", err)
+		utils.InternalServerError(c, "获取审核规则失败")
+		return
+	}
+	utils.Success(c, gin.H{"rules": rules})
+}
+
+// AdminCreateRule 创建审核规则
+func (mc *ModerationController) AdminCreateRule(c *gin.Context) {
+	rule, errMsg := buildModerationRule(c)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	created, err := mc.moderationRepo.CreateRule(rule)
+	if err != nil {
+		utils.LogError("❌ [内容审核] 创建审核规则失败: %v", err)
+		utils.InternalServerError(c, "创建审核规则失败")
+		return
+	}
+	invalidateModerationRules()
+
+	utils.LogInfo("🛡️ [内容审核] 创建审核规则 %d（%s/%s）", created.ID, created.RuleType, created.Action)
+	utils.SuccessWithMessage(c, "创建成功", created)
+}
+
+// AdminUpdateRule 更新审核规则
+func (mc *ModerationController) AdminUpdateRule(c *gin.Context) {
+	ruleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的规则ID")
+		return
+	}
+
+	rule, errMsg := buildModerationRule(c)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+	rule.ID = ruleID
+
+	updated, err := mc.moderationRepo.UpdateRule(rule)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "审核规则不存在")
+			return
+		}
+		utils.LogError("❌ [内容审核] 更新审核规则 %d 失败: %v", ruleID, err)
+		utils.InternalServerError(c, "更新审核规则失败")
+		return
+	}
+	invalidateModerationRules()
+
+	utils.SuccessWithMessage(c, "更新成功", updated)
+}
+
+// AdminDeleteRule 删除审核规则
+func (mc *ModerationController) AdminDeleteRule(c *gin.Context) {
+	ruleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的规则ID")
+		return
+	}
+
+	deleted, err := mc.moderationRepo.DeleteRule(ruleID)
+	if err != nil {
+		utils.LogError("❌ [内容审核] 删除审核规则 %d 失败: %v", ruleID, err)
+		utils.InternalServerError(c, "删除审核规则失败")
+		return
+	}
+	if !deleted {
+		utils.NotFound(c, "审核规则不存在")
+		return
+	}
+	invalidateModerationRules()
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// AdminGetFlags 获取审核队列（分页）
+// GET /api/admin/moderation/flags?status=pending&page=1&page_size=20
+func (mc *ModerationController) AdminGetFlags(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ModerationFlagPending)
+	if status == "all" {
+		status = ""
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	flags, total, err := mc.moderationRepo.ListFlags(status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		utils.LogError("❌ [内容审核] 获取审核队列失败: %v", err)
+		utils.InternalServerError(c, "获取审核队列失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"flags":     flags,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// AdminReviewFlag 审核队列中的消息：approve 保留消息，remove 撤回消息并通知相关用户
+func (mc *ModerationController) AdminReviewFlag(c *gin.Context) {
+	flagID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的审核记录ID")
+		return
+	}
+
+	var req models.ReviewModerationFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "无效的请求参数")
+		return
+	}
+
+	var status string
+	switch req.Decision {
+	case "approve":
+		status = models.ModerationFlagApproved
+	case "remove":
+		status = models.ModerationFlagRemoved
+	default:
+		utils.BadRequest(c, "审核结果必须是 approve 或 remove")
+		return
+	}
+	if utf8.RuneCountInString(req.Note) > 500 {
+		utils.BadRequest(c, "审核备注不能超过500个字符")
+		return
+	}
+
+	flag, err := mc.moderationRepo.GetFlag(flagID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "审核记录不存在")
+			return
+		}
+		utils.LogError("❌ [内容审核] 获取审核记录 %d 失败: %v", flagID, err)
+		utils.InternalServerError(c, "审核失败")
+		return
+	}
+
+	reviewed, err := mc.moderationRepo.ReviewFlag(flagID, status, req.Note)
+	if err != nil {
+		utils.LogError("❌ [内容审核] 更新审核记录 %d 失败: %v", flagID, err)
+		utils.InternalServerError(c, "审核失败")
+		return
+	}
+	if !reviewed {
+		utils.BadRequest(c, "该记录已审核")
+		return
+	}
+
+	if status == models.ModerationFlagRemoved {
+		if err := mc.removeFlaggedMessage(flag); err != nil {
+			utils.LogError("❌ [内容审核] 撤回违规消息 %s/%d 失败: %v", flag.Source, flag.MessageID, err)
+			utils.InternalServerError(c, "撤回违规消息失败")
+			return
+		}
+	}
+
+	utils.LogInfo("🛡️ [内容审核] 审核记录 %d 处理结果: %s", flagID, status)
+	utils.SuccessWithMessage(c, "审核完成", gin.H{"id": flagID, "status": status})
+}
+
+// removeFlaggedMessage 撤回被审核移除的消息并通知相关用户
+func (mc *ModerationController) removeFlaggedMessage(flag *models.ModerationFlag) error {
+	now := time.Now().UTC()
+	switch flag.Source {
+	case models.ModerationSourceMessage:
+		if _, err := db.DB.Exec(`UPDATE messages SET status = 'recalled', recalled_at = $2 WHERE id = $1`, flag.MessageID, now); err != nil {
+			return err
+		}
+		notification := models.WSMessage{
+			Type: "message_recalled",
+			Data: gin.H{
+				"message_id":    flag.MessageID,
+				"sender_id":     flag.SenderID,
+				"is_moderation": true,
+			},
+		}
+		notificationBytes, _ := json.Marshal(notification)
+		mc.Hub.BroadcastToUsers([]int{flag.SenderID, flag.TargetID}, notificationBytes, 0)
+
+	case models.ModerationSourceGroupMessage:
+		if _, err := db.DB.Exec(`UPDATE group_messages SET status = 'recalled', recalled_at = $2 WHERE id = $1`, flag.MessageID, now); err != nil {
+			return err
+		}
+		memberIDs, err := models.NewGroupRepository(db.DB).GetGroupMemberIDs(flag.TargetID)
+		if err != nil {
+			utils.LogDebug("⚠️ [内容审核] 获取群组成员ID列表失败: %v", err)
+			return nil
+		}
+		notification := models.WSMessage{
+			Type: "message_recalled",
+			Data: gin.H{
+				"message_id":         flag.MessageID,
+				"group_id":           flag.TargetID,
+				"original_sender_id": flag.SenderID,
+				"is_moderation":      true,
+				"recall_notice":      "该消息因违反内容规范已被移除",
+			},
+		}
+		notificationBytes, _ := json.Marshal(notification)
+		mc.Hub.BroadcastToUsers(memberIDs, notificationBytes, 0)
+
+	case models.ModerationSourceFileAssistant:
+		if _, err := db.DB.Exec(`UPDATE file_assistant_messages SET status = 'recalled' WHERE id = $1`, flag.MessageID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildModerationRule 解析并校验审核规则请求
+func buildModerationRule(c *gin.Context) (*models.ModerationRule, string) {
+	var req models.SaveModerationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, "规则内容和动作不能为空"
+	}
+
+	rule := &models.ModerationRule{
+		Pattern:     req.Pattern,
+		RuleType:    req.RuleType,
+		Action:      req.Action,
+		Description: strings.TrimSpace(req.Description),
+		Enabled:     true,
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if rule.RuleType == "" {
+		rule.RuleType = models.ModerationRuleWord
+	}
+
+	switch rule.RuleType {
+	case models.ModerationRuleWord:
+		rule.Pattern = strings.TrimSpace(rule.Pattern)
+		if rule.Pattern == "" {
+			return nil, "敏感词不能为空"
+		}
+	case models.ModerationRuleRegex:
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return nil, "正则表达式无效: " + err.Error()
+		}
+	default:
+		return nil, "规则类型必须是 word 或 regex"
+	}
+	if utf8.RuneCountInString(rule.Pattern) > maxModerationPatternLen {
+		return nil, "规则内容不能超过500个字符"
+	}
+
+	switch rule.Action {
+	case models.ModerationActionBlock, models.ModerationActionMask, models.ModerationActionFlag:
+	default:
+		return nil, "规则动作必须是 block、mask 或 flag"
+	}
+
+	if utf8.RuneCountInString(rule.Description) > 255 {
+		return nil, "规则说明不能超过255个字符"
+	}
+	return rule, ""
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"youdu-server/models"
+)
+
+func TestModerationRuleSetModerate(t *testing.T) {
+	rules := compileModerationRules([]models.ModerationRule{
+		{ID: 1, Pattern: "违禁品", RuleType: models.ModerationRuleWord, Action: models.ModerationActionBlock},
+		{ID: 2, Pattern: "傻瓜", RuleType: models.ModerationRuleWord, Action: models.ModerationActionMask},
+		{ID: 3, Pattern: "瓜子", RuleType: models.ModerationRuleWord, Action: models.ModerationActionMask},
+		{ID: 4, Pattern: "Damn", RuleType: models.ModerationRuleWord, Action: models.ModerationActionMask},
+		{ID: 5, Pattern: "转账", RuleType: models.ModerationRuleWord, Action: models.ModerationActionFlag},
+		{ID: 6, Pattern: `1[3-9]\d{9}`, RuleType: models.ModerationRuleRegex, Action: models.ModerationActionMask},
+		{ID: 7, Pattern: `(?i)casino\d*`, RuleType: models.ModerationRuleRegex, Action: models.ModerationActionBlock},
+		{ID: 8, Pattern: `加微信\S+`, RuleType: models.ModerationRuleRegex, Action: models.ModerationActionFlag},
+		{ID: 9, Pattern: `([`, RuleType: models.ModerationRuleRegex, Action: models.ModerationActionBlock},
+	})
+
+	tests := []struct {
+		name        string
+		content     string
+		wantBlocked bool
+		wantContent string
+		wantFlag    int // 命中的 flag 规则ID，0 表示不加入审核队列
+		wantMatched []string
+	}{
+		{name: "未命中", content: "你好，世界", wantContent: "你好，世界"},
+		{name: "敏感词拦截", content: "出售违禁品", wantBlocked: true},
+		{name: "正则拦截", content: "visit CASINO88 now", wantBlocked: true},
+		{name: "中文打码按字符替换", content: "你这个傻瓜", wantContent: "你这个**"},
+		{name: "重叠命中合并打码", content: "傻瓜子", wantContent: "***"},
+		{name: "忽略大小写打码", content: "oh DAMN it", wantContent: "oh **** it"},
+		{name: "正则打码", content: "电话13812345678找我", wantContent: "电话***********找我"},
+		{name: "仅命中flag正常发送", content: "明天转账", wantContent: "明天转账", wantFlag: 5, wantMatched: []string{"转账"}},
+		{name: "正则flag", content: "加微信abc123", wantContent: "加微信abc123", wantFlag: 8, wantMatched: []string{"加微信abc123"}},
+		{name: "block优先于mask和flag", content: "傻瓜转账买违禁品", wantBlocked: true},
+		{name: "mask与flag同时命中", content: "傻瓜转账", wantContent: "**转账", wantFlag: 5, wantMatched: []string{"转账"}},
+		{name: "无效正则被跳过", content: "([ 普通文本", wantContent: "([ 普通文本"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome := rules.moderate("text", tt.content)
+			if outcome.Blocked != tt.wantBlocked {
+				t.Fatalf("Blocked = %v, want %v", outcome.Blocked, tt.wantBlocked)
+			}
+			if tt.wantBlocked {
+				return
+			}
+			if outcome.Content != tt.wantContent {
+				t.Errorf("Content = %q, want %q", outcome.Content, tt.wantContent)
+			}
+			gotFlag := 0
+			if outcome.Flag != nil {
+				gotFlag = outcome.Flag.Rule.ID
+			}
+			if gotFlag != tt.wantFlag {
+				t.Errorf("Flag rule = %d, want %d", gotFlag, tt.wantFlag)
+			}
+			if len(outcome.Matched) != len(tt.wantMatched) {
+				t.Fatalf("Matched = %q, want %q", outcome.Matched, tt.wantMatched)
+			}
+			for i := range tt.wantMatched {
+				if outcome.Matched[i] != tt.wantMatched[i] {
+					t.Errorf("Matched[%d] = %q, want %q", i, outcome.Matched[i], tt.wantMatched[i])
+				}
+			}
+		})
+	}
+}
+
+func TestModerationRuleSetModerateRichText(t *testing.T) {
+	rules := compileModerationRules([]models.ModerationRule{
+		{ID: 1, Pattern: "傻瓜", RuleType: models.ModerationRuleWord, Action: models.ModerationActionMask},
+		{ID: 2, Pattern: "违禁品", RuleType: models.ModerationRuleWord, Action: models.ModerationActionBlock},
+	})
+
+	doc, err := models.ParseRichText("**傻瓜** 见 [傻瓜链接](https://example.com)\n```\n傻瓜代码\n```")
+	if err != nil {
+		t.Fatalf("ParseRichText 返回错误: %v", err)
+	}
+	data, _ := json.Marshal(doc)
+
+	outcome := rules.moderate(models.MessageTypeRichText, string(data))
+	if outcome.Blocked {
+		t.Fatal("仅命中 mask 规则时不应拦截")
+	}
+	masked, err := models.ParseRichTextContent(outcome.Content)
+	if err != nil {
+		t.Fatalf("打码后的内容应为有效的富文本文档: %v", err)
+	}
+	if want := "** 见 **链接 (https://example.com)\n**代码"; masked.PlainText() != want {
+		t.Errorf("PlainText = %q, want %q", masked.PlainText(), want)
+	}
+
+	blockedDoc, _ := models.ParseRichText("- 出售违禁品")
+	blockedData, _ := json.Marshal(blockedDoc)
+	if !rules.moderate(models.MessageTypeRichText, string(blockedData)).Blocked {
+		t.Error("列表中的拦截词也应拦截整条富文本消息")
+	}
+}
+
+func TestModerateMessageContentSkipsNonText(t *testing.T) {
+	for _, messageType := range []string{"image", "file", models.MessageTypePoll, models.MessageTypeEncrypted} {
+		outcome := moderateMessageContent(messageType, "违禁品")
+		if outcome.Blocked || outcome.Content != "违禁品" || outcome.Flag != nil {
+			t.Errorf("%s 类型消息不应审核，实际 %+v", messageType, outcome)
+		}
+	}
+}
@@ -355,6 +355,18 @@ func resolveFolderByType(fileType string) (string, error) {
 	}
 }
 
+// isUserUploadKey 判断对象键是否位于用户自己的上传目录（{folder}/user/{userID}/）下
+func isUserUploadKey(objectKey string, userID int) bool {
+	folder, rest, ok := strings.Cut(objectKey, "/")
+	if !ok {
+		return false
+	}
+	if resolved, err := resolveFolderByType(folder); err != nil || resolved != folder {
+		return false
+	}
+	return strings.HasPrefix(rest, fmt.Sprintf("user/%d/", userID))
+}
+
 type getOpusUploadURLRequest struct {
 	FileName string `json:"fileName" binding:"required"`
 }
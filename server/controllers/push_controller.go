@@ -0,0 +1,381 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 离线推送相关常量
+const (
+	pushCollapseWindow = 3 * time.Second  // 同一会话在窗口内的多条消息合并为一条通知
+	pushSendTimeout    = 10 * time.Second // 单次推送超时
+	pushBodyMaxRunes   = 100              // 通知正文最大长度
+)
+
+// pushKey 待发送通知的折叠键（接收者 + 会话）
+type pushKey struct {
+	userID           int
+	conversationType string
+	targetID         int
+}
+
+// pushMessage 待推送的一条消息
+type pushMessage struct {
+	conversationType string
+	targetID         int // 私聊为发送者ID，群聊为群组ID
+	messageID        int
+	senderName       string
+	groupName        string
+	messageType      string
+	content          string
+	mentioned        bool
+}
+
+// pendingPush 折叠窗口内累积的通知
+type pendingPush struct {
+	count     int
+	mentioned bool
+	latest    pushMessage
+}
+
+var (
+	pushPendingMu sync.Mutex
+	pushPending   = map[pushKey]*pendingPush{}
+)
+
+// 推送依赖的令牌与免打扰查询（测试时可替换，无需真实数据库）
+var (
+	pushConversationMuted = func(userID int, conversationType string, targetID int) (bool, error) {
+		return models.NewPushTokenRepository(db.DB).IsConversationMuted(userID, conversationType, targetID)
+	}
+	pushActiveTokens = func(userID int) ([]models.PushToken, error) {
+		return models.NewPushTokenRepository(db.DB).ListActiveTokens(userID)
+	}
+	pushDeleteToken = func(provider, token string) error {
+		return models.NewPushTokenRepository(db.DB).DeleteByToken(provider, token)
+	}
+)
+
+// PushController 离线推送控制器
+type PushController struct {
+	pushRepo *models.PushTokenRepository
+}
+
+// NewPushController 创建离线推送控制器
+func NewPushController() *PushController {
+	return &PushController{
+		pushRepo: models.NewPushTokenRepository(db.DB),
+	}
+}
+
+// RegisterToken 为当前登录会话登记设备推送令牌
+// PUT /api/push/token
+func (pc *PushController) RegisterToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	var req models.RegisterPushTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	req.Provider = strings.ToLower(strings.TrimSpace(req.Provider))
+	req.Token = strings.TrimSpace(req.Token)
+	if !utils.ValidPushProviderName(req.Provider) {
+		utils.BadRequest(c, "不支持的推送通道")
+		return
+	}
+	if req.Token == "" || len(req.Token) > models.MaxPushTokenLength {
+		utils.BadRequest(c, "推送令牌无效")
+		return
+	}
+	if utils.GetPushProvider(req.Provider) == nil {
+		utils.BadRequest(c, "服务器未启用该推送通道")
+		return
+	}
+
+	locale := strings.TrimSpace(req.Locale)
+	if locale == "" || len(locale) > 20 {
+		locale = "zh-CN"
+	}
+	platform := strings.ToLower(strings.TrimSpace(req.Platform))
+	if len(platform) > 20 {
+		platform = platform[:20]
+	}
+
+	sessionExpiresAt := time.Now().UTC().Add(7 * 24 * time.Hour)
+	if expiresAt, ok := c.Get("session_expires_at"); ok {
+		sessionExpiresAt = expiresAt.(time.Time).UTC()
+	}
+
+	token := &models.PushToken{
+		UserID:           userID.(int),
+		SessionID:        c.GetString("session_id"),
+		Provider:         req.Provider,
+		Token:            req.Token,
+		Platform:         platform,
+		Locale:           locale,
+		SessionExpiresAt: sessionExpiresAt,
+	}
+	if err := pc.pushRepo.Register(token); err != nil {
+		utils.LogError("❌ [推送] 用户 %d 登记推送令牌失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "登记推送令牌失败")
+		return
+	}
+
+	utils.LogDebug("📲 [推送] 用户 %d 登记推送令牌 - 通道: %s, 平台: %s, 语言: %s", token.UserID, token.Provider, token.Platform, token.Locale)
+	utils.SuccessWithMessage(c, "推送令牌已登记", token)
+}
+
+// UnregisterToken 注销当前登录会话的推送令牌（退出登录时调用）
+// DELETE /api/push/token
+func (pc *PushController) UnregisterToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	deleted, err := pc.pushRepo.DeleteBySession(userID.(int), c.GetString("session_id"))
+	if err != nil {
+		utils.LogError("❌ [推送] 用户 %d 注销推送令牌失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "注销推送令牌失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "推送令牌已注销", gin.H{"deleted": deleted})
+}
+
+// pushPrivateMessage 接收者离线时推送私聊消息
+func pushPrivateMessage(hub *ws.Hub, msg *models.Message) {
+	enqueuePush(hub, msg.ReceiverID, pushMessage{
+		conversationType: models.ConversationTypeUser,
+		targetID:         msg.SenderID,
+		messageID:        msg.ID,
+		senderName:       msg.SenderName,
+		messageType:      msg.MessageType,
+		content:          msg.Content,
+	})
+}
+
+// pushGroupMessage 向离线的群成员推送群消息（被@的成员不受免打扰限制）
+func pushGroupMessage(hub *ws.Hub, groupRepo *models.GroupRepository, message *models.GroupMessage, offlineIDs []int, mentionTargets []int) {
+	if len(offlineIDs) == 0 || !utils.PushEnabled() {
+		return
+	}
+
+	groupName := ""
+	if group, err := groupRepo.GetGroupByID(message.GroupID); err == nil {
+		groupName = group.Name
+	}
+
+	mentioned := make(map[int]bool, len(mentionTargets))
+	for _, id := range mentionTargets {
+		mentioned[id] = true
+	}
+
+	for _, userID := range offlineIDs {
+		enqueuePush(hub, userID, pushMessage{
+			conversationType: models.ConversationTypeGroup,
+			targetID:         message.GroupID,
+			messageID:        message.ID,
+			senderName:       message.SenderName,
+			groupName:        groupName,
+			messageType:      message.MessageType,
+			content:          message.Content,
+			mentioned:        mentioned[userID],
+		})
+	}
+}
+
+// enqueuePush 将消息加入接收者的折叠窗口，窗口结束时合并为一条通知发送
+func enqueuePush(hub *ws.Hub, userID int, msg pushMessage) {
+	if !utils.PushEnabled() {
+		return
+	}
+	// 系统消息和通话记录不推送
+	if msg.messageType == "system" || strings.HasPrefix(msg.messageType, "call_") {
+		return
+	}
+
+	// 会话免打扰（群聊还包括群成员免打扰）时只推送@我的消息
+	if !msg.mentioned {
+		muted, err := pushConversationMuted(userID, msg.conversationType, msg.targetID)
+		if err != nil {
+			utils.LogError("❌ [推送] 查询用户 %d 的免打扰状态失败: %v", userID, err)
+			return
+		}
+		if muted {
+			return
+		}
+	}
+
+	key := pushKey{userID: userID, conversationType: msg.conversationType, targetID: msg.targetID}
+
+	pushPendingMu.Lock()
+	defer pushPendingMu.Unlock()
+
+	if pending, ok := pushPending[key]; ok {
+		pending.count++
+		pending.mentioned = pending.mentioned || msg.mentioned
+		pending.latest = msg
+		return
+	}
+
+	pushPending[key] = &pendingPush{count: 1, mentioned: msg.mentioned, latest: msg}
+	time.AfterFunc(pushCollapseWindow, func() {
+		flushPush(hub, key)
+	})
+}
+
+// flushPush 发送折叠窗口内累积的通知（接收者已重新上线时丢弃）
+func flushPush(hub *ws.Hub, key pushKey) {
+	pushPendingMu.Lock()
+	pending, ok := pushPending[key]
+	delete(pushPending, key)
+	pushPendingMu.Unlock()
+
+	if !ok || hub.IsUserOnline(key.userID) {
+		return
+	}
+
+	sent := sendPushToUser(key.userID, func(locale string) *utils.PushNotification {
+		return buildPushNotification(locale, pending)
+	})
+	if sent > 0 {
+		utils.LogDebug("📲 [推送] 已向用户 %d 推送 %s:%d 的 %d 条消息 - 设备数: %d", key.userID, key.conversationType, key.targetID, pending.count, sent)
+	}
+}
+
+// sendPushToUser 向用户的所有有效设备发送通知（按令牌语言生成内容），返回成功发送的设备数
+// 推送通道返回令牌失效时删除该令牌
+func sendPushToUser(userID int, build func(locale string) *utils.PushNotification) int {
+	tokens, err := pushActiveTokens(userID)
+	if err != nil {
+		utils.LogError("❌ [推送] 获取用户 %d 的推送令牌失败: %v", userID, err)
+		return 0
+	}
+
+	sent := 0
+	for _, token := range tokens {
+		provider := utils.GetPushProvider(token.Provider)
+		if provider == nil {
+			continue
+		}
+
+		notification := build(token.Locale)
+		ctx, cancel := context.WithTimeout(context.Background(), pushSendTimeout)
+		err := provider.Send(ctx, token.Token, notification)
+		cancel()
+
+		if errors.Is(err, utils.ErrPushTokenInvalid) {
+			utils.LogDebug("🗑️ [推送] 用户 %d 的 %s 推送令牌已失效，删除", userID, token.Provider)
+			if err := pushDeleteToken(token.Provider, token.Token); err != nil {
+				utils.LogError("❌ [推送] 删除失效推送令牌失败: %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			utils.LogError("❌ [推送] 向用户 %d 发送 %s 推送失败: %v", userID, token.Provider, err)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// pushLanguage 根据令牌登记的语言选择推送文案（目前支持中文和英文，默认中文）
+func pushLanguage(locale string) string {
+	if strings.HasPrefix(strings.ToLower(locale), "en") {
+		return "en"
+	}
+	return "zh"
+}
+
+// pushTypeLabelsEN 英文推送中非文本消息的占位文案
+var pushTypeLabelsEN = map[string]string{
+	"image":                       "[Image]",
+	"video":                       "[Video]",
+	"file":                        "[File]",
+	"audio":                       "[Voice]",
+	models.MessageTypeLocation:    "[Location]",
+	models.MessageTypeContactCard: "[Contact]",
+	models.MessageTypePoll:        "[Poll]",
+	models.MessageTypeSticker:     "[Sticker]",
+	models.MessageTypeEncrypted:   "[Encrypted message]",
+}
+
+// pushPreview 按语言生成消息预览
+func pushPreview(lang, messageType, content string) string {
+	if lang == "en" {
+		if label, ok := pushTypeLabelsEN[messageType]; ok {
+			return label
+		}
+		return models.MessagePlainText(messageType, content)
+	}
+	return models.MessagePreviewText(messageType, content)
+}
+
+// buildPushNotification 生成本地化的通知内容
+func buildPushNotification(locale string, pending *pendingPush) *utils.PushNotification {
+	lang := pushLanguage(locale)
+	msg := pending.latest
+
+	title := msg.senderName
+	body := pushPreview(lang, msg.messageType, msg.content)
+	if msg.conversationType == models.ConversationTypeGroup {
+		if msg.groupName != "" {
+			title = msg.groupName
+		}
+		body = msg.senderName + ": " + body
+	}
+
+	if pending.count > 1 {
+		if lang == "en" {
+			body = fmt.Sprintf("[%d messages] %s", pending.count, body)
+		} else {
+			body = fmt.Sprintf("[%d条] %s", pending.count, body)
+		}
+	}
+	if pending.mentioned {
+		if lang == "en" {
+			body = "[Mentioned] " + body
+		} else {
+			body = "[有人@我] " + body
+		}
+	}
+
+	if utf8.RuneCountInString(body) > pushBodyMaxRunes {
+		body = string([]rune(body)[:pushBodyMaxRunes]) + "…"
+	}
+
+	collapseKey := msg.conversationType + "_" + strconv.Itoa(msg.targetID)
+	return &utils.PushNotification{
+		Title:       title,
+		Body:        body,
+		CollapseKey: collapseKey,
+		Data: map[string]string{
+			"conversation_type": msg.conversationType,
+			"target_id":         strconv.Itoa(msg.targetID),
+			"message_id":        strconv.Itoa(msg.messageID),
+		},
+	}
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+)
+
+// setupPushTest 注册内存推送通道并替换令牌/免打扰查询，测试结束时自动还原
+func setupPushTest(t *testing.T, muted map[int]bool, locale string) *utils.InMemoryPushProvider {
+	t.Helper()
+
+	provider := utils.NewInMemoryPushProvider()
+	utils.RegisterPushProvider(provider)
+
+	origMuted, origTokens := pushConversationMuted, pushActiveTokens
+	pushConversationMuted = func(userID int, conversationType string, targetID int) (bool, error) {
+		return muted[userID], nil
+	}
+	pushActiveTokens = func(userID int) ([]models.PushToken, error) {
+		return []models.PushToken{{
+			UserID:   userID,
+			Provider: utils.PushProviderMemory,
+			Token:    "memory-token",
+			Locale:   locale,
+		}}, nil
+	}
+
+	pushPendingMu.Lock()
+	pushPending = map[pushKey]*pendingPush{}
+	pushPendingMu.Unlock()
+
+	t.Cleanup(func() {
+		pushConversationMuted, pushActiveTokens = origMuted, origTokens
+		provider.Reset()
+	})
+	return provider
+}
+
+func TestEnqueuePushMuteBypass(t *testing.T) {
+	tests := []struct {
+		name      string
+		muted     bool
+		mentioned bool
+		wantSent  bool
+	}{
+		{name: "未免打扰正常推送", muted: false, mentioned: false, wantSent: true},
+		{name: "免打扰不推送", muted: true, mentioned: false, wantSent: false},
+		{name: "免打扰但被@仍推送", muted: true, mentioned: true, wantSent: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := 1000 + i
+			setupPushTest(t, map[int]bool{userID: tt.muted}, "zh-CN")
+
+			enqueuePush(ws.NewHub(), userID, pushMessage{
+				conversationType: models.ConversationTypeGroup,
+				targetID:         7,
+				messageID:        1,
+				senderName:       "alice",
+				groupName:        "研发群",
+				messageType:      "text",
+				content:          "hello",
+				mentioned:        tt.mentioned,
+			})
+
+			// 取出待发送通知，窗口到期时不再访问已还原的查询函数
+			key := pushKey{userID: userID, conversationType: models.ConversationTypeGroup, targetID: 7}
+			pushPendingMu.Lock()
+			_, queued := pushPending[key]
+			delete(pushPending, key)
+			pushPendingMu.Unlock()
+			if queued != tt.wantSent {
+				t.Fatalf("queued = %v, want %v", queued, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestEnqueuePushCollapseWindow(t *testing.T) {
+	const userID = 2001
+	provider := setupPushTest(t, map[int]bool{userID: true}, "zh-CN")
+	hub := ws.NewHub()
+
+	messages := []pushMessage{
+		{messageID: 1, messageType: "text", content: "第一条"},
+		{messageID: 2, messageType: "text", content: "第二条", mentioned: true},
+		{messageID: 3, messageType: "image", content: "https://example.com/a.png", mentioned: true},
+	}
+	for _, msg := range messages {
+		msg.conversationType = models.ConversationTypeGroup
+		msg.targetID = 9
+		msg.senderName = "bob"
+		msg.groupName = "产品群"
+		enqueuePush(hub, userID, msg)
+	}
+
+	// 第一条未@且会话免打扰，只有后两条进入折叠窗口
+	time.Sleep(pushCollapseWindow / 2)
+	if sent := provider.Sent(); len(sent) != 0 {
+		t.Fatalf("折叠窗口结束前不应推送，实际推送 %d 条", len(sent))
+	}
+
+	deadline := time.Now().Add(pushCollapseWindow)
+	for len(provider.Sent()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	sent := provider.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("折叠窗口内的消息应合并为 1 条通知，实际 %d 条", len(sent))
+	}
+	notification := sent[0].Notification
+	if want := "[有人@我] [2条] bob: [图片]"; notification.Body != want {
+		t.Errorf("Body = %q, want %q", notification.Body, want)
+	}
+	if notification.Data["message_id"] != "3" {
+		t.Errorf("message_id = %q, want 3", notification.Data["message_id"])
+	}
+	if notification.CollapseKey != models.ConversationTypeGroup+"_9" {
+		t.Errorf("CollapseKey = %q", notification.CollapseKey)
+	}
+}
+
+func TestEnqueuePushSkipsSystemAndCallMessages(t *testing.T) {
+	const userID = 3001
+	setupPushTest(t, nil, "zh-CN")
+
+	for _, messageType := range []string{"system", "call_audio", "call_video"} {
+		enqueuePush(ws.NewHub(), userID, pushMessage{
+			conversationType: models.ConversationTypeUser,
+			targetID:         5,
+			messageType:      messageType,
+			mentioned:        true,
+		})
+	}
+
+	pushPendingMu.Lock()
+	defer pushPendingMu.Unlock()
+	if len(pushPending) != 0 {
+		t.Fatalf("系统消息和通话记录不应进入推送队列，实际 %d 个", len(pushPending))
+	}
+}
+
+func TestBuildPushNotification(t *testing.T) {
+	longText := strings.Repeat("字", pushBodyMaxRunes+10)
+
+	tests := []struct {
+		name      string
+		locale    string
+		pending   pendingPush
+		wantTitle string
+		wantBody  string
+	}{
+		{
+			name:      "私聊文本",
+			locale:    "zh-CN",
+			pending:   pendingPush{count: 1, latest: pushMessage{conversationType: models.ConversationTypeUser, targetID: 2, senderName: "alice", messageType: "text", content: "你好"}},
+			wantTitle: "alice",
+			wantBody:  "你好",
+		},
+		{
+			name:      "私聊语音",
+			locale:    "zh-CN",
+			pending:   pendingPush{count: 1, latest: pushMessage{conversationType: models.ConversationTypeUser, targetID: 2, senderName: "alice", messageType: "audio"}},
+			wantTitle: "alice",
+			wantBody:  "[语音]",
+		},
+		{
+			name:      "英文语音",
+			locale:    "en-US",
+			pending:   pendingPush{count: 1, latest: pushMessage{conversationType: models.ConversationTypeUser, targetID: 2, senderName: "alice", messageType: "audio"}},
+			wantTitle: "alice",
+			wantBody:  "[Voice]",
+		},
+		{
+			name:      "群聊合并且被@",
+			locale:    "zh-CN",
+			pending:   pendingPush{count: 3, mentioned: true, latest: pushMessage{conversationType: models.ConversationTypeGroup, targetID: 8, senderName: "bob", groupName: "研发群", messageType: "text", content: "上线了"}},
+			wantTitle: "研发群",
+			wantBody:  "[有人@我] [3条] bob: 上线了",
+		},
+		{
+			name:      "英文群聊合并且被@",
+			locale:    "en-GB",
+			pending:   pendingPush{count: 2, mentioned: true, latest: pushMessage{conversationType: models.ConversationTypeGroup, targetID: 8, senderName: "bob", groupName: "Dev", messageType: "image"}},
+			wantTitle: "Dev",
+			wantBody:  "[Mentioned] [2 messages] bob: [Image]",
+		},
+		{
+			name:      "群名为空时使用发送者",
+			locale:    "zh-CN",
+			pending:   pendingPush{count: 1, latest: pushMessage{conversationType: models.ConversationTypeGroup, targetID: 8, senderName: "bob", messageType: "text", content: "hi"}},
+			wantTitle: "bob",
+			wantBody:  "bob: hi",
+		},
+		{
+			name:      "正文超长截断",
+			locale:    "zh-CN",
+			pending:   pendingPush{count: 1, latest: pushMessage{conversationType: models.ConversationTypeUser, targetID: 2, senderName: "alice", messageType: "text", content: longText}},
+			wantTitle: "alice",
+			wantBody:  strings.Repeat("字", pushBodyMaxRunes) + "…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending := tt.pending
+			notification := buildPushNotification(tt.locale, &pending)
+			if notification.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", notification.Title, tt.wantTitle)
+			}
+			if notification.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", notification.Body, tt.wantBody)
+			}
+		})
+	}
+}
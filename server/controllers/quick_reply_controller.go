@@ -0,0 +1,597 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quickReplyPlaceholderPattern 模板占位符，如 {{recipient_name}}
+var quickReplyPlaceholderPattern = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+// QuickReplyController 快捷回复模板控制器
+type QuickReplyController struct {
+	replyRepo *models.QuickReplyRepository
+	groupRepo *models.GroupRepository
+	userRepo  *models.UserRepository
+}
+
+// NewQuickReplyController 创建快捷回复模板控制器
+func NewQuickReplyController() *QuickReplyController {
+	return &QuickReplyController{
+		replyRepo: models.NewQuickReplyRepository(db.DB),
+		groupRepo: models.NewGroupRepository(db.DB),
+		userRepo:  models.NewUserRepository(db.DB),
+	}
+}
+
+// GetQuickReplies 获取当前用户可用的快捷回复模板（个人、所在群组和组织模板）
+// GET /api/quick-replies?scope=personal|group|organization&group_id=1&keyword=xx
+func (qc *QuickReplyController) GetQuickReplies(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	scope := c.Query("scope")
+	switch scope {
+	case "", models.QuickReplyScopePersonal, models.QuickReplyScopeGroup, models.QuickReplyScopeOrganization:
+	default:
+		utils.BadRequest(c, "scope 必须是 personal、group 或 organization")
+		return
+	}
+
+	groupID := 0
+	if raw := c.Query("group_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			utils.BadRequest(c, "无效的群组ID")
+			return
+		}
+		groupID = id
+	}
+
+	replies, err := qc.replyRepo.ListAvailable(userID.(int), scope, groupID, strings.TrimSpace(c.Query("keyword")))
+	if err != nil {
+		utils.LogError("❌ [快捷回复] 获取用户 %d 的模板失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "获取快捷回复失败")
+		return
+	}
+	utils.Success(c, gin.H{"items": replies})
+}
+
+// CreateQuickReply 创建个人模板或群组模板（群组模板仅群主和管理员可创建）
+// POST /api/quick-replies
+func (qc *QuickReplyController) CreateQuickReply(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	currentUserID := userID.(int)
+
+	var req models.SaveQuickReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	reply := &models.QuickReply{CreatedBy: &currentUserID}
+	switch req.Scope {
+	case "", models.QuickReplyScopePersonal:
+		reply.Scope = models.QuickReplyScopePersonal
+		reply.OwnerID = &currentUserID
+	case models.QuickReplyScopeGroup:
+		if req.GroupID <= 0 {
+			utils.BadRequest(c, "请指定群组")
+			return
+		}
+		if status, errMsg := qc.checkGroupManager(req.GroupID, currentUserID); errMsg != "" {
+			utils.Error(c, status, errMsg)
+			return
+		}
+		reply.Scope = models.QuickReplyScopeGroup
+		reply.GroupID = &req.GroupID
+	default:
+		utils.BadRequest(c, "scope 必须是 personal 或 group")
+		return
+	}
+
+	if errMsg := applyQuickReplyRequest(reply, &req, currentUserID); errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	if err := qc.replyRepo.Create(reply); err != nil {
+		if err == models.ErrQuickReplyLimitReached {
+			utils.BadRequest(c, "个人快捷回复最多 "+strconv.Itoa(models.MaxPersonalQuickReplies)+" 条")
+			return
+		}
+		utils.LogError("❌ [快捷回复] 创建模板失败: %v", err)
+		utils.InternalServerError(c, "创建快捷回复失败")
+		return
+	}
+
+	utils.LogDebug("📝 [快捷回复] 用户 %d 创建%s模板 %d", currentUserID, reply.Scope, reply.ID)
+	utils.SuccessWithMessage(c, "快捷回复已创建", reply)
+}
+
+// UpdateQuickReply 更新模板（个人模板仅本人，群组模板仅群主和管理员）
+// PUT /api/quick-replies/:id
+func (qc *QuickReplyController) UpdateQuickReply(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	reply, ok := qc.loadManageable(c, userID.(int))
+	if !ok {
+		return
+	}
+
+	var req models.SaveQuickReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if errMsg := applyQuickReplyRequest(reply, &req, userID.(int)); errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	if err := qc.replyRepo.Update(reply); err != nil {
+		utils.LogError("❌ [快捷回复] 更新模板 %d 失败: %v", reply.ID, err)
+		utils.InternalServerError(c, "更新快捷回复失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "快捷回复已更新", reply)
+}
+
+// DeleteQuickReply 删除模板（个人模板仅本人，群组模板仅群主和管理员）
+// DELETE /api/quick-replies/:id
+func (qc *QuickReplyController) DeleteQuickReply(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	reply, ok := qc.loadManageable(c, userID.(int))
+	if !ok {
+		return
+	}
+
+	if _, err := qc.replyRepo.Delete(reply.ID); err != nil {
+		utils.LogError("❌ [快捷回复] 删除模板 %d 失败: %v", reply.ID, err)
+		utils.InternalServerError(c, "删除快捷回复失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "快捷回复已删除", nil)
+}
+
+// RenderQuickReply 使用发送者和接收者的用户信息填充模板占位符
+// POST /api/quick-replies/:id/render
+func (qc *QuickReplyController) RenderQuickReply(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	currentUserID := userID.(int)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的模板ID")
+		return
+	}
+
+	var req models.RenderQuickReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	reply, err := qc.replyRepo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "快捷回复不存在")
+			return
+		}
+		utils.LogError("❌ [快捷回复] 获取模板 %d 失败: %v", id, err)
+		utils.InternalServerError(c, "获取快捷回复失败")
+		return
+	}
+	if !qc.canUse(reply, currentUserID) {
+		utils.NotFound(c, "快捷回复不存在")
+		return
+	}
+
+	sender, err := qc.userRepo.FindByID(currentUserID)
+	if err != nil {
+		utils.InternalServerError(c, "获取用户信息失败")
+		return
+	}
+
+	var recipient *models.User
+	if req.ReceiverID > 0 {
+		recipient, err = qc.userRepo.FindByID(req.ReceiverID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				utils.NotFound(c, "接收者不存在")
+				return
+			}
+			utils.InternalServerError(c, "获取接收者信息失败")
+			return
+		}
+	}
+
+	groupName := ""
+	if req.GroupID > 0 {
+		isMember, err := qc.groupRepo.IsGroupMember(req.GroupID, currentUserID)
+		if err != nil {
+			utils.InternalServerError(c, "验证群组成员失败")
+			return
+		}
+		if !isMember {
+			utils.Forbidden(c, "您不是该群组成员")
+			return
+		}
+		group, err := qc.groupRepo.GetGroupByID(req.GroupID)
+		if err != nil {
+			utils.InternalServerError(c, "获取群组信息失败")
+			return
+		}
+		groupName = group.Name
+	}
+
+	values := quickReplyPlaceholderValues(sender, recipient, groupName, time.Now())
+	content, unresolved := renderQuickReplyContent(reply.Content, values)
+	utils.Success(c, models.RenderedQuickReply{
+		ID:          reply.ID,
+		Title:       reply.Title,
+		Content:     content,
+		Attachments: reply.Attachments,
+		Unresolved:  unresolved,
+	})
+}
+
+// AdminGetQuickReplies 获取组织模板
+func (qc *QuickReplyController) AdminGetQuickReplies(c *gin.Context) {
+	replies, err := qc.replyRepo.ListOrganization()
+	if err != nil {
+		utils.LogError("❌ [快捷回复] 获取组织模板失败: %v", err)
+		utils.InternalServerError(c, "获取快捷回复失败")
+		return
+	}
+	utils.Success(c, gin.H{"items": replies})
+}
+
+// AdminCreateQuickReply 创建组织模板
+func (qc *QuickReplyController) AdminCreateQuickReply(c *gin.Context) {
+	var req models.SaveQuickReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	reply := &models.QuickReply{Scope: models.QuickReplyScopeOrganization}
+	if errMsg := applyQuickReplyRequest(reply, &req, 0); errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	if err := qc.replyRepo.Create(reply); err != nil {
+		utils.LogError("❌ [快捷回复] 创建组织模板失败: %v", err)
+		utils.InternalServerError(c, "创建快捷回复失败")
+		return
+	}
+
+	utils.LogInfo("📝 [快捷回复] 管理员创建组织模板 %d", reply.ID)
+	utils.SuccessWithMessage(c, "快捷回复已创建", reply)
+}
+
+// AdminUpdateQuickReply 更新组织模板
+func (qc *QuickReplyController) AdminUpdateQuickReply(c *gin.Context) {
+	reply, ok := qc.loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req models.SaveQuickReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if errMsg := applyQuickReplyRequest(reply, &req, 0); errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	if err := qc.replyRepo.Update(reply); err != nil {
+		utils.LogError("❌ [快捷回复] 更新组织模板 %d 失败: %v", reply.ID, err)
+		utils.InternalServerError(c, "更新快捷回复失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "快捷回复已更新", reply)
+}
+
+// AdminDeleteQuickReply 删除组织模板
+func (qc *QuickReplyController) AdminDeleteQuickReply(c *gin.Context) {
+	reply, ok := qc.loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	if _, err := qc.replyRepo.Delete(reply.ID); err != nil {
+		utils.LogError("❌ [快捷回复] 删除组织模板 %d 失败: %v", reply.ID, err)
+		utils.InternalServerError(c, "删除快捷回复失败")
+		return
+	}
+
+	utils.LogInfo("📝 [快捷回复] 管理员删除组织模板 %d", reply.ID)
+	utils.SuccessWithMessage(c, "快捷回复已删除", nil)
+}
+
+// loadOrganization 按路径参数加载组织模板，失败时已写入响应
+func (qc *QuickReplyController) loadOrganization(c *gin.Context) (*models.QuickReply, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的模板ID")
+		return nil, false
+	}
+
+	reply, err := qc.replyRepo.GetByID(id)
+	if err != nil && err != sql.ErrNoRows {
+		utils.LogError("❌ [快捷回复] 获取模板 %d 失败: %v", id, err)
+		utils.InternalServerError(c, "获取快捷回复失败")
+		return nil, false
+	}
+	if err == sql.ErrNoRows || reply.Scope != models.QuickReplyScopeOrganization {
+		utils.NotFound(c, "快捷回复不存在")
+		return nil, false
+	}
+	return reply, true
+}
+
+// loadManageable 按路径参数加载当前用户可管理的模板，失败时已写入响应
+// 组织模板只能通过管理后台修改
+func (qc *QuickReplyController) loadManageable(c *gin.Context, userID int) (*models.QuickReply, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的模板ID")
+		return nil, false
+	}
+
+	reply, err := qc.replyRepo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "快捷回复不存在")
+			return nil, false
+		}
+		utils.LogError("❌ [快捷回复] 获取模板 %d 失败: %v", id, err)
+		utils.InternalServerError(c, "获取快捷回复失败")
+		return nil, false
+	}
+	if !qc.canUse(reply, userID) {
+		utils.NotFound(c, "快捷回复不存在")
+		return nil, false
+	}
+
+	switch reply.Scope {
+	case models.QuickReplyScopePersonal:
+		return reply, true
+	case models.QuickReplyScopeGroup:
+		if status, errMsg := qc.checkGroupManager(*reply.GroupID, userID); errMsg != "" {
+			utils.Error(c, status, errMsg)
+			return nil, false
+		}
+		return reply, true
+	default:
+		utils.Forbidden(c, "组织快捷回复只能由管理员修改")
+		return nil, false
+	}
+}
+
+// canUse 判断用户是否可以使用模板
+func (qc *QuickReplyController) canUse(reply *models.QuickReply, userID int) bool {
+	switch reply.Scope {
+	case models.QuickReplyScopePersonal:
+		return reply.OwnerID != nil && *reply.OwnerID == userID
+	case models.QuickReplyScopeGroup:
+		if reply.GroupID == nil {
+			return false
+		}
+		isMember, err := qc.groupRepo.IsGroupMember(*reply.GroupID, userID)
+		return err == nil && isMember
+	case models.QuickReplyScopeOrganization:
+		return true
+	}
+	return false
+}
+
+// checkGroupManager 校验用户是群主或管理员，返回错误状态码和错误信息
+func (qc *QuickReplyController) checkGroupManager(groupID, userID int) (int, string) {
+	role, err := qc.groupRepo.GetUserGroupRole(groupID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return http.StatusForbidden, "您不是该群组成员"
+		}
+		return http.StatusInternalServerError, "验证群组成员失败"
+	}
+	if role != "owner" && role != "admin" {
+		return http.StatusForbidden, "只有群主和管理员可以管理群组快捷回复"
+	}
+	return 0, ""
+}
+
+// applyQuickReplyRequest 将请求字段写入模板并校验，返回错误信息
+// uploaderID 为操作者ID，新增的附件必须是其本人上传的文件（管理后台没有操作用户，传 0 不限制）
+func applyQuickReplyRequest(reply *models.QuickReply, req *models.SaveQuickReplyRequest, uploaderID int) string {
+	if req.Title != nil {
+		reply.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Content != nil {
+		reply.Content = strings.TrimSpace(*req.Content)
+	}
+	if req.Attachments != nil {
+		attachments, errMsg := buildQuickReplyAttachments(*req.Attachments, uploaderID, reply.Attachments)
+		if errMsg != "" {
+			return errMsg
+		}
+		reply.Attachments = attachments
+	}
+	if reply.Attachments == nil {
+		reply.Attachments = []models.QuickReplyAttachment{}
+	}
+
+	if reply.Title == "" {
+		return "标题不能为空"
+	}
+	if utf8.RuneCountInString(reply.Title) > models.MaxQuickReplyTitleLength {
+		return "标题不能超过 " + strconv.Itoa(models.MaxQuickReplyTitleLength) + " 个字符"
+	}
+	if reply.Content == "" && len(reply.Attachments) == 0 {
+		return "内容和附件不能同时为空"
+	}
+	if utf8.RuneCountInString(reply.Content) > models.MaxQuickReplyContentLength {
+		return "内容不能超过 " + strconv.Itoa(models.MaxQuickReplyContentLength) + " 个字符"
+	}
+	return ""
+}
+
+// buildQuickReplyAttachments 校验模板附件：必须是本服务OSS中已存在的文件，且扩展名与类型匹配
+// uploaderID 大于 0 时，模板原有附件以外的文件必须位于该用户的上传目录下
+func buildQuickReplyAttachments(inputs []models.QuickReplyAttachmentInput, uploaderID int, existing []models.QuickReplyAttachment) ([]models.QuickReplyAttachment, string) {
+	attachments := []models.QuickReplyAttachment{}
+	if len(inputs) == 0 {
+		return attachments, ""
+	}
+	if len(inputs) > models.MaxQuickReplyAttachments {
+		return nil, "附件最多 " + strconv.Itoa(models.MaxQuickReplyAttachments) + " 个"
+	}
+
+	attached := make(map[string]bool, len(existing))
+	for _, a := range existing {
+		attached[a.ObjectKey] = true
+	}
+
+	ossCtrl := NewOSSController()
+	ossCtx, err := ossCtrl.getOSSContext()
+	if err != nil {
+		utils.LogError("获取OSS配置失败: %v", err)
+		return nil, "OSS配置未设置"
+	}
+
+	for _, input := range inputs {
+		if !isAttachmentMessageType(input.MessageType) {
+			return nil, "附件类型必须是 image、video、file 或 audio"
+		}
+
+		objectKey := ossObjectKeyFromURL(ossCtx, input.URL)
+		if objectKey == "" || strings.Contains(objectKey, "..") {
+			return nil, "附件必须通过上传接口上传"
+		}
+		if uploaderID > 0 && !attached[objectKey] && !isUserUploadKey(objectKey, uploaderID) {
+			return nil, "只能使用自己上传的文件作为附件"
+		}
+		if !ossCtrl.isAllowedFileType(strings.ToLower(path.Ext(objectKey)), input.MessageType) {
+			return nil, "不支持的附件格式: " + path.Ext(objectKey)
+		}
+
+		meta, err := ossCtx.bucket.GetObjectMeta(objectKey)
+		if err != nil {
+			utils.LogDebug("⚠️ 快捷回复附件不存在: %s, %v", objectKey, err)
+			return nil, "附件不存在: " + input.URL
+		}
+		size, _ := strconv.ParseInt(meta.Get("Content-Length"), 10, 64)
+
+		fileName := strings.TrimSpace(input.FileName)
+		if fileName == "" {
+			fileName = path.Base(objectKey)
+		}
+		if utf8.RuneCountInString(fileName) > 255 {
+			return nil, "附件文件名不能超过255个字符"
+		}
+
+		attachments = append(attachments, models.QuickReplyAttachment{
+			MessageType: input.MessageType,
+			URL:         strings.TrimSpace(input.URL),
+			ObjectKey:   objectKey,
+			FileName:    fileName,
+			FileSize:    size,
+		})
+	}
+	return attachments, ""
+}
+
+// quickReplyPlaceholderValues 根据发送者、接收者和群组构建占位符取值（接收者为空时 recipient_* 无法填充）
+// 日期时间按上海时区格式化
+func quickReplyPlaceholderValues(sender, recipient *models.User, groupName string, now time.Time) map[string]string {
+	values := map[string]string{
+		"date":       utils.ToShanghaiTime(now).Format("2006-01-02"),
+		"time":       utils.ToShanghaiTime(now).Format("15:04"),
+		"group_name": groupName,
+	}
+	addUser := func(prefix string, user *models.User) {
+		if user == nil {
+			for _, field := range []string{"name", "username", "department", "position", "region", "email"} {
+				values[prefix+field] = ""
+			}
+			return
+		}
+		name := user.Username
+		if user.FullName != nil && *user.FullName != "" {
+			name = *user.FullName
+		}
+		values[prefix+"name"] = name
+		values[prefix+"username"] = user.Username
+		values[prefix+"department"] = stringOrEmpty(user.Department)
+		values[prefix+"position"] = stringOrEmpty(user.Position)
+		values[prefix+"region"] = stringOrEmpty(user.Region)
+		values[prefix+"email"] = stringOrEmpty(user.Email)
+	}
+	addUser("my_", sender)
+	addUser("recipient_", recipient)
+	return values
+}
+
+// renderQuickReplyContent 填充模板占位符，返回渲染结果和无法填充（取值为空）的占位符
+// 未知的占位符原样保留
+func renderQuickReplyContent(content string, values map[string]string) (string, []string) {
+	var unresolved []string
+	seen := make(map[string]bool)
+	rendered := quickReplyPlaceholderPattern.ReplaceAllStringFunc(content, func(match string) string {
+		name := quickReplyPlaceholderPattern.FindStringSubmatch(match)[1]
+		value, known := values[name]
+		if !known {
+			return match
+		}
+		if value == "" && !seen[name] {
+			seen[name] = true
+			unresolved = append(unresolved, name)
+		}
+		return value
+	})
+	return rendered, unresolved
+}
+
+// stringOrEmpty 返回字符串指针的值，为空时返回空字符串
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
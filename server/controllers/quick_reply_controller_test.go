@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"youdu-server/models"
+)
+
+func TestRenderQuickReplyContent(t *testing.T) {
+	values := map[string]string{
+		"my_name":        "张三",
+		"recipient_name": "李四",
+		"group_name":     "",
+		"date":           "2026-10-18",
+	}
+
+	tests := []struct {
+		name           string
+		content        string
+		want           string
+		wantUnresolved []string
+	}{
+		{name: "填充占位符", content: "{{recipient_name}}您好，我是{{my_name}}", want: "李四您好，我是张三"},
+		{name: "占位符允许空白", content: "日期：{{ date }}", want: "日期：2026-10-18"},
+		{name: "未知占位符原样保留", content: "{{unknown}} {{my_name}}", want: "{{unknown}} 张三"},
+		{name: "空值记录为无法填充且只记录一次", content: "{{group_name}}/{{group_name}}", want: "/", wantUnresolved: []string{"group_name"}},
+		{name: "大写不视为占位符", content: "{{MY_NAME}}", want: "{{MY_NAME}}"},
+		{name: "没有占位符", content: "收到，谢谢", want: "收到，谢谢"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, unresolved := renderQuickReplyContent(tt.content, values)
+			if got != tt.want {
+				t.Errorf("rendered = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(unresolved, tt.wantUnresolved) {
+				t.Errorf("unresolved = %v, want %v", unresolved, tt.wantUnresolved)
+			}
+		})
+	}
+}
+
+func TestQuickReplyPlaceholderValues(t *testing.T) {
+	str := func(s string) *string { return &s }
+	sender := &models.User{Username: "zhangsan", FullName: str("张三"), Department: str("研发部"), Email: str("zs@example.com")}
+	recipient := &models.User{Username: "lisi", FullName: str("")}
+	now := time.Date(2026, 10, 18, 16, 30, 0, 0, time.UTC) // 上海时间次日 00:30
+
+	tests := []struct {
+		name      string
+		recipient *models.User
+		want      map[string]string
+	}{
+		{
+			name:      "私聊",
+			recipient: recipient,
+			want: map[string]string{
+				"date":                 "2026-10-19",
+				"time":                 "00:30",
+				"my_name":              "张三",
+				"my_username":          "zhangsan",
+				"my_department":        "研发部",
+				"my_position":          "",
+				"my_email":             "zs@example.com",
+				"recipient_name":       "lisi",
+				"recipient_username":   "lisi",
+				"recipient_department": "",
+			},
+		},
+		{
+			name:      "群聊没有接收者",
+			recipient: nil,
+			want: map[string]string{
+				"group_name":         "研发群",
+				"recipient_name":     "",
+				"recipient_username": "",
+				"recipient_email":    "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := quickReplyPlaceholderValues(sender, tt.recipient, "研发群", now)
+			for key, want := range tt.want {
+				got, ok := values[key]
+				if !ok {
+					t.Errorf("缺少占位符 %s", key)
+					continue
+				}
+				if got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyQuickReplyRequest(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name        string
+		req         models.SaveQuickReplyRequest
+		wantErr     string
+		wantTitle   string
+		wantContent string
+	}{
+		{
+			name:        "去除首尾空白",
+			req:         models.SaveQuickReplyRequest{Title: str("  问候 "), Content: str(" 您好，{{recipient_name}} ")},
+			wantTitle:   "问候",
+			wantContent: "您好，{{recipient_name}}",
+		},
+		{
+			name:    "标题为空",
+			req:     models.SaveQuickReplyRequest{Title: str("  "), Content: str("内容")},
+			wantErr: "标题不能为空",
+		},
+		{
+			name:    "标题过长",
+			req:     models.SaveQuickReplyRequest{Title: str(strings.Repeat("标", models.MaxQuickReplyTitleLength+1)), Content: str("内容")},
+			wantErr: "标题不能超过",
+		},
+		{
+			name:    "内容和附件都为空",
+			req:     models.SaveQuickReplyRequest{Title: str("标题"), Content: str(" ")},
+			wantErr: "内容和附件不能同时为空",
+		},
+		{
+			name:    "内容过长",
+			req:     models.SaveQuickReplyRequest{Title: str("标题"), Content: str(strings.Repeat("长", models.MaxQuickReplyContentLength+1))},
+			wantErr: "内容不能超过",
+		},
+		{
+			name:    "附件过多",
+			req:     models.SaveQuickReplyRequest{Title: str("标题"), Attachments: &[]models.QuickReplyAttachmentInput{{}, {}, {}, {}, {}, {}, {}, {}, {}, {}}},
+			wantErr: "附件最多",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := &models.QuickReply{Scope: models.QuickReplyScopePersonal}
+			errMsg := applyQuickReplyRequest(reply, &tt.req, 1)
+			if tt.wantErr == "" {
+				if errMsg != "" {
+					t.Fatalf("applyQuickReplyRequest() = %q, want no error", errMsg)
+				}
+				if reply.Title != tt.wantTitle || reply.Content != tt.wantContent {
+					t.Errorf("Title, Content = %q, %q, want %q, %q", reply.Title, reply.Content, tt.wantTitle, tt.wantContent)
+				}
+				if reply.Attachments == nil {
+					t.Error("Attachments 不应为 nil")
+				}
+				return
+			}
+			if !strings.Contains(errMsg, tt.wantErr) {
+				t.Errorf("applyQuickReplyRequest() = %q, want %q", errMsg, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsUserUploadKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		objectKey string
+		want      bool
+	}{
+		{name: "本人图片", objectKey: "images/user/7/1700000000_a.png", want: true},
+		{name: "本人语音", objectKey: "voice/user/7/1700000000_a.opus", want: true},
+		{name: "本人文件", objectKey: "files/user/7/1700000000_a.pdf", want: true},
+		{name: "他人文件", objectKey: "files/user/8/1700000000_a.pdf", want: false},
+		{name: "用户ID前缀相同", objectKey: "files/user/77/1700000000_a.pdf", want: false},
+		{name: "非上传目录", objectKey: "exports/user/7/a.zip", want: false},
+		{name: "类型别名不是目录", objectKey: "image/user/7/a.png", want: false},
+		{name: "用户目录不在第二级", objectKey: "images/x/user/7/a.png", want: false},
+		{name: "没有目录", objectKey: "a.png", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUserUploadKey(tt.objectKey, 7); got != tt.want {
+				t.Errorf("isUserUploadKey(%q) = %v, want %v", tt.objectKey, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,428 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 保留策略清理相关常量
+const (
+	retentionBatchSize  = 500 // 每批删除的消息数
+	retentionMaxBatches = 20  // 每个范围单次运行最多处理的批次数，剩余的留到下次运行
+)
+
+// retentionPurgeMu 防止同一实例上的清理任务重叠运行
+var retentionPurgeMu sync.Mutex
+
+// retentionPurgeSummary 单个会话在本次清理中的统计
+type retentionPurgeSummary struct {
+	log      models.RetentionPurgeLog
+	contents []string // 被清理的附件消息内容（OSS 文件地址）
+}
+
+// retentionPurgeRun 一次清理运行
+type retentionPurgeRun struct {
+	hub       *ws.Hub
+	repo      *models.RetentionRepository
+	groupRepo *models.GroupRepository
+	ossCtx    *ossContext
+	ossErr    error
+	ossLoaded bool
+	summaries map[string]*retentionPurgeSummary
+	order     []string
+}
+
+// PurgeExpiredMessages 按保留策略分批清理过期消息（由定时任务调用）
+// 全局策略开启法律保全时暂停所有清理；群组、私聊策略覆盖全局策略；文件传输助手只受全局策略约束
+func PurgeExpiredMessages(hub *ws.Hub) {
+	if !retentionPurgeMu.TryLock() {
+		return
+	}
+	defer retentionPurgeMu.Unlock()
+
+	repo := models.NewRetentionRepository(db.DB)
+	policies, err := repo.ListPolicies()
+	if err != nil {
+		utils.LogError("❌ [保留策略] 获取保留策略失败: %v", err)
+		return
+	}
+
+	var global *models.RetentionPolicy
+	for i := range policies {
+		if policies[i].Scope == models.RetentionScopeGlobal {
+			global = &policies[i]
+		}
+	}
+	if global != nil && global.LegalHold {
+		utils.LogDebug("⏸️ [保留策略] 全局法律保全已开启，跳过清理")
+		return
+	}
+
+	run := &retentionPurgeRun{
+		hub:       hub,
+		repo:      repo,
+		groupRepo: models.NewGroupRepository(db.DB),
+		summaries: map[string]*retentionPurgeSummary{},
+	}
+	now := time.Now().UTC()
+
+	for i := range policies {
+		policy := &policies[i]
+		if policy.Scope == models.RetentionScopeGlobal || !policy.Purges() {
+			continue
+		}
+		cutoff := now.AddDate(0, 0, -*policy.RetentionDays)
+		switch policy.Scope {
+		case models.RetentionScopeGroup:
+			run.purge(models.ConversationTypeGroup, policy, cutoff, func() ([]models.PurgedMessage, error) {
+				return repo.PurgeGroupMessages(policy.TargetID, cutoff, retentionBatchSize)
+			})
+		case models.RetentionScopeUser:
+			run.purge(models.ConversationTypeUser, policy, cutoff, func() ([]models.PurgedMessage, error) {
+				return repo.PurgePrivateMessages(policy.TargetID, policy.PeerID, cutoff, retentionBatchSize)
+			})
+		}
+	}
+
+	if global != nil && global.Purges() {
+		cutoff := now.AddDate(0, 0, -*global.RetentionDays)
+		run.purge(models.ConversationTypeGroup, global, cutoff, func() ([]models.PurgedMessage, error) {
+			return repo.PurgeGroupMessages(0, cutoff, retentionBatchSize)
+		})
+		run.purge(models.ConversationTypeUser, global, cutoff, func() ([]models.PurgedMessage, error) {
+			return repo.PurgePrivateMessages(0, 0, cutoff, retentionBatchSize)
+		})
+		run.purge(models.ConversationTypeFileAssistant, global, cutoff, func() ([]models.PurgedMessage, error) {
+			return repo.PurgeFileAssistantMessages(cutoff, retentionBatchSize)
+		})
+	}
+
+	run.finish()
+}
+
+// purge 分批清理一个范围内的过期消息，按会话汇总
+func (run *retentionPurgeRun) purge(conversationType string, policy *models.RetentionPolicy, cutoff time.Time, batch func() ([]models.PurgedMessage, error)) {
+	for i := 0; i < retentionMaxBatches; i++ {
+		purged, err := batch()
+		if err != nil {
+			utils.LogError("❌ [保留策略] 清理%s消息失败 - 策略: %d, 错误: %v", conversationType, policy.ID, err)
+			return
+		}
+
+		for _, msg := range purged {
+			key := conversationType + ":" + strconv.Itoa(msg.TargetID) + ":" + strconv.Itoa(msg.PeerID)
+			summary, ok := run.summaries[key]
+			if !ok {
+				policyID := policy.ID
+				summary = &retentionPurgeSummary{log: models.RetentionPurgeLog{
+					PolicyID:         &policyID,
+					ConversationType: conversationType,
+					TargetID:         msg.TargetID,
+					PeerID:           msg.PeerID,
+					RetentionDays:    *policy.RetentionDays,
+					Cutoff:           cutoff,
+				}}
+				run.summaries[key] = summary
+				run.order = append(run.order, key)
+			}
+			summary.log.MessageCount++
+			if isAttachmentMessageType(msg.MessageType) {
+				summary.contents = append(summary.contents, msg.Content)
+			}
+		}
+
+		if len(purged) < retentionBatchSize {
+			return
+		}
+	}
+}
+
+// finish 删除 OSS 文件、写入审计日志并通知客户端
+func (run *retentionPurgeRun) finish() {
+	total := 0
+	for _, key := range run.order {
+		summary := run.summaries[key]
+		run.deleteObjects(summary)
+
+		if err := run.repo.CreatePurgeLog(&summary.log); err != nil {
+			utils.LogError("❌ [保留策略] 写入清理审计日志失败: %v", err)
+		}
+		run.notify(&summary.log)
+		total += summary.log.MessageCount
+	}
+
+	if total > 0 {
+		utils.LogInfo("🧹 [保留策略] 本次共清理 %d 条消息，涉及 %d 个会话", total, len(run.order))
+	}
+}
+
+// deleteObjects 删除已清理消息引用的 OSS 文件（仍被其他消息或收藏引用的文件保留）
+func (run *retentionPurgeRun) deleteObjects(summary *retentionPurgeSummary) {
+	if len(summary.contents) == 0 {
+		return
+	}
+	if !run.ossLoaded {
+		run.ossCtx, run.ossErr = NewOSSController().getOSSContext()
+		run.ossLoaded = true
+		if run.ossErr != nil {
+			utils.LogError("❌ [保留策略] 获取OSS配置失败，附件不会被删除: %v", run.ossErr)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, content := range summary.contents {
+		if seen[content] {
+			continue
+		}
+		seen[content] = true
+
+		if run.ossErr != nil {
+			summary.log.ObjectFailedCount++
+			continue
+		}
+		objectKey := ossObjectKeyFromURL(run.ossCtx, content)
+		if objectKey == "" {
+			continue
+		}
+		referenced, err := run.repo.IsContentReferenced(content)
+		if err != nil {
+			summary.log.ObjectFailedCount++
+			continue
+		}
+		if referenced {
+			continue
+		}
+		if err := run.ossCtx.bucket.DeleteObject(objectKey); err != nil {
+			utils.LogDebug("⚠️ [保留策略] 删除OSS文件失败: %s, %v", objectKey, err)
+			summary.log.ObjectFailedCount++
+			continue
+		}
+		summary.log.ObjectCount++
+	}
+}
+
+// notify 通知会话参与者清理本地缓存中早于 cutoff 的消息
+func (run *retentionPurgeRun) notify(log *models.RetentionPurgeLog) {
+	send := func(userID, targetID int) {
+		msg := models.WSMessage{
+			Type: "messages_purged",
+			Data: gin.H{
+				"conversation_type": log.ConversationType,
+				"target_id":         targetID,
+				"purged_before":     log.Cutoff,
+				"message_count":     log.MessageCount,
+			},
+		}
+		msgBytes, _ := json.Marshal(msg)
+		run.hub.SendToUser(userID, msgBytes)
+	}
+
+	switch log.ConversationType {
+	case models.ConversationTypeUser:
+		send(log.TargetID, log.PeerID)
+		send(log.PeerID, log.TargetID)
+	case models.ConversationTypeGroup:
+		memberIDs, err := run.groupRepo.GetGroupMemberIDs(log.TargetID)
+		if err != nil {
+			utils.LogDebug("⚠️ [保留策略] 获取群成员失败，无法发送清理通知: %v", err)
+			return
+		}
+		for _, memberID := range memberIDs {
+			send(memberID, log.TargetID)
+		}
+	case models.ConversationTypeFileAssistant:
+		send(log.TargetID, 0)
+	}
+}
+
+// RetentionController 消息保留策略控制器
+type RetentionController struct {
+	Hub           *ws.Hub
+	retentionRepo *models.RetentionRepository
+	groupRepo     *models.GroupRepository
+	userRepo      *models.UserRepository
+}
+
+// NewRetentionController 创建保留策略控制器
+func NewRetentionController(hub *ws.Hub) *RetentionController {
+	return &RetentionController{
+		Hub:           hub,
+		retentionRepo: models.NewRetentionRepository(db.DB),
+		groupRepo:     models.NewGroupRepository(db.DB),
+		userRepo:      models.NewUserRepository(db.DB),
+	}
+}
+
+// GetEffectiveRetention 获取会话实际生效的保留策略（客户端用于展示"消息将在 N 天后删除"）
+// GET /api/conversation-settings/retention?conversation_type=group&target_id=1
+func (rc *RetentionController) GetEffectiveRetention(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	conversationType := c.Query("conversation_type")
+	targetID, _ := strconv.Atoi(c.Query("target_id"))
+
+	var effective *models.EffectiveRetention
+	var err error
+	switch conversationType {
+	case models.ConversationTypeGroup:
+		if _, roleErr := rc.groupRepo.GetUserGroupRole(targetID, userID.(int)); roleErr != nil {
+			utils.Forbidden(c, "您不是该群组成员")
+			return
+		}
+		effective, err = rc.retentionRepo.GetEffectiveRetention(models.RetentionScopeGroup, targetID, 0)
+	case models.ConversationTypeUser:
+		if targetID <= 0 || targetID == userID.(int) {
+			utils.BadRequest(c, "无效的会话")
+			return
+		}
+		a, b := models.RetentionUserPair(userID.(int), targetID)
+		effective, err = rc.retentionRepo.GetEffectiveRetention(models.RetentionScopeUser, a, b)
+	case models.ConversationTypeFileAssistant:
+		effective, err = rc.retentionRepo.GetEffectiveRetention(models.RetentionScopeGlobal, 0, 0)
+	default:
+		utils.BadRequest(c, "无效的会话类型")
+		return
+	}
+	if err != nil {
+		utils.LogError("❌ [保留策略] 获取生效策略失败: %v", err)
+		utils.InternalServerError(c, "获取保留策略失败")
+		return
+	}
+
+	utils.Success(c, effective)
+}
+
+// AdminGetPolicies 获取所有保留策略
+func (rc *RetentionController) AdminGetPolicies(c *gin.Context) {
+	policies, err := rc.retentionRepo.ListPolicies()
+	if err != nil {
+		utils.LogError("❌ [保留策略] 获取保留策略失败: %v", err)
+		utils.InternalServerError(c, "获取保留策略失败")
+		return
+	}
+	utils.Success(c, policies)
+}
+
+// AdminSavePolicy 保存保留策略（同一范围已有策略时覆盖）
+func (rc *RetentionController) AdminSavePolicy(c *gin.Context) {
+	var req models.SaveRetentionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	policy, err := req.ToPolicy()
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	switch policy.Scope {
+	case models.RetentionScopeGroup:
+		if _, err := rc.groupRepo.GetGroupByID(policy.TargetID); err != nil {
+			utils.NotFound(c, "群组不存在")
+			return
+		}
+	case models.RetentionScopeUser:
+		for _, id := range []int{policy.TargetID, policy.PeerID} {
+			if _, err := rc.userRepo.FindByID(id); err != nil {
+				utils.NotFound(c, "用户不存在")
+				return
+			}
+		}
+	}
+
+	if adminID, ok := c.Get("user_id"); ok {
+		id := adminID.(int)
+		policy.UpdatedBy = &id
+	}
+
+	saved, err := rc.retentionRepo.SavePolicy(policy)
+	if err != nil {
+		utils.LogError("❌ [保留策略] 保存保留策略失败: %v", err)
+		utils.InternalServerError(c, "保存保留策略失败")
+		return
+	}
+
+	utils.LogInfo("📜 [保留策略] 保存策略 - 范围: %s, 目标: %d/%d, 保留天数: %v, 法律保全: %v",
+		saved.Scope, saved.TargetID, saved.PeerID, retentionDaysText(saved.RetentionDays), saved.LegalHold)
+	utils.SuccessWithMessage(c, "保留策略已保存", saved)
+}
+
+// AdminDeletePolicy 删除保留策略（该范围回退到全局策略）
+func (rc *RetentionController) AdminDeletePolicy(c *gin.Context) {
+	policyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的策略ID")
+		return
+	}
+
+	deleted, err := rc.retentionRepo.DeletePolicy(policyID)
+	if err != nil {
+		utils.LogError("❌ [保留策略] 删除保留策略失败: %v", err)
+		utils.InternalServerError(c, "删除保留策略失败")
+		return
+	}
+	if !deleted {
+		utils.NotFound(c, "保留策略不存在")
+		return
+	}
+	utils.SuccessWithMessage(c, "保留策略已删除", nil)
+}
+
+// AdminGetPurgeLogs 分页获取清理审计日志
+func (rc *RetentionController) AdminGetPurgeLogs(c *gin.Context) {
+	conversationType := c.Query("conversation_type")
+	targetID, _ := strconv.Atoi(c.Query("target_id"))
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	logs, total, err := rc.retentionRepo.ListPurgeLogs(conversationType, targetID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		utils.LogError("❌ [保留策略] 获取清理审计日志失败: %v", err)
+		utils.InternalServerError(c, "获取清理记录失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"logs":      logs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// AdminRunPurge 立即执行一次清理（后台运行）
+func (rc *RetentionController) AdminRunPurge(c *gin.Context) {
+	go PurgeExpiredMessages(rc.Hub)
+	utils.SuccessWithMessage(c, "已开始清理", nil)
+}
+
+// retentionDaysText 保留天数的日志文本
+func retentionDaysText(days *int) string {
+	if days == nil {
+		return "永久"
+	}
+	return strconv.Itoa(*days)
+}
@@ -0,0 +1,810 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 斜杠命令相关常量
+const (
+	botCommandTimeout      = 5 * time.Second     // 机器人命令回调超时
+	maxCommandMuteDuration = 30 * 24 * time.Hour // /mute 最长禁言时长
+	maxReminderDuration    = 365 * 24 * time.Hour
+	maxReminderLength      = 500 // 提醒内容最大长度（字符）
+	maxTopicLength         = 500 // 群公告最大长度（字符）
+	reminderBatchSize      = 100
+)
+
+// slashCommandPattern 命令格式：/名称 参数...（名称为小写字母、数字、下划线或连字符）
+var slashCommandPattern = regexp.MustCompile(`^/([A-Za-z0-9_-]{1,32})(?:\s+([\s\S]*))?$`)
+
+// botCommandNamePattern 机器人命令名
+var botCommandNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// commandMentionPattern 富文本格式的@提及：@[名称](user:ID)
+var commandMentionPattern = regexp.MustCompile(`^@\[[^\]]*\]\(user:(\d+)\)$`)
+
+var botCommandClient = &http.Client{Timeout: botCommandTimeout}
+
+// groupRoleRank 群角色等级，用于命令权限检查
+var groupRoleRank = map[string]int{
+	"member": 1,
+	"admin":  2,
+	"owner":  3,
+}
+
+// roleAllows 判断群角色是否满足命令要求的最低角色
+func roleAllows(role, minRole string) bool {
+	return groupRoleRank[role] >= groupRoleRank[minRole]
+}
+
+// slashCommandContext 命令执行上下文
+type slashCommandContext struct {
+	gc               *GroupController
+	GroupID          int
+	UserID           int
+	Role             string   // 调用者在群组中的角色（GetUserGroupRole）
+	Name             string   // 命令名
+	Args             []string // 解析后的参数（支持引号包裹含空格的参数）
+	RawArgs          string   // 命令名之后的原始文本
+	MentionedUserIDs []int
+}
+
+// slashCommandReply 命令回复（仅调用者可见）
+type slashCommandReply struct {
+	Command string `json:"command"`
+	Text    string `json:"text"`
+}
+
+// builtinSlashCommand 内置命令
+type builtinSlashCommand struct {
+	Description string
+	Usage       string
+	MinRole     string
+	Handler     func(ctx *slashCommandContext) string
+}
+
+// builtinSlashCommands 内置命令表（在 init 中注册，/help 需要引用该表）
+var builtinSlashCommands map[string]*builtinSlashCommand
+
+func init() {
+	builtinSlashCommands = map[string]*builtinSlashCommand{
+		"help": {
+			Description: "查看可用命令",
+			MinRole:     "member",
+			Handler:     runHelpCommand,
+		},
+		"poll": {
+			Description: "发起投票",
+			Usage:       `"主题" "选项1" "选项2" ...`,
+			MinRole:     "member",
+			Handler:     runPollCommand,
+		},
+		"remind": {
+			Description: "定时提醒自己",
+			Usage:       "<时长，如 30m、2h、1d> <内容>",
+			MinRole:     "member",
+			Handler:     runRemindCommand,
+		},
+		"mute": {
+			Description: "禁言成员一段时间",
+			Usage:       "@成员 <时长，如 10m、1h>",
+			MinRole:     "admin",
+			Handler:     runMuteCommand,
+		},
+		"unmute": {
+			Description: "解除成员禁言",
+			Usage:       "@成员",
+			MinRole:     "admin",
+			Handler:     runUnmuteCommand,
+		},
+		"topic": {
+			Description: "设置群公告",
+			Usage:       "<公告内容>",
+			MinRole:     "admin",
+			Handler:     runTopicCommand,
+		},
+	}
+}
+
+// parseSlashCommand 解析斜杠命令，返回小写命令名和命令名之后的原始文本
+func parseSlashCommand(content string) (string, string, bool) {
+	m := slashCommandPattern.FindStringSubmatch(strings.TrimSpace(content))
+	if m == nil {
+		return "", "", false
+	}
+	return strings.ToLower(m[1]), strings.TrimSpace(m[2]), true
+}
+
+// splitCommandArgs 按空白拆分参数，双引号（含中文引号）包裹的内容作为一个参数
+func splitCommandArgs(raw string) []string {
+	args := []string{}
+	var sb strings.Builder
+	inQuote := false
+	hasToken := false
+	for _, r := range raw {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			inQuote = !inQuote
+			hasToken = true
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '　'):
+			if hasToken {
+				args = append(args, sb.String())
+				sb.Reset()
+				hasToken = false
+			}
+		default:
+			sb.WriteRune(r)
+			hasToken = true
+		}
+	}
+	if hasToken {
+		args = append(args, sb.String())
+	}
+	return args
+}
+
+// parseCommandDuration 解析时长参数：数字 + 单位 s（秒）、m（分钟）、h（小时）、d（天）
+func parseCommandDuration(s string) (time.Duration, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	switch s[len(s)-1] {
+	case 's':
+		return time.Duration(n) * time.Second, true
+	case 'm':
+		return time.Duration(n) * time.Minute, true
+	case 'h':
+		return time.Duration(n) * time.Hour, true
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// formatCommandDuration 将时长格式化为中文描述
+func formatCommandDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return strconv.Itoa(int(d/(24*time.Hour))) + "天"
+	case d >= time.Hour && d%time.Hour == 0:
+		return strconv.Itoa(int(d/time.Hour)) + "小时"
+	case d >= time.Minute && d%time.Minute == 0:
+		return strconv.Itoa(int(d/time.Minute)) + "分钟"
+	default:
+		return strconv.Itoa(int(d/time.Second)) + "秒"
+	}
+}
+
+// resolveCommandTarget 解析命令中的目标成员：@[名称](user:ID)、消息附带的@用户，或按群昵称/姓名/用户名匹配
+func resolveCommandTarget(ctx *slashCommandContext, arg string) (int, string) {
+	if m := commandMentionPattern.FindStringSubmatch(arg); m != nil {
+		id, _ := strconv.Atoi(m[1])
+		return id, ""
+	}
+	if !strings.HasPrefix(arg, "@") {
+		return 0, "请用 @ 指定成员"
+	}
+	for _, id := range ctx.MentionedUserIDs {
+		if id > 0 {
+			return id, ""
+		}
+	}
+
+	name := strings.TrimPrefix(arg, "@")
+	members, err := ctx.gc.groupRepo.GetGroupMembers(ctx.GroupID)
+	if err != nil {
+		return 0, "获取群成员失败"
+	}
+	for _, member := range members {
+		if (member.Nickname != nil && *member.Nickname == name) ||
+			(member.FullName != nil && *member.FullName == name) ||
+			member.Username == name {
+			return member.UserID, ""
+		}
+	}
+	return 0, "找不到成员 " + arg
+}
+
+// dispatchSlashCommand 如果群消息是已注册的斜杠命令则执行并返回回复（handled 为 true）
+// 内容不是命令或命令未注册时 handled 为 false，按普通消息发送（例如 /path/to/file）
+// async 为 true 时机器人命令在后台回调，完成后通过 command_response 推送回复，返回的回复文本为空
+func dispatchSlashCommand(hub *ws.Hub, groupID, userID int, role, messageType, content string, mentionedUserIDs []int, async bool) (*slashCommandReply, bool) {
+	if messageType != "" && messageType != "text" {
+		return nil, false
+	}
+	name, rawArgs, ok := parseSlashCommand(content)
+	if !ok {
+		return nil, false
+	}
+
+	ctx := &slashCommandContext{
+		gc:               NewGroupController(hub),
+		GroupID:          groupID,
+		UserID:           userID,
+		Role:             role,
+		Name:             name,
+		Args:             splitCommandArgs(rawArgs),
+		RawArgs:          rawArgs,
+		MentionedUserIDs: mentionedUserIDs,
+	}
+	reply := &slashCommandReply{Command: name}
+
+	if cmd, ok := builtinSlashCommands[name]; ok {
+		if !roleAllows(role, cmd.MinRole) {
+			reply.Text = "你没有权限使用 /" + name + " 命令"
+			return reply, true
+		}
+		reply.Text = cmd.Handler(ctx)
+		utils.LogInfo("⌨️ [斜杠命令] 用户 %d 在群组 %d 执行 /%s", userID, groupID, name)
+		return reply, true
+	}
+
+	botCommands, err := models.NewSlashCommandRepository(db.DB).ListGroupBotCommands(groupID)
+	if err != nil {
+		utils.LogError("❌ [斜杠命令] 获取群组 %d 机器人命令失败: %v", groupID, err)
+		return nil, false
+	}
+	for i := range botCommands {
+		cmd := &botCommands[i]
+		if cmd.Command != name {
+			continue
+		}
+		if !roleAllows(role, cmd.MinRole) {
+			reply.Text = "你没有权限使用 /" + name + " 命令"
+			return reply, true
+		}
+		utils.LogInfo("⌨️ [斜杠命令] 用户 %d 在群组 %d 执行机器人 %d 的 /%s", userID, groupID, cmd.BotID, name)
+		if async {
+			// 回调最长等待 botCommandClient 的超时时间，不阻塞 WebSocket 读循环
+			go func() {
+				sendSlashCommandReply(hub, userID, groupID, &slashCommandReply{Command: name, Text: runBotCommand(ctx, cmd)})
+			}()
+			return reply, true
+		}
+		reply.Text = runBotCommand(ctx, cmd)
+		return reply, true
+	}
+	return nil, false
+}
+
+// sendSlashCommandReply 通过 WebSocket 向调用者推送命令回复（仅调用者可见）
+func sendSlashCommandReply(hub *ws.Hub, userID, groupID int, reply *slashCommandReply) {
+	if reply == nil || reply.Text == "" {
+		return
+	}
+	frame := models.WSMessage{
+		Type: "command_response",
+		Data: gin.H{
+			"group_id":  groupID,
+			"command":   reply.Command,
+			"text":      reply.Text,
+			"ephemeral": true,
+		},
+	}
+	frameBytes, _ := json.Marshal(frame)
+	hub.SendToUser(userID, frameBytes)
+}
+
+// runHelpCommand /help
+func runHelpCommand(ctx *slashCommandContext) string {
+	commands, err := listGroupSlashCommands(ctx.GroupID, ctx.Role, "")
+	if err != nil {
+		return "获取命令列表失败"
+	}
+	lines := []string{"可用命令："}
+	for _, cmd := range commands {
+		line := "/" + cmd.Name
+		if cmd.Usage != "" {
+			line += " " + cmd.Usage
+		}
+		if cmd.Description != "" {
+			line += " - " + cmd.Description
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// runPollCommand /poll "主题" "选项1" "选项2" ...
+func runPollCommand(ctx *slashCommandContext) string {
+	if len(ctx.Args) < 3 {
+		return `用法：/poll "主题" "选项1" "选项2" ...`
+	}
+	question, options, errMsg := normalizePollInput(ctx.Args[0], ctx.Args[1:])
+	if errMsg != "" {
+		return errMsg
+	}
+
+	pc := NewGroupPollController(ctx.gc.Hub)
+	poll, _, err := pc.createPoll(ctx.GroupID, ctx.UserID, question, options, false, false, nil)
+	if err == errPollGroupDisbanded || err == errPollContentBlocked {
+		return err.Error()
+	}
+	if err != nil {
+		utils.LogError("❌ [斜杠命令] 创建投票失败: %v", err)
+		return "创建投票失败"
+	}
+	return "已发起投票（ID: " + strconv.Itoa(poll.ID) + "）"
+}
+
+// runRemindCommand /remind <时长> <内容>
+func runRemindCommand(ctx *slashCommandContext) string {
+	if len(ctx.Args) < 2 {
+		return "用法：/remind <时长，如 30m、2h、1d> <内容>"
+	}
+	duration, ok := parseCommandDuration(ctx.Args[0])
+	if !ok || duration > maxReminderDuration {
+		return "时长格式错误，例如 30m、2h、1d（最长365天）"
+	}
+	content := strings.TrimSpace(strings.TrimPrefix(ctx.RawArgs, ctx.Args[0]))
+	if utf8.RuneCountInString(content) > maxReminderLength {
+		return "提醒内容不能超过500个字符"
+	}
+
+	reminder := &models.CommandReminder{
+		UserID:   ctx.UserID,
+		GroupID:  ctx.GroupID,
+		Content:  content,
+		RemindAt: time.Now().UTC().Add(duration),
+	}
+	if err := models.NewSlashCommandRepository(db.DB).CreateReminder(reminder); err != nil {
+		utils.LogError("❌ [斜杠命令] 创建提醒失败: %v", err)
+		return "创建提醒失败"
+	}
+	return "好的，将在" + formatCommandDuration(duration) + "后提醒你：" + content
+}
+
+// runMuteCommand /mute @成员 <时长>
+func runMuteCommand(ctx *slashCommandContext) string {
+	if len(ctx.Args) < 2 {
+		return "用法：/mute @成员 <时长，如 10m、1h>"
+	}
+	duration, ok := parseCommandDuration(ctx.Args[len(ctx.Args)-1])
+	if !ok || duration > maxCommandMuteDuration {
+		return "时长格式错误，例如 10m、1h、1d（最长30天）"
+	}
+	targetArg := strings.Join(ctx.Args[:len(ctx.Args)-1], " ")
+	targetID, errMsg := resolveCommandTarget(ctx, targetArg)
+	if errMsg != "" {
+		return errMsg
+	}
+	if targetID == ctx.UserID {
+		return "不能禁言自己"
+	}
+
+	// 与 MuteGroupMember 一致：不能禁言群主和管理员
+	targetRole, err := ctx.gc.groupRepo.GetUserGroupRole(ctx.GroupID, targetID)
+	if err != nil {
+		return "目标用户不是群组成员"
+	}
+	if targetRole == "owner" || targetRole == "admin" {
+		return "不能禁言群主和管理员"
+	}
+
+	if err := ctx.gc.groupRepo.MuteGroupMemberUntil(ctx.GroupID, targetID, time.Now().UTC().Add(duration)); err != nil {
+		utils.LogError("❌ [斜杠命令] 禁言成员失败: %v", err)
+		return "禁言失败"
+	}
+
+	operatorName := commandOperatorName(ctx)
+	go ctx.gc.sendMuteNotificationToUser(ctx.GroupID, targetID, ctx.UserID, operatorName, true)
+	return "已禁言 " + targetArg + " " + formatCommandDuration(duration)
+}
+
+// runUnmuteCommand /unmute @成员
+func runUnmuteCommand(ctx *slashCommandContext) string {
+	if len(ctx.Args) < 1 {
+		return "用法：/unmute @成员"
+	}
+	targetArg := strings.Join(ctx.Args, " ")
+	targetID, errMsg := resolveCommandTarget(ctx, targetArg)
+	if errMsg != "" {
+		return errMsg
+	}
+
+	if err := ctx.gc.groupRepo.UnmuteGroupMember(ctx.GroupID, targetID); err != nil {
+		if err == sql.ErrNoRows {
+			return "目标用户不是群组成员"
+		}
+		utils.LogError("❌ [斜杠命令] 解除禁言失败: %v", err)
+		return "解除禁言失败"
+	}
+
+	go ctx.gc.sendMuteNotificationToUser(ctx.GroupID, targetID, ctx.UserID, commandOperatorName(ctx), false)
+	return "已解除 " + targetArg + " 的禁言"
+}
+
+// runTopicCommand /topic <公告内容>
+func runTopicCommand(ctx *slashCommandContext) string {
+	topic := ctx.RawArgs
+	if topic == "" {
+		return "用法：/topic <公告内容>"
+	}
+	if utf8.RuneCountInString(topic) > maxTopicLength {
+		return "群公告不能超过500个字符"
+	}
+	if err := ctx.gc.groupRepo.UpdateGroup(ctx.GroupID, nil, &topic, nil); err != nil {
+		utils.LogError("❌ [斜杠命令] 更新群公告失败: %v", err)
+		return "更新群公告失败"
+	}
+	go ctx.gc.notifyGroupInfoUpdated(ctx.GroupID)
+	return "群公告已更新"
+}
+
+// commandOperatorName 命令调用者的显示名称
+func commandOperatorName(ctx *slashCommandContext) string {
+	operatorName := "管理员"
+	if operator, err := ctx.gc.userRepo.FindByID(ctx.UserID); err == nil && operator != nil {
+		operatorName = operator.Username
+		if operator.FullName != nil && *operator.FullName != "" {
+			operatorName = *operator.FullName
+		}
+	}
+	return operatorName
+}
+
+// botCommandRequest 机器人命令回调请求体
+type botCommandRequest struct {
+	Command  string   `json:"command"`
+	Text     string   `json:"text"`
+	Args     []string `json:"args"`
+	GroupID  int      `json:"group_id"`
+	UserID   int      `json:"user_id"`
+	UserName string   `json:"user_name"`
+	Role     string   `json:"role"`
+	BotID    int      `json:"bot_id"`
+}
+
+// botCommandResponse 机器人命令回调响应
+// response_type 为 in_channel 时以机器人身份发到群里，否则仅回复调用者
+type botCommandResponse struct {
+	models.WebhookPayload
+	ResponseType string `json:"response_type"`
+}
+
+// runBotCommand 回调机器人命令地址（请求签名方式与事件订阅一致）
+func runBotCommand(ctx *slashCommandContext, cmd *models.GroupBotCommand) string {
+	body, _ := json.Marshal(botCommandRequest{
+		Command:  cmd.Command,
+		Text:     ctx.RawArgs,
+		Args:     ctx.Args,
+		GroupID:  ctx.GroupID,
+		UserID:   ctx.UserID,
+		UserName: commandOperatorName(ctx),
+		Role:     ctx.Role,
+		BotID:    cmd.BotID,
+	})
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, cmd.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return "命令地址无效"
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Youdu-Command/1.0")
+	req.Header.Set(eventHeaderTimestamp, timestamp)
+	req.Header.Set(eventHeaderSignature, signEventPayload(cmd.Secret, timestamp, body))
+
+	resp, err := botCommandClient.Do(req)
+	if err != nil {
+		utils.LogError("❌ [斜杠命令] 机器人 %d 命令 /%s 回调失败: %v", cmd.BotID, cmd.Command, err)
+		return cmd.BotName + " 暂时无法响应，请稍后再试"
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		utils.LogError("❌ [斜杠命令] 机器人 %d 命令 /%s 回调返回 %s", cmd.BotID, cmd.Command, resp.Status)
+		return cmd.BotName + " 暂时无法响应，请稍后再试"
+	}
+
+	var result botCommandResponse
+	if len(bytes.TrimSpace(respBody)) == 0 {
+		return ""
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return cmd.BotName + " 返回了无法识别的内容"
+	}
+
+	if result.ResponseType != "in_channel" {
+		return strings.TrimSpace(result.Text)
+	}
+
+	messageType, content, errMsg := buildWebhookContent(&result.WebhookPayload)
+	if errMsg != "" {
+		return cmd.BotName + " 返回的消息无效：" + errMsg
+	}
+	if _, _, errMsg := sendBotGroupMessage(ctx.gc, cmd.BotID, ctx.GroupID, messageType, content); errMsg != "" {
+		return cmd.BotName + " 发送消息失败：" + errMsg
+	}
+	return ""
+}
+
+// listGroupSlashCommands 获取群组中调用者可用的命令（内置命令 + 群内机器人命令），按前缀过滤
+func listGroupSlashCommands(groupID int, role, prefix string) ([]models.SlashCommandInfo, error) {
+	prefix = strings.ToLower(strings.TrimPrefix(prefix, "/"))
+	commands := []models.SlashCommandInfo{}
+	for name, cmd := range builtinSlashCommands {
+		if !strings.HasPrefix(name, prefix) || !roleAllows(role, cmd.MinRole) {
+			continue
+		}
+		commands = append(commands, models.SlashCommandInfo{
+			Name:        name,
+			Description: cmd.Description,
+			Usage:       cmd.Usage,
+			Source:      models.SlashCommandSourceBuiltin,
+		})
+	}
+
+	botCommands, err := models.NewSlashCommandRepository(db.DB).ListGroupBotCommands(groupID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, cmd := range botCommands {
+		// 与内置命令或其他机器人重名时以先注册者为准（与 dispatchSlashCommand 一致）
+		if _, ok := builtinSlashCommands[cmd.Command]; ok || seen[cmd.Command] {
+			continue
+		}
+		seen[cmd.Command] = true
+		if !strings.HasPrefix(cmd.Command, prefix) || !roleAllows(role, cmd.MinRole) {
+			continue
+		}
+		commands = append(commands, models.SlashCommandInfo{
+			Name:        cmd.Command,
+			Description: cmd.Description,
+			Usage:       cmd.Usage,
+			Source:      models.SlashCommandSourceBot,
+			BotID:       cmd.BotID,
+			BotName:     cmd.BotName,
+		})
+	}
+
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands, nil
+}
+
+// DeliverDueReminders 送达到期的 /remind 提醒（由定时任务调用），用户不在线时保留到上线后送达
+func DeliverDueReminders(hub *ws.Hub) {
+	repo := models.NewSlashCommandRepository(db.DB)
+	reminders, err := repo.ListDueReminders(reminderBatchSize)
+	if err != nil {
+		utils.LogError("❌ [斜杠命令] 获取到期提醒失败: %v", err)
+		return
+	}
+
+	for _, reminder := range reminders {
+		if !hub.IsUserOnline(reminder.UserID) {
+			continue
+		}
+		delivered, err := repo.MarkReminderDelivered(reminder.ID)
+		if err != nil || !delivered {
+			continue
+		}
+		sendSlashCommandReply(hub, reminder.UserID, reminder.GroupID, &slashCommandReply{
+			Command: "remind",
+			Text:    "⏰ 提醒：" + reminder.Content,
+		})
+	}
+}
+
+// UnmuteExpiredMembers 解除已到期的定时禁言并通知成员（由定时任务调用）
+func UnmuteExpiredMembers(hub *ws.Hub) {
+	gc := NewGroupController(hub)
+	refs, err := gc.groupRepo.UnmuteExpiredMembers()
+	if err != nil {
+		utils.LogError("❌ [斜杠命令] 解除到期禁言失败: %v", err)
+		return
+	}
+	for _, ref := range refs {
+		// 系统消息以被解除禁言的成员作为发送者（避免外键约束错误）
+		gc.sendMuteNotificationToUser(ref.GroupID, ref.UserID, ref.UserID, "系统", false)
+		utils.LogInfo("🔈 [斜杠命令] 群组 %d 成员 %d 定时禁言已到期", ref.GroupID, ref.UserID)
+	}
+}
+
+// SlashCommandController 斜杠命令控制器
+type SlashCommandController struct {
+	Hub         *ws.Hub
+	groupRepo   *models.GroupRepository
+	botRepo     *models.BotRepository
+	commandRepo *models.SlashCommandRepository
+}
+
+// NewSlashCommandController 创建斜杠命令控制器
+func NewSlashCommandController(hub *ws.Hub) *SlashCommandController {
+	return &SlashCommandController{
+		Hub:         hub,
+		groupRepo:   models.NewGroupRepository(db.DB),
+		botRepo:     models.NewBotRepository(db.DB),
+		commandRepo: models.NewSlashCommandRepository(db.DB),
+	}
+}
+
+// GetGroupCommands 命令自动补全：返回群组中当前用户可用的命令
+// GET /api/groups/:id/commands?prefix=po
+func (sc *SlashCommandController) GetGroupCommands(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的群组ID")
+		return
+	}
+
+	role, err := sc.groupRepo.GetUserGroupRole(groupID, userID.(int))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.Forbidden(c, "您不是该群组成员")
+			return
+		}
+		utils.InternalServerError(c, "验证群组成员失败")
+		return
+	}
+
+	commands, err := listGroupSlashCommands(groupID, role, c.Query("prefix"))
+	if err != nil {
+		utils.LogError("❌ [斜杠命令] 获取群组 %d 命令列表失败: %v", groupID, err)
+		utils.InternalServerError(c, "获取命令列表失败")
+		return
+	}
+	utils.Success(c, gin.H{"commands": commands})
+}
+
+// AdminGetBotCommands 获取机器人注册的命令
+func (sc *SlashCommandController) AdminGetBotCommands(c *gin.Context) {
+	botID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的机器人ID")
+		return
+	}
+
+	commands, err := sc.commandRepo.ListBotCommands(botID)
+	if err != nil {
+		utils.LogError("❌ [斜杠命令] 获取机器人命令失败: %v", err)
+		utils.InternalServerError(c, "获取机器人命令失败")
+		return
+	}
+	utils.Success(c, gin.H{"commands": commands})
+}
+
+// AdminSaveBotCommand 为机器人注册命令（同名命令覆盖），签名密钥只在生成时返回
+func (sc *SlashCommandController) AdminSaveBotCommand(c *gin.Context) {
+	botID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的机器人ID")
+		return
+	}
+
+	var req models.SaveBotCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if _, err := sc.botRepo.GetBot(botID); err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "机器人不存在")
+			return
+		}
+		utils.InternalServerError(c, "获取机器人失败")
+		return
+	}
+
+	command := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(req.Command), "/"))
+	if !botCommandNamePattern.MatchString(command) {
+		utils.BadRequest(c, "命令名只能包含小写字母、数字、下划线和连字符，且不超过32个字符")
+		return
+	}
+	if _, ok := builtinSlashCommands[command]; ok {
+		utils.BadRequest(c, "不能覆盖内置命令 /"+command)
+		return
+	}
+
+	u, err := url.Parse(strings.TrimSpace(req.CallbackURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		utils.BadRequest(c, "回调地址必须是有效的 http/https 地址")
+		return
+	}
+
+	minRole := req.MinRole
+	if minRole == "" {
+		minRole = "member"
+	}
+	if _, ok := groupRoleRank[minRole]; !ok {
+		utils.BadRequest(c, "min_role 只能是 member、admin 或 owner")
+		return
+	}
+
+	secret := strings.TrimSpace(req.Secret)
+	generated := false
+	if secret == "" {
+		if existing, err := sc.commandRepo.GetBotCommand(botID, command); err == nil {
+			secret = existing.Secret
+		} else {
+			buf := make([]byte, 24)
+			if _, err := rand.Read(buf); err != nil {
+				utils.InternalServerError(c, "生成签名密钥失败")
+				return
+			}
+			secret = hex.EncodeToString(buf)
+			generated = true
+		}
+	}
+	if len(secret) > 128 {
+		utils.BadRequest(c, "签名密钥不能超过128个字符")
+		return
+	}
+
+	saved, err := sc.commandRepo.SaveBotCommand(&models.BotCommand{
+		BotID:       botID,
+		Command:     command,
+		Description: strings.TrimSpace(req.Description),
+		Usage:       strings.TrimSpace(req.Usage),
+		CallbackURL: u.String(),
+		Secret:      secret,
+		MinRole:     minRole,
+	})
+	if err != nil {
+		utils.LogError("❌ [斜杠命令] 保存机器人命令失败: %v", err)
+		utils.InternalServerError(c, "保存机器人命令失败")
+		return
+	}
+
+	utils.LogInfo("⌨️ [斜杠命令] 机器人 %d 注册命令 /%s", botID, command)
+	data := gin.H{"command": saved}
+	if generated {
+		data["secret"] = secret
+	}
+	utils.Success(c, data)
+}
+
+// AdminDeleteBotCommand 删除机器人命令
+func (sc *SlashCommandController) AdminDeleteBotCommand(c *gin.Context) {
+	commandID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的命令ID")
+		return
+	}
+
+	deleted, err := sc.commandRepo.DeleteBotCommand(commandID)
+	if err != nil {
+		utils.LogError("❌ [斜杠命令] 删除机器人命令失败: %v", err)
+		utils.InternalServerError(c, "删除机器人命令失败")
+		return
+	}
+	if !deleted {
+		utils.NotFound(c, "命令不存在")
+		return
+	}
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
@@ -0,0 +1,398 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 表情图片允许的格式
+var allowedStickerExts = map[string]bool{
+	".png":  true,
+	".gif":  true,
+	".webp": true,
+	".jpg":  true,
+	".jpeg": true,
+}
+
+// StickerController 表情控制器
+type StickerController struct {
+	stickerRepo *models.StickerRepository
+}
+
+// NewStickerController 创建表情控制器
+func NewStickerController() *StickerController {
+	return &StickerController{
+		stickerRepo: models.NewStickerRepository(db.DB),
+	}
+}
+
+// GetStickerPacks 获取全局表情包列表（含表情）
+func (sc *StickerController) GetStickerPacks(c *gin.Context) {
+	packs, err := sc.stickerRepo.ListPacks()
+	if err != nil {
+		utils.LogError("获取表情包列表失败: %v", err)
+		utils.InternalServerError(c, "获取表情包列表失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"packs": packs,
+	})
+}
+
+// GetSticker 根据ID获取表情（已删除的表情也可获取，用于展示历史消息）
+func (sc *StickerController) GetSticker(c *gin.Context) {
+	stickerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的表情ID")
+		return
+	}
+
+	sticker, err := sc.stickerRepo.GetByID(stickerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "表情不存在")
+			return
+		}
+		utils.InternalServerError(c, "获取表情失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"sticker": sticker,
+	})
+}
+
+// GetMyStickers 获取当前用户的个人表情
+func (sc *StickerController) GetMyStickers(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	stickers, err := sc.stickerRepo.ListUserStickers(userID.(int))
+	if err != nil {
+		utils.LogError("获取个人表情失败: %v", err)
+		utils.InternalServerError(c, "获取个人表情失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"stickers": stickers,
+	})
+}
+
+// AddMySticker 添加个人表情
+// 传 image_url（通过 /upload/image 上传后的地址）时创建新表情；传 sticker_id 时收藏已有表情
+func (sc *StickerController) AddMySticker(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	currentUserID := userID.(int)
+
+	var req models.AddStickerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	var sticker *models.Sticker
+	var err error
+	if req.StickerID > 0 {
+		existing, getErr := sc.stickerRepo.GetByID(req.StickerID)
+		if getErr != nil || existing.DeletedAt != nil {
+			utils.NotFound(c, "表情不存在")
+			return
+		}
+		sticker = existing
+		err = sc.stickerRepo.AddUserSticker(currentUserID, sticker.ID)
+	} else {
+		newSticker, errMsg := buildStickerFromRequest(&req)
+		if errMsg != "" {
+			utils.BadRequest(c, errMsg)
+			return
+		}
+		sticker = newSticker
+		err = sc.stickerRepo.CreateUserSticker(currentUserID, newSticker)
+	}
+	if err != nil {
+		if err == models.ErrStickerLimitReached {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+		utils.LogError("添加个人表情失败: %v", err)
+		utils.InternalServerError(c, "添加表情失败")
+		return
+	}
+
+	utils.LogDebug("✅ [表情] 用户 %d 添加个人表情 %d", currentUserID, sticker.ID)
+	utils.Success(c, gin.H{
+		"sticker": sticker,
+	})
+}
+
+// ReorderMyStickers 调整个人表情顺序
+func (sc *StickerController) ReorderMyStickers(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	var req models.ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := sc.stickerRepo.ReorderUserStickers(userID.(int), req.IDs); err != nil {
+		utils.LogError("调整个人表情顺序失败: %v", err)
+		utils.InternalServerError(c, "调整顺序失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "调整顺序成功", nil)
+}
+
+// RemoveMySticker 移除个人表情（已发送的表情消息不受影响）
+func (sc *StickerController) RemoveMySticker(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	stickerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的表情ID")
+		return
+	}
+
+	removed, err := sc.stickerRepo.RemoveUserSticker(userID.(int), stickerID)
+	if err != nil {
+		utils.LogError("移除个人表情失败: %v", err)
+		utils.InternalServerError(c, "移除表情失败")
+		return
+	}
+	if !removed {
+		utils.NotFound(c, "表情不存在")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "移除成功", nil)
+}
+
+// AdminCreateStickerPack 创建全局表情包（管理后台）
+func (sc *StickerController) AdminCreateStickerPack(c *gin.Context) {
+	var req models.CreateStickerPackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	pack, err := sc.stickerRepo.CreatePack(&req)
+	if err != nil {
+		utils.LogError("创建表情包失败: %v", err)
+		utils.InternalServerError(c, "创建表情包失败")
+		return
+	}
+
+	utils.LogInfo("✅ [表情] 创建表情包 %d: %s", pack.ID, pack.Name)
+	utils.Success(c, gin.H{
+		"pack": pack,
+	})
+}
+
+// AdminUpdateStickerPack 更新全局表情包信息（管理后台）
+func (sc *StickerController) AdminUpdateStickerPack(c *gin.Context) {
+	packID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的表情包ID")
+		return
+	}
+
+	var req models.CreateStickerPackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := sc.stickerRepo.UpdatePack(packID, &req); err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "表情包不存在")
+			return
+		}
+		utils.LogError("更新表情包失败: %v", err)
+		utils.InternalServerError(c, "更新表情包失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "更新成功", nil)
+}
+
+// AdminDeleteStickerPack 删除全局表情包（软删除，历史表情消息仍可展示）
+func (sc *StickerController) AdminDeleteStickerPack(c *gin.Context) {
+	packID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的表情包ID")
+		return
+	}
+
+	if err := sc.stickerRepo.DeletePack(packID); err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "表情包不存在")
+			return
+		}
+		utils.LogError("删除表情包失败: %v", err)
+		utils.InternalServerError(c, "删除表情包失败")
+		return
+	}
+
+	utils.LogInfo("🗑️ [表情] 删除表情包 %d", packID)
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// AdminReorderStickerPacks 调整全局表情包顺序（管理后台）
+func (sc *StickerController) AdminReorderStickerPacks(c *gin.Context) {
+	var req models.ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := sc.stickerRepo.ReorderPacks(req.IDs); err != nil {
+		utils.LogError("调整表情包顺序失败: %v", err)
+		utils.InternalServerError(c, "调整顺序失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "调整顺序成功", nil)
+}
+
+// AdminAddPackSticker 向全局表情包添加表情（管理后台，图片先通过 /upload/image 上传）
+func (sc *StickerController) AdminAddPackSticker(c *gin.Context) {
+	packID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的表情包ID")
+		return
+	}
+
+	var req models.AddStickerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if _, err := sc.stickerRepo.GetPack(packID); err != nil {
+		utils.NotFound(c, "表情包不存在")
+		return
+	}
+
+	sticker, errMsg := buildStickerFromRequest(&req)
+	if errMsg != "" {
+		utils.BadRequest(c, errMsg)
+		return
+	}
+
+	if err := sc.stickerRepo.AddPackSticker(packID, sticker); err != nil {
+		utils.LogError("添加表情失败: %v", err)
+		utils.InternalServerError(c, "添加表情失败")
+		return
+	}
+	sticker.PackID = &packID
+
+	utils.Success(c, gin.H{
+		"sticker": sticker,
+	})
+}
+
+// AdminReorderPackStickers 调整表情包内表情的顺序（管理后台）
+func (sc *StickerController) AdminReorderPackStickers(c *gin.Context) {
+	packID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的表情包ID")
+		return
+	}
+
+	var req models.ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := sc.stickerRepo.ReorderPackStickers(packID, req.IDs); err != nil {
+		utils.LogError("调整表情顺序失败: %v", err)
+		utils.InternalServerError(c, "调整顺序失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "调整顺序成功", nil)
+}
+
+// AdminDeleteSticker 删除表情（软删除，历史表情消息仍可展示）
+func (sc *StickerController) AdminDeleteSticker(c *gin.Context) {
+	stickerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的表情ID")
+		return
+	}
+
+	if err := sc.stickerRepo.DeleteSticker(stickerID); err != nil {
+		if err == sql.ErrNoRows {
+			utils.NotFound(c, "表情不存在")
+			return
+		}
+		utils.LogError("删除表情失败: %v", err)
+		utils.InternalServerError(c, "删除表情失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// buildStickerFromRequest 校验表情图片地址（必须是本服务OSS中的图片）并构建表情
+func buildStickerFromRequest(req *models.AddStickerRequest) (*models.Sticker, string) {
+	if req.ImageURL == "" {
+		return nil, "表情图片地址不能为空"
+	}
+	if req.Name != nil && len([]rune(*req.Name)) > 50 {
+		return nil, "表情名称不能超过50个字符"
+	}
+	if req.Width < 0 || req.Height < 0 {
+		return nil, "表情尺寸无效"
+	}
+
+	ossCtx, err := NewOSSController().getOSSContext()
+	if err != nil {
+		utils.LogError("获取OSS配置失败: %v", err)
+		return nil, "OSS配置未设置"
+	}
+
+	objectKey := ossObjectKeyFromURL(ossCtx, req.ImageURL)
+	if objectKey == "" || strings.Contains(objectKey, "..") {
+		return nil, "表情图片必须通过上传接口上传"
+	}
+	if !allowedStickerExts[strings.ToLower(path.Ext(objectKey))] {
+		return nil, "不支持的表情格式，仅支持 png, gif, webp, jpg, jpeg"
+	}
+
+	return &models.Sticker{
+		Name:      req.Name,
+		ImageURL:  req.ImageURL,
+		ObjectKey: objectKey,
+		Width:     req.Width,
+		Height:    req.Height,
+	}, ""
+}
@@ -0,0 +1,472 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"youdu-server/config"
+	"youdu-server/db"
+	"youdu-server/models"
+	"youdu-server/utils"
+	ws "youdu-server/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 加急消息相关常量
+const (
+	urgentQuotaWindow         = 24 * time.Hour     // 配额统计窗口
+	urgentPendingLookback     = 7 * 24 * time.Hour // 待确认列表只返回最近 7 天的加急消息
+	urgentEscalationBatchSize = 100                // 每批领取的升级提醒数
+)
+
+var errUrgentDisabled = errors.New("加急消息功能未开启")
+
+// urgentEscalationMu 防止同一实例上的升级提醒任务重叠运行
+var urgentEscalationMu sync.Mutex
+
+// sendUrgentEmail 加急提醒邮件发送函数（测试时可替换，无需真实 SMTP 服务）
+var sendUrgentEmail = utils.SendEmail
+
+// UrgentMessageController 加急消息（DING）控制器
+type UrgentMessageController struct {
+	Hub        *ws.Hub
+	urgentRepo *models.UrgentMessageRepository
+}
+
+// NewUrgentMessageController 创建加急消息控制器
+func NewUrgentMessageController(hub *ws.Hub) *UrgentMessageController {
+	return &UrgentMessageController{
+		Hub:        hub,
+		urgentRepo: models.NewUrgentMessageRepository(db.DB),
+	}
+}
+
+// Confirm 接收者确认已收到加急消息，并通知发送者
+// POST /api/urgent/:id/confirm
+func (uc *UrgentMessageController) Confirm(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	urgentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的加急消息ID")
+		return
+	}
+
+	isRecipient, err := uc.urgentRepo.IsRecipient(urgentID, userID.(int))
+	if err != nil {
+		utils.LogError("❌ [加急消息] 查询用户 %d 的加急消息 %d 失败: %v", userID.(int), urgentID, err)
+		utils.InternalServerError(c, "确认加急消息失败")
+		return
+	}
+	if !isRecipient {
+		utils.NotFound(c, "加急消息不存在")
+		return
+	}
+
+	confirmed, err := uc.urgentRepo.Confirm(urgentID, userID.(int))
+	if err != nil {
+		utils.LogError("❌ [加急消息] 用户 %d 确认加急消息 %d 失败: %v", userID.(int), urgentID, err)
+		utils.InternalServerError(c, "确认加急消息失败")
+		return
+	}
+
+	if confirmed {
+		if urgent, err := uc.urgentRepo.GetByID(urgentID); err == nil {
+			wsMsg := models.WSMessage{
+				Type: "urgent_confirmed",
+				Data: gin.H{
+					"urgent_id":         urgent.ID,
+					"conversation_type": urgent.ConversationType,
+					"message_id":        urgent.MessageID,
+					"group_id":          urgent.GroupID,
+					"user_id":           userID.(int),
+					"confirmed_count":   urgent.ConfirmedCount,
+					"recipient_count":   urgent.RecipientCount,
+				},
+			}
+			msgBytes, _ := json.Marshal(wsMsg)
+			uc.Hub.SendToUser(urgent.SenderID, msgBytes)
+		}
+		utils.LogDebug("✅ [加急消息] 用户 %d 已确认加急消息 %d", userID.(int), urgentID)
+	}
+
+	utils.SuccessWithMessage(c, "已确认", gin.H{"urgent_id": urgentID})
+}
+
+// GetReceipts 查看加急消息的确认情况（仅发送者可查看）
+// GET /api/urgent/:id/receipts
+func (uc *UrgentMessageController) GetReceipts(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	urgentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "无效的加急消息ID")
+		return
+	}
+
+	urgent, err := uc.urgentRepo.GetByID(urgentID)
+	if err == sql.ErrNoRows {
+		utils.NotFound(c, "加急消息不存在")
+		return
+	}
+	if err != nil {
+		utils.LogError("❌ [加急消息] 获取加急消息 %d 失败: %v", urgentID, err)
+		utils.InternalServerError(c, "获取确认情况失败")
+		return
+	}
+	if urgent.SenderID != userID.(int) {
+		utils.Forbidden(c, "只有发送者可以查看确认情况")
+		return
+	}
+
+	receipts, err := uc.urgentRepo.ListReceipts(urgent)
+	if err != nil {
+		utils.LogError("❌ [加急消息] 获取加急消息 %d 的回执失败: %v", urgentID, err)
+		utils.InternalServerError(c, "获取确认情况失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"urgent":   urgent,
+		"receipts": receipts,
+	})
+}
+
+// GetPending 获取当前用户尚未确认的加急消息
+// GET /api/urgent/pending
+func (uc *UrgentMessageController) GetPending(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	since := time.Now().UTC().Add(-urgentPendingLookback)
+	messages, err := uc.urgentRepo.ListPendingForUser(userID.(int), since)
+	if err != nil {
+		utils.LogError("❌ [加急消息] 获取用户 %d 待确认的加急消息失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "获取加急消息失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"messages": messages,
+		"total":    len(messages),
+	})
+}
+
+// GetQuota 获取当前用户的加急消息配额
+// GET /api/urgent/quota
+func (uc *UrgentMessageController) GetQuota(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+
+	used, err := uc.urgentRepo.CountSentSince(userID.(int), time.Now().UTC().Add(-urgentQuotaWindow))
+	if err != nil {
+		utils.LogError("❌ [加急消息] 统计用户 %d 的加急消息数失败: %v", userID.(int), err)
+		utils.InternalServerError(c, "获取加急配额失败")
+		return
+	}
+
+	quota := config.AppConfig.UrgentDailyQuota
+	remaining := quota - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	utils.Success(c, gin.H{
+		"daily_quota":        quota,
+		"used":               used,
+		"remaining":          remaining,
+		"escalation_minutes": config.AppConfig.UrgentEscalationMinutes,
+	})
+}
+
+// validateUrgentMessage 发送前校验加急消息：消息类型、接收者数量及发送者最近 24 小时的配额
+// 配额在这里只做提前拦截，消息保存后由 reserveUrgent 原子地占用
+func validateUrgentMessage(senderID int, messageType string, recipientCount int) error {
+	quota := config.AppConfig.UrgentDailyQuota
+	if quota <= 0 {
+		return errUrgentDisabled
+	}
+	if messageType == "system" || strings.HasPrefix(messageType, "call_") {
+		return errors.New("该类型的消息不能加急")
+	}
+	if recipientCount == 0 {
+		return errors.New("没有可以加急提醒的成员")
+	}
+	if recipientCount > models.MaxUrgentRecipients {
+		return fmt.Errorf("加急消息最多提醒 %d 人，请@指定成员", models.MaxUrgentRecipients)
+	}
+
+	used, err := models.NewUrgentMessageRepository(db.DB).CountSentSince(senderID, time.Now().UTC().Add(-urgentQuotaWindow))
+	if err != nil {
+		utils.LogError("❌ [加急消息] 统计用户 %d 的加急消息数失败: %v", senderID, err)
+		return errors.New("加急配额校验失败，请稍后再试")
+	}
+	if used >= quota {
+		return models.ErrUrgentQuotaExceeded
+	}
+	return nil
+}
+
+// urgentGroupRecipients 群加急消息的接收者：@了指定成员时为被@的群成员，否则（包括@所有人）为除发送者外的全体成员
+func urgentGroupRecipients(groupRepo *models.GroupRepository, groupID, senderID int, mentionedIDs []int, mentions string) ([]int, error) {
+	memberIDs, err := groupRepo.GetGroupMemberIDs(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	isMember := make(map[int]bool, len(memberIDs))
+	for _, id := range memberIDs {
+		isMember[id] = true
+	}
+
+	candidates := memberIDs
+	if len(mentionedIDs) > 0 && !strings.Contains(mentions, "@all") {
+		candidates = mentionedIDs
+	}
+
+	seen := make(map[int]bool, len(candidates))
+	recipients := make([]int, 0, len(candidates))
+	for _, id := range candidates {
+		if id == senderID || !isMember[id] || seen[id] {
+			continue
+		}
+		seen[id] = true
+		recipients = append(recipients, id)
+	}
+	return recipients, nil
+}
+
+// reserveUrgent 消息保存后、投递前记录加急并占用发送者的配额（同步执行，并发发送时不会超出配额）
+// 返回错误时消息按普通消息投递
+func reserveUrgent(urgent *models.UrgentMessage, messageType, content string, recipientIDs []int) error {
+	urgent.Preview = urgentPreview(messageType, content)
+	urgent.EscalateAt = time.Now().UTC().Add(time.Duration(config.AppConfig.UrgentEscalationMinutes) * time.Minute)
+
+	since := time.Now().UTC().Add(-urgentQuotaWindow)
+	err := models.NewUrgentMessageRepository(db.DB).Create(urgent, recipientIDs, config.AppConfig.UrgentDailyQuota, since)
+	if err == models.ErrUrgentQuotaExceeded {
+		return fmt.Errorf("%w，消息已按普通消息发送", err)
+	}
+	if err != nil {
+		utils.LogError("❌ [加急消息] 保存消息 %s/%d 的加急记录失败: %v", urgent.ConversationType, urgent.MessageID, err)
+		return errors.New("加急失败，消息已按普通消息发送")
+	}
+	return nil
+}
+
+// markUrgent 消息投递后提醒加急接收者：在线的推送 urgent_message 事件，离线的立即发送加急推送
+// 加急推送不受免打扰影响，也不参与同一会话的通知合并
+func markUrgent(hub *ws.Hub, urgent *models.UrgentMessage, messageType, content string, recipientIDs []int) {
+	targetID := urgent.SenderID
+	groupName := ""
+	if urgent.ConversationType == models.ConversationTypeGroup {
+		targetID = urgent.GroupID
+		if group, err := models.NewGroupRepository(db.DB).GetGroupByID(urgent.GroupID); err == nil {
+			groupName = group.Name
+		}
+	}
+
+	wsMsg := models.WSMessage{
+		Type: "urgent_message",
+		Data: gin.H{
+			"urgent_id":         urgent.ID,
+			"conversation_type": urgent.ConversationType,
+			"target_id":         targetID,
+			"message_id":        urgent.MessageID,
+			"sender_id":         urgent.SenderID,
+			"sender_name":       urgent.SenderName,
+			"group_name":        groupName,
+			"preview":           urgent.Preview,
+			"escalate_at":       urgent.EscalateAt,
+			"created_at":        urgent.CreatedAt,
+		},
+	}
+	msgBytes, err := json.Marshal(wsMsg)
+	if err != nil {
+		utils.LogDebug("序列化加急消息失败: %v", err)
+		return
+	}
+
+	var offlineIDs []int
+	for _, userID := range recipientIDs {
+		if !hub.SendToUser(userID, msgBytes) {
+			offlineIDs = append(offlineIDs, userID)
+		}
+	}
+
+	// 告知发送者加急记录ID，便于查看确认情况
+	sentMsg := models.WSMessage{
+		Type: "urgent_message_sent",
+		Data: gin.H{
+			"urgent_id":         urgent.ID,
+			"conversation_type": urgent.ConversationType,
+			"message_id":        urgent.MessageID,
+			"recipient_count":   urgent.RecipientCount,
+			"escalate_at":       urgent.EscalateAt,
+		},
+	}
+	sentBytes, _ := json.Marshal(sentMsg)
+	hub.SendToUser(urgent.SenderID, sentBytes)
+
+	if len(offlineIDs) > 0 && utils.PushEnabled() {
+		msg := pushMessage{
+			conversationType: urgent.ConversationType,
+			targetID:         targetID,
+			messageID:        urgent.MessageID,
+			senderName:       urgent.SenderName,
+			groupName:        groupName,
+			messageType:      messageType,
+			content:          content,
+		}
+		for _, userID := range offlineIDs {
+			sendPushToUser(userID, func(locale string) *utils.PushNotification {
+				return buildUrgentPushNotification(locale, urgent.ID, msg)
+			})
+		}
+	}
+
+	utils.LogInfo("🔔 [加急消息] 用户 %d 发送加急消息 %d（%s/%d）- 接收者: %d, 离线: %d", urgent.SenderID, urgent.ID, urgent.ConversationType, urgent.MessageID, len(recipientIDs), len(offlineIDs))
+}
+
+// urgentPreview 生成加急消息预览（用于 WebSocket 事件、短信和邮件）
+func urgentPreview(messageType, content string) string {
+	preview := pushPreview("zh", messageType, content)
+	if utf8.RuneCountInString(preview) > models.UrgentPreviewMaxRune {
+		preview = string([]rune(preview)[:models.UrgentPreviewMaxRune]) + "…"
+	}
+	return preview
+}
+
+// buildUrgentPushNotification 生成加急推送（正文带加急标记，使用独立的折叠键避免被普通通知覆盖）
+func buildUrgentPushNotification(locale string, urgentID int, msg pushMessage) *utils.PushNotification {
+	notification := buildPushNotification(locale, &pendingPush{count: 1, latest: msg})
+
+	prefix := "[加急] "
+	if pushLanguage(locale) == "en" {
+		prefix = "[Urgent] "
+	}
+	notification.Body = prefix + notification.Body
+	notification.CollapseKey = "urgent_" + strconv.Itoa(urgentID)
+	notification.Data["urgent_id"] = strconv.Itoa(urgentID)
+	return notification
+}
+
+// EscalateUrgentMessages 对超时仍未读且未确认的加急消息通过短信或邮件提醒接收者（由定时任务调用）
+func EscalateUrgentMessages() {
+	if !urgentEscalationMu.TryLock() {
+		return
+	}
+	defer urgentEscalationMu.Unlock()
+
+	repo := models.NewUrgentMessageRepository(db.DB)
+	sentCount := 0
+	for {
+		escalations, err := repo.ClaimDueEscalations(time.Now().UTC(), urgentEscalationBatchSize)
+		if err != nil {
+			utils.LogError("❌ [加急消息] 领取待升级提醒失败: %v", err)
+			return
+		}
+
+		for i := range escalations {
+			e := &escalations[i]
+			status, channel := escalateUrgent(e)
+			if status == models.UrgentEscalationSent {
+				sentCount++
+			}
+			if err := repo.FinishEscalation(e.ReceiptID, status, channel); err != nil {
+				utils.LogError("❌ [加急消息] 记录加急消息 %d 对用户 %d 的提醒结果失败: %v", e.UrgentID, e.UserID, err)
+			}
+		}
+
+		if len(escalations) < urgentEscalationBatchSize {
+			break
+		}
+	}
+
+	if sentCount > 0 {
+		utils.LogInfo("📱 [加急消息] 本次发送升级提醒 %d 条", sentCount)
+	}
+}
+
+// escalateUrgent 按配置的优先方式发送升级提醒，失败或缺少联系方式时改用另一种，返回提醒状态和实际使用的方式
+func escalateUrgent(e *models.UrgentEscalation) (string, string) {
+	channels := []string{models.UrgentChannelSMS, models.UrgentChannelEmail}
+	if config.AppConfig.UrgentEscalationChannel == models.UrgentChannelEmail {
+		channels = []string{models.UrgentChannelEmail, models.UrgentChannelSMS}
+	}
+
+	attempted := false
+	for _, channel := range channels {
+		var err error
+		switch channel {
+		case models.UrgentChannelSMS:
+			if e.Phone == nil || *e.Phone == "" || utils.DefaultSMSProvider == nil {
+				continue
+			}
+			content := fmt.Sprintf("%s 给您发送了加急消息：%s。请尽快登录查看。", e.SenderName, e.Preview)
+			err = utils.DefaultSMSProvider.SendSMS(*e.Phone, content)
+		case models.UrgentChannelEmail:
+			if e.Email == nil || *e.Email == "" || config.AppConfig.SMTPHost == "" {
+				continue
+			}
+			subject, body := urgentEscalationEmail(e.SenderName, e.Preview)
+			err = sendUrgentEmail(*e.Email, subject, body)
+		}
+
+		attempted = true
+		if err != nil {
+			utils.LogError("❌ [加急消息] 通过 %s 向用户 %d 发送加急消息 %d 的提醒失败: %v", channel, e.UserID, e.UrgentID, err)
+			continue
+		}
+		utils.LogDebug("📱 [加急消息] 已通过 %s 提醒用户 %d 查看加急消息 %d", channel, e.UserID, e.UrgentID)
+		return models.UrgentEscalationSent, channel
+	}
+
+	if attempted {
+		return models.UrgentEscalationFailed, ""
+	}
+	utils.LogDebug("⏭️ [加急消息] 用户 %d 没有可用的联系方式，跳过加急消息 %d 的提醒", e.UserID, e.UrgentID)
+	return models.UrgentEscalationSkipped, ""
+}
+
+// urgentEscalationEmail 生成加急提醒邮件的主题和 HTML 正文
+func urgentEscalationEmail(senderName, preview string) (string, string) {
+	subject := fmt.Sprintf("%s 给您发送了加急消息", senderName)
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; padding: 20px;">
+			<h2 style="color: #E24A4A;">您有一条加急消息未读</h2>
+			<p>%s 给您发送了加急消息：</p>
+			<div style="background-color: #f5f5f5; padding: 15px; border-radius: 5px; margin: 20px 0;">%s</div>
+			<p>请尽快登录查看并确认。</p>
+			<hr style="border: none; border-top: 1px solid #eee; margin: 20px 0;">
+			<p style="color: #999; font-size: 12px;">此邮件由系统自动发送，请勿回复。</p>
+		</body>
+		</html>
+	`, html.EscapeString(senderName), html.EscapeString(preview))
+	return subject, body
+}
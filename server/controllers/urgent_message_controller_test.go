@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"youdu-server/config"
+	"youdu-server/models"
+)
+
+func TestValidateUrgentMessage(t *testing.T) {
+	orig := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = orig })
+
+	tests := []struct {
+		name           string
+		quota          int
+		messageType    string
+		recipientCount int
+		wantErr        string
+	}{
+		{name: "未开启加急", quota: 0, messageType: "text", recipientCount: 1, wantErr: errUrgentDisabled.Error()},
+		{name: "系统消息不能加急", quota: 5, messageType: "system", recipientCount: 1, wantErr: "该类型的消息不能加急"},
+		{name: "通话记录不能加急", quota: 5, messageType: "call_ended", recipientCount: 1, wantErr: "该类型的消息不能加急"},
+		{name: "没有接收者", quota: 5, messageType: "text", recipientCount: 0, wantErr: "没有可以加急提醒的成员"},
+		{name: "接收者过多", quota: 5, messageType: "text", recipientCount: models.MaxUrgentRecipients + 1, wantErr: "加急消息最多提醒"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.AppConfig = &config.Config{UrgentDailyQuota: tt.quota}
+			err := validateUrgentMessage(1, tt.messageType, tt.recipientCount)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateUrgentMessage() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUrgentPreview(t *testing.T) {
+	tests := []struct {
+		name        string
+		messageType string
+		content     string
+		want        string
+	}{
+		{name: "文本", messageType: "text", content: "快看群公告", want: "快看群公告"},
+		{name: "图片", messageType: "image", content: "https://example.com/a.png", want: "[图片]"},
+		{name: "语音", messageType: "audio", content: "https://example.com/a.m4a", want: "[语音]"},
+		{name: "超长截断", messageType: "text", content: strings.Repeat("急", models.UrgentPreviewMaxRune+1), want: strings.Repeat("急", models.UrgentPreviewMaxRune) + "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := urgentPreview(tt.messageType, tt.content); got != tt.want {
+				t.Errorf("urgentPreview() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildUrgentPushNotification(t *testing.T) {
+	msg := pushMessage{
+		conversationType: models.ConversationTypeGroup,
+		targetID:         8,
+		messageID:        42,
+		senderName:       "bob",
+		groupName:        "研发群",
+		messageType:      "text",
+		content:          "服务挂了",
+	}
+
+	tests := []struct {
+		name     string
+		locale   string
+		wantBody string
+	}{
+		{name: "中文", locale: "zh-CN", wantBody: "[加急] bob: 服务挂了"},
+		{name: "英文", locale: "en-US", wantBody: "[Urgent] bob: 服务挂了"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notification := buildUrgentPushNotification(tt.locale, 7, msg)
+			if notification.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", notification.Body, tt.wantBody)
+			}
+			if notification.Title != "研发群" {
+				t.Errorf("Title = %q, want 研发群", notification.Title)
+			}
+			if notification.CollapseKey != "urgent_7" {
+				t.Errorf("CollapseKey = %q, want urgent_7", notification.CollapseKey)
+			}
+			if notification.Data["urgent_id"] != "7" || notification.Data["message_id"] != "42" {
+				t.Errorf("Data = %v", notification.Data)
+			}
+		})
+	}
+}
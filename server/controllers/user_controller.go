@@ -93,6 +93,9 @@ func (ctrl *UserController) UpdateStatus(c *gin.Context) {
 
 	utils.LogDebug("✅ 用户 %d 状态更新为: %s", userID.(int), req.Status)
 
+	// 离开/忙碌状态联动自动回复
+	syncAutoReplyStatus(ctrl.hub, userID.(int), req.Status)
+
 	// 获取当前用户信息（用于发送通知）
 	user, err := ctrl.userRepo.FindByID(userID.(int))
 	if err != nil {
@@ -711,6 +714,11 @@ func (ctrl *UserController) ForceLogout(c *gin.Context) {
 		utils.LogDebug("✅ 已向用户 %d 发送强制下线通知", req.UserID)
 	}
 
+	// 强制下线后不再向该用户的设备推送离线通知
+	if err := models.NewPushTokenRepository(db.DB).DeleteByUser(req.UserID); err != nil {
+		utils.LogError("❌ [推送] 删除用户 %d 的推送令牌失败: %v", req.UserID, err)
+	}
+
 	utils.Success(c, gin.H{
 		"success":    true,
 		"was_online": isOnline,
@@ -0,0 +1,31 @@
+-- 自动回复（外出/忙碌）
+-- 用户可设置自动回复内容和可选的生效时间段；规则生效期间收到私聊消息时，每个发送者每个生效周期只自动回复一次
+
+-- 自动回复规则表（每个用户一条）
+CREATE TABLE IF NOT EXISTS auto_reply_rules (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT false,             -- 是否开启
+    message VARCHAR(500) NOT NULL DEFAULT '',           -- 自动回复内容
+    start_at TIMESTAMP,                                 -- 生效开始时间（可选）
+    end_at TIMESTAMP,                                   -- 生效结束时间（可选，到期后自动关闭）
+    status_triggered BOOLEAN NOT NULL DEFAULT false,    -- 用户当前是否处于离开/忙碌状态
+    activated_at TIMESTAMP DEFAULT NOW(),               -- 当前生效周期的开始时间
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 自动回复记录表（记录每个发送者最近一次收到自动回复的时间）
+CREATE TABLE IF NOT EXISTS auto_reply_logs (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sender_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    replied_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, sender_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_auto_reply_rules_end_at ON auto_reply_rules(end_at) WHERE enabled = true;
+
+-- 添加注释
+COMMENT ON TABLE auto_reply_rules IS '自动回复规则：设置了时间段时在时间段内生效，否则在用户处于离开/忙碌状态时生效';
+COMMENT ON COLUMN auto_reply_rules.status_triggered IS '用户切换为离开/忙碌时为 true，切换回在线时为 false（断线不影响）';
+COMMENT ON COLUMN auto_reply_rules.activated_at IS '当前生效周期的开始时间，周期内每个发送者只自动回复一次';
+COMMENT ON TABLE auto_reply_logs IS '自动回复记录（每个发送者最近一次收到自动回复的时间）';
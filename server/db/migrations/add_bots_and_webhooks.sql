@@ -0,0 +1,28 @@
+-- 机器人账号与群组 Incoming Webhook
+-- 机器人是不能交互式登录的特殊用户，外部系统（CI、监控、工单等）通过 Webhook 以机器人身份向群组发消息
+
+-- 用户表增加机器人标记
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT false;
+COMMENT ON COLUMN users.is_bot IS '是否为机器人账号（不能登录）';
+
+-- 机器人 Incoming Webhook 表（每个机器人在每个群组一个 Webhook）
+CREATE TABLE IF NOT EXISTS bot_webhooks (
+    id SERIAL PRIMARY KEY,
+    bot_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,                 -- 密钥的 SHA-256（密钥本身只在创建/轮换时返回一次）
+    token_prefix VARCHAR(8) NOT NULL DEFAULT '',     -- 密钥前缀，便于管理员辨认
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,                            -- 吊销时间（非空表示已吊销）
+    rotated_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bot_webhooks_token_hash ON bot_webhooks(token_hash);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bot_webhooks_bot_group_active ON bot_webhooks(bot_id, group_id) WHERE revoked_at IS NULL;
+
+-- 添加注释
+COMMENT ON TABLE bot_webhooks IS '机器人群组 Incoming Webhook 表';
+COMMENT ON COLUMN bot_webhooks.token_hash IS 'Webhook 密钥的 SHA-256 十六进制摘要';
+COMMENT ON COLUMN bot_webhooks.revoked_at IS '吊销时间，非空表示 Webhook 已失效';
@@ -0,0 +1,26 @@
+-- 群发列表表
+-- 用户保存的联系人列表，群发时为每个接收者单独生成一条私聊消息
+
+CREATE TABLE IF NOT EXISTS broadcast_lists (
+    id SERIAL PRIMARY KEY,
+    owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,                  -- 列表名称
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 群发列表成员表
+CREATE TABLE IF NOT EXISTS broadcast_list_members (
+    list_id INTEGER NOT NULL REFERENCES broadcast_lists(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (list_id, user_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_broadcast_lists_owner_id ON broadcast_lists(owner_id);
+
+-- 添加注释
+COMMENT ON TABLE broadcast_lists IS '群发列表表（一条消息分别发送给多个联系人）';
+COMMENT ON COLUMN broadcast_lists.owner_id IS '列表所属用户ID';
+COMMENT ON TABLE broadcast_list_members IS '群发列表成员表';
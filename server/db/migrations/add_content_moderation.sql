@@ -0,0 +1,41 @@
+-- 内容审核规则表
+-- 敏感词（Aho-Corasick 多关键词匹配）和正则规则，命中后按规则动作处理：
+-- block-拦截发送，mask-替换为*后发送，flag-正常发送并进入人工审核队列
+
+CREATE TABLE IF NOT EXISTS moderation_rules (
+    id SERIAL PRIMARY KEY,
+    pattern TEXT NOT NULL,                        -- 敏感词或正则表达式
+    rule_type VARCHAR(20) NOT NULL DEFAULT 'word', -- 规则类型: word, regex
+    action VARCHAR(20) NOT NULL DEFAULT 'block',   -- 命中动作: block, mask, flag
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 内容审核队列表（flag 规则命中的消息）
+CREATE TABLE IF NOT EXISTS moderation_flags (
+    id SERIAL PRIMARY KEY,
+    rule_id INTEGER REFERENCES moderation_rules(id) ON DELETE SET NULL,
+    source VARCHAR(30) NOT NULL,                   -- 消息来源: message, group_message, file_assistant
+    message_id INTEGER NOT NULL,                   -- 对应表中的消息ID
+    sender_id INTEGER NOT NULL,
+    target_id INTEGER NOT NULL DEFAULT 0,          -- 接收者ID或群组ID（文件助手为0）
+    content TEXT NOT NULL,                         -- 被标记时的消息内容
+    matched TEXT NOT NULL DEFAULT '',              -- 命中的文本（逗号分隔）
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 审核状态: pending, approved, removed
+    review_note VARCHAR(500),
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_moderation_rules_enabled ON moderation_rules(enabled);
+CREATE INDEX IF NOT EXISTS idx_moderation_flags_status ON moderation_flags(status, created_at DESC);
+
+-- 添加注释
+COMMENT ON TABLE moderation_rules IS '内容审核规则表（敏感词/正则）';
+COMMENT ON COLUMN moderation_rules.rule_type IS '规则类型: word-敏感词（忽略大小写）, regex-正则表达式';
+COMMENT ON COLUMN moderation_rules.action IS '命中动作: block-拦截, mask-打码, flag-标记待审核';
+COMMENT ON TABLE moderation_flags IS '内容审核队列表';
+COMMENT ON COLUMN moderation_flags.status IS '审核状态: pending-待审核, approved-审核通过, removed-已移除（消息撤回）';
@@ -0,0 +1,25 @@
+-- 会话草稿表
+-- 用于在服务端保存用户未发送的草稿（含引用消息），实现多端草稿同步
+
+CREATE TABLE IF NOT EXISTS conversation_drafts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_type VARCHAR(20) NOT NULL,      -- 会话类型: user, group, file_assistant
+    target_id INTEGER NOT NULL DEFAULT 0,        -- 对方用户ID或群组ID（文件助手固定为0）
+    content TEXT NOT NULL DEFAULT '',            -- 草稿内容
+    quoted_message_id INTEGER,                   -- 引用的消息ID
+    quoted_message_content TEXT,                 -- 引用的消息内容
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(user_id, conversation_type, target_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_conversation_drafts_user_id ON conversation_drafts(user_id);
+
+-- 添加注释
+COMMENT ON TABLE conversation_drafts IS '会话草稿表（多端同步）';
+COMMENT ON COLUMN conversation_drafts.conversation_type IS '会话类型: user, group, file_assistant';
+COMMENT ON COLUMN conversation_drafts.target_id IS '对方用户ID或群组ID，文件助手为0';
+COMMENT ON COLUMN conversation_drafts.quoted_message_id IS '草稿中引用的消息ID';
+COMMENT ON COLUMN conversation_drafts.quoted_message_content IS '草稿中引用的消息内容';
@@ -0,0 +1,32 @@
+-- 会话导出任务表
+-- 导出私聊、群聊或文件助手在指定时间范围内的聊天记录，生成包含 JSON、HTML、纯文本（可选附件）的 ZIP 包并上传到 OSS
+
+CREATE TABLE IF NOT EXISTS conversation_exports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_type VARCHAR(20) NOT NULL,            -- 会话类型: user, group, file_assistant
+    target_id INTEGER NOT NULL DEFAULT 0,              -- 对方用户ID或群组ID（文件助手固定为0）
+    start_time TIMESTAMP,                              -- 导出起始时间（UTC，为空表示不限）
+    end_time TIMESTAMP,                                -- 导出结束时间（UTC，为空表示不限）
+    include_attachments BOOLEAN DEFAULT FALSE,         -- 是否打包OSS附件
+    requested_by_admin BOOLEAN DEFAULT FALSE,          -- 是否由管理后台发起（合规导出）
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',     -- 状态: pending, running, completed, failed
+    progress INTEGER NOT NULL DEFAULT 0,               -- 进度（0-100）
+    message_count INTEGER NOT NULL DEFAULT 0,          -- 导出的消息数量
+    object_key VARCHAR(500),                           -- ZIP 在 OSS 中的对象键
+    file_size BIGINT DEFAULT 0,                        -- ZIP 文件大小（字节）
+    error_message TEXT,                                -- 失败原因
+    created_at TIMESTAMP DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_conversation_exports_user_id ON conversation_exports(user_id, created_at DESC);
+
+-- 添加注释
+COMMENT ON TABLE conversation_exports IS '会话导出任务表';
+COMMENT ON COLUMN conversation_exports.conversation_type IS '会话类型: user, group, file_assistant';
+COMMENT ON COLUMN conversation_exports.status IS '任务状态: pending-等待中, running-导出中, completed-已完成, failed-失败';
+COMMENT ON COLUMN conversation_exports.progress IS '导出进度（0-100）';
+COMMENT ON COLUMN conversation_exports.object_key IS 'ZIP 文件在 OSS 中的对象键，下载时生成签名链接';
+COMMENT ON COLUMN conversation_exports.requested_by_admin IS '是否由管理后台发起的合规导出';
@@ -0,0 +1,30 @@
+-- 会话设置表
+-- 用于存储用户对每个会话（私聊、群聊、文件助手）的个人设置：置顶、免打扰、归档、标记未读、隐藏
+
+CREATE TABLE IF NOT EXISTS conversation_settings (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_type VARCHAR(20) NOT NULL,      -- 会话类型: user, group, file_assistant
+    target_id INTEGER NOT NULL DEFAULT 0,        -- 对方用户ID或群组ID（文件助手固定为0）
+    is_pinned BOOLEAN DEFAULT FALSE,             -- 是否置顶
+    pin_order INTEGER DEFAULT 0,                 -- 置顶排序（数值越大越靠前）
+    muted_until TIMESTAMP,                       -- 免打扰截止时间（为空表示未开启）
+    is_archived BOOLEAN DEFAULT FALSE,           -- 是否归档
+    marked_unread BOOLEAN DEFAULT FALSE,         -- 是否手动标记为未读
+    hidden_at TIMESTAMP,                         -- 隐藏时间（收到新消息后自动重新显示）
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(user_id, conversation_type, target_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_conversation_settings_user_id ON conversation_settings(user_id);
+CREATE INDEX IF NOT EXISTS idx_conversation_settings_pinned ON conversation_settings(user_id, is_pinned) WHERE (is_pinned = true);
+
+-- 添加注释
+COMMENT ON TABLE conversation_settings IS '用户会话设置表';
+COMMENT ON COLUMN conversation_settings.conversation_type IS '会话类型: user, group, file_assistant';
+COMMENT ON COLUMN conversation_settings.target_id IS '对方用户ID或群组ID，文件助手为0';
+COMMENT ON COLUMN conversation_settings.pin_order IS '置顶排序，数值越大越靠前';
+COMMENT ON COLUMN conversation_settings.muted_until IS '免打扰截止时间（UTC），为空表示未开启';
+COMMENT ON COLUMN conversation_settings.hidden_at IS '隐藏时间（UTC），晚于该时间的新消息会让会话重新显示';
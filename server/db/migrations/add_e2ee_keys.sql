@@ -0,0 +1,37 @@
+-- 端到端加密公钥目录
+-- 按设备保存 X3DH 所需的身份公钥、签名预共享公钥和一次性预共享公钥；服务端只存储和分发公钥，不接触私钥和消息明文
+
+-- 加密设备表（每台设备一套密钥包）
+CREATE TABLE IF NOT EXISTS e2ee_devices (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_id VARCHAR(64) NOT NULL,                  -- 客户端生成的设备标识
+    registration_id INTEGER NOT NULL,                -- 客户端注册ID（用于检测设备重装）
+    identity_key TEXT NOT NULL,                      -- 身份公钥（Base64）
+    signed_prekey_id INTEGER NOT NULL,
+    signed_prekey TEXT NOT NULL,                     -- 签名预共享公钥（Base64）
+    signed_prekey_signature TEXT NOT NULL,           -- 身份私钥对签名预共享公钥的签名（Base64）
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(user_id, device_id)
+);
+
+-- 一次性预共享公钥表（每次获取密钥包时消费一个）
+CREATE TABLE IF NOT EXISTS e2ee_one_time_prekeys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_id VARCHAR(64) NOT NULL,
+    key_id INTEGER NOT NULL,
+    public_key TEXT NOT NULL,                        -- 一次性预共享公钥（Base64）
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(user_id, device_id, key_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_e2ee_devices_user_id ON e2ee_devices(user_id);
+CREATE INDEX IF NOT EXISTS idx_e2ee_one_time_prekeys_device ON e2ee_one_time_prekeys(user_id, device_id, id);
+
+-- 添加注释
+COMMENT ON TABLE e2ee_devices IS '端到端加密设备密钥包';
+COMMENT ON COLUMN e2ee_devices.identity_key IS '设备身份公钥，变更时通知联系人重新验证';
+COMMENT ON TABLE e2ee_one_time_prekeys IS '端到端加密一次性预共享公钥，获取密钥包时消费';
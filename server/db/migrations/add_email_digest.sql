@@ -0,0 +1,19 @@
+-- 未读消息邮件摘要
+-- 记录用户最近在线时间；长时间离线且有未读私聊或@消息的用户定时收到摘要邮件
+
+-- 用户最近在线时间（WebSocket 断开时更新）
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP DEFAULT NULL;
+
+-- 邮件摘要设置表（无记录时按默认设置：开启，每天最多一封）
+CREATE TABLE IF NOT EXISTS email_digest_settings (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT true,           -- 是否接收摘要邮件
+    frequency VARCHAR(20) NOT NULL DEFAULT 'daily',  -- daily, weekly
+    last_sent_at TIMESTAMP,                          -- 最近一次发送时间
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 添加注释
+COMMENT ON COLUMN users.last_seen_at IS '最近在线时间（WebSocket 断开时更新）';
+COMMENT ON TABLE email_digest_settings IS '未读消息邮件摘要设置';
+COMMENT ON COLUMN email_digest_settings.frequency IS '发送频率：daily 每天最多一封，weekly 每周最多一封';
@@ -0,0 +1,43 @@
+-- 事件订阅（Outgoing Webhook）
+-- 群消息、成员进出群、联系人通过、通话结束等事件以带 HMAC 签名的 HTTP 回调推送给外部系统，失败按指数退避重试
+
+-- 事件订阅表
+CREATE TABLE IF NOT EXISTS event_subscriptions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    url TEXT NOT NULL,                               -- 回调地址
+    secret VARCHAR(128) NOT NULL,                    -- HMAC-SHA256 签名密钥
+    events TEXT NOT NULL DEFAULT '*',                -- 订阅的事件类型（逗号分隔，* 表示全部）
+    group_id INTEGER REFERENCES groups(id) ON DELETE CASCADE, -- 为空表示全局订阅，否则只接收该群组的群事件
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 事件投递记录表
+CREATE TABLE IF NOT EXISTS event_deliveries (
+    id SERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES event_subscriptions(id) ON DELETE CASCADE,
+    event_id VARCHAR(64) NOT NULL,                   -- 事件ID（同一事件投递给多个订阅时相同）
+    event_type VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,                           -- 请求体（重试时原样发送）
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',   -- pending-待投递/重试中, success-成功, failed-重试耗尽
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,                         -- 最近一次响应状态码
+    last_error TEXT,                                 -- 最近一次失败原因
+    next_attempt_at TIMESTAMP,                       -- 下次投递时间
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_event_subscriptions_group_id ON event_subscriptions(group_id);
+CREATE INDEX IF NOT EXISTS idx_event_deliveries_subscription ON event_deliveries(subscription_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_event_deliveries_pending ON event_deliveries(next_attempt_at) WHERE status = 'pending';
+
+-- 添加注释
+COMMENT ON TABLE event_subscriptions IS '事件订阅表（Outgoing Webhook）';
+COMMENT ON COLUMN event_subscriptions.events IS '订阅的事件类型，逗号分隔，* 表示全部';
+COMMENT ON COLUMN event_subscriptions.group_id IS '为空表示全局订阅';
+COMMENT ON TABLE event_deliveries IS '事件投递记录表';
+COMMENT ON COLUMN event_deliveries.status IS '投递状态：pending-待投递, success-成功, failed-失败';
@@ -0,0 +1,32 @@
+-- 群关键词自动回复
+-- 群管理员配置关键词或正则触发规则，群成员发送的消息命中时以系统消息发送预设回复（如常见问题解答、链接），每条规则独立冷却
+
+CREATE TABLE IF NOT EXISTS group_keyword_replies (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    pattern VARCHAR(500) NOT NULL,                      -- 关键词或正则表达式
+    match_type VARCHAR(20) NOT NULL DEFAULT 'keyword',  -- keyword（包含关键词，忽略大小写）, regex
+    response TEXT NOT NULL,                             -- 回复内容
+    cooldown_seconds INTEGER NOT NULL DEFAULT 60,       -- 冷却时间（秒），冷却期内再次命中不回复
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    hit_count INTEGER NOT NULL DEFAULT 0,               -- 触发回复次数
+    last_triggered_at TIMESTAMP,                        -- 最近一次触发回复的时间
+    created_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_group_keyword_replies_group ON group_keyword_replies(group_id, id);
+
+-- 添加注释
+COMMENT ON TABLE group_keyword_replies IS '群关键词自动回复规则';
+COMMENT ON COLUMN group_keyword_replies.match_type IS 'keyword 包含关键词（忽略大小写），regex 正则表达式';
+COMMENT ON COLUMN group_keyword_replies.cooldown_seconds IS '冷却时间（秒），冷却期内再次命中不回复';
+COMMENT ON COLUMN group_keyword_replies.hit_count IS '触发回复次数';
+COMMENT ON COLUMN group_keyword_replies.created_by IS '创建者';
+
+-- 自动回复的发送者账号（机器人账号，不能登录，不属于任何群组；password 不是有效的密码哈希）
+INSERT INTO users (username, full_name, password, avatar, status, is_bot, created_at, updated_at)
+VALUES ('__keyword_reply__', '自动回复', '!', '', 'offline', true, NOW(), NOW())
+ON CONFLICT (username) DO NOTHING;
@@ -0,0 +1,44 @@
+-- 群消息@提及表
+-- 将 group_messages.mentioned_user_ids（逗号分隔字符串）规范化为独立的索引表，
+-- 每个被@的用户一条记录（@所有人 会为每个成员生成记录），用于"@我的"消息列表
+
+CREATE TABLE IF NOT EXISTS group_message_mentions (
+    id SERIAL PRIMARY KEY,
+    group_message_id INTEGER NOT NULL REFERENCES group_messages(id) ON DELETE CASCADE,
+    group_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,                      -- 被@的用户
+    sender_id INTEGER NOT NULL,                    -- 发送者
+    is_mention_all BOOLEAN NOT NULL DEFAULT false, -- 是否来自 @所有人
+    is_read BOOLEAN NOT NULL DEFAULT false,        -- 是否已读
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(group_message_id, user_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_group_message_mentions_user ON group_message_mentions(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_group_message_mentions_user_unread ON group_message_mentions(user_id, group_id) WHERE is_read = false;
+
+-- 回填历史数据：指定成员的@
+INSERT INTO group_message_mentions (group_message_id, group_id, user_id, sender_id, is_mention_all, is_read, created_at)
+SELECT gm.id, gm.group_id, CAST(TRIM(uid) AS INTEGER), gm.sender_id, false, true, gm.created_at
+FROM group_messages gm,
+     LATERAL unnest(string_to_array(gm.mentioned_user_ids, ',')) AS uid
+WHERE gm.mentioned_user_ids IS NOT NULL AND gm.mentioned_user_ids <> ''
+  AND TRIM(uid) ~ '^[0-9]+$'
+  AND CAST(TRIM(uid) AS INTEGER) <> gm.sender_id
+ON CONFLICT (group_message_id, user_id) DO NOTHING;
+
+-- 回填历史数据：@所有人（按当前成员生成）
+INSERT INTO group_message_mentions (group_message_id, group_id, user_id, sender_id, is_mention_all, is_read, created_at)
+SELECT gm.id, gm.group_id, mem.user_id, gm.sender_id, true, true, gm.created_at
+FROM group_messages gm
+JOIN group_members mem ON mem.group_id = gm.group_id AND mem.approval_status = 'approved'
+WHERE gm.mentions LIKE '%@all%'
+  AND mem.user_id <> gm.sender_id
+ON CONFLICT (group_message_id, user_id) DO NOTHING;
+
+-- 添加注释
+COMMENT ON TABLE group_message_mentions IS '群消息@提及表（每个被@的用户一条记录）';
+COMMENT ON COLUMN group_message_mentions.is_mention_all IS '是否来自 @所有人';
+COMMENT ON COLUMN group_message_mentions.is_read IS '是否已读（历史数据回填为已读）';
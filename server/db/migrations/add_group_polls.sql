@@ -0,0 +1,54 @@
+-- 群投票表
+-- 群聊中的投票消息，支持单选/多选、匿名/实名投票以及截止时间
+
+CREATE TABLE IF NOT EXISTS group_polls (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    message_id INTEGER,                              -- 对应的群消息ID（message_type = 'poll'）
+    creator_id INTEGER NOT NULL,                     -- 发起人ID
+    question VARCHAR(200) NOT NULL,                  -- 投票主题
+    multiple_choice BOOLEAN NOT NULL DEFAULT false,  -- 是否多选
+    anonymous BOOLEAN NOT NULL DEFAULT false,        -- 是否匿名投票
+    deadline TIMESTAMP,                              -- 截止时间（为空表示不限时）
+    is_closed BOOLEAN NOT NULL DEFAULT false,        -- 是否已结束
+    closed_at TIMESTAMP,
+    closed_by INTEGER,                               -- 手动结束的操作人（自动到期为空）
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 投票选项表
+CREATE TABLE IF NOT EXISTS group_poll_options (
+    id SERIAL PRIMARY KEY,
+    poll_id INTEGER NOT NULL REFERENCES group_polls(id) ON DELETE CASCADE,
+    option_text VARCHAR(100) NOT NULL,
+    sort_order INTEGER NOT NULL DEFAULT 0
+);
+
+-- 投票记录表
+-- 不随成员退群删除，保证投票结果不受成员变动影响；voter_name 保存投票时的显示名称
+CREATE TABLE IF NOT EXISTS group_poll_votes (
+    id SERIAL PRIMARY KEY,
+    poll_id INTEGER NOT NULL REFERENCES group_polls(id) ON DELETE CASCADE,
+    option_id INTEGER NOT NULL REFERENCES group_poll_options(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+    voter_name VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(poll_id, option_id, user_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_group_polls_group_id ON group_polls(group_id);
+CREATE INDEX IF NOT EXISTS idx_group_polls_deadline ON group_polls(deadline) WHERE is_closed = false;
+CREATE INDEX IF NOT EXISTS idx_group_poll_options_poll_id ON group_poll_options(poll_id);
+CREATE INDEX IF NOT EXISTS idx_group_poll_votes_poll_id ON group_poll_votes(poll_id);
+
+-- 添加注释
+COMMENT ON TABLE group_polls IS '群投票表';
+COMMENT ON COLUMN group_polls.message_id IS '对应的群消息ID';
+COMMENT ON COLUMN group_polls.multiple_choice IS '是否多选';
+COMMENT ON COLUMN group_polls.anonymous IS '是否匿名投票（匿名时不返回投票人）';
+COMMENT ON COLUMN group_polls.deadline IS '截止时间，到期后自动结束';
+COMMENT ON COLUMN group_polls.closed_by IS '手动结束投票的操作人ID，自动到期时为空';
+COMMENT ON TABLE group_poll_options IS '群投票选项表';
+COMMENT ON TABLE group_poll_votes IS '群投票记录表（成员退群后保留）';
+COMMENT ON COLUMN group_poll_votes.voter_name IS '投票时的显示名称';
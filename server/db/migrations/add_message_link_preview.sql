@@ -0,0 +1,12 @@
+-- 消息链接预览
+-- 服务端抓取文本消息中第一个链接的 OpenGraph / Twitter 元数据，保存到消息上，客户端无需自行抓取
+
+ALTER TABLE messages
+ADD COLUMN IF NOT EXISTS link_preview JSONB;
+
+ALTER TABLE group_messages
+ADD COLUMN IF NOT EXISTS link_preview JSONB;
+
+-- 添加注释
+COMMENT ON COLUMN messages.link_preview IS '链接预览元数据（url, title, description, image, site_name）';
+COMMENT ON COLUMN group_messages.link_preview IS '链接预览元数据（url, title, description, image, site_name）';
@@ -0,0 +1,22 @@
+-- 富文本消息纯文本
+-- rich_text 消息的 content 保存服务端规范化后的结构化文档，plain_text 保存其纯文本，
+-- 用于会话预览、通知、收藏以及后续的消息检索
+
+ALTER TABLE messages
+ADD COLUMN IF NOT EXISTS plain_text TEXT;
+
+ALTER TABLE group_messages
+ADD COLUMN IF NOT EXISTS plain_text TEXT;
+
+ALTER TABLE favorites
+ADD COLUMN IF NOT EXISTS plain_text TEXT;
+
+-- 创建全文索引（仅富文本消息有值）
+CREATE INDEX IF NOT EXISTS idx_messages_plain_text ON messages USING GIN (to_tsvector('simple', plain_text)) WHERE plain_text IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_group_messages_plain_text ON group_messages USING GIN (to_tsvector('simple', plain_text)) WHERE plain_text IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_favorites_plain_text ON favorites USING GIN (to_tsvector('simple', plain_text)) WHERE plain_text IS NOT NULL;
+
+-- 添加注释
+COMMENT ON COLUMN messages.plain_text IS '富文本消息的纯文本内容（其他类型为空）';
+COMMENT ON COLUMN group_messages.plain_text IS '富文本消息的纯文本内容（其他类型为空）';
+COMMENT ON COLUMN favorites.plain_text IS '收藏的富文本消息的纯文本内容（其他类型为空）';
@@ -0,0 +1,27 @@
+-- 消息撤回策略
+-- 1. 撤回时限通过 server_settings 配置：全局键 message_recall_window_minutes，群组键 group_recall_window_minutes_<群组ID>
+-- 2. 记录消息由谁撤回（群主/管理员可随时撤回成员消息）
+
+-- 全局默认撤回时限（分钟），0 表示不限制
+INSERT INTO server_settings (key, value, description)
+VALUES ('message_recall_window_minutes', '3', '消息撤回时限（分钟），0表示不限制；可用 group_recall_window_minutes_<群组ID> 为单个群组单独配置')
+ON CONFLICT (key) DO NOTHING;
+
+-- 记录撤回操作人和撤回时间
+ALTER TABLE messages
+ADD COLUMN IF NOT EXISTS recalled_by INTEGER;
+
+ALTER TABLE messages
+ADD COLUMN IF NOT EXISTS recalled_at TIMESTAMP;
+
+ALTER TABLE group_messages
+ADD COLUMN IF NOT EXISTS recalled_by INTEGER;
+
+ALTER TABLE group_messages
+ADD COLUMN IF NOT EXISTS recalled_at TIMESTAMP;
+
+-- 添加注释
+COMMENT ON COLUMN messages.recalled_by IS '撤回操作人用户ID';
+COMMENT ON COLUMN messages.recalled_at IS '撤回时间（UTC）';
+COMMENT ON COLUMN group_messages.recalled_by IS '撤回操作人用户ID（群主/管理员撤回成员消息时与发送者不同）';
+COMMENT ON COLUMN group_messages.recalled_at IS '撤回时间（UTC）';
@@ -0,0 +1,39 @@
+-- 消息任务
+-- 私聊或群聊中的任意消息可转为任务，指定负责人、截止时间和状态；到期提醒负责人，状态变更时在原会话中发送系统消息
+
+CREATE TABLE IF NOT EXISTS message_tasks (
+    id SERIAL PRIMARY KEY,
+    conversation_type VARCHAR(20) NOT NULL,             -- user, group
+    target_id INTEGER NOT NULL,                         -- 私聊为创建者的会话对方ID，群聊为群组ID
+    message_id INTEGER NOT NULL,                        -- 来源消息（messages.id 或 group_messages.id）
+    creator_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(200) NOT NULL,                        -- 任务标题（默认取消息预览）
+    content TEXT NOT NULL DEFAULT '',                   -- 来源消息预览
+    status VARCHAR(20) NOT NULL DEFAULT 'todo',         -- todo, in_progress, done, cancelled
+    due_at TIMESTAMP,                                   -- 截止时间（为空表示不限时）
+    reminded_at TIMESTAMP,                              -- 到期提醒发送时间（修改截止时间后重置）
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 任务负责人表
+CREATE TABLE IF NOT EXISTS message_task_assignees (
+    task_id INTEGER NOT NULL REFERENCES message_tasks(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (task_id, user_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_message_tasks_creator ON message_tasks(creator_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_message_tasks_group ON message_tasks(target_id, created_at DESC) WHERE conversation_type = 'group';
+CREATE INDEX IF NOT EXISTS idx_message_tasks_due ON message_tasks(due_at) WHERE reminded_at IS NULL AND status IN ('todo', 'in_progress');
+CREATE INDEX IF NOT EXISTS idx_message_task_assignees_user ON message_task_assignees(user_id);
+
+-- 添加注释
+COMMENT ON TABLE message_tasks IS '由消息转成的任务';
+COMMENT ON COLUMN message_tasks.target_id IS '私聊为创建者的会话对方ID，群聊为群组ID';
+COMMENT ON COLUMN message_tasks.status IS 'todo 待办，in_progress 进行中，done 已完成，cancelled 已取消';
+COMMENT ON COLUMN message_tasks.reminded_at IS '到期提醒发送时间，修改截止时间后重置';
+COMMENT ON TABLE message_task_assignees IS '任务负责人';
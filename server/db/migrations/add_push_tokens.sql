@@ -0,0 +1,25 @@
+-- 离线推送
+-- 按登录会话登记设备推送令牌；接收者不在线时通过 APNs、FCM 或厂商通道推送
+
+-- 推送令牌表
+CREATE TABLE IF NOT EXISTS push_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id VARCHAR(64) NOT NULL,                 -- 登录会话标识（JWT 的 SHA-256）
+    provider VARCHAR(20) NOT NULL,                   -- apns, fcm, xiaomi, huawei, memory
+    token TEXT NOT NULL,                             -- 设备推送令牌
+    platform VARCHAR(20) NOT NULL DEFAULT '',        -- ios, android, macos, windows
+    locale VARCHAR(20) NOT NULL DEFAULT 'zh-CN',     -- 推送文案语言
+    session_expires_at TIMESTAMP NOT NULL,           -- 会话过期后不再推送
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(provider, token)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_push_tokens_user_id ON push_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_push_tokens_session ON push_tokens(user_id, session_id);
+
+-- 添加注释
+COMMENT ON TABLE push_tokens IS '设备推送令牌（按登录会话登记，同一令牌只属于最后登记的用户）';
+COMMENT ON COLUMN push_tokens.session_id IS '登录会话标识，退出登录时删除该会话的令牌';
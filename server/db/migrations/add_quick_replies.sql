@@ -0,0 +1,38 @@
+-- 快捷回复模板
+-- 个人模板仅本人可见；群组模板由群主/管理员维护，群成员可用；组织模板由管理后台维护，所有用户可用
+-- 模板内容支持占位符（如 {{recipient_name}}、{{date}}），渲染时根据发送者和接收者的用户信息填充
+
+CREATE TABLE IF NOT EXISTS quick_replies (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(20) NOT NULL DEFAULT 'personal',      -- personal, group, organization
+    owner_id INTEGER REFERENCES users(id) ON DELETE CASCADE,   -- 个人模板的所有者
+    group_id INTEGER REFERENCES groups(id) ON DELETE CASCADE,  -- 群组模板所属群组
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL DEFAULT '',                   -- 模板内容（可包含占位符）
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL, -- 创建者（组织模板为空）
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 模板附件表（引用OSS中已上传的文件）
+CREATE TABLE IF NOT EXISTS quick_reply_attachments (
+    id SERIAL PRIMARY KEY,
+    quick_reply_id INTEGER NOT NULL REFERENCES quick_replies(id) ON DELETE CASCADE,
+    message_type VARCHAR(20) NOT NULL,                  -- image, video, file, audio
+    url TEXT NOT NULL,
+    object_key TEXT NOT NULL,
+    file_name VARCHAR(255) NOT NULL DEFAULT '',
+    file_size BIGINT NOT NULL DEFAULT 0,
+    sort_order INTEGER NOT NULL DEFAULT 0
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_quick_replies_owner ON quick_replies(owner_id) WHERE scope = 'personal';
+CREATE INDEX IF NOT EXISTS idx_quick_replies_group ON quick_replies(group_id) WHERE scope = 'group';
+CREATE INDEX IF NOT EXISTS idx_quick_reply_attachments_reply ON quick_reply_attachments(quick_reply_id, sort_order);
+
+-- 添加注释
+COMMENT ON TABLE quick_replies IS '快捷回复模板';
+COMMENT ON COLUMN quick_replies.scope IS 'personal 个人，group 群组共享，organization 组织共享';
+COMMENT ON COLUMN quick_replies.content IS '模板内容，支持 {{recipient_name}}、{{my_name}}、{{date}} 等占位符';
+COMMENT ON TABLE quick_reply_attachments IS '快捷回复模板附件（OSS中已存在的文件）';
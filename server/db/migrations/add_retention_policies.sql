@@ -0,0 +1,40 @@
+-- 消息保留策略
+-- 支持全局、群组、私聊三个范围；定时任务按策略分批清理过期消息及其 OSS 文件，并记录清理审计日志
+
+-- 保留策略表
+CREATE TABLE IF NOT EXISTS retention_policies (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(20) NOT NULL,                      -- global, group, user
+    target_id INTEGER NOT NULL DEFAULT 0,            -- 群组ID；私聊为较小的用户ID；全局为0
+    peer_id INTEGER NOT NULL DEFAULT 0,              -- 私聊为较大的用户ID；其他为0
+    retention_days INTEGER,                          -- 保留天数（为空表示永久保留）
+    legal_hold BOOLEAN NOT NULL DEFAULT false,       -- 法律保全：开启后不清理任何消息
+    note VARCHAR(500) NOT NULL DEFAULT '',
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(scope, target_id, peer_id)
+);
+
+-- 清理审计日志表（每次清理每个会话一条记录）
+CREATE TABLE IF NOT EXISTS retention_purge_logs (
+    id SERIAL PRIMARY KEY,
+    policy_id INTEGER REFERENCES retention_policies(id) ON DELETE SET NULL,
+    conversation_type VARCHAR(20) NOT NULL,          -- user, group, file_assistant
+    target_id INTEGER NOT NULL,                      -- 群组ID；私聊为较小的用户ID；文件助手为用户ID
+    peer_id INTEGER NOT NULL DEFAULT 0,              -- 私聊为较大的用户ID
+    retention_days INTEGER NOT NULL,
+    cutoff TIMESTAMP NOT NULL,                       -- 早于该时间的消息被清理
+    message_count INTEGER NOT NULL DEFAULT 0,        -- 删除的消息数
+    object_count INTEGER NOT NULL DEFAULT 0,         -- 删除的 OSS 文件数
+    object_failed_count INTEGER NOT NULL DEFAULT 0,  -- 删除失败的 OSS 文件数
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 创建索引（messages、group_messages、file_assistant_messages 已有 created_at 索引）
+CREATE INDEX IF NOT EXISTS idx_retention_purge_logs_created_at ON retention_purge_logs(created_at DESC);
+
+-- 添加注释
+COMMENT ON TABLE retention_policies IS '消息保留策略（全局、群组、私聊）';
+COMMENT ON COLUMN retention_policies.legal_hold IS '法律保全：开启后该范围内的消息永久保留，全局开启时暂停所有清理';
+COMMENT ON TABLE retention_purge_logs IS '消息保留策略清理审计日志';
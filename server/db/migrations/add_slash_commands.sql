@@ -0,0 +1,41 @@
+-- 群组斜杠命令
+-- 以 / 开头的群消息路由到内置命令或机器人注册的命令；/mute 支持定时禁言，/remind 支持定时提醒
+
+-- 群成员定时禁言：到期后由定时任务自动解除
+ALTER TABLE group_members ADD COLUMN IF NOT EXISTS muted_until TIMESTAMP;
+COMMENT ON COLUMN group_members.muted_until IS '禁言到期时间（为空表示永久禁言或未禁言）';
+
+-- 机器人注册的斜杠命令表
+CREATE TABLE IF NOT EXISTS bot_commands (
+    id SERIAL PRIMARY KEY,
+    bot_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    command VARCHAR(32) NOT NULL,                    -- 命令名（不含 /，小写）
+    description VARCHAR(200) NOT NULL DEFAULT '',
+    usage VARCHAR(200) NOT NULL DEFAULT '',          -- 参数说明，例如 <工单号>
+    callback_url TEXT NOT NULL,                      -- 命令回调地址
+    secret VARCHAR(128) NOT NULL,                    -- 回调 HMAC-SHA256 签名密钥
+    min_role VARCHAR(20) NOT NULL DEFAULT 'member',  -- 最低群角色：member, admin, owner
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(bot_id, command)
+);
+
+-- 命令提醒表（/remind）
+CREATE TABLE IF NOT EXISTS command_reminders (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    remind_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP,                          -- 提醒送达时间（为空表示未送达）
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_group_members_muted_until ON group_members(muted_until) WHERE muted_until IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_command_reminders_due ON command_reminders(remind_at) WHERE delivered_at IS NULL;
+
+-- 添加注释
+COMMENT ON TABLE bot_commands IS '机器人斜杠命令表';
+COMMENT ON COLUMN bot_commands.min_role IS '可使用该命令的最低群角色：member, admin, owner';
+COMMENT ON TABLE command_reminders IS '斜杠命令 /remind 创建的提醒';
@@ -0,0 +1,50 @@
+-- 表情包
+-- 管理后台上传的全局表情包和用户个人收藏的表情，图片均存储在OSS（通过现有上传接口）
+-- 表情包/表情删除均为软删除，已发送的表情消息仍可通过表情ID解析
+
+CREATE TABLE IF NOT EXISTS sticker_packs (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,                  -- 表情包名称
+    description VARCHAR(200),                   -- 描述
+    cover_url TEXT,                             -- 封面图片URL
+    sort_order INTEGER NOT NULL DEFAULT 0,      -- 排序（越小越靠前）
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    deleted_at TIMESTAMP                        -- 软删除时间
+);
+
+CREATE TABLE IF NOT EXISTS stickers (
+    id SERIAL PRIMARY KEY,
+    pack_id INTEGER REFERENCES sticker_packs(id),  -- 所属表情包（个人表情为空）
+    uploader_id INTEGER,                           -- 上传者（个人表情）
+    name VARCHAR(50),                              -- 表情名称/关键词
+    image_url TEXT NOT NULL,                       -- 图片URL
+    object_key TEXT NOT NULL,                      -- OSS对象key
+    width INTEGER NOT NULL DEFAULT 0,
+    height INTEGER NOT NULL DEFAULT 0,
+    sort_order INTEGER NOT NULL DEFAULT 0,         -- 在表情包内的排序
+    created_at TIMESTAMP DEFAULT NOW(),
+    deleted_at TIMESTAMP                           -- 软删除时间
+);
+
+-- 用户个人表情（收藏）
+CREATE TABLE IF NOT EXISTS user_stickers (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sticker_id INTEGER NOT NULL REFERENCES stickers(id),
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(user_id, sticker_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_stickers_pack_id ON stickers(pack_id);
+CREATE INDEX IF NOT EXISTS idx_user_stickers_user_id ON user_stickers(user_id);
+
+-- 添加注释
+COMMENT ON TABLE sticker_packs IS '全局表情包（管理后台维护）';
+COMMENT ON COLUMN sticker_packs.deleted_at IS '软删除时间，删除后不再展示，但历史表情消息仍可解析';
+COMMENT ON TABLE stickers IS '表情（全局表情包中的表情或用户上传的个人表情）';
+COMMENT ON COLUMN stickers.pack_id IS '所属表情包ID，个人表情为空';
+COMMENT ON COLUMN stickers.object_key IS 'OSS对象key';
+COMMENT ON TABLE user_stickers IS '用户个人表情收藏';
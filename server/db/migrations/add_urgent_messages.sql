@@ -0,0 +1,38 @@
+-- 加急消息（DING）
+-- 私聊或群聊消息可标记为加急；接收者超过设定时间仍未读且未确认时，通过短信或邮件提醒
+
+-- 加急消息表
+CREATE TABLE IF NOT EXISTS urgent_messages (
+    id SERIAL PRIMARY KEY,
+    conversation_type VARCHAR(20) NOT NULL,          -- user, group
+    message_id INTEGER NOT NULL,                     -- messages.id 或 group_messages.id
+    group_id INTEGER NOT NULL DEFAULT 0,             -- 群聊时为群组ID
+    sender_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    preview VARCHAR(200) NOT NULL DEFAULT '',        -- 消息预览（用于推送和短信）
+    escalate_at TIMESTAMP NOT NULL,                  -- 到期仍未读时升级提醒
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(conversation_type, message_id)
+);
+
+-- 加急消息接收回执表（每个接收者一条记录）
+CREATE TABLE IF NOT EXISTS urgent_message_receipts (
+    id SERIAL PRIMARY KEY,
+    urgent_id INTEGER NOT NULL REFERENCES urgent_messages(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    confirmed_at TIMESTAMP,                                   -- 接收者确认时间
+    escalation_status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, sending, sent, failed, skipped
+    escalation_channel VARCHAR(20),                           -- sms, email
+    escalated_at TIMESTAMP,
+    UNIQUE(urgent_id, user_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_urgent_messages_sender ON urgent_messages(sender_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_urgent_messages_escalate_at ON urgent_messages(escalate_at);
+CREATE INDEX IF NOT EXISTS idx_urgent_message_receipts_user ON urgent_message_receipts(user_id) WHERE confirmed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_urgent_message_receipts_pending ON urgent_message_receipts(urgent_id) WHERE escalation_status = 'pending';
+
+-- 添加注释
+COMMENT ON TABLE urgent_messages IS '加急消息（DING）';
+COMMENT ON TABLE urgent_message_receipts IS '加急消息接收回执（确认状态及短信/邮件升级提醒状态）';
+COMMENT ON COLUMN urgent_message_receipts.escalation_status IS 'pending 等待中，sending 发送中，sent 已发送，failed 发送失败，skipped 已读或无联系方式无需发送';
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"time"
 	"youdu-server/config"
+	"youdu-server/controllers"
 	"youdu-server/db"
 	"youdu-server/routes"
 	"youdu-server/utils"
@@ -50,6 +51,9 @@ func main() {
 	defer utils.CloseRedis()
 	utils.LogInfo("✅ Redis连接成功")
 
+	// 初始化离线推送通道（只注册已配置的通道）
+	utils.InitPushProviders()
+
 	// 加载已解散的群组到内存 - 暂时禁用（groups表不存在）
 	// disbandedManager := models.GetDisbandedGroupsManager()
 	// if err := disbandedManager.LoadDisbandedGroups(); err != nil {
@@ -71,6 +75,79 @@ func main() {
 		}
 	}()
 
+	// 启动投票截止检查定时器（每30秒结束已到期的群投票）
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			controllers.CloseExpiredPolls(hub)
+		}
+	}()
+
+	// 启动事件投递重试定时器（每15秒重试到期的失败回调）
+	go func() {
+		ticker := time.NewTicker(15 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			controllers.RetryEventDeliveries()
+		}
+	}()
+
+	// 启动斜杠命令定时器（每30秒送达到期的提醒并解除到期的定时禁言）
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			controllers.DeliverDueReminders(hub)
+			controllers.UnmuteExpiredMembers(hub)
+		}
+	}()
+
+	// 启动消息保留策略清理定时器（每小时分批清理过期消息）
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			controllers.PurgeExpiredMessages(hub)
+		}
+	}()
+
+	// 启动未读消息邮件摘要定时器（每15分钟向长时间离线的用户发送摘要）
+	go func() {
+		ticker := time.NewTicker(15 * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			controllers.SendEmailDigests(hub)
+		}
+	}()
+
+	// 启动加急消息升级提醒定时器（每30秒对超时未读的加急消息发送短信或邮件提醒）
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			controllers.EscalateUrgentMessages()
+		}
+	}()
+
+	// 启动任务到期提醒定时器（每30秒提醒到期仍未完成的任务负责人）
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			controllers.RemindDueTasks(hub)
+		}
+	}()
+
+	// 启动自动回复到期检查定时器（每分钟关闭生效时间段已结束的自动回复）
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			controllers.ExpireAutoReplyRules(hub)
+		}
+	}()
+
 	// 设置HTTP API路由
 	apiRouter := routes.SetupRouter(hub)
 
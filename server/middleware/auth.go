@@ -37,6 +37,10 @@ func AuthMiddleware() gin.HandlerFunc {
 		// 将用户信息存储到上下文
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("session_id", utils.TokenSessionID(parts[1]))
+		if claims.ExpiresAt != nil {
+			c.Set("session_expires_at", claims.ExpiresAt.Time)
+		}
 
 		c.Next()
 	}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// MessageTypeAutoReply 自动回复消息类型（服务端代用户发送，客户端按系统消息样式展示）
+const MessageTypeAutoReply = "auto_reply"
+
+// MaxAutoReplyLength 自动回复内容最大长度（字符）
+const MaxAutoReplyLength = 500
+
+// AutoReplyRule 用户的自动回复规则
+// 设置了时间段时在时间段内生效；未设置时间段时在用户处于离开/忙碌状态期间生效
+type AutoReplyRule struct {
+	UserID          int        `json:"user_id" db:"user_id"`
+	Enabled         bool       `json:"enabled" db:"enabled"`
+	Message         string     `json:"message" db:"message"`
+	StartAt         *time.Time `json:"start_at,omitempty" db:"start_at"`
+	EndAt           *time.Time `json:"end_at,omitempty" db:"end_at"`
+	StatusTriggered bool       `json:"status_triggered" db:"status_triggered"`
+	ActivatedAt     time.Time  `json:"activated_at" db:"activated_at"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	Active          bool       `json:"active"` // 当前是否生效
+}
+
+// UpdateAutoReplyRequest 更新自动回复请求（字段为空表示不修改）
+type UpdateAutoReplyRequest struct {
+	Enabled *bool   `json:"enabled"`
+	Message *string `json:"message"`
+	StartAt *string `json:"start_at"` // RFC3339 格式，空字符串表示不限开始时间
+	EndAt   *string `json:"end_at"`   // RFC3339 格式，空字符串表示不限结束时间
+}
+
+// IsStatusTriggering 判断用户状态是否触发自动回复（离开或忙碌）
+func IsStatusTriggering(status string) bool {
+	return status == "away" || status == "busy"
+}
+
+// HasWindow 是否设置了生效时间段
+func (r *AutoReplyRule) HasWindow() bool {
+	return r.StartAt != nil || r.EndAt != nil
+}
+
+// IsActive 判断规则在指定时间是否生效
+func (r *AutoReplyRule) IsActive(now time.Time) bool {
+	if !r.Enabled || r.Message == "" {
+		return false
+	}
+	if r.StartAt != nil && now.Before(*r.StartAt) {
+		return false
+	}
+	if r.EndAt != nil && !now.Before(*r.EndAt) {
+		return false
+	}
+	return r.HasWindow() || r.StatusTriggered
+}
+
+// PeriodStart 当前生效周期的开始时间（同一发送者在周期内只收到一次自动回复）
+func (r *AutoReplyRule) PeriodStart() time.Time {
+	if r.StartAt != nil && r.StartAt.After(r.ActivatedAt) {
+		return *r.StartAt
+	}
+	return r.ActivatedAt
+}
+
+// AutoReplyRepository 自动回复数据仓库
+type AutoReplyRepository struct {
+	DB *sql.DB
+}
+
+// NewAutoReplyRepository 创建自动回复仓库
+func NewAutoReplyRepository(db *sql.DB) *AutoReplyRepository {
+	return &AutoReplyRepository{DB: db}
+}
+
+// GetRule 获取用户的自动回复规则（没有记录时返回关闭状态的默认规则）
+func (r *AutoReplyRepository) GetRule(userID int) (*AutoReplyRule, error) {
+	rule := &AutoReplyRule{UserID: userID}
+	err := r.DB.QueryRow(`
+		SELECT enabled, message, start_at, end_at, status_triggered, activated_at, updated_at
+		FROM auto_reply_rules
+		WHERE user_id = $1
+	`, userID).Scan(&rule.Enabled, &rule.Message, &rule.StartAt, &rule.EndAt, &rule.StatusTriggered, &rule.ActivatedAt, &rule.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return rule, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rule.Active = rule.IsActive(time.Now().UTC())
+	return rule, nil
+}
+
+// SaveRule 保存自动回复规则并开始新的生效周期（之前回复过的发送者会再次收到自动回复）
+// 新建规则时根据用户当前状态初始化 status_triggered
+func (r *AutoReplyRepository) SaveRule(rule *AutoReplyRule) error {
+	now := time.Now().UTC()
+	err := r.DB.QueryRow(`
+		INSERT INTO auto_reply_rules (user_id, enabled, message, start_at, end_at, status_triggered, activated_at, updated_at)
+		SELECT $1, $2, $3, $4, $5, u.status IN ('away', 'busy'), $6, $6
+		FROM users u WHERE u.id = $1
+		ON CONFLICT (user_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			message = EXCLUDED.message,
+			start_at = EXCLUDED.start_at,
+			end_at = EXCLUDED.end_at,
+			activated_at = EXCLUDED.activated_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING status_triggered, activated_at, updated_at
+	`, rule.UserID, rule.Enabled, rule.Message, rule.StartAt, rule.EndAt, now).Scan(&rule.StatusTriggered, &rule.ActivatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	rule.Active = rule.IsActive(now)
+	return nil
+}
+
+// SetStatusTriggered 同步用户的离开/忙碌状态，从非离开切换为离开/忙碌时开始新的生效周期
+// 用户没有自动回复规则时返回 nil
+func (r *AutoReplyRepository) SetStatusTriggered(userID int, triggered bool) (*AutoReplyRule, error) {
+	now := time.Now().UTC()
+	rule := &AutoReplyRule{UserID: userID}
+	err := r.DB.QueryRow(`
+		UPDATE auto_reply_rules
+		SET activated_at = CASE WHEN $2 AND NOT status_triggered THEN $3 ELSE activated_at END,
+		    status_triggered = $2
+		WHERE user_id = $1
+		RETURNING enabled, message, start_at, end_at, status_triggered, activated_at, updated_at
+	`, userID, triggered, now).Scan(&rule.Enabled, &rule.Message, &rule.StartAt, &rule.EndAt, &rule.StatusTriggered, &rule.ActivatedAt, &rule.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rule.Active = rule.IsActive(now)
+	return rule, nil
+}
+
+// ClaimReply 记录向发送者的自动回复，返回 false 表示本生效周期内已回复过
+func (r *AutoReplyRepository) ClaimReply(userID, senderID int, periodStart time.Time) (bool, error) {
+	result, err := r.DB.Exec(`
+		INSERT INTO auto_reply_logs (user_id, sender_id, replied_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, sender_id) DO UPDATE SET replied_at = EXCLUDED.replied_at
+		WHERE auto_reply_logs.replied_at < $4
+	`, userID, senderID, time.Now().UTC(), periodStart)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// DisableExpired 关闭生效时间段已结束的规则，返回被关闭规则的用户ID
+func (r *AutoReplyRepository) DisableExpired(now time.Time) ([]int, error) {
+	rows, err := r.DB.Query(`
+		UPDATE auto_reply_rules
+		SET enabled = false, updated_at = $1
+		WHERE enabled = true AND end_at IS NOT NULL AND end_at <= $1
+		RETURNING user_id
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
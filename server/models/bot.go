@@ -0,0 +1,256 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+)
+
+// Bot 机器人账号
+type Bot struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	FullName  *string   `json:"full_name,omitempty"`
+	Avatar    string    `json:"avatar"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateBotRequest 创建机器人请求
+type CreateBotRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	FullName string `json:"full_name" binding:"required"`
+	Avatar   string `json:"avatar"`
+}
+
+// BotWebhook 机器人群组 Incoming Webhook
+type BotWebhook struct {
+	ID          int        `json:"id"`
+	BotID       int        `json:"bot_id"`
+	GroupID     int        `json:"group_id"`
+	GroupName   string     `json:"group_name"`
+	TokenPrefix string     `json:"token_prefix"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RotatedAt   *time.Time `json:"rotated_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreateBotWebhookRequest 创建 Webhook 请求
+type CreateBotWebhookRequest struct {
+	GroupID int `json:"group_id" binding:"required"`
+}
+
+// WebhookCard 卡片消息
+type WebhookCard struct {
+	Title  string             `json:"title"`
+	Text   string             `json:"text"`
+	URL    string             `json:"url"`
+	Fields []WebhookCardField `json:"fields"`
+}
+
+// WebhookCardField 卡片字段
+type WebhookCardField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// WebhookPayload Incoming Webhook 请求体
+// type 为 text（纯文本）、rich_text（受限 Markdown）或 card（卡片，转换为富文本发送）
+type WebhookPayload struct {
+	Type string       `json:"type"`
+	Text string       `json:"text"`
+	Card *WebhookCard `json:"card"`
+}
+
+// BotRepository 机器人数据仓库
+type BotRepository struct {
+	DB *sql.DB
+}
+
+// NewBotRepository 创建机器人仓库
+func NewBotRepository(db *sql.DB) *BotRepository {
+	return &BotRepository{DB: db}
+}
+
+// generateWebhookToken 生成 Webhook 密钥，返回密钥和其 SHA-256 摘要
+func generateWebhookToken() (token, hash string, err error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(buf)
+	return token, HashWebhookToken(token), nil
+}
+
+// HashWebhookToken 计算 Webhook 密钥的摘要
+func HashWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateBot 创建机器人账号，password 应为无法用于登录的随机密码哈希
+func (r *BotRepository) CreateBot(req *CreateBotRequest, passwordHash string) (*Bot, error) {
+	bot := &Bot{}
+	err := r.DB.QueryRow(`
+		INSERT INTO users (username, full_name, password, avatar, status, is_bot, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 'offline', true, $5, $5)
+		RETURNING id, username, full_name, avatar, created_at
+	`, req.Username, req.FullName, passwordHash, req.Avatar, time.Now().UTC()).Scan(&bot.ID, &bot.Username, &bot.FullName, &bot.Avatar, &bot.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return bot, nil
+}
+
+// ListBots 获取所有机器人
+func (r *BotRepository) ListBots() ([]Bot, error) {
+	rows, err := r.DB.Query(`
+		SELECT id, username, full_name, COALESCE(avatar, ''), created_at
+		FROM users
+		WHERE is_bot = true
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bots := []Bot{}
+	for rows.Next() {
+		var bot Bot
+		if err := rows.Scan(&bot.ID, &bot.Username, &bot.FullName, &bot.Avatar, &bot.CreatedAt); err != nil {
+			return nil, err
+		}
+		bots = append(bots, bot)
+	}
+	return bots, nil
+}
+
+// GetBot 根据ID获取机器人
+func (r *BotRepository) GetBot(botID int) (*Bot, error) {
+	bot := &Bot{}
+	err := r.DB.QueryRow(`
+		SELECT id, username, full_name, COALESCE(avatar, ''), created_at
+		FROM users
+		WHERE id = $1 AND is_bot = true
+	`, botID).Scan(&bot.ID, &bot.Username, &bot.FullName, &bot.Avatar, &bot.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return bot, nil
+}
+
+// IsBot 判断用户是否为机器人账号
+func (r *BotRepository) IsBot(userID int) (bool, error) {
+	var isBot bool
+	err := r.DB.QueryRow(`SELECT COALESCE(is_bot, false) FROM users WHERE id = $1`, userID).Scan(&isBot)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return isBot, err
+}
+
+// CreateWebhook 为机器人创建群组 Webhook，返回 Webhook 和密钥（仅此一次返回）
+func (r *BotRepository) CreateWebhook(botID, groupID int) (*BotWebhook, string, error) {
+	token, hash, err := generateWebhookToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	webhook := &BotWebhook{BotID: botID, GroupID: groupID, TokenPrefix: token[:8]}
+	err = r.DB.QueryRow(`
+		INSERT INTO bot_webhooks (bot_id, group_id, token_hash, token_prefix, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, botID, groupID, hash, webhook.TokenPrefix, time.Now().UTC()).Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		return nil, "", err
+	}
+	return webhook, token, nil
+}
+
+const botWebhookSelect = `
+	SELECT w.id, w.bot_id, w.group_id, COALESCE(g.name, ''), w.token_prefix, w.last_used_at, w.revoked_at, w.rotated_at, w.created_at
+	FROM bot_webhooks w
+	LEFT JOIN groups g ON g.id = w.group_id
+`
+
+func scanBotWebhook(scanner interface{ Scan(...interface{}) error }) (*BotWebhook, error) {
+	w := &BotWebhook{}
+	err := scanner.Scan(&w.ID, &w.BotID, &w.GroupID, &w.GroupName, &w.TokenPrefix, &w.LastUsedAt, &w.RevokedAt, &w.RotatedAt, &w.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// ListWebhooks 获取机器人的所有 Webhook（含已吊销）
+func (r *BotRepository) ListWebhooks(botID int) ([]BotWebhook, error) {
+	rows, err := r.DB.Query(botWebhookSelect+` WHERE w.bot_id = $1 ORDER BY w.id`, botID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []BotWebhook{}
+	for rows.Next() {
+		w, err := scanBotWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *w)
+	}
+	return webhooks, nil
+}
+
+// GetWebhookByToken 根据密钥获取有效（未吊销）的 Webhook
+func (r *BotRepository) GetWebhookByToken(token string) (*BotWebhook, error) {
+	return scanBotWebhook(r.DB.QueryRow(botWebhookSelect+` WHERE w.token_hash = $1 AND w.revoked_at IS NULL`, HashWebhookToken(token)))
+}
+
+// RotateWebhook 轮换 Webhook 密钥，旧密钥立即失效，返回新密钥
+func (r *BotRepository) RotateWebhook(webhookID int) (*BotWebhook, string, error) {
+	token, hash, err := generateWebhookToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	result, err := r.DB.Exec(`
+		UPDATE bot_webhooks
+		SET token_hash = $1, token_prefix = $2, rotated_at = $3
+		WHERE id = $4 AND revoked_at IS NULL
+	`, hash, token[:8], time.Now().UTC(), webhookID)
+	if err != nil {
+		return nil, "", err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, "", sql.ErrNoRows
+	}
+
+	webhook, err := scanBotWebhook(r.DB.QueryRow(botWebhookSelect+` WHERE w.id = $1`, webhookID))
+	if err != nil {
+		return nil, "", err
+	}
+	return webhook, token, nil
+}
+
+// RevokeWebhook 吊销 Webhook，返回 false 表示 Webhook 不存在或已吊销
+func (r *BotRepository) RevokeWebhook(webhookID int) (bool, error) {
+	result, err := r.DB.Exec(`
+		UPDATE bot_webhooks SET revoked_at = $1
+		WHERE id = $2 AND revoked_at IS NULL
+	`, time.Now().UTC(), webhookID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// TouchWebhook 更新 Webhook 最近使用时间
+func (r *BotRepository) TouchWebhook(webhookID int) error {
+	_, err := r.DB.Exec(`UPDATE bot_webhooks SET last_used_at = $1 WHERE id = $2`, time.Now().UTC(), webhookID)
+	return err
+}
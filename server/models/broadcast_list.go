@@ -0,0 +1,268 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 群发列表限制
+const (
+	MaxBroadcastLists      = 50  // 每个用户最多保存的群发列表数
+	MaxBroadcastRecipients = 200 // 每个群发列表最多成员数
+)
+
+// ErrBroadcastListLimitReached 群发列表数量已达上限
+var ErrBroadcastListLimitReached = errors.New("群发列表数量已达上限")
+
+// BroadcastList 群发列表
+type BroadcastList struct {
+	ID          int                   `json:"id" db:"id"`
+	OwnerID     int                   `json:"owner_id" db:"owner_id"`
+	Name        string                `json:"name" db:"name"`
+	MemberCount int                   `json:"member_count"`
+	Members     []BroadcastListMember `json:"members,omitempty"`
+	CreatedAt   time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// BroadcastListMember 群发列表成员
+type BroadcastListMember struct {
+	UserID   int     `json:"user_id"`
+	Username string  `json:"username"`
+	FullName *string `json:"full_name,omitempty"`
+	Avatar   string  `json:"avatar"`
+}
+
+// SaveBroadcastListRequest 创建/更新群发列表请求
+// 更新时 MemberIDs 为 nil 表示不修改成员
+type SaveBroadcastListRequest struct {
+	Name      string `json:"name"`
+	MemberIDs []int  `json:"member_ids"`
+}
+
+// SendBroadcastRequest 群发消息请求
+type SendBroadcastRequest struct {
+	Content       string `json:"content" binding:"required"`
+	MessageType   string `json:"message_type"`
+	FileName      string `json:"file_name,omitempty"`
+	VoiceDuration int    `json:"voice_duration,omitempty"`
+}
+
+// BroadcastDelivery 群发投递结果（每个接收者一条）
+type BroadcastDelivery struct {
+	UserID  int      `json:"user_id"`
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`           // sent-已发送, failed-未发送
+	Reason  string   `json:"reason,omitempty"` // 未发送原因
+	Message *Message `json:"message,omitempty"`
+}
+
+// BroadcastListRepository 群发列表数据仓库
+type BroadcastListRepository struct {
+	DB *sql.DB
+}
+
+// NewBroadcastListRepository 创建群发列表仓库
+func NewBroadcastListRepository(db *sql.DB) *BroadcastListRepository {
+	return &BroadcastListRepository{DB: db}
+}
+
+// ListByOwner 获取用户的所有群发列表（不含成员明细）
+func (r *BroadcastListRepository) ListByOwner(ownerID int) ([]BroadcastList, error) {
+	rows, err := r.DB.Query(`
+		SELECT bl.id, bl.owner_id, bl.name, COUNT(m.user_id), bl.created_at, bl.updated_at
+		FROM broadcast_lists bl
+		LEFT JOIN broadcast_list_members m ON m.list_id = bl.id
+		WHERE bl.owner_id = $1
+		GROUP BY bl.id
+		ORDER BY bl.updated_at DESC
+	`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []BroadcastList{}
+	for rows.Next() {
+		var l BroadcastList
+		if err := rows.Scan(&l.ID, &l.OwnerID, &l.Name, &l.MemberCount, &l.CreatedAt, &l.UpdatedAt); err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	return lists, nil
+}
+
+// GetByID 获取群发列表及成员（仅限列表所有者）
+func (r *BroadcastListRepository) GetByID(listID, ownerID int) (*BroadcastList, error) {
+	l := &BroadcastList{}
+	err := r.DB.QueryRow(`
+		SELECT id, owner_id, name, created_at, updated_at
+		FROM broadcast_lists
+		WHERE id = $1 AND owner_id = $2
+	`, listID, ownerID).Scan(&l.ID, &l.OwnerID, &l.Name, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.DB.Query(`
+		SELECT u.id, u.username, u.full_name, u.avatar
+		FROM broadcast_list_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.list_id = $1
+		ORDER BY m.created_at, u.id
+	`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	l.Members = []BroadcastListMember{}
+	for rows.Next() {
+		var m BroadcastListMember
+		if err := rows.Scan(&m.UserID, &m.Username, &m.FullName, &m.Avatar); err != nil {
+			return nil, err
+		}
+		l.Members = append(l.Members, m)
+	}
+	l.MemberCount = len(l.Members)
+	return l, nil
+}
+
+// FilterExistingUsers 过滤出存在的用户ID（保持原顺序）
+func (r *BroadcastListRepository) FilterExistingUsers(userIDs []int) ([]int, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(userIDs))
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = id
+	}
+
+	rows, err := r.DB.Query(`SELECT id FROM users WHERE id IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing[id] = true
+	}
+
+	var result []int
+	for _, id := range userIDs {
+		if existing[id] {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+// Create 创建群发列表
+func (r *BroadcastListRepository) Create(ownerID int, name string, memberIDs []int) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// 锁定用户行，避免并发创建超出上限
+	if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, ownerID); err != nil {
+		return 0, err
+	}
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM broadcast_lists WHERE owner_id = $1`, ownerID).Scan(&count); err != nil {
+		return 0, err
+	}
+	if count >= MaxBroadcastLists {
+		return 0, ErrBroadcastListLimitReached
+	}
+
+	now := time.Now().UTC()
+	var listID int
+	err = tx.QueryRow(`
+		INSERT INTO broadcast_lists (owner_id, name, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		RETURNING id
+	`, ownerID, name, now).Scan(&listID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := insertBroadcastListMembers(tx, listID, memberIDs, now); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return listID, nil
+}
+
+// Update 更新群发列表名称和成员，name 为空表示不修改名称，memberIDs 为 nil 表示不修改成员
+func (r *BroadcastListRepository) Update(listID, ownerID int, name string, memberIDs []int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	result, err := tx.Exec(`
+		UPDATE broadcast_lists
+		SET name = CASE WHEN $1 = '' THEN name ELSE $1 END, updated_at = $2
+		WHERE id = $3 AND owner_id = $4
+	`, name, now, listID, ownerID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if memberIDs != nil {
+		if _, err := tx.Exec(`DELETE FROM broadcast_list_members WHERE list_id = $1`, listID); err != nil {
+			return err
+		}
+		if err := insertBroadcastListMembers(tx, listID, memberIDs, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete 删除群发列表
+func (r *BroadcastListRepository) Delete(listID, ownerID int) (bool, error) {
+	result, err := r.DB.Exec(`DELETE FROM broadcast_lists WHERE id = $1 AND owner_id = $2`, listID, ownerID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func insertBroadcastListMembers(tx *sql.Tx, listID int, memberIDs []int, now time.Time) error {
+	for _, userID := range memberIDs {
+		_, err := tx.Exec(`
+			INSERT INTO broadcast_list_members (list_id, user_id, created_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (list_id, user_id) DO NOTHING
+		`, listID, userID, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
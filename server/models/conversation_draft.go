@@ -0,0 +1,131 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ConversationDraft 会话草稿模型
+type ConversationDraft struct {
+	ID                   int       `json:"id" db:"id"`
+	UserID               int       `json:"user_id" db:"user_id"`
+	ConversationType     string    `json:"conversation_type" db:"conversation_type"` // user, group, file_assistant
+	TargetID             int       `json:"target_id" db:"target_id"`                 // 对方用户ID或群组ID（文件助手为0）
+	Content              string    `json:"content" db:"content"`                     // 草稿内容
+	QuotedMessageID      *int      `json:"quoted_message_id,omitempty" db:"quoted_message_id"`
+	QuotedMessageContent *string   `json:"quoted_message_content,omitempty" db:"quoted_message_content"`
+	UpdatedAt            time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SaveConversationDraftRequest 保存会话草稿请求
+type SaveConversationDraftRequest struct {
+	ConversationType     string `json:"conversation_type" binding:"required"`
+	TargetID             int    `json:"target_id"`
+	Content              string `json:"content"`
+	QuotedMessageID      int    `json:"quoted_message_id,omitempty"`
+	QuotedMessageContent string `json:"quoted_message_content,omitempty"`
+}
+
+// ConversationDraftRepository 会话草稿数据仓库
+type ConversationDraftRepository struct {
+	DB *sql.DB
+}
+
+// NewConversationDraftRepository 创建会话草稿仓库
+func NewConversationDraftRepository(db *sql.DB) *ConversationDraftRepository {
+	return &ConversationDraftRepository{DB: db}
+}
+
+const conversationDraftColumns = `id, user_id, conversation_type, target_id, content, quoted_message_id, quoted_message_content, updated_at`
+
+func scanConversationDraft(scanner interface{ Scan(...interface{}) error }) (*ConversationDraft, error) {
+	d := &ConversationDraft{}
+	var quotedID sql.NullInt64
+	var quotedContent sql.NullString
+	err := scanner.Scan(
+		&d.ID,
+		&d.UserID,
+		&d.ConversationType,
+		&d.TargetID,
+		&d.Content,
+		&quotedID,
+		&quotedContent,
+		&d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if quotedID.Valid {
+		id := int(quotedID.Int64)
+		d.QuotedMessageID = &id
+	}
+	if quotedContent.Valid {
+		d.QuotedMessageContent = &quotedContent.String
+	}
+	return d, nil
+}
+
+// GetByUserID 获取用户的所有草稿
+func (r *ConversationDraftRepository) GetByUserID(userID int) ([]ConversationDraft, error) {
+	query := `SELECT ` + conversationDraftColumns + `
+		FROM conversation_drafts
+		WHERE user_id = $1
+		ORDER BY updated_at DESC
+	`
+
+	rows, err := r.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	drafts := []ConversationDraft{}
+	for rows.Next() {
+		d, err := scanConversationDraft(rows)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, *d)
+	}
+
+	return drafts, nil
+}
+
+// Save 保存草稿（存在则覆盖）
+func (r *ConversationDraftRepository) Save(userID int, req *SaveConversationDraftRequest) (*ConversationDraft, error) {
+	var quotedID sql.NullInt64
+	if req.QuotedMessageID > 0 {
+		quotedID = sql.NullInt64{Int64: int64(req.QuotedMessageID), Valid: true}
+	}
+	var quotedContent sql.NullString
+	if req.QuotedMessageContent != "" {
+		quotedContent = sql.NullString{String: req.QuotedMessageContent, Valid: true}
+	}
+
+	query := `
+		INSERT INTO conversation_drafts (user_id, conversation_type, target_id, content, quoted_message_id, quoted_message_content, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		ON CONFLICT (user_id, conversation_type, target_id) DO UPDATE
+		SET content = EXCLUDED.content,
+			quoted_message_id = EXCLUDED.quoted_message_id,
+			quoted_message_content = EXCLUDED.quoted_message_content,
+			updated_at = EXCLUDED.updated_at
+		RETURNING ` + conversationDraftColumns
+
+	return scanConversationDraft(r.DB.QueryRow(query, userID, req.ConversationType, req.TargetID, req.Content, quotedID, quotedContent, time.Now().UTC()))
+}
+
+// Delete 删除草稿，返回是否确实删除了记录
+func (r *ConversationDraftRepository) Delete(userID int, conversationType string, targetID int) (bool, error) {
+	query := `
+		DELETE FROM conversation_drafts
+		WHERE user_id = $1 AND conversation_type = $2 AND target_id = $3
+	`
+
+	result, err := r.DB.Exec(query, userID, conversationType, targetID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 导出任务状态
+const (
+	ExportStatusPending   = "pending"   // 等待中
+	ExportStatusRunning   = "running"   // 导出中
+	ExportStatusCompleted = "completed" // 已完成
+	ExportStatusFailed    = "failed"    // 失败
+)
+
+// ConversationExport 会话导出任务模型
+type ConversationExport struct {
+	ID                 int        `json:"id" db:"id"`
+	UserID             int        `json:"user_id" db:"user_id"`
+	ConversationType   string     `json:"conversation_type" db:"conversation_type"` // user, group, file_assistant
+	TargetID           int        `json:"target_id" db:"target_id"`                 // 对方用户ID或群组ID（文件助手为0）
+	StartTime          *time.Time `json:"start_time,omitempty" db:"start_time"`
+	EndTime            *time.Time `json:"end_time,omitempty" db:"end_time"`
+	IncludeAttachments bool       `json:"include_attachments" db:"include_attachments"`
+	RequestedByAdmin   bool       `json:"requested_by_admin" db:"requested_by_admin"`
+	Status             string     `json:"status" db:"status"`     // pending, running, completed, failed
+	Progress           int        `json:"progress" db:"progress"` // 0-100
+	MessageCount       int        `json:"message_count" db:"message_count"`
+	ObjectKey          *string    `json:"-" db:"object_key"` // 不直接返回，下载时生成签名链接
+	FileSize           int64      `json:"file_size" db:"file_size"`
+	ErrorMessage       *string    `json:"error_message,omitempty" db:"error_message"`
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// CreateConversationExportRequest 创建会话导出请求
+type CreateConversationExportRequest struct {
+	ConversationType   string `json:"conversation_type" binding:"required"`
+	TargetID           int    `json:"target_id"`
+	StartTime          string `json:"start_time,omitempty"` // 起始时间（RFC3339 或 2006-01-02）
+	EndTime            string `json:"end_time,omitempty"`   // 结束时间（RFC3339 或 2006-01-02，按天时包含当天）
+	IncludeAttachments bool   `json:"include_attachments,omitempty"`
+}
+
+// ConversationExportRepository 会话导出数据仓库
+type ConversationExportRepository struct {
+	DB *sql.DB
+}
+
+// NewConversationExportRepository 创建会话导出仓库
+func NewConversationExportRepository(db *sql.DB) *ConversationExportRepository {
+	return &ConversationExportRepository{DB: db}
+}
+
+const conversationExportColumns = `id, user_id, conversation_type, target_id, start_time, end_time, include_attachments, requested_by_admin,
+	status, progress, message_count, object_key, COALESCE(file_size, 0), error_message, created_at, completed_at`
+
+func scanConversationExport(scanner interface{ Scan(...interface{}) error }) (*ConversationExport, error) {
+	e := &ConversationExport{}
+	err := scanner.Scan(
+		&e.ID,
+		&e.UserID,
+		&e.ConversationType,
+		&e.TargetID,
+		&e.StartTime,
+		&e.EndTime,
+		&e.IncludeAttachments,
+		&e.RequestedByAdmin,
+		&e.Status,
+		&e.Progress,
+		&e.MessageCount,
+		&e.ObjectKey,
+		&e.FileSize,
+		&e.ErrorMessage,
+		&e.CreatedAt,
+		&e.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Create 创建导出任务
+func (r *ConversationExportRepository) Create(export *ConversationExport) error {
+	query := `
+		INSERT INTO conversation_exports (user_id, conversation_type, target_id, start_time, end_time, include_attachments, requested_by_admin, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	export.Status = ExportStatusPending
+	return r.DB.QueryRow(query,
+		export.UserID,
+		export.ConversationType,
+		export.TargetID,
+		export.StartTime,
+		export.EndTime,
+		export.IncludeAttachments,
+		export.RequestedByAdmin,
+		export.Status,
+		time.Now().UTC(),
+	).Scan(&export.ID, &export.CreatedAt)
+}
+
+// GetByID 根据ID获取导出任务
+func (r *ConversationExportRepository) GetByID(id int) (*ConversationExport, error) {
+	query := `SELECT ` + conversationExportColumns + ` FROM conversation_exports WHERE id = $1`
+	return scanConversationExport(r.DB.QueryRow(query, id))
+}
+
+// ListByUserID 获取用户最近的导出任务（不含管理后台发起的任务）
+func (r *ConversationExportRepository) ListByUserID(userID, limit int) ([]ConversationExport, error) {
+	query := `SELECT ` + conversationExportColumns + `
+		FROM conversation_exports
+		WHERE user_id = $1 AND requested_by_admin = false
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.DB.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := []ConversationExport{}
+	for rows.Next() {
+		e, err := scanConversationExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, *e)
+	}
+
+	return exports, nil
+}
+
+// UpdateProgress 更新导出进度
+func (r *ConversationExportRepository) UpdateProgress(id int, status string, progress, messageCount int) error {
+	query := `UPDATE conversation_exports SET status = $1, progress = $2, message_count = $3 WHERE id = $4`
+	_, err := r.DB.Exec(query, status, progress, messageCount, id)
+	return err
+}
+
+// MarkCompleted 标记导出完成
+func (r *ConversationExportRepository) MarkCompleted(id int, objectKey string, fileSize int64, messageCount int) error {
+	query := `
+		UPDATE conversation_exports
+		SET status = $1, progress = 100, object_key = $2, file_size = $3, message_count = $4, completed_at = $5
+		WHERE id = $6
+	`
+	_, err := r.DB.Exec(query, ExportStatusCompleted, objectKey, fileSize, messageCount, time.Now().UTC(), id)
+	return err
+}
+
+// MarkFailed 标记导出失败
+func (r *ConversationExportRepository) MarkFailed(id int, errorMessage string) error {
+	query := `
+		UPDATE conversation_exports
+		SET status = $1, error_message = $2, completed_at = $3
+		WHERE id = $4
+	`
+	_, err := r.DB.Exec(query, ExportStatusFailed, errorMessage, time.Now().UTC(), id)
+	return err
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// 会话类型常量
+const (
+	ConversationTypeUser          = "user"           // 私聊
+	ConversationTypeGroup         = "group"          // 群聊
+	ConversationTypeFileAssistant = "file_assistant" // 文件传输助手
+)
+
+// ConversationSetting 用户会话设置模型
+type ConversationSetting struct {
+	ID               int        `json:"id" db:"id"`
+	UserID           int        `json:"user_id" db:"user_id"`
+	ConversationType string     `json:"conversation_type" db:"conversation_type"` // user, group, file_assistant
+	TargetID         int        `json:"target_id" db:"target_id"`                 // 对方用户ID或群组ID（文件助手为0）
+	IsPinned         bool       `json:"is_pinned" db:"is_pinned"`                 // 是否置顶
+	PinOrder         int        `json:"pin_order" db:"pin_order"`                 // 置顶排序（数值越大越靠前）
+	MutedUntil       *time.Time `json:"muted_until,omitempty" db:"muted_until"`   // 免打扰截止时间
+	IsArchived       bool       `json:"is_archived" db:"is_archived"`             // 是否归档
+	MarkedUnread     bool       `json:"marked_unread" db:"marked_unread"`         // 是否手动标记为未读
+	HiddenAt         *time.Time `json:"hidden_at,omitempty" db:"hidden_at"`       // 隐藏时间（收到新消息后自动显示）
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsMuted 当前是否处于免打扰状态
+func (s *ConversationSetting) IsMuted() bool {
+	return s.MutedUntil != nil && s.MutedUntil.After(time.Now().UTC())
+}
+
+// UpdateConversationSettingRequest 更新会话设置请求（字段为空表示不修改）
+type UpdateConversationSettingRequest struct {
+	ConversationType string     `json:"conversation_type" binding:"required"`
+	TargetID         int        `json:"target_id"`
+	IsPinned         *bool      `json:"is_pinned,omitempty"`
+	PinOrder         *int       `json:"pin_order,omitempty"`
+	MutedUntil       *time.Time `json:"muted_until,omitempty"` // 免打扰截止时间（RFC3339）
+	Unmute           bool       `json:"unmute,omitempty"`      // 为true时取消免打扰
+	IsArchived       *bool      `json:"is_archived,omitempty"`
+	MarkedUnread     *bool      `json:"marked_unread,omitempty"`
+	Hidden           *bool      `json:"hidden,omitempty"` // true-隐藏到下一条消息，false-取消隐藏
+}
+
+// IsValidConversationType 检查会话类型是否有效
+func IsValidConversationType(conversationType string) bool {
+	switch conversationType {
+	case ConversationTypeUser, ConversationTypeGroup, ConversationTypeFileAssistant:
+		return true
+	}
+	return false
+}
+
+// ConversationSettingRepository 会话设置数据仓库
+type ConversationSettingRepository struct {
+	DB *sql.DB
+}
+
+// NewConversationSettingRepository 创建会话设置仓库
+func NewConversationSettingRepository(db *sql.DB) *ConversationSettingRepository {
+	return &ConversationSettingRepository{DB: db}
+}
+
+const conversationSettingColumns = `id, user_id, conversation_type, target_id, is_pinned, pin_order, muted_until, is_archived, marked_unread, hidden_at, updated_at`
+
+func scanConversationSetting(scanner interface{ Scan(...interface{}) error }) (*ConversationSetting, error) {
+	s := &ConversationSetting{}
+	err := scanner.Scan(
+		&s.ID,
+		&s.UserID,
+		&s.ConversationType,
+		&s.TargetID,
+		&s.IsPinned,
+		&s.PinOrder,
+		&s.MutedUntil,
+		&s.IsArchived,
+		&s.MarkedUnread,
+		&s.HiddenAt,
+		&s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetByUserID 获取用户的所有会话设置
+func (r *ConversationSettingRepository) GetByUserID(userID int) ([]ConversationSetting, error) {
+	query := `SELECT ` + conversationSettingColumns + `
+		FROM conversation_settings
+		WHERE user_id = $1
+		ORDER BY is_pinned DESC, pin_order DESC, updated_at DESC
+	`
+
+	rows, err := r.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := []ConversationSetting{}
+	for rows.Next() {
+		s, err := scanConversationSetting(rows)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, *s)
+	}
+
+	return settings, nil
+}
+
+// Get 获取单个会话设置（不存在时返回 sql.ErrNoRows）
+func (r *ConversationSettingRepository) Get(userID int, conversationType string, targetID int) (*ConversationSetting, error) {
+	query := `SELECT ` + conversationSettingColumns + `
+		FROM conversation_settings
+		WHERE user_id = $1 AND conversation_type = $2 AND target_id = $3
+	`
+
+	return scanConversationSetting(r.DB.QueryRow(query, userID, conversationType, targetID))
+}
+
+// Update 更新会话设置（记录不存在时自动创建）
+func (r *ConversationSettingRepository) Update(userID int, req *UpdateConversationSettingRequest) (*ConversationSetting, error) {
+	// 确保记录存在
+	insertQuery := `
+		INSERT INTO conversation_settings (user_id, conversation_type, target_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, conversation_type, target_id) DO NOTHING
+	`
+	if _, err := r.DB.Exec(insertQuery, userID, req.ConversationType, req.TargetID); err != nil {
+		return nil, err
+	}
+
+	// 构建动态更新语句
+	query := `UPDATE conversation_settings SET updated_at = $1`
+	args := []interface{}{time.Now().UTC()}
+	argIndex := 2
+
+	addField := func(column string, value interface{}) {
+		query += fmt.Sprintf(", %s = $%d", column, argIndex)
+		args = append(args, value)
+		argIndex++
+	}
+
+	if req.IsPinned != nil {
+		addField("is_pinned", *req.IsPinned)
+		// 取消置顶时重置排序
+		if !*req.IsPinned && req.PinOrder == nil {
+			addField("pin_order", 0)
+		}
+	}
+	if req.PinOrder != nil {
+		addField("pin_order", *req.PinOrder)
+	}
+	if req.Unmute {
+		query += ", muted_until = NULL"
+	} else if req.MutedUntil != nil {
+		addField("muted_until", req.MutedUntil.UTC())
+	}
+	if req.IsArchived != nil {
+		addField("is_archived", *req.IsArchived)
+	}
+	if req.MarkedUnread != nil {
+		addField("marked_unread", *req.MarkedUnread)
+	}
+	if req.Hidden != nil {
+		if *req.Hidden {
+			addField("hidden_at", time.Now().UTC())
+		} else {
+			query += ", hidden_at = NULL"
+		}
+	}
+
+	query += fmt.Sprintf(" WHERE user_id = $%d AND conversation_type = $%d AND target_id = $%d RETURNING "+conversationSettingColumns, argIndex, argIndex+1, argIndex+2)
+	args = append(args, userID, req.ConversationType, req.TargetID)
+
+	return scanConversationSetting(r.DB.QueryRow(query, args...))
+}
+
+// ClearMarkedUnread 清除手动标记的未读状态（用户阅读会话时调用）
+func (r *ConversationSettingRepository) ClearMarkedUnread(userID int, conversationType string, targetID int) error {
+	query := `
+		UPDATE conversation_settings
+		SET marked_unread = false, updated_at = $4
+		WHERE user_id = $1 AND conversation_type = $2 AND target_id = $3 AND marked_unread = true
+	`
+
+	_, err := r.DB.Exec(query, userID, conversationType, targetID, time.Now().UTC())
+	return err
+}
+
+// Delete 删除会话设置（恢复默认）
+func (r *ConversationSettingRepository) Delete(userID int, conversationType string, targetID int) error {
+	query := `
+		DELETE FROM conversation_settings
+		WHERE user_id = $1 AND conversation_type = $2 AND target_id = $3
+	`
+
+	_, err := r.DB.Exec(query, userID, conversationType, targetID)
+	return err
+}
@@ -0,0 +1,445 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MessageTypeEncrypted 端到端加密消息（content 为加密信封，服务端只校验结构，不解密）
+const MessageTypeEncrypted = "encrypted"
+
+// EncryptedMessagePreview 加密消息在会话列表、收藏、导出等服务端功能中的展示文本
+const EncryptedMessagePreview = "[加密消息]"
+
+// 端到端加密限制
+const (
+	MaxEncryptedContentSize    = 64 * 1024 // 加密信封最大字节数
+	MaxEncryptedRecipients     = 50        // 单条消息最多包含的设备密文数
+	MaxE2EEDevicesPerUser      = 10        // 每个用户最多登记的加密设备数
+	MaxOneTimePrekeysPerUpload = 100       // 单次上传的一次性预共享公钥数量上限
+	MaxOneTimePrekeysPerDevice = 500       // 每台设备保存的一次性预共享公钥数量上限
+	E2EEPrekeyLowThreshold     = 10        // 一次性预共享公钥低于该数量时提醒设备补充
+)
+
+// 加密信封中的密文类型
+const (
+	EncryptedPayloadPrekey  = 1 // 携带 X3DH 初始密钥协商信息的首条消息
+	EncryptedPayloadMessage = 2 // 已建立会话后的普通密文
+)
+
+// 密钥变更原因（推送给联系人的 e2ee_key_changed 通知）
+const (
+	E2EEKeyChangeDeviceAdded     = "device_added"     // 新增加密设备
+	E2EEKeyChangeIdentityChanged = "identity_changed" // 设备身份公钥变更（重装或重置）
+	E2EEKeyChangeDeviceRemoved   = "device_removed"   // 加密设备被移除
+)
+
+var e2eeDeviceIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// E2EEDevice 加密设备密钥包（公开部分）
+type E2EEDevice struct {
+	ID                    int       `json:"-" db:"id"`
+	UserID                int       `json:"user_id" db:"user_id"`
+	DeviceID              string    `json:"device_id" db:"device_id"`
+	RegistrationID        int       `json:"registration_id" db:"registration_id"`
+	IdentityKey           string    `json:"identity_key" db:"identity_key"`
+	SignedPrekeyID        int       `json:"signed_prekey_id" db:"signed_prekey_id"`
+	SignedPrekey          string    `json:"signed_prekey" db:"signed_prekey"`
+	SignedPrekeySignature string    `json:"signed_prekey_signature" db:"signed_prekey_signature"`
+	CreatedAt             time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// E2EEPrekey 一次性预共享公钥
+type E2EEPrekey struct {
+	KeyID     int    `json:"key_id"`
+	PublicKey string `json:"public_key"`
+}
+
+// E2EEPrekeyBundle 建立会话所需的设备密钥包
+type E2EEPrekeyBundle struct {
+	E2EEDevice
+	OneTimePrekey *E2EEPrekey `json:"one_time_prekey"` // 已耗尽时为空，发送方只用签名预共享公钥协商
+}
+
+// PublishE2EEDeviceRequest 发布设备密钥包请求
+type PublishE2EEDeviceRequest struct {
+	RegistrationID        int          `json:"registration_id" binding:"required"`
+	IdentityKey           string       `json:"identity_key" binding:"required"`
+	SignedPrekeyID        int          `json:"signed_prekey_id"`
+	SignedPrekey          string       `json:"signed_prekey" binding:"required"`
+	SignedPrekeySignature string       `json:"signed_prekey_signature" binding:"required"`
+	OneTimePrekeys        []E2EEPrekey `json:"one_time_prekeys"`
+}
+
+// UploadE2EEPrekeysRequest 补充一次性预共享公钥请求
+type UploadE2EEPrekeysRequest struct {
+	OneTimePrekeys []E2EEPrekey `json:"one_time_prekeys" binding:"required"`
+}
+
+// EncryptedPayload 发给某台设备的密文
+type EncryptedPayload struct {
+	UserID   int    `json:"user_id"`   // 设备所属用户（接收者或发送者本人的其他设备）
+	DeviceID string `json:"device_id"` // 目标设备
+	Type     int    `json:"type"`      // 1 首条协商消息，2 普通密文
+	Body     string `json:"body"`      // Base64 密文，服务端不解析
+}
+
+// EncryptedEnvelope 加密消息信封
+type EncryptedEnvelope struct {
+	Version      int                `json:"v"`
+	SenderDevice string             `json:"sender_device"`
+	Messages     []EncryptedPayload `json:"messages"`
+}
+
+// ValidE2EEDeviceID 校验设备标识
+func ValidE2EEDeviceID(deviceID string) bool {
+	return e2eeDeviceIDPattern.MatchString(deviceID)
+}
+
+// validE2EEKey 校验 Base64 编码的公钥或签名长度
+func validE2EEKey(value string, sizes ...int) bool {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return false
+	}
+	for _, size := range sizes {
+		if len(data) == size {
+			return true
+		}
+	}
+	return false
+}
+
+// validE2EEPublicKey 校验 Curve25519 公钥（32 字节，或带 1 字节类型前缀的 33 字节）
+func validE2EEPublicKey(value string) bool {
+	return validE2EEKey(value, 32, 33)
+}
+
+// Validate 校验设备密钥包格式
+func (req *PublishE2EEDeviceRequest) Validate() error {
+	if req.RegistrationID <= 0 {
+		return errors.New("注册ID无效")
+	}
+	if !validE2EEPublicKey(req.IdentityKey) {
+		return errors.New("身份公钥格式错误")
+	}
+	if req.SignedPrekeyID < 0 || !validE2EEPublicKey(req.SignedPrekey) {
+		return errors.New("签名预共享公钥格式错误")
+	}
+	if !validE2EEKey(req.SignedPrekeySignature, 64) {
+		return errors.New("签名预共享公钥的签名格式错误")
+	}
+	return ValidateE2EEPrekeys(req.OneTimePrekeys)
+}
+
+// ValidateE2EEPrekeys 校验一次性预共享公钥列表
+func ValidateE2EEPrekeys(prekeys []E2EEPrekey) error {
+	if len(prekeys) > MaxOneTimePrekeysPerUpload {
+		return errors.New("单次上传的一次性预共享公钥过多")
+	}
+	seen := make(map[int]bool, len(prekeys))
+	for _, prekey := range prekeys {
+		if prekey.KeyID < 0 || seen[prekey.KeyID] {
+			return errors.New("一次性预共享公钥ID无效或重复")
+		}
+		seen[prekey.KeyID] = true
+		if !validE2EEPublicKey(prekey.PublicKey) {
+			return errors.New("一次性预共享公钥格式错误")
+		}
+	}
+	return nil
+}
+
+// ParseEncryptedEnvelope 解析并校验加密消息信封
+// 只检查信封结构、大小和目标设备，密文本身不做任何解析
+func ParseEncryptedEnvelope(content string) (*EncryptedEnvelope, error) {
+	if len(content) > MaxEncryptedContentSize {
+		return nil, errors.New("加密消息过大")
+	}
+
+	var envelope EncryptedEnvelope
+	if err := json.Unmarshal([]byte(content), &envelope); err != nil {
+		return nil, errors.New("加密消息格式错误")
+	}
+	if envelope.Version != 1 {
+		return nil, errors.New("不支持的加密消息版本")
+	}
+	if !ValidE2EEDeviceID(envelope.SenderDevice) {
+		return nil, errors.New("发送设备标识无效")
+	}
+	if len(envelope.Messages) == 0 {
+		return nil, errors.New("加密消息缺少密文")
+	}
+	if len(envelope.Messages) > MaxEncryptedRecipients {
+		return nil, errors.New("加密消息的目标设备过多")
+	}
+
+	for _, payload := range envelope.Messages {
+		if payload.UserID <= 0 || !ValidE2EEDeviceID(payload.DeviceID) {
+			return nil, errors.New("密文目标设备无效")
+		}
+		if payload.Type != EncryptedPayloadPrekey && payload.Type != EncryptedPayloadMessage {
+			return nil, errors.New("密文类型无效")
+		}
+		if payload.Body == "" {
+			return nil, errors.New("密文为空")
+		}
+		if _, err := base64.StdEncoding.DecodeString(payload.Body); err != nil {
+			return nil, errors.New("密文编码错误")
+		}
+	}
+
+	return &envelope, nil
+}
+
+// E2EERepository 端到端加密公钥目录仓库
+type E2EERepository struct {
+	DB *sql.DB
+}
+
+// NewE2EERepository 创建端到端加密仓库
+func NewE2EERepository(db *sql.DB) *E2EERepository {
+	return &E2EERepository{DB: db}
+}
+
+const e2eeDeviceColumns = `id, user_id, device_id, registration_id, identity_key, signed_prekey_id, signed_prekey, signed_prekey_signature, created_at, updated_at`
+
+func scanE2EEDevice(scanner interface{ Scan(...interface{}) error }) (*E2EEDevice, error) {
+	device := &E2EEDevice{}
+	err := scanner.Scan(
+		&device.ID,
+		&device.UserID,
+		&device.DeviceID,
+		&device.RegistrationID,
+		&device.IdentityKey,
+		&device.SignedPrekeyID,
+		&device.SignedPrekey,
+		&device.SignedPrekeySignature,
+		&device.CreatedAt,
+		&device.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// ErrE2EEDeviceLimitReached 加密设备数量已达上限
+var ErrE2EEDeviceLimitReached = errors.New("加密设备数量已达上限")
+
+// PublishDevice 发布或更新设备密钥包
+// 返回更新前的身份公钥（新设备为空）；身份公钥变更时清空该设备旧的一次性预共享公钥
+func (r *E2EERepository) PublishDevice(device *E2EEDevice, prekeys []E2EEPrekey) (string, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var previousIdentityKey string
+	err = tx.QueryRow(`
+		SELECT identity_key FROM e2ee_devices
+		WHERE user_id = $1 AND device_id = $2
+		FOR UPDATE
+	`, device.UserID, device.DeviceID).Scan(&previousIdentityKey)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	if err == sql.ErrNoRows {
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM e2ee_devices WHERE user_id = $1`, device.UserID).Scan(&count); err != nil {
+			return "", err
+		}
+		if count >= MaxE2EEDevicesPerUser {
+			return "", ErrE2EEDeviceLimitReached
+		}
+	} else if previousIdentityKey != device.IdentityKey {
+		if _, err := tx.Exec(`DELETE FROM e2ee_one_time_prekeys WHERE user_id = $1 AND device_id = $2`, device.UserID, device.DeviceID); err != nil {
+			return "", err
+		}
+	}
+
+	now := time.Now().UTC()
+	saved, err := scanE2EEDevice(tx.QueryRow(`
+		INSERT INTO e2ee_devices (user_id, device_id, registration_id, identity_key, signed_prekey_id, signed_prekey, signed_prekey_signature, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (user_id, device_id) DO UPDATE
+		SET registration_id = EXCLUDED.registration_id, identity_key = EXCLUDED.identity_key,
+		    signed_prekey_id = EXCLUDED.signed_prekey_id, signed_prekey = EXCLUDED.signed_prekey,
+		    signed_prekey_signature = EXCLUDED.signed_prekey_signature, updated_at = EXCLUDED.updated_at
+		RETURNING `+e2eeDeviceColumns,
+		device.UserID, device.DeviceID, device.RegistrationID, device.IdentityKey,
+		device.SignedPrekeyID, device.SignedPrekey, device.SignedPrekeySignature, now))
+	if err != nil {
+		return "", err
+	}
+	*device = *saved
+
+	if err := addOneTimePrekeys(tx, device.UserID, device.DeviceID, prekeys); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return previousIdentityKey, nil
+}
+
+// addOneTimePrekeys 批量写入一次性预共享公钥，已存在的 key_id 忽略
+func addOneTimePrekeys(tx *sql.Tx, userID int, deviceID string, prekeys []E2EEPrekey) error {
+	now := time.Now().UTC()
+	for _, prekey := range prekeys {
+		_, err := tx.Exec(`
+			INSERT INTO e2ee_one_time_prekeys (user_id, device_id, key_id, public_key, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, device_id, key_id) DO NOTHING
+		`, userID, deviceID, prekey.KeyID, prekey.PublicKey, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddOneTimePrekeys 补充一次性预共享公钥，超过单设备上限时返回错误
+func (r *E2EERepository) AddOneTimePrekeys(userID int, deviceID string, prekeys []E2EEPrekey) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM e2ee_one_time_prekeys WHERE user_id = $1 AND device_id = $2
+	`, userID, deviceID).Scan(&count); err != nil {
+		return err
+	}
+	if count+len(prekeys) > MaxOneTimePrekeysPerDevice {
+		return errors.New("一次性预共享公钥数量超过上限")
+	}
+
+	if err := addOneTimePrekeys(tx, userID, deviceID, prekeys); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountOneTimePrekeys 获取设备剩余的一次性预共享公钥数量
+func (r *E2EERepository) CountOneTimePrekeys(userID int, deviceID string) (int, error) {
+	var count int
+	err := r.DB.QueryRow(`
+		SELECT COUNT(*) FROM e2ee_one_time_prekeys WHERE user_id = $1 AND device_id = $2
+	`, userID, deviceID).Scan(&count)
+	return count, err
+}
+
+// ClaimOneTimePrekey 取出并删除设备最早上传的一个一次性预共享公钥，已耗尽时返回 nil
+func (r *E2EERepository) ClaimOneTimePrekey(userID int, deviceID string) (*E2EEPrekey, error) {
+	prekey := &E2EEPrekey{}
+	err := r.DB.QueryRow(`
+		DELETE FROM e2ee_one_time_prekeys
+		WHERE id = (
+			SELECT id FROM e2ee_one_time_prekeys
+			WHERE user_id = $1 AND device_id = $2
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING key_id, public_key
+	`, userID, deviceID).Scan(&prekey.KeyID, &prekey.PublicKey)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return prekey, nil
+}
+
+// ListDevices 获取用户的所有加密设备
+func (r *E2EERepository) ListDevices(userID int) ([]E2EEDevice, error) {
+	rows, err := r.DB.Query(`SELECT `+e2eeDeviceColumns+` FROM e2ee_devices WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []E2EEDevice{}
+	for rows.Next() {
+		device, err := scanE2EEDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *device)
+	}
+	return devices, nil
+}
+
+// GetDevice 获取单个加密设备
+func (r *E2EERepository) GetDevice(userID int, deviceID string) (*E2EEDevice, error) {
+	return scanE2EEDevice(r.DB.QueryRow(`SELECT `+e2eeDeviceColumns+` FROM e2ee_devices WHERE user_id = $1 AND device_id = $2`, userID, deviceID))
+}
+
+// DeleteDevice 移除加密设备及其一次性预共享公钥
+func (r *E2EERepository) DeleteDevice(userID int, deviceID string) (bool, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM e2ee_one_time_prekeys WHERE user_id = $1 AND device_id = $2`, userID, deviceID); err != nil {
+		return false, err
+	}
+	result, err := tx.Exec(`DELETE FROM e2ee_devices WHERE user_id = $1 AND device_id = $2`, userID, deviceID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// DeviceIDSet 获取若干用户已登记的设备，返回 user_id -> device_id 集合
+func (r *E2EERepository) DeviceIDSet(userIDs ...int) (map[int]map[string]bool, error) {
+	result := make(map[int]map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		result[userID] = map[string]bool{}
+	}
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, len(userIDs))
+	args := make([]interface{}, len(userIDs))
+	for i, userID := range userIDs {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = userID
+	}
+	rows, err := r.DB.Query(`SELECT user_id, device_id FROM e2ee_devices WHERE user_id IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int
+		var deviceID string
+		if err := rows.Scan(&userID, &deviceID); err != nil {
+			return nil, err
+		}
+		result[userID][deviceID] = true
+	}
+	return result, nil
+}
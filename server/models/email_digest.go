@@ -0,0 +1,319 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// 邮件摘要发送频率
+const (
+	EmailDigestFrequencyDaily  = "daily"  // 每天最多一封
+	EmailDigestFrequencyWeekly = "weekly" // 每周最多一封
+)
+
+// EmailDigestLookback 摘要只统计该时间范围内的未读消息，避免把很久以前的积压消息发给用户
+const EmailDigestLookback = 7 * 24 * time.Hour
+
+// IsValidEmailDigestFrequency 检查发送频率是否有效
+func IsValidEmailDigestFrequency(frequency string) bool {
+	return frequency == EmailDigestFrequencyDaily || frequency == EmailDigestFrequencyWeekly
+}
+
+// EmailDigestInterval 发送频率对应的最小发送间隔
+func EmailDigestInterval(frequency string) time.Duration {
+	if frequency == EmailDigestFrequencyWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// EmailDigestSetting 用户的邮件摘要设置
+type EmailDigestSetting struct {
+	UserID     int        `json:"user_id" db:"user_id"`
+	Enabled    bool       `json:"enabled" db:"enabled"`
+	Frequency  string     `json:"frequency" db:"frequency"` // daily, weekly
+	LastSentAt *time.Time `json:"last_sent_at,omitempty" db:"last_sent_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// UpdateEmailDigestSettingRequest 更新邮件摘要设置请求（字段为空表示不修改）
+type UpdateEmailDigestSettingRequest struct {
+	Enabled   *bool   `json:"enabled"`
+	Frequency *string `json:"frequency"`
+}
+
+// EmailDigestCandidate 待发送摘要的用户
+type EmailDigestCandidate struct {
+	UserID     int
+	Username   string
+	FullName   *string
+	Email      string
+	Frequency  string
+	LastSentAt *time.Time
+}
+
+// DisplayName 优先使用全名
+func (c *EmailDigestCandidate) DisplayName() string {
+	if c.FullName != nil && *c.FullName != "" {
+		return *c.FullName
+	}
+	return c.Username
+}
+
+// Since 摘要统计的起始时间：上次发送之后，且不早于回看范围
+func (c *EmailDigestCandidate) Since(now time.Time) time.Time {
+	since := now.Add(-EmailDigestLookback)
+	if c.LastSentAt != nil && c.LastSentAt.After(since) {
+		since = *c.LastSentAt
+	}
+	return since
+}
+
+// EmailDigestSummary 单个会话的未读统计
+type EmailDigestSummary struct {
+	ConversationType string // user, group
+	TargetID         int    // 私聊为发送者ID，群聊为群组ID
+	Title            string
+	Count            int
+	LatestAt         time.Time
+}
+
+// EmailDigestSnippet 摘要中的一条消息
+type EmailDigestSnippet struct {
+	SenderName  string
+	MessageType string
+	Content     string
+}
+
+// EmailDigestRepository 邮件摘要数据仓库
+type EmailDigestRepository struct {
+	DB *sql.DB
+}
+
+// NewEmailDigestRepository 创建邮件摘要仓库
+func NewEmailDigestRepository(db *sql.DB) *EmailDigestRepository {
+	return &EmailDigestRepository{DB: db}
+}
+
+// GetSetting 获取用户的邮件摘要设置（未设置时返回默认设置）
+func (r *EmailDigestRepository) GetSetting(userID int) (*EmailDigestSetting, error) {
+	s := &EmailDigestSetting{UserID: userID}
+	err := r.DB.QueryRow(`
+		SELECT enabled, frequency, last_sent_at, updated_at
+		FROM email_digest_settings
+		WHERE user_id = $1
+	`, userID).Scan(&s.Enabled, &s.Frequency, &s.LastSentAt, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		s.Enabled = true
+		s.Frequency = EmailDigestFrequencyDaily
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SaveSetting 保存用户的邮件摘要设置
+func (r *EmailDigestRepository) SaveSetting(setting *EmailDigestSetting) error {
+	now := time.Now().UTC()
+	_, err := r.DB.Exec(`
+		INSERT INTO email_digest_settings (user_id, enabled, frequency, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			frequency = EXCLUDED.frequency,
+			updated_at = EXCLUDED.updated_at
+	`, setting.UserID, setting.Enabled, setting.Frequency, now)
+	if err != nil {
+		return err
+	}
+	setting.UpdatedAt = &now
+	return nil
+}
+
+// MarkSent 记录摘要发送时间
+func (r *EmailDigestRepository) MarkSent(userID int, sentAt time.Time) error {
+	_, err := r.DB.Exec(`
+		INSERT INTO email_digest_settings (user_id, last_sent_at, updated_at)
+		VALUES ($1, $2, $2)
+		ON CONFLICT (user_id) DO UPDATE SET last_sent_at = EXCLUDED.last_sent_at
+	`, userID, sentAt)
+	return err
+}
+
+// ListCandidates 获取离线时间超过 offlineBefore、已到发送间隔且有未读私聊或@消息的用户（按用户ID分页，afterID 为上一页最后一个用户ID）
+func (r *EmailDigestRepository) ListCandidates(offlineBefore, now time.Time, afterID, limit int) ([]EmailDigestCandidate, error) {
+	query := `
+		SELECT u.id, u.username, u.full_name, u.email, COALESCE(s.frequency, 'daily'), s.last_sent_at
+		FROM users u
+		LEFT JOIN email_digest_settings s ON s.user_id = u.id
+		WHERE u.id > $5
+		  AND u.email IS NOT NULL AND u.email <> ''
+		  AND u.is_bot = false
+		  AND COALESCE(s.enabled, true)
+		  AND COALESCE(u.last_seen_at, u.last_login_at, u.created_at) < $1
+		  AND (s.last_sent_at IS NULL
+		       OR (s.frequency = 'weekly' AND s.last_sent_at < $2)
+		       OR (s.frequency <> 'weekly' AND s.last_sent_at < $3))
+		  AND (
+		      EXISTS (
+		          SELECT 1 FROM messages m
+		          WHERE m.receiver_id = u.id AND m.is_read = false AND m.status != 'recalled'
+		            AND m.created_at > GREATEST(COALESCE(s.last_sent_at, $4), $4)
+		      )
+		      OR EXISTS (
+		          SELECT 1 FROM group_message_mentions mm
+		          WHERE mm.user_id = u.id AND mm.is_read = false
+		            AND mm.created_at > GREATEST(COALESCE(s.last_sent_at, $4), $4)
+		      )
+		  )
+		ORDER BY u.id
+		LIMIT $6
+	`
+
+	rows, err := r.DB.Query(query,
+		offlineBefore,
+		now.Add(-EmailDigestInterval(EmailDigestFrequencyWeekly)),
+		now.Add(-EmailDigestInterval(EmailDigestFrequencyDaily)),
+		now.Add(-EmailDigestLookback),
+		afterID,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := []EmailDigestCandidate{}
+	for rows.Next() {
+		var c EmailDigestCandidate
+		if err := rows.Scan(&c.UserID, &c.Username, &c.FullName, &c.Email, &c.Frequency, &c.LastSentAt); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// privateDigestFilter 私聊未读消息过滤条件（$1 接收者ID，$2 起始时间，$3 接收者ID字符串）
+// 排除已撤回、已被接收者删除、通话记录以及接收者设置了免打扰的会话
+const privateDigestFilter = `
+	m.receiver_id = $1 AND m.is_read = false AND m.created_at > $2
+	AND m.status != 'recalled'
+	AND (m.deleted_by_users = '' OR m.deleted_by_users NOT LIKE '%' || $3 || '%')
+	AND m.message_type NOT LIKE 'call\_%'
+	AND NOT EXISTS (
+		SELECT 1 FROM conversation_settings cs
+		WHERE cs.user_id = $1 AND cs.conversation_type = 'user' AND cs.target_id = m.sender_id
+		  AND cs.muted_until > NOW() AT TIME ZONE 'UTC'
+	)
+`
+
+// PrivateSummaries 按发送者统计私聊未读消息（最近的会话在前）
+func (r *EmailDigestRepository) PrivateSummaries(userID int, since time.Time) ([]EmailDigestSummary, error) {
+	query := `
+		SELECT m.sender_id, COALESCE(MAX(m.sender_name), ''), COUNT(*), MAX(m.created_at)
+		FROM messages m
+		WHERE ` + privateDigestFilter + `
+		GROUP BY m.sender_id
+		ORDER BY MAX(m.created_at) DESC
+	`
+
+	rows, err := r.DB.Query(query, userID, since, strconv.Itoa(userID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []EmailDigestSummary{}
+	for rows.Next() {
+		s := EmailDigestSummary{ConversationType: ConversationTypeUser}
+		if err := rows.Scan(&s.TargetID, &s.Title, &s.Count, &s.LatestAt); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// PrivateSnippets 获取某个私聊会话最近的未读消息
+func (r *EmailDigestRepository) PrivateSnippets(userID, senderID int, since time.Time, limit int) ([]EmailDigestSnippet, error) {
+	query := `
+		SELECT COALESCE(m.sender_name, ''), m.message_type, m.content
+		FROM messages m
+		WHERE ` + privateDigestFilter + ` AND m.sender_id = $4
+		ORDER BY m.created_at DESC
+		LIMIT $5
+	`
+
+	rows, err := r.DB.Query(query, userID, since, strconv.Itoa(userID), senderID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanEmailDigestSnippets(rows)
+}
+
+// MentionSummaries 按群组统计未读的@我的消息（最近的群组在前）
+func (r *EmailDigestRepository) MentionSummaries(userID int, since time.Time) ([]EmailDigestSummary, error) {
+	query := `
+		SELECT mm.group_id, COALESCE(g.name, ''), COUNT(*), MAX(mm.created_at)
+		FROM group_message_mentions mm
+		JOIN group_messages gm ON gm.id = mm.group_message_id
+		LEFT JOIN groups g ON g.id = mm.group_id
+		WHERE mm.user_id = $1 AND mm.is_read = false AND mm.created_at > $2
+		  AND COALESCE(gm.status, '') != 'recalled'
+		GROUP BY mm.group_id, g.name
+		ORDER BY MAX(mm.created_at) DESC
+	`
+
+	rows, err := r.DB.Query(query, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []EmailDigestSummary{}
+	for rows.Next() {
+		s := EmailDigestSummary{ConversationType: ConversationTypeGroup}
+		if err := rows.Scan(&s.TargetID, &s.Title, &s.Count, &s.LatestAt); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// MentionSnippets 获取某个群组最近的未读@我的消息
+func (r *EmailDigestRepository) MentionSnippets(userID, groupID int, since time.Time, limit int) ([]EmailDigestSnippet, error) {
+	query := `
+		SELECT COALESCE(gm.sender_name, ''), gm.message_type, gm.content
+		FROM group_message_mentions mm
+		JOIN group_messages gm ON gm.id = mm.group_message_id
+		WHERE mm.user_id = $1 AND mm.group_id = $2 AND mm.is_read = false AND mm.created_at > $3
+		  AND COALESCE(gm.status, '') != 'recalled'
+		ORDER BY mm.created_at DESC
+		LIMIT $4
+	`
+
+	rows, err := r.DB.Query(query, userID, groupID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanEmailDigestSnippets(rows)
+}
+
+func scanEmailDigestSnippets(rows *sql.Rows) ([]EmailDigestSnippet, error) {
+	snippets := []EmailDigestSnippet{}
+	for rows.Next() {
+		var s EmailDigestSnippet
+		if err := rows.Scan(&s.SenderName, &s.MessageType, &s.Content); err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	return snippets, rows.Err()
+}
@@ -0,0 +1,335 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 事件类型
+const (
+	EventGroupMessageSent  = "group.message_sent"  // 群组中发送了消息
+	EventGroupMemberJoined = "group.member_joined" // 成员加入群组（主动加入、被邀请、审核通过）
+	EventGroupMemberLeft   = "group.member_left"   // 成员离开群组（主动退出、被移除）
+	EventContactApproved   = "contact.approved"    // 联系人申请通过
+	EventCallEnded         = "call.ended"          // 通话结束
+)
+
+// EventTypeAll 订阅全部事件
+const EventTypeAll = "*"
+
+// ValidEventTypes 支持订阅的事件类型
+var ValidEventTypes = map[string]bool{
+	EventGroupMessageSent:  true,
+	EventGroupMemberJoined: true,
+	EventGroupMemberLeft:   true,
+	EventContactApproved:   true,
+	EventCallEnded:         true,
+}
+
+// 事件投递状态
+const (
+	EventDeliveryPending = "pending" // 待投递/等待重试
+	EventDeliverySuccess = "success" // 投递成功
+	EventDeliveryFailed  = "failed"  // 重试耗尽
+)
+
+// EventSubscription 事件订阅（Outgoing Webhook）
+type EventSubscription struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	URL       string    `json:"url" db:"url"`
+	Secret    string    `json:"-" db:"secret"`
+	Events    []string  `json:"events" db:"events"`
+	GroupID   *int      `json:"group_id,omitempty" db:"group_id"` // 为空表示全局订阅
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Matches 判断订阅是否接收该事件（groupID 为 0 表示非群组事件，只投递给全局订阅）
+func (s *EventSubscription) Matches(eventType string, groupID int) bool {
+	if !s.Enabled {
+		return false
+	}
+	if s.GroupID != nil && *s.GroupID != groupID {
+		return false
+	}
+	for _, e := range s.Events {
+		if e == EventTypeAll || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveEventSubscriptionRequest 创建/更新事件订阅请求
+type SaveEventSubscriptionRequest struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url" binding:"required"`
+	Secret  string   `json:"secret"` // 创建时为空则自动生成
+	Events  []string `json:"events"` // 为空表示全部事件
+	GroupID *int     `json:"group_id"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// EventDelivery 事件投递记录
+type EventDelivery struct {
+	ID             int        `json:"id" db:"id"`
+	SubscriptionID int        `json:"subscription_id" db:"subscription_id"`
+	EventID        string     `json:"event_id" db:"event_id"`
+	EventType      string     `json:"event_type" db:"event_type"`
+	Payload        string     `json:"payload" db:"payload"`
+	Status         string     `json:"status" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	ResponseStatus *int       `json:"response_status,omitempty" db:"response_status"`
+	LastError      *string    `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// EventSubscriptionRepository 事件订阅数据仓库
+type EventSubscriptionRepository struct {
+	DB *sql.DB
+}
+
+// NewEventSubscriptionRepository 创建事件订阅仓库
+func NewEventSubscriptionRepository(db *sql.DB) *EventSubscriptionRepository {
+	return &EventSubscriptionRepository{DB: db}
+}
+
+const eventSubscriptionColumns = `id, name, url, secret, events, group_id, enabled, created_at, updated_at`
+
+func scanEventSubscription(scanner interface{ Scan(...interface{}) error }) (*EventSubscription, error) {
+	sub := &EventSubscription{}
+	var events string
+	err := scanner.Scan(
+		&sub.ID,
+		&sub.Name,
+		&sub.URL,
+		&sub.Secret,
+		&events,
+		&sub.GroupID,
+		&sub.Enabled,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	sub.Events = strings.Split(events, ",")
+	return sub, nil
+}
+
+func eventsColumn(events []string) string {
+	if len(events) == 0 {
+		return EventTypeAll
+	}
+	return strings.Join(events, ",")
+}
+
+// ListSubscriptions 获取事件订阅，enabledOnly 为 true 时只返回启用的订阅
+func (r *EventSubscriptionRepository) ListSubscriptions(enabledOnly bool) ([]EventSubscription, error) {
+	query := `SELECT ` + eventSubscriptionColumns + ` FROM event_subscriptions`
+	if enabledOnly {
+		query += ` WHERE enabled = true`
+	}
+	query += ` ORDER BY id`
+
+	rows, err := r.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []EventSubscription{}
+	for rows.Next() {
+		sub, err := scanEventSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, nil
+}
+
+// GetSubscription 获取事件订阅
+func (r *EventSubscriptionRepository) GetSubscription(subID int) (*EventSubscription, error) {
+	return scanEventSubscription(r.DB.QueryRow(`SELECT `+eventSubscriptionColumns+` FROM event_subscriptions WHERE id = $1`, subID))
+}
+
+// CreateSubscription 创建事件订阅
+func (r *EventSubscriptionRepository) CreateSubscription(sub *EventSubscription) (*EventSubscription, error) {
+	query := `
+		INSERT INTO event_subscriptions (name, url, secret, events, group_id, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING ` + eventSubscriptionColumns
+	return scanEventSubscription(r.DB.QueryRow(query, sub.Name, sub.URL, sub.Secret, eventsColumn(sub.Events), sub.GroupID, sub.Enabled, time.Now().UTC()))
+}
+
+// UpdateSubscription 更新事件订阅
+func (r *EventSubscriptionRepository) UpdateSubscription(sub *EventSubscription) (*EventSubscription, error) {
+	query := `
+		UPDATE event_subscriptions
+		SET name = $1, url = $2, secret = $3, events = $4, group_id = $5, enabled = $6, updated_at = $7
+		WHERE id = $8
+		RETURNING ` + eventSubscriptionColumns
+	return scanEventSubscription(r.DB.QueryRow(query, sub.Name, sub.URL, sub.Secret, eventsColumn(sub.Events), sub.GroupID, sub.Enabled, time.Now().UTC(), sub.ID))
+}
+
+// DeleteSubscription 删除事件订阅（投递记录级联删除）
+func (r *EventSubscriptionRepository) DeleteSubscription(subID int) (bool, error) {
+	result, err := r.DB.Exec(`DELETE FROM event_subscriptions WHERE id = $1`, subID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// CreateDelivery 创建投递记录，nextAttemptAt 之前定时任务不会处理该记录
+func (r *EventSubscriptionRepository) CreateDelivery(delivery *EventDelivery, nextAttemptAt time.Time) error {
+	return r.DB.QueryRow(`
+		INSERT INTO event_deliveries (subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, 0, $6, $7)
+		RETURNING id, status, created_at
+	`, delivery.SubscriptionID, delivery.EventID, delivery.EventType, delivery.Payload, EventDeliveryPending, nextAttemptAt, time.Now().UTC()).Scan(&delivery.ID, &delivery.Status, &delivery.CreatedAt)
+}
+
+const eventDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, response_status, last_error, next_attempt_at, delivered_at, created_at`
+
+func scanEventDelivery(scanner interface{ Scan(...interface{}) error }) (*EventDelivery, error) {
+	d := &EventDelivery{}
+	err := scanner.Scan(
+		&d.ID,
+		&d.SubscriptionID,
+		&d.EventID,
+		&d.EventType,
+		&d.Payload,
+		&d.Status,
+		&d.Attempts,
+		&d.ResponseStatus,
+		&d.LastError,
+		&d.NextAttemptAt,
+		&d.DeliveredAt,
+		&d.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ClaimDueDeliveries 领取到期待重试的投递记录，领取后 lease 时间内不会被再次领取（多实例部署时避免重复投递）
+func (r *EventSubscriptionRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]EventDelivery, error) {
+	now := time.Now().UTC()
+	rows, err := r.DB.Query(`
+		UPDATE event_deliveries
+		SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM event_deliveries
+			WHERE status = $2 AND next_attempt_at <= $3
+			ORDER BY next_attempt_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+eventDeliveryColumns, now.Add(lease), EventDeliveryPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []EventDelivery{}
+	for rows.Next() {
+		d, err := scanEventDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt 记录一次投递结果
+// 成功时 status 为 success；失败且还可重试时 status 为 pending 并设置 nextAttemptAt；重试耗尽时 status 为 failed
+func (r *EventSubscriptionRepository) RecordAttempt(delivery *EventDelivery) error {
+	var deliveredAt *time.Time
+	if delivery.Status == EventDeliverySuccess {
+		now := time.Now().UTC()
+		deliveredAt = &now
+	}
+	_, err := r.DB.Exec(`
+		UPDATE event_deliveries
+		SET status = $1, attempts = $2, response_status = $3, last_error = $4, next_attempt_at = $5, delivered_at = $6
+		WHERE id = $7
+	`, delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.LastError, delivery.NextAttemptAt, deliveredAt, delivery.ID)
+	return err
+}
+
+// GetDelivery 获取投递记录
+func (r *EventSubscriptionRepository) GetDelivery(deliveryID int) (*EventDelivery, error) {
+	return scanEventDelivery(r.DB.QueryRow(`SELECT `+eventDeliveryColumns+` FROM event_deliveries WHERE id = $1`, deliveryID))
+}
+
+// ListDeliveries 分页查询投递记录，subscriptionID 为 0、status/eventType 为空时不过滤
+func (r *EventSubscriptionRepository) ListDeliveries(subscriptionID int, status, eventType string, limit, offset int) ([]EventDelivery, int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	if subscriptionID > 0 {
+		args = append(args, subscriptionID)
+		conditions = append(conditions, `subscription_id = $`+strconv.Itoa(len(args)))
+	}
+	if status != "" {
+		args = append(args, status)
+		conditions = append(conditions, `status = $`+strconv.Itoa(len(args)))
+	}
+	if eventType != "" {
+		args = append(args, eventType)
+		conditions = append(conditions, `event_type = $`+strconv.Itoa(len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	var total int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM event_deliveries`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	n := len(args)
+	query := `SELECT ` + eventDeliveryColumns + ` FROM event_deliveries` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $` + strconv.Itoa(n+1) + ` OFFSET $` + strconv.Itoa(n+2)
+	rows, err := r.DB.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := []EventDelivery{}
+	for rows.Next() {
+		d, err := scanEventDelivery(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, total, nil
+}
+
+// ResetDelivery 将投递记录重置为待投递（管理员手动重试），重试次数从零开始计算
+func (r *EventSubscriptionRepository) ResetDelivery(deliveryID int) (bool, error) {
+	result, err := r.DB.Exec(`
+		UPDATE event_deliveries
+		SET status = $1, attempts = 0, next_attempt_at = $2
+		WHERE id = $3
+	`, EventDeliveryPending, time.Now().UTC(), deliveryID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
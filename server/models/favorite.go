@@ -31,8 +31,8 @@ func NewFavoriteRepository(db *sql.DB) *FavoriteRepository {
 // Create 创建收藏
 func (r *FavoriteRepository) Create(userID int, messageID *int, content, messageType string, fileName *string, senderID int, senderName string) (*Favorite, error) {
 	query := `
-		INSERT INTO favorites (user_id, message_id, content, message_type, file_name, sender_id, sender_name, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+		INSERT INTO favorites (user_id, message_id, content, message_type, file_name, sender_id, sender_name, plain_text, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP)
 		RETURNING id, user_id, message_id, content, message_type, file_name, sender_id, sender_name, created_at
 	`
 
@@ -46,6 +46,7 @@ func (r *FavoriteRepository) Create(userID int, messageID *int, content, message
 		fileName,
 		senderID,
 		senderName,
+		StoredPlainText(messageType, content),
 	).Scan(
 		&favorite.ID,
 		&favorite.UserID,
@@ -39,27 +39,30 @@ type GroupMember struct {
 
 // GroupMessage 群组消息模型
 type GroupMessage struct {
-	ID                   int       `json:"id" db:"id"`
-	GroupID              int       `json:"group_id" db:"group_id"`
-	SenderID             int       `json:"sender_id" db:"sender_id"`
-	SenderName           string    `json:"sender_name" db:"sender_name"`
-	SenderNickname       *string   `json:"sender_nickname,omitempty" db:"sender_nickname"`   // 发送者在群组中的昵称
-	SenderFullName       *string   `json:"sender_full_name,omitempty" db:"sender_full_name"` // 发送者全名
-	SenderAvatar         *string   `json:"sender_avatar,omitempty" db:"sender_avatar"`       // 发送者头像
-	Content              string    `json:"content" db:"content"`
-	MessageType          string    `json:"message_type" db:"message_type"`
-	FileName             *string   `json:"file_name,omitempty" db:"file_name"`
-	QuotedMessageID      *int      `json:"quoted_message_id,omitempty" db:"quoted_message_id"`
-	QuotedMessageContent *string   `json:"quoted_message_content,omitempty" db:"quoted_message_content"`
-	MentionedUserIDs     *string   `json:"mentioned_user_ids,omitempty" db:"mentioned_user_ids"` // 被@的用户ID列表（逗号分隔的字符串）
-	Mentions             *string   `json:"mentions,omitempty" db:"mentions"`                     // @文本内容（如"@all"或"@张三(zhangsan)"）
-	CallType             *string   `json:"call_type,omitempty" db:"call_type"`                   // 通话类型（voice/video），仅用于call_initiated消息
-	ChannelName          *string   `json:"channel_name,omitempty" db:"channel_name"`             // Agora频道名称，用于加入群组通话
-	VoiceDuration        *int      `json:"voice_duration,omitempty" db:"voice_duration"`         // 语音消息时长（秒）
-	Status               string    `json:"status" db:"status"`
-	DeletedByUsers       string    `json:"deleted_by_users" db:"deleted_by_users"` // 已删除该消息的用户ID列表（逗号分隔）
-	IsRead               bool      `json:"is_read"`                                // 🔴 当前用户是否已读（不存储在数据库，动态计算）
-	CreatedAt            time.Time `json:"-" db:"created_at"`                      // 🔴 不直接序列化，使用 MarshalJSON 方法
+	ID                   int             `json:"id" db:"id"`
+	GroupID              int             `json:"group_id" db:"group_id"`
+	SenderID             int             `json:"sender_id" db:"sender_id"`
+	SenderName           string          `json:"sender_name" db:"sender_name"`
+	SenderNickname       *string         `json:"sender_nickname,omitempty" db:"sender_nickname"`   // 发送者在群组中的昵称
+	SenderFullName       *string         `json:"sender_full_name,omitempty" db:"sender_full_name"` // 发送者全名
+	SenderAvatar         *string         `json:"sender_avatar,omitempty" db:"sender_avatar"`       // 发送者头像
+	Content              string          `json:"content" db:"content"`
+	MessageType          string          `json:"message_type" db:"message_type"`
+	FileName             *string         `json:"file_name,omitempty" db:"file_name"`
+	QuotedMessageID      *int            `json:"quoted_message_id,omitempty" db:"quoted_message_id"`
+	QuotedMessageContent *string         `json:"quoted_message_content,omitempty" db:"quoted_message_content"`
+	MentionedUserIDs     *string         `json:"mentioned_user_ids,omitempty" db:"mentioned_user_ids"` // 被@的用户ID列表（逗号分隔的字符串）
+	Mentions             *string         `json:"mentions,omitempty" db:"mentions"`                     // @文本内容（如"@all"或"@张三(zhangsan)"）
+	CallType             *string         `json:"call_type,omitempty" db:"call_type"`                   // 通话类型（voice/video），仅用于call_initiated消息
+	ChannelName          *string         `json:"channel_name,omitempty" db:"channel_name"`             // Agora频道名称，用于加入群组通话
+	VoiceDuration        *int            `json:"voice_duration,omitempty" db:"voice_duration"`         // 语音消息时长（秒）
+	LinkPreview          json.RawMessage `json:"link_preview,omitempty" db:"link_preview"`             // 链接预览元数据（服务端抓取）
+	Status               string          `json:"status" db:"status"`
+	RecalledBy           *int            `json:"recalled_by,omitempty" db:"recalled_by"` // 撤回操作人ID（群主/管理员撤回时与发送者不同）
+	DeletedByUsers       string          `json:"deleted_by_users" db:"deleted_by_users"` // 已删除该消息的用户ID列表（逗号分隔）
+	IsRead               bool            `json:"is_read"`                                // 🔴 当前用户是否已读（不存储在数据库，动态计算）
+	ReadCount            *int            `json:"read_count,omitempty"`                   // 已读人数（仅发送者自己的消息返回，动态计算）
+	CreatedAt            time.Time       `json:"-" db:"created_at"`                      // 🔴 不直接序列化，使用 MarshalJSON 方法
 }
 
 // MarshalJSON 自定义 JSON 序列化，确保 CreatedAt 使用 UTC 时间
@@ -104,6 +107,7 @@ type CreateGroupMessageRequest struct {
 	MentionedUserIds     []int  `json:"mentioned_user_ids,omitempty"`
 	Mentions             string `json:"mentions,omitempty"`
 	VoiceDuration        int    `json:"voice_duration,omitempty"`
+	Urgent               bool   `json:"urgent,omitempty"` // 加急消息（DING）
 }
 
 // GroupDetailResponse 群组详情响应
@@ -154,20 +158,21 @@ type WSGroupMessage struct {
 
 // WSGroupMessageData WebSocket群组消息数据
 type WSGroupMessageData struct {
-	ID                   int       `json:"id"`
-	GroupID              int       `json:"group_id"`
-	SenderID             int       `json:"sender_id"`
-	SenderName           string    `json:"sender_name"`
-	SenderAvatar         *string   `json:"sender_avatar,omitempty"`
-	Content              string    `json:"content"`
-	MessageType          string    `json:"message_type"`
-	FileName             *string   `json:"file_name,omitempty"`
-	QuotedMessageID      *int      `json:"quoted_message_id,omitempty"`
-	QuotedMessageContent *string   `json:"quoted_message_content,omitempty"`
-	MentionedUserIds     []int     `json:"mentioned_user_ids,omitempty"`
-	Mentions             *string   `json:"mentions,omitempty"`
-	VoiceDuration        *int      `json:"voice_duration,omitempty"`
-	CreatedAt            time.Time `json:"created_at"` // 🔴 UTC 时间，客户端需要转换为本地时区显示
+	ID                   int             `json:"id"`
+	GroupID              int             `json:"group_id"`
+	SenderID             int             `json:"sender_id"`
+	SenderName           string          `json:"sender_name"`
+	SenderAvatar         *string         `json:"sender_avatar,omitempty"`
+	Content              string          `json:"content"`
+	MessageType          string          `json:"message_type"`
+	FileName             *string         `json:"file_name,omitempty"`
+	QuotedMessageID      *int            `json:"quoted_message_id,omitempty"`
+	QuotedMessageContent *string         `json:"quoted_message_content,omitempty"`
+	MentionedUserIds     []int           `json:"mentioned_user_ids,omitempty"`
+	Mentions             *string         `json:"mentions,omitempty"`
+	VoiceDuration        *int            `json:"voice_duration,omitempty"`
+	LinkPreview          json.RawMessage `json:"link_preview,omitempty"` // 链接预览元数据
+	CreatedAt            time.Time       `json:"created_at"`             // 🔴 UTC 时间，客户端需要转换为本地时区显示
 }
 
 // GetCreatedAtUTC 返回 UTC 时间
@@ -657,8 +662,8 @@ func (r *GroupRepository) CreateGroupMessage(msg *CreateGroupMessageRequest, sen
 
 	// 🔴 显式使用 UTC 时间，确保时区一致性
 	query := `
-		INSERT INTO group_messages (group_id, sender_id, sender_name, sender_nickname, sender_full_name, sender_avatar, content, message_type, file_name, quoted_message_id, quoted_message_content, mentioned_user_ids, mentions, voice_duration, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		INSERT INTO group_messages (group_id, sender_id, sender_name, sender_nickname, sender_full_name, sender_avatar, content, message_type, file_name, quoted_message_id, quoted_message_content, mentioned_user_ids, mentions, voice_duration, created_at, plain_text)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		RETURNING id, group_id, sender_id, sender_name, sender_nickname, sender_full_name, sender_avatar, content, message_type, file_name, quoted_message_id, quoted_message_content, mentioned_user_ids, mentions, voice_duration, status, created_at
 	`
 
@@ -705,7 +710,7 @@ func (r *GroupRepository) CreateGroupMessage(msg *CreateGroupMessageRequest, sen
 	message := &GroupMessage{}
 	// 🔴 使用 UTC 时间
 	now := time.Now().UTC()
-	err := r.DB.QueryRow(query, msg.GroupID, senderID, senderName, senderNickname, senderFullName, senderAvatar, msg.Content, messageType, fileName, quotedMessageID, quotedMessageContent, mentionedUserIDs, mentions, voiceDuration, now).Scan(
+	err := r.DB.QueryRow(query, msg.GroupID, senderID, senderName, senderNickname, senderFullName, senderAvatar, msg.Content, messageType, fileName, quotedMessageID, quotedMessageContent, mentionedUserIDs, mentions, voiceDuration, now, StoredPlainText(messageType, msg.Content)).Scan(
 		&message.ID,
 		&message.GroupID,
 		&message.SenderID,
@@ -826,7 +831,7 @@ func (r *GroupRepository) GetGroupMemberIDs(groupID int) ([]int, error) {
 func (r *GroupRepository) MuteGroupMember(groupID, userID int) error {
 	query := `
 		UPDATE group_members
-		SET is_muted = true
+		SET is_muted = true, muted_until = NULL
 		WHERE group_id = $1 AND user_id = $2
 	`
 
@@ -851,7 +856,7 @@ func (r *GroupRepository) MuteGroupMember(groupID, userID int) error {
 func (r *GroupRepository) UnmuteGroupMember(groupID, userID int) error {
 	query := `
 		UPDATE group_members
-		SET is_muted = false
+		SET is_muted = false, muted_until = NULL
 		WHERE group_id = $1 AND user_id = $2
 	`
 
@@ -872,6 +877,63 @@ func (r *GroupRepository) UnmuteGroupMember(groupID, userID int) error {
 	return nil
 }
 
+// MuteGroupMemberUntil 禁言群组成员直到指定时间，到期后由 UnmuteExpiredMembers 自动解除
+func (r *GroupRepository) MuteGroupMemberUntil(groupID, userID int, until time.Time) error {
+	query := `
+		UPDATE group_members
+		SET is_muted = true, muted_until = $3
+		WHERE group_id = $1 AND user_id = $2
+	`
+
+	result, err := r.DB.Exec(query, groupID, userID, until.UTC())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GroupMemberRef 群组成员引用（群组ID + 用户ID）
+type GroupMemberRef struct {
+	GroupID int
+	UserID  int
+}
+
+// UnmuteExpiredMembers 解除所有已到期的定时禁言，返回被解除禁言的成员
+func (r *GroupRepository) UnmuteExpiredMembers() ([]GroupMemberRef, error) {
+	query := `
+		UPDATE group_members
+		SET is_muted = false, muted_until = NULL
+		WHERE muted_until IS NOT NULL AND muted_until <= $1
+		RETURNING group_id, user_id
+	`
+
+	rows, err := r.DB.Query(query, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []GroupMemberRef
+	for rows.Next() {
+		var ref GroupMemberRef
+		if err := rows.Scan(&ref.GroupID, &ref.UserID); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
 // MuteAllNormalMembers 禁言所有普通成员（不包括群主和管理员）
 func (r *GroupRepository) MuteAllNormalMembers(groupID int) error {
 	query := `
@@ -898,14 +960,15 @@ func (r *GroupRepository) UnmuteAllNormalMembers(groupID int) error {
 
 // IsGroupMemberMuted 检查群组成员是否被禁言
 func (r *GroupRepository) IsGroupMemberMuted(groupID, userID int) (bool, error) {
+	// 定时禁言已到期但尚未被定时任务解除时视为未禁言
 	query := `
-		SELECT is_muted
+		SELECT is_muted AND (muted_until IS NULL OR muted_until > $3)
 		FROM group_members
 		WHERE group_id = $1 AND user_id = $2
 	`
 
 	var isMuted bool
-	err := r.DB.QueryRow(query, groupID, userID).Scan(&isMuted)
+	err := r.DB.QueryRow(query, groupID, userID, time.Now().UTC()).Scan(&isMuted)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, fmt.Errorf("用户不是群组成员")
@@ -929,6 +992,27 @@ func (r *GroupRepository) IsGroupMember(groupID, userID int) (bool, error) {
 	return isMember, err
 }
 
+// CanViewMemberProfile 检查用户能否通过共同群组查看另一用户的资料
+// 双方需同在一个未解散的群组中，且该群开启了"群成员查看权限"或查看者是群主/管理员
+func (r *GroupRepository) CanViewMemberProfile(viewerID, targetID int) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM group_members viewer
+			JOIN group_members target ON target.group_id = viewer.group_id
+			JOIN groups g ON g.id = viewer.group_id
+			WHERE viewer.user_id = $1 AND target.user_id = $2
+			  AND viewer.approval_status = 'approved' AND target.approval_status = 'approved'
+			  AND g.deleted_at IS NULL
+			  AND (g.member_view_permission = true OR viewer.role IN ('owner', 'admin'))
+		)
+	`
+
+	var canView bool
+	err := r.DB.QueryRow(query, viewerID, targetID).Scan(&canView)
+	return canView, err
+}
+
 // TransferOwnership 转让群主权限
 func (r *GroupRepository) TransferOwnership(groupID, newOwnerID int) error {
 	// 使用事务确保数据一致性
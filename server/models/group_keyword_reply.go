@@ -0,0 +1,202 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// 关键词自动回复匹配方式
+const (
+	KeywordReplyMatchKeyword = "keyword" // 包含关键词（忽略大小写）
+	KeywordReplyMatchRegex   = "regex"   // 正则表达式
+)
+
+// 关键词自动回复限制
+const (
+	MaxKeywordRepliesPerGroup   = 50    // 每个群最多规则数
+	MaxKeywordReplyPatternLen   = 500   // 关键词/正则最大长度（字符）
+	MaxKeywordReplyResponseLen  = 1000  // 回复内容最大长度（字符）
+	DefaultKeywordReplyCooldown = 60    // 默认冷却时间（秒）
+	MaxKeywordReplyCooldown     = 86400 // 最大冷却时间（秒）
+)
+
+// 关键词自动回复的发送者：不能登录、不属于任何群组的机器人账号，由迁移创建，被删除时自动重建
+const (
+	KeywordReplySenderUsername = "__keyword_reply__"
+	KeywordReplySenderName     = "自动回复"
+)
+
+// ErrKeywordReplySenderTaken 发送者账号的用户名已被普通用户占用
+var ErrKeywordReplySenderTaken = errors.New("关键词自动回复发送者账号的用户名已被占用")
+
+// GroupKeywordReply 群关键词自动回复规则
+type GroupKeywordReply struct {
+	ID              int        `json:"id" db:"id"`
+	GroupID         int        `json:"group_id" db:"group_id"`
+	Pattern         string     `json:"pattern" db:"pattern"`
+	MatchType       string     `json:"match_type" db:"match_type"` // keyword, regex
+	Response        string     `json:"response" db:"response"`
+	CooldownSeconds int        `json:"cooldown_seconds" db:"cooldown_seconds"`
+	Enabled         bool       `json:"enabled" db:"enabled"`
+	HitCount        int        `json:"hit_count" db:"hit_count"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty" db:"last_triggered_at"`
+	CreatedBy       int        `json:"created_by" db:"created_by"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SaveGroupKeywordReplyRequest 创建/更新关键词自动回复请求（更新时字段为空表示不修改）
+type SaveGroupKeywordReplyRequest struct {
+	Pattern         *string `json:"pattern"`
+	MatchType       *string `json:"match_type"`
+	Response        *string `json:"response"`
+	CooldownSeconds *int    `json:"cooldown_seconds"`
+	Enabled         *bool   `json:"enabled"`
+}
+
+// GroupKeywordReplyRepository 群关键词自动回复数据仓库
+type GroupKeywordReplyRepository struct {
+	DB *sql.DB
+}
+
+// NewGroupKeywordReplyRepository 创建群关键词自动回复仓库
+func NewGroupKeywordReplyRepository(db *sql.DB) *GroupKeywordReplyRepository {
+	return &GroupKeywordReplyRepository{DB: db}
+}
+
+// SenderID 获取关键词自动回复发送者账号的ID，账号不存在时创建
+func (r *GroupKeywordReplyRepository) SenderID() (int, error) {
+	var id int
+	err := r.DB.QueryRow(`SELECT id FROM users WHERE username = $1 AND is_bot = true`, KeywordReplySenderUsername).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+
+	now := time.Now().UTC()
+	err = r.DB.QueryRow(`
+		INSERT INTO users (username, full_name, password, avatar, status, is_bot, created_at, updated_at)
+		VALUES ($1, $2, '!', '', 'offline', true, $3, $3)
+		ON CONFLICT (username) DO NOTHING
+		RETURNING id
+	`, KeywordReplySenderUsername, KeywordReplySenderName, now).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+
+	// 并发创建时由另一个请求创建成功，否则用户名被普通用户占用
+	err = r.DB.QueryRow(`SELECT id FROM users WHERE username = $1 AND is_bot = true`, KeywordReplySenderUsername).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrKeywordReplySenderTaken
+	}
+	return id, err
+}
+
+const groupKeywordReplyColumns = `id, group_id, pattern, match_type, response, cooldown_seconds, enabled, hit_count, last_triggered_at, created_by, created_at, updated_at`
+
+func scanGroupKeywordReply(scanner interface{ Scan(...interface{}) error }) (*GroupKeywordReply, error) {
+	rule := &GroupKeywordReply{}
+	err := scanner.Scan(
+		&rule.ID,
+		&rule.GroupID,
+		&rule.Pattern,
+		&rule.MatchType,
+		&rule.Response,
+		&rule.CooldownSeconds,
+		&rule.Enabled,
+		&rule.HitCount,
+		&rule.LastTriggeredAt,
+		&rule.CreatedBy,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// ListByGroup 获取群组的关键词自动回复规则，enabledOnly 为 true 时只返回启用的规则
+func (r *GroupKeywordReplyRepository) ListByGroup(groupID int, enabledOnly bool) ([]GroupKeywordReply, error) {
+	query := `SELECT ` + groupKeywordReplyColumns + ` FROM group_keyword_replies WHERE group_id = $1`
+	if enabledOnly {
+		query += ` AND enabled = true`
+	}
+	query += ` ORDER BY id`
+
+	rows, err := r.DB.Query(query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []GroupKeywordReply{}
+	for rows.Next() {
+		rule, err := scanGroupKeywordReply(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, rows.Err()
+}
+
+// CountByGroup 统计群组的规则数量
+func (r *GroupKeywordReplyRepository) CountByGroup(groupID int) (int, error) {
+	var count int
+	err := r.DB.QueryRow(`SELECT COUNT(*) FROM group_keyword_replies WHERE group_id = $1`, groupID).Scan(&count)
+	return count, err
+}
+
+// GetByID 获取群组中的规则
+func (r *GroupKeywordReplyRepository) GetByID(groupID, ruleID int) (*GroupKeywordReply, error) {
+	query := `SELECT ` + groupKeywordReplyColumns + ` FROM group_keyword_replies WHERE id = $1 AND group_id = $2`
+	return scanGroupKeywordReply(r.DB.QueryRow(query, ruleID, groupID))
+}
+
+// Create 创建规则
+func (r *GroupKeywordReplyRepository) Create(rule *GroupKeywordReply) (*GroupKeywordReply, error) {
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO group_keyword_replies (group_id, pattern, match_type, response, cooldown_seconds, enabled, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		RETURNING ` + groupKeywordReplyColumns
+	return scanGroupKeywordReply(r.DB.QueryRow(query, rule.GroupID, rule.Pattern, rule.MatchType, rule.Response, rule.CooldownSeconds, rule.Enabled, rule.CreatedBy, now))
+}
+
+// Update 更新规则内容、冷却时间和启用状态（触发次数保留）
+func (r *GroupKeywordReplyRepository) Update(rule *GroupKeywordReply) (*GroupKeywordReply, error) {
+	query := `
+		UPDATE group_keyword_replies
+		SET pattern = $1, match_type = $2, response = $3, cooldown_seconds = $4, enabled = $5, updated_at = $6
+		WHERE id = $7 AND group_id = $8
+		RETURNING ` + groupKeywordReplyColumns
+	return scanGroupKeywordReply(r.DB.QueryRow(query, rule.Pattern, rule.MatchType, rule.Response, rule.CooldownSeconds, rule.Enabled, time.Now().UTC(), rule.ID, rule.GroupID))
+}
+
+// Delete 删除规则
+func (r *GroupKeywordReplyRepository) Delete(groupID, ruleID int) (bool, error) {
+	result, err := r.DB.Exec(`DELETE FROM group_keyword_replies WHERE id = $1 AND group_id = $2`, ruleID, groupID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ClaimTrigger 记录规则触发（触发次数加一），返回 false 表示规则已停用或仍在冷却期内
+// 条件更新保证多实例并发命中同一规则时冷却期内只回复一次
+func (r *GroupKeywordReplyRepository) ClaimTrigger(ruleID int) (bool, error) {
+	now := time.Now().UTC()
+	result, err := r.DB.Exec(`
+		UPDATE group_keyword_replies
+		SET hit_count = hit_count + 1, last_triggered_at = $2
+		WHERE id = $1 AND enabled = true
+		  AND (last_triggered_at IS NULL OR last_triggered_at <= $2 - cooldown_seconds * INTERVAL '1 second')
+	`, ruleID, now)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
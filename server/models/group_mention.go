@@ -0,0 +1,239 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GroupMention "@我的"消息记录
+type GroupMention struct {
+	ID             int       `json:"id" db:"id"`
+	GroupMessageID int       `json:"group_message_id" db:"group_message_id"`
+	GroupID        int       `json:"group_id" db:"group_id"`
+	GroupName      string    `json:"group_name"`
+	GroupAvatar    *string   `json:"group_avatar,omitempty"`
+	SenderID       int       `json:"sender_id" db:"sender_id"`
+	SenderName     string    `json:"sender_name"`
+	SenderAvatar   *string   `json:"sender_avatar,omitempty"`
+	Content        string    `json:"content"` // 消息预览文本
+	MessageType    string    `json:"message_type"`
+	MessageStatus  string    `json:"message_status"` // 消息状态（撤回后为 recalled）
+	IsMentionAll   bool      `json:"is_mention_all" db:"is_mention_all"`
+	IsRead         bool      `json:"is_read" db:"is_read"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// MarkMentionsReadRequest 标记@消息已读请求（ids、group_id、all 三选一）
+type MarkMentionsReadRequest struct {
+	IDs     []int `json:"ids"`
+	GroupID int   `json:"group_id"`
+	All     bool  `json:"all"`
+}
+
+// GroupMentionRepository 群消息@提及数据仓库
+type GroupMentionRepository struct {
+	DB *sql.DB
+}
+
+// NewGroupMentionRepository 创建群消息@提及仓库
+func NewGroupMentionRepository(db *sql.DB) *GroupMentionRepository {
+	return &GroupMentionRepository{DB: db}
+}
+
+// IsMentionAll 判断群消息是否 @所有人
+func (m *GroupMessage) IsMentionAll() bool {
+	return m.Mentions != nil && strings.Contains(*m.Mentions, "@all")
+}
+
+// MentionedIDs 解析群消息中被@的用户ID
+func (m *GroupMessage) MentionedIDs() []int {
+	if m.MentionedUserIDs == nil || *m.MentionedUserIDs == "" {
+		return nil
+	}
+	var ids []int
+	for _, idStr := range strings.Split(*m.MentionedUserIDs, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(idStr)); err == nil && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// CreateForMessage 为群消息生成@记录，返回被@的用户ID
+// @所有人 时为所有成员（发送者除外）生成记录；否则只为仍在群内的被@成员生成记录
+func (r *GroupMentionRepository) CreateForMessage(message *GroupMessage, memberIDs []int) ([]int, error) {
+	isMentionAll := message.IsMentionAll()
+
+	var targets []int
+	if isMentionAll {
+		for _, memberID := range memberIDs {
+			if memberID != message.SenderID {
+				targets = append(targets, memberID)
+			}
+		}
+	} else {
+		members := make(map[int]bool, len(memberIDs))
+		for _, memberID := range memberIDs {
+			members[memberID] = true
+		}
+		seen := make(map[int]bool)
+		for _, id := range message.MentionedIDs() {
+			if id != message.SenderID && members[id] && !seen[id] {
+				seen[id] = true
+				targets = append(targets, id)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO group_message_mentions (group_message_id, group_id, user_id, sender_id, is_mention_all, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (group_message_id, user_id) DO NOTHING
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	for _, userID := range targets {
+		if _, err := stmt.Exec(message.ID, message.GroupID, userID, message.SenderID, isMentionAll, message.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// ListByUser 分页获取用户的@消息（按时间倒序），返回列表和总数
+func (r *GroupMentionRepository) ListByUser(userID int, unreadOnly bool, limit, offset int) ([]GroupMention, int, error) {
+	filter := ""
+	if unreadOnly {
+		filter = " AND m.is_read = false"
+	}
+
+	var total int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM group_message_mentions m WHERE m.user_id = $1`+filter, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT m.id, m.group_message_id, m.group_id, COALESCE(g.name, ''), g.avatar,
+		       m.sender_id, gm.sender_name, gm.sender_avatar, gm.content, gm.message_type, COALESCE(gm.status, ''),
+		       m.is_mention_all, m.is_read, m.created_at
+		FROM group_message_mentions m
+		JOIN group_messages gm ON gm.id = m.group_message_id
+		LEFT JOIN groups g ON g.id = m.group_id
+		WHERE m.user_id = $1` + filter + `
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.DB.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	mentions := []GroupMention{}
+	for rows.Next() {
+		var m GroupMention
+		if err := rows.Scan(
+			&m.ID,
+			&m.GroupMessageID,
+			&m.GroupID,
+			&m.GroupName,
+			&m.GroupAvatar,
+			&m.SenderID,
+			&m.SenderName,
+			&m.SenderAvatar,
+			&m.Content,
+			&m.MessageType,
+			&m.MessageStatus,
+			&m.IsMentionAll,
+			&m.IsRead,
+			&m.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		if m.MessageStatus == "recalled" {
+			m.Content = ""
+		} else {
+			m.Content = MessagePreviewText(m.MessageType, m.Content)
+		}
+		mentions = append(mentions, m)
+	}
+
+	return mentions, total, nil
+}
+
+// CountUnread 获取用户未读的@消息数量
+func (r *GroupMentionRepository) CountUnread(userID int) (int, error) {
+	var count int
+	err := r.DB.QueryRow(`SELECT COUNT(*) FROM group_message_mentions WHERE user_id = $1 AND is_read = false`, userID).Scan(&count)
+	return count, err
+}
+
+// MarkRead 将指定的@消息标记为已读
+func (r *GroupMentionRepository) MarkRead(userID int, ids []int) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := []interface{}{userID, time.Now().UTC()}
+	for i, id := range ids {
+		placeholders[i] = "$" + strconv.Itoa(i+3)
+		args = append(args, id)
+	}
+
+	query := `
+		UPDATE group_message_mentions
+		SET is_read = true, read_at = $2
+		WHERE user_id = $1 AND is_read = false AND id IN (` + strings.Join(placeholders, ", ") + `)
+	`
+	result, err := r.DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// MarkGroupRead 将用户在某群组中的@消息全部标记为已读
+func (r *GroupMentionRepository) MarkGroupRead(userID, groupID int) (int64, error) {
+	result, err := r.DB.Exec(`
+		UPDATE group_message_mentions
+		SET is_read = true, read_at = $1
+		WHERE user_id = $2 AND group_id = $3 AND is_read = false
+	`, time.Now().UTC(), userID, groupID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// MarkAllRead 将用户的@消息全部标记为已读
+func (r *GroupMentionRepository) MarkAllRead(userID int) (int64, error) {
+	result, err := r.DB.Exec(`
+		UPDATE group_message_mentions
+		SET is_read = true, read_at = $1
+		WHERE user_id = $2 AND is_read = false
+	`, time.Now().UTC(), userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
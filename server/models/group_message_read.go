@@ -0,0 +1,135 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GroupReadMember 群消息已读/未读成员
+type GroupReadMember struct {
+	UserID   int        `json:"user_id"`
+	Username string     `json:"username"`
+	FullName *string    `json:"full_name,omitempty"`
+	Nickname *string    `json:"nickname,omitempty"` // 群昵称
+	Avatar   string     `json:"avatar"`
+	ReadAt   *time.Time `json:"read_at,omitempty"` // 已读时间（未读成员为空）
+}
+
+// GroupReadProgress 群消息已读进度
+// 统计范围为当前已通过审核的群成员（发送者除外）
+type GroupReadProgress struct {
+	MessageID   int `json:"message_id"`
+	SenderID    int `json:"-"`
+	ReadCount   int `json:"read_count"`
+	UnreadCount int `json:"unread_count"`
+}
+
+// GroupMessageReadRepository 群消息已读记录数据仓库
+type GroupMessageReadRepository struct {
+	DB *sql.DB
+}
+
+// NewGroupMessageReadRepository 创建群消息已读记录仓库
+func NewGroupMessageReadRepository(db *sql.DB) *GroupMessageReadRepository {
+	return &GroupMessageReadRepository{DB: db}
+}
+
+// GetMessageSender 获取群消息的群组ID和发送者ID
+func (r *GroupMessageReadRepository) GetMessageSender(messageID int) (groupID, senderID int, err error) {
+	err = r.DB.QueryRow(`SELECT group_id, sender_id FROM group_messages WHERE id = $1`, messageID).Scan(&groupID, &senderID)
+	return
+}
+
+// GetProgress 批量获取群消息的已读进度
+func (r *GroupMessageReadRepository) GetProgress(messageIDs []int) ([]GroupReadProgress, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(messageIDs))
+	args := make([]interface{}, len(messageIDs))
+	for i, id := range messageIDs {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = id
+	}
+
+	query := `
+		SELECT gm.id, gm.sender_id,
+		       COUNT(gmr.id) AS read_count,
+		       COUNT(mem.user_id) - COUNT(gmr.id) AS unread_count
+		FROM group_messages gm
+		LEFT JOIN group_members mem ON mem.group_id = gm.group_id
+			AND mem.approval_status = 'approved'
+			AND mem.user_id != gm.sender_id
+		LEFT JOIN group_message_reads gmr ON gmr.group_message_id = gm.id AND gmr.user_id = mem.user_id
+		WHERE gm.id IN (` + strings.Join(placeholders, ", ") + `)
+		GROUP BY gm.id, gm.sender_id
+	`
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var progress []GroupReadProgress
+	for rows.Next() {
+		var p GroupReadProgress
+		if err := rows.Scan(&p.MessageID, &p.SenderID, &p.ReadCount, &p.UnreadCount); err != nil {
+			return nil, err
+		}
+		progress = append(progress, p)
+	}
+	return progress, nil
+}
+
+// GetReadMembers 分页获取已读该消息的成员（按已读时间倒序）
+func (r *GroupMessageReadRepository) GetReadMembers(messageID, groupID, senderID, limit, offset int) ([]GroupReadMember, error) {
+	query := `
+		SELECT u.id, u.username, u.full_name, mem.nickname, u.avatar, gmr.read_at
+		FROM group_message_reads gmr
+		JOIN group_members mem ON mem.group_id = $2 AND mem.user_id = gmr.user_id AND mem.approval_status = 'approved'
+		JOIN users u ON u.id = gmr.user_id
+		WHERE gmr.group_message_id = $1 AND gmr.user_id != $3
+		ORDER BY gmr.read_at DESC, u.id
+		LIMIT $4 OFFSET $5
+	`
+	return r.queryMembers(query, messageID, groupID, senderID, limit, offset)
+}
+
+// GetUnreadMembers 分页获取尚未读该消息的成员（按入群时间排序）
+func (r *GroupMessageReadRepository) GetUnreadMembers(messageID, groupID, senderID, limit, offset int) ([]GroupReadMember, error) {
+	query := `
+		SELECT u.id, u.username, u.full_name, mem.nickname, u.avatar, NULL::timestamp
+		FROM group_members mem
+		JOIN users u ON u.id = mem.user_id
+		WHERE mem.group_id = $2 AND mem.approval_status = 'approved' AND mem.user_id != $3
+			AND NOT EXISTS (
+				SELECT 1 FROM group_message_reads gmr
+				WHERE gmr.group_message_id = $1 AND gmr.user_id = mem.user_id
+			)
+		ORDER BY mem.joined_at, u.id
+		LIMIT $4 OFFSET $5
+	`
+	return r.queryMembers(query, messageID, groupID, senderID, limit, offset)
+}
+
+func (r *GroupMessageReadRepository) queryMembers(query string, args ...interface{}) ([]GroupReadMember, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []GroupReadMember{}
+	for rows.Next() {
+		var m GroupReadMember
+		if err := rows.Scan(&m.UserID, &m.Username, &m.FullName, &m.Nickname, &m.Avatar, &m.ReadAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
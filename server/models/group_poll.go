@@ -0,0 +1,323 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// 投票限制
+const (
+	MinPollOptions = 2  // 最少选项数
+	MaxPollOptions = 20 // 最多选项数
+)
+
+// ErrPollClosed 投票已结束
+var ErrPollClosed = errors.New("投票已结束")
+
+// GroupPoll 群投票模型
+type GroupPoll struct {
+	ID             int        `json:"id" db:"id"`
+	GroupID        int        `json:"group_id" db:"group_id"`
+	MessageID      *int       `json:"message_id,omitempty" db:"message_id"`
+	CreatorID      int        `json:"creator_id" db:"creator_id"`
+	Question       string     `json:"question" db:"question"`
+	MultipleChoice bool       `json:"multiple_choice" db:"multiple_choice"`
+	Anonymous      bool       `json:"anonymous" db:"anonymous"`
+	Deadline       *time.Time `json:"deadline,omitempty" db:"deadline"`
+	IsClosed       bool       `json:"is_closed" db:"is_closed"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty" db:"closed_at"`
+	ClosedBy       *int       `json:"closed_by,omitempty" db:"closed_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// GroupPollOption 投票选项
+type GroupPollOption struct {
+	ID        int    `json:"id" db:"id"`
+	PollID    int    `json:"poll_id" db:"poll_id"`
+	Text      string `json:"text" db:"option_text"`
+	SortOrder int    `json:"sort_order" db:"sort_order"`
+}
+
+// PollVoter 投票人（匿名投票时不返回）
+type PollVoter struct {
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PollOptionResult 选项统计结果
+type PollOptionResult struct {
+	GroupPollOption
+	VoteCount int         `json:"vote_count"`
+	Voters    []PollVoter `json:"voters,omitempty"`
+}
+
+// PollResult 投票详情及统计结果
+type PollResult struct {
+	GroupPoll
+	Options     []PollOptionResult `json:"options"`
+	TotalVoters int                `json:"total_voters"`            // 参与投票的人数
+	MyOptionIDs []int              `json:"my_option_ids,omitempty"` // 当前用户选择的选项
+}
+
+// CreateGroupPollRequest 创建投票请求
+type CreateGroupPollRequest struct {
+	Question       string   `json:"question" binding:"required"`
+	Options        []string `json:"options" binding:"required"`
+	MultipleChoice bool     `json:"multiple_choice"`
+	Anonymous      bool     `json:"anonymous"`
+	Deadline       string   `json:"deadline,omitempty"` // RFC3339 格式
+}
+
+// CastPollVoteRequest 投票请求
+type CastPollVoteRequest struct {
+	OptionIDs []int `json:"option_ids" binding:"required"`
+}
+
+// GroupPollRepository 群投票数据仓库
+type GroupPollRepository struct {
+	DB *sql.DB
+}
+
+// NewGroupPollRepository 创建群投票仓库
+func NewGroupPollRepository(db *sql.DB) *GroupPollRepository {
+	return &GroupPollRepository{DB: db}
+}
+
+// Create 创建投票及其选项
+func (r *GroupPollRepository) Create(poll *GroupPoll, options []string) ([]GroupPollOption, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO group_polls (group_id, creator_id, question, multiple_choice, anonymous, deadline, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, poll.GroupID, poll.CreatorID, poll.Question, poll.MultipleChoice, poll.Anonymous, poll.Deadline, time.Now().UTC()).Scan(&poll.ID, &poll.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]GroupPollOption, 0, len(options))
+	for i, text := range options {
+		option := GroupPollOption{PollID: poll.ID, Text: text, SortOrder: i}
+		err := tx.QueryRow(`
+			INSERT INTO group_poll_options (poll_id, option_text, sort_order)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`, poll.ID, text, i).Scan(&option.ID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, option)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetMessageID 关联投票对应的群消息
+func (r *GroupPollRepository) SetMessageID(pollID, messageID int) error {
+	_, err := r.DB.Exec(`UPDATE group_polls SET message_id = $1 WHERE id = $2`, messageID, pollID)
+	return err
+}
+
+// GetByID 根据ID获取投票
+func (r *GroupPollRepository) GetByID(pollID int) (*GroupPoll, error) {
+	query := `
+		SELECT id, group_id, message_id, creator_id, question, multiple_choice, anonymous, deadline, is_closed, closed_at, closed_by, created_at
+		FROM group_polls
+		WHERE id = $1
+	`
+
+	poll := &GroupPoll{}
+	err := r.DB.QueryRow(query, pollID).Scan(
+		&poll.ID,
+		&poll.GroupID,
+		&poll.MessageID,
+		&poll.CreatorID,
+		&poll.Question,
+		&poll.MultipleChoice,
+		&poll.Anonymous,
+		&poll.Deadline,
+		&poll.IsClosed,
+		&poll.ClosedAt,
+		&poll.ClosedBy,
+		&poll.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+// GetOptions 获取投票的所有选项
+func (r *GroupPollRepository) GetOptions(pollID int) ([]GroupPollOption, error) {
+	rows, err := r.DB.Query(`
+		SELECT id, poll_id, option_text, sort_order
+		FROM group_poll_options
+		WHERE poll_id = $1
+		ORDER BY sort_order, id
+	`, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := []GroupPollOption{}
+	for rows.Next() {
+		var option GroupPollOption
+		if err := rows.Scan(&option.ID, &option.PollID, &option.Text, &option.SortOrder); err != nil {
+			return nil, err
+		}
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+// Vote 投票（覆盖该用户之前的选择）
+// 在事务中锁定投票记录，已结束或已过截止时间时返回 ErrPollClosed
+func (r *GroupPollRepository) Vote(pollID, userID int, voterName string, optionIDs []int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var isClosed bool
+	var deadline *time.Time
+	err = tx.QueryRow(`SELECT is_closed, deadline FROM group_polls WHERE id = $1 FOR UPDATE`, pollID).Scan(&isClosed, &deadline)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if isClosed || (deadline != nil && !deadline.After(now)) {
+		return ErrPollClosed
+	}
+
+	if _, err := tx.Exec(`DELETE FROM group_poll_votes WHERE poll_id = $1 AND user_id = $2`, pollID, userID); err != nil {
+		return err
+	}
+
+	for _, optionID := range optionIDs {
+		_, err := tx.Exec(`
+			INSERT INTO group_poll_votes (poll_id, option_id, user_id, voter_name, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, pollID, optionID, userID, voterName, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Close 结束投票，closedBy 为空表示到期自动结束
+// 返回 false 表示投票此前已结束
+func (r *GroupPollRepository) Close(pollID int, closedBy *int) (bool, error) {
+	result, err := r.DB.Exec(`
+		UPDATE group_polls
+		SET is_closed = true, closed_at = $1, closed_by = $2
+		WHERE id = $3 AND is_closed = false
+	`, time.Now().UTC(), closedBy, pollID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// CloseExpired 结束所有已到截止时间的投票，返回被结束的投票ID
+func (r *GroupPollRepository) CloseExpired() ([]int, error) {
+	now := time.Now().UTC()
+	rows, err := r.DB.Query(`
+		UPDATE group_polls
+		SET is_closed = true, closed_at = $1
+		WHERE is_closed = false AND deadline IS NOT NULL AND deadline <= $1
+		RETURNING id
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pollIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		pollIDs = append(pollIDs, id)
+	}
+	return pollIDs, nil
+}
+
+// GetResult 获取投票详情及统计结果
+// 统计基于全部投票记录，不受成员退群影响；匿名投票不返回投票人；
+// viewerID > 0 时返回该用户的选择
+func (r *GroupPollRepository) GetResult(pollID, viewerID int) (*PollResult, error) {
+	poll, err := r.GetByID(pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := r.GetOptions(pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PollResult{
+		GroupPoll: *poll,
+		Options:   make([]PollOptionResult, len(options)),
+	}
+	optionIndex := make(map[int]int, len(options))
+	for i, option := range options {
+		result.Options[i] = PollOptionResult{GroupPollOption: option}
+		optionIndex[option.ID] = i
+	}
+
+	rows, err := r.DB.Query(`
+		SELECT option_id, user_id, voter_name, created_at
+		FROM group_poll_votes
+		WHERE poll_id = $1
+		ORDER BY created_at, id
+	`, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	voters := make(map[int]bool)
+	for rows.Next() {
+		var optionID int
+		var voter PollVoter
+		if err := rows.Scan(&optionID, &voter.UserID, &voter.Name, &voter.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		i, ok := optionIndex[optionID]
+		if !ok {
+			continue
+		}
+		result.Options[i].VoteCount++
+		if !poll.Anonymous {
+			result.Options[i].Voters = append(result.Options[i].Voters, voter)
+		}
+		voters[voter.UserID] = true
+		if viewerID > 0 && voter.UserID == viewerID {
+			result.MyOptionIDs = append(result.MyOptionIDs, optionID)
+		}
+	}
+	result.TotalVoters = len(voters)
+
+	return result, nil
+}
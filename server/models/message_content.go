@@ -0,0 +1,156 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// 结构化消息类型（content 字段存储 JSON）
+const (
+	MessageTypeLocation    = "location"     // 位置消息
+	MessageTypeContactCard = "contact_card" // 名片消息
+	MessageTypePoll        = "poll"         // 群投票消息
+)
+
+// 位置消息字段长度限制
+const (
+	maxLocationPoiNameLen      = 100
+	maxLocationAddressLen      = 300
+	maxLocationThumbnailKeyLen = 512
+)
+
+// LocationContent 位置消息内容
+type LocationContent struct {
+	Latitude     float64 `json:"latitude"`                // 纬度 -90 ~ 90
+	Longitude    float64 `json:"longitude"`               // 经度 -180 ~ 180
+	PoiName      string  `json:"poi_name,omitempty"`      // 地点名称
+	Address      string  `json:"address,omitempty"`       // 详细地址
+	ThumbnailKey string  `json:"thumbnail_key,omitempty"` // 静态地图缩略图的OSS对象key
+}
+
+// ContactCardContent 名片消息内容（由服务端根据用户当前资料生成）
+type ContactCardContent struct {
+	UserID     int     `json:"user_id"`
+	Username   string  `json:"username"`
+	FullName   *string `json:"full_name,omitempty"`
+	Avatar     string  `json:"avatar,omitempty"`
+	Department *string `json:"department,omitempty"`
+	Position   *string `json:"position,omitempty"`
+}
+
+// ParseLocationContent 解析并校验位置消息内容
+func ParseLocationContent(content string) (*LocationContent, error) {
+	var loc LocationContent
+	if err := json.Unmarshal([]byte(content), &loc); err != nil {
+		return nil, errors.New("位置消息格式错误")
+	}
+
+	loc.PoiName = strings.TrimSpace(loc.PoiName)
+	loc.Address = strings.TrimSpace(loc.Address)
+	loc.ThumbnailKey = strings.TrimSpace(loc.ThumbnailKey)
+
+	if math.IsNaN(loc.Latitude) || loc.Latitude < -90 || loc.Latitude > 90 {
+		return nil, errors.New("纬度超出范围")
+	}
+	if math.IsNaN(loc.Longitude) || loc.Longitude < -180 || loc.Longitude > 180 {
+		return nil, errors.New("经度超出范围")
+	}
+	if utf8.RuneCountInString(loc.PoiName) > maxLocationPoiNameLen {
+		return nil, errors.New("地点名称过长")
+	}
+	if utf8.RuneCountInString(loc.Address) > maxLocationAddressLen {
+		return nil, errors.New("地址过长")
+	}
+	if loc.ThumbnailKey != "" {
+		if len(loc.ThumbnailKey) > maxLocationThumbnailKeyLen ||
+			strings.HasPrefix(loc.ThumbnailKey, "/") ||
+			strings.Contains(loc.ThumbnailKey, "..") ||
+			strings.Contains(loc.ThumbnailKey, "://") {
+			return nil, errors.New("缩略图地址无效")
+		}
+	}
+
+	return &loc, nil
+}
+
+// ParseContactCardUserID 从名片消息内容中解析被分享的用户ID
+// 客户端可以只传 {"user_id": 123}，其余字段由服务端补全
+func ParseContactCardUserID(content string) (int, error) {
+	var card ContactCardContent
+	if err := json.Unmarshal([]byte(content), &card); err != nil {
+		return 0, errors.New("名片消息格式错误")
+	}
+	if card.UserID <= 0 {
+		return 0, errors.New("名片用户ID无效")
+	}
+	return card.UserID, nil
+}
+
+// NewContactCardContent 根据用户当前资料生成名片内容
+func NewContactCardContent(user *User) *ContactCardContent {
+	return &ContactCardContent{
+		UserID:     user.ID,
+		Username:   user.Username,
+		FullName:   user.FullName,
+		Avatar:     user.Avatar,
+		Department: user.Department,
+		Position:   user.Position,
+	}
+}
+
+// MessagePreviewText 生成会话列表中的消息预览文本
+func MessagePreviewText(messageType, content string) string {
+	switch messageType {
+	case "image":
+		return "[图片]"
+	case "video":
+		return "[视频]"
+	case "file":
+		return "[文件]"
+	case "audio":
+		return "[语音]"
+	case MessageTypeLocation:
+		var loc LocationContent
+		if err := json.Unmarshal([]byte(content), &loc); err == nil {
+			if loc.PoiName != "" {
+				return "[位置] " + loc.PoiName
+			}
+			if loc.Address != "" {
+				return "[位置] " + loc.Address
+			}
+		}
+		return "[位置]"
+	case MessageTypeContactCard:
+		var card ContactCardContent
+		if err := json.Unmarshal([]byte(content), &card); err == nil {
+			if card.FullName != nil && *card.FullName != "" {
+				return "[名片] " + *card.FullName
+			}
+			if card.Username != "" {
+				return "[名片] " + card.Username
+			}
+		}
+		return "[名片]"
+	case MessageTypePoll:
+		var poll struct {
+			Question string `json:"question"`
+		}
+		if err := json.Unmarshal([]byte(content), &poll); err == nil && poll.Question != "" {
+			return "[投票] " + poll.Question
+		}
+		return "[投票]"
+	case MessageTypeRichText:
+		return MessagePlainText(messageType, content)
+	case MessageTypeSticker:
+		return "[表情]"
+	case MessageTypeEncrypted:
+		return EncryptedMessagePreview
+	case MessageTypeAutoReply:
+		return "[自动回复] " + content
+	default:
+		return content
+	}
+}
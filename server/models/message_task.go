@@ -0,0 +1,438 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 任务状态
+const (
+	TaskStatusTodo       = "todo"        // 待办
+	TaskStatusInProgress = "in_progress" // 进行中
+	TaskStatusDone       = "done"        // 已完成
+	TaskStatusCancelled  = "cancelled"   // 已取消
+)
+
+// 任务限制
+const (
+	MaxTaskTitleLength = 200 // 任务标题最大长度（字符）
+	MaxTaskAssignees   = 20  // 单个任务最多负责人数
+)
+
+// TaskStatusLabels 任务状态的中文名称（用于会话内的系统消息）
+var TaskStatusLabels = map[string]string{
+	TaskStatusTodo:       "待办",
+	TaskStatusInProgress: "进行中",
+	TaskStatusDone:       "已完成",
+	TaskStatusCancelled:  "已取消",
+}
+
+// IsValidTaskStatus 判断任务状态是否有效
+func IsValidTaskStatus(status string) bool {
+	_, ok := TaskStatusLabels[status]
+	return ok
+}
+
+// MessageTask 由消息转成的任务
+type MessageTask struct {
+	ID               int            `json:"id" db:"id"`
+	ConversationType string         `json:"conversation_type" db:"conversation_type"` // user, group
+	TargetID         int            `json:"target_id" db:"target_id"`                 // 私聊为创建者的会话对方ID，群聊为群组ID
+	MessageID        int            `json:"message_id" db:"message_id"`
+	CreatorID        int            `json:"creator_id" db:"creator_id"`
+	CreatorName      string         `json:"creator_name"`
+	Title            string         `json:"title" db:"title"`
+	Content          string         `json:"content" db:"content"`
+	Status           string         `json:"status" db:"status"`
+	DueAt            *time.Time     `json:"due_at,omitempty" db:"due_at"`
+	RemindedAt       *time.Time     `json:"reminded_at,omitempty" db:"reminded_at"`
+	CompletedAt      *time.Time     `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at" db:"updated_at"`
+	Assignees        []TaskAssignee `json:"assignees"`
+}
+
+// TaskAssignee 任务负责人
+type TaskAssignee struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	Avatar string `json:"avatar"`
+}
+
+// AssigneeIDs 负责人ID列表
+func (t *MessageTask) AssigneeIDs() []int {
+	ids := make([]int, 0, len(t.Assignees))
+	for _, a := range t.Assignees {
+		ids = append(ids, a.UserID)
+	}
+	return ids
+}
+
+// IsAssignee 判断用户是否为任务负责人
+func (t *MessageTask) IsAssignee(userID int) bool {
+	for _, a := range t.Assignees {
+		if a.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateMessageTaskRequest 将消息转为任务请求
+type CreateMessageTaskRequest struct {
+	ConversationType string `json:"conversation_type" binding:"required"` // user, group
+	MessageID        int    `json:"message_id" binding:"required"`
+	Title            string `json:"title"`            // 为空时使用消息预览
+	AssigneeIDs      []int  `json:"assignee_ids"`     // 为空时负责人为创建者本人
+	DueAt            string `json:"due_at,omitempty"` // RFC3339 格式
+}
+
+// UpdateMessageTaskRequest 修改任务请求（仅创建者）
+type UpdateMessageTaskRequest struct {
+	Title       *string `json:"title"`
+	AssigneeIDs *[]int  `json:"assignee_ids"`
+	DueAt       *string `json:"due_at"` // RFC3339 格式，空字符串表示取消截止时间
+}
+
+// UpdateMessageTaskStatusRequest 修改任务状态请求
+type UpdateMessageTaskStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// TaskSourceMessage 任务的来源消息
+type TaskSourceMessage struct {
+	SenderID    int
+	ReceiverID  int // 私聊消息的接收者
+	GroupID     int // 群消息的群组ID
+	MessageType string
+	Content     string
+	Status      string
+}
+
+// MessageTaskRepository 消息任务数据仓库
+type MessageTaskRepository struct {
+	DB *sql.DB
+}
+
+// NewMessageTaskRepository 创建消息任务仓库
+func NewMessageTaskRepository(db *sql.DB) *MessageTaskRepository {
+	return &MessageTaskRepository{DB: db}
+}
+
+// GetSourceMessage 获取任务来源消息（不存在时返回 sql.ErrNoRows）
+func (r *MessageTaskRepository) GetSourceMessage(conversationType string, messageID int) (*TaskSourceMessage, error) {
+	msg := &TaskSourceMessage{}
+	var err error
+	if conversationType == ConversationTypeGroup {
+		err = r.DB.QueryRow(`
+			SELECT COALESCE(sender_id, 0), group_id, COALESCE(message_type, 'text'), content, COALESCE(status, '')
+			FROM group_messages WHERE id = $1
+		`, messageID).Scan(&msg.SenderID, &msg.GroupID, &msg.MessageType, &msg.Content, &msg.Status)
+	} else {
+		err = r.DB.QueryRow(`
+			SELECT sender_id, receiver_id, COALESCE(message_type, 'text'), content, COALESCE(status, '')
+			FROM messages WHERE id = $1
+		`, messageID).Scan(&msg.SenderID, &msg.ReceiverID, &msg.MessageType, &msg.Content, &msg.Status)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Create 创建任务及负责人
+func (r *MessageTaskRepository) Create(task *MessageTask, assigneeIDs []int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	task.Status = TaskStatusTodo
+	err = tx.QueryRow(`
+		INSERT INTO message_tasks (conversation_type, target_id, message_id, creator_id, title, content, status, due_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		RETURNING id, created_at, updated_at
+	`, task.ConversationType, task.TargetID, task.MessageID, task.CreatorID, task.Title, task.Content, task.Status, task.DueAt, now).Scan(&task.ID, &task.CreatedAt, &task.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceTaskAssignees(tx, task.ID, assigneeIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceTaskAssignees 重新设置任务负责人
+func replaceTaskAssignees(tx *sql.Tx, taskID int, assigneeIDs []int) error {
+	if _, err := tx.Exec(`DELETE FROM message_task_assignees WHERE task_id = $1`, taskID); err != nil {
+		return err
+	}
+	for _, userID := range assigneeIDs {
+		if _, err := tx.Exec(`
+			INSERT INTO message_task_assignees (task_id, user_id, created_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (task_id, user_id) DO NOTHING
+		`, taskID, userID, time.Now().UTC()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update 修改任务标题、截止时间和负责人（assigneeIDs 为 nil 时不修改负责人）
+// 截止时间变更后重置到期提醒
+func (r *MessageTaskRepository) Update(task *MessageTask, assigneeIDs []int, dueChanged bool) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		UPDATE message_tasks
+		SET title = $1, due_at = $2,
+		    reminded_at = CASE WHEN $3 THEN NULL ELSE reminded_at END,
+		    updated_at = $4
+		WHERE id = $5
+		RETURNING reminded_at, updated_at
+	`, task.Title, task.DueAt, dueChanged, time.Now().UTC(), task.ID).Scan(&task.RemindedAt, &task.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if assigneeIDs != nil {
+		if err := replaceTaskAssignees(tx, task.ID, assigneeIDs); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UpdateStatus 修改任务状态，返回 false 表示状态未变化
+func (r *MessageTaskRepository) UpdateStatus(task *MessageTask, status string) (bool, error) {
+	now := time.Now().UTC()
+	var completedAt *time.Time
+	if status == TaskStatusDone {
+		completedAt = &now
+	}
+
+	result, err := r.DB.Exec(`
+		UPDATE message_tasks
+		SET status = $1, completed_at = $2, updated_at = $3
+		WHERE id = $4 AND status != $1
+	`, status, completedAt, now, task.ID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	task.Status = status
+	task.CompletedAt = completedAt
+	task.UpdatedAt = now
+	return true, nil
+}
+
+const messageTaskColumns = `t.id, t.conversation_type, t.target_id, t.message_id, t.creator_id,
+	COALESCE(NULLIF(u.full_name, ''), u.username, ''), t.title, t.content, t.status,
+	t.due_at, t.reminded_at, t.completed_at, t.created_at, t.updated_at`
+
+func scanMessageTask(scanner interface{ Scan(...interface{}) error }) (*MessageTask, error) {
+	t := &MessageTask{}
+	err := scanner.Scan(
+		&t.ID,
+		&t.ConversationType,
+		&t.TargetID,
+		&t.MessageID,
+		&t.CreatorID,
+		&t.CreatorName,
+		&t.Title,
+		&t.Content,
+		&t.Status,
+		&t.DueAt,
+		&t.RemindedAt,
+		&t.CompletedAt,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetByID 获取任务及负责人（不存在时返回 sql.ErrNoRows）
+func (r *MessageTaskRepository) GetByID(id int) (*MessageTask, error) {
+	task, err := scanMessageTask(r.DB.QueryRow(`
+		SELECT `+messageTaskColumns+`
+		FROM message_tasks t
+		LEFT JOIN users u ON u.id = t.creator_id
+		WHERE t.id = $1
+	`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []MessageTask{*task}
+	if err := r.loadAssignees(tasks); err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+// ListAssignedTo 获取分配给用户的任务（分页，status 为空时不过滤）
+func (r *MessageTaskRepository) ListAssignedTo(userID int, status string, limit, offset int) ([]MessageTask, int, error) {
+	return r.list(`t.id IN (SELECT task_id FROM message_task_assignees WHERE user_id = $1)`, userID, status, limit, offset)
+}
+
+// ListCreatedBy 获取用户创建（分配出去）的任务
+func (r *MessageTaskRepository) ListCreatedBy(userID int, status string, limit, offset int) ([]MessageTask, int, error) {
+	return r.list(`t.creator_id = $1`, userID, status, limit, offset)
+}
+
+// ListByGroup 获取群组中的任务
+func (r *MessageTaskRepository) ListByGroup(groupID int, status string, limit, offset int) ([]MessageTask, int, error) {
+	return r.list(`t.conversation_type = 'group' AND t.target_id = $1`, groupID, status, limit, offset)
+}
+
+// list 按条件分页查询任务：未完成的在前，按截止时间（无截止时间的在后）和创建时间排序
+func (r *MessageTaskRepository) list(where string, id int, status string, limit, offset int) ([]MessageTask, int, error) {
+	args := []interface{}{id}
+	if status != "" {
+		args = append(args, status)
+		where += ` AND t.status = $2`
+	}
+
+	var total int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM message_tasks t WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + messageTaskColumns + `
+		FROM message_tasks t
+		LEFT JOIN users u ON u.id = t.creator_id
+		WHERE ` + where + `
+		ORDER BY t.status IN ('done', 'cancelled'), t.due_at NULLS LAST, t.created_at DESC
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	tasks := []MessageTask{}
+	for rows.Next() {
+		task, err := scanMessageTask(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		tasks = append(tasks, *task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if err := r.loadAssignees(tasks); err != nil {
+		return nil, 0, err
+	}
+	return tasks, total, nil
+}
+
+// loadAssignees 批量加载任务负责人
+func (r *MessageTaskRepository) loadAssignees(tasks []MessageTask) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(tasks))
+	placeholders := make([]string, 0, len(tasks))
+	args := make([]interface{}, 0, len(tasks))
+	for i := range tasks {
+		tasks[i].Assignees = []TaskAssignee{}
+		index[tasks[i].ID] = i
+		args = append(args, tasks[i].ID)
+		placeholders = append(placeholders, "$"+strconv.Itoa(len(args)))
+	}
+
+	rows, err := r.DB.Query(`
+		SELECT a.task_id, a.user_id, COALESCE(NULLIF(u.full_name, ''), u.username), COALESCE(u.avatar, '')
+		FROM message_task_assignees a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.task_id IN (`+strings.Join(placeholders, ",")+`)
+		ORDER BY a.created_at, a.user_id
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		var a TaskAssignee
+		if err := rows.Scan(&taskID, &a.UserID, &a.Name, &a.Avatar); err != nil {
+			return err
+		}
+		if i, ok := index[taskID]; ok {
+			tasks[i].Assignees = append(tasks[i].Assignees, a)
+		}
+	}
+	return rows.Err()
+}
+
+// ClaimDueReminders 领取已到期、未完成且尚未提醒的任务并标记为已提醒（多实例下不会重复领取）
+func (r *MessageTaskRepository) ClaimDueReminders(now time.Time, limit int) ([]MessageTask, error) {
+	rows, err := r.DB.Query(`
+		WITH due AS (
+			SELECT id FROM message_tasks
+			WHERE reminded_at IS NULL AND due_at <= $1 AND status IN ('todo', 'in_progress')
+			ORDER BY due_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE message_tasks t
+		SET reminded_at = $1
+		FROM due
+		WHERE t.id = due.id
+		RETURNING t.id
+	`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]MessageTask, 0, len(ids))
+	for _, id := range ids {
+		task, err := r.GetByID(id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, nil
+}
@@ -0,0 +1,260 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// 审核规则类型
+const (
+	ModerationRuleWord  = "word"  // 敏感词（忽略大小写）
+	ModerationRuleRegex = "regex" // 正则表达式
+)
+
+// 审核规则动作
+const (
+	ModerationActionBlock = "block" // 拦截发送
+	ModerationActionMask  = "mask"  // 命中文本替换为*后发送
+	ModerationActionFlag  = "flag"  // 正常发送并进入审核队列
+)
+
+// 被审核内容来源
+const (
+	ModerationSourceMessage       = "message"        // 私聊消息
+	ModerationSourceGroupMessage  = "group_message"  // 群组消息
+	ModerationSourceFileAssistant = "file_assistant" // 文件传输助手
+)
+
+// 审核队列状态
+const (
+	ModerationFlagPending  = "pending"  // 待审核
+	ModerationFlagApproved = "approved" // 审核通过
+	ModerationFlagRemoved  = "removed"  // 已移除（消息撤回）
+)
+
+// ModerationRule 内容审核规则
+type ModerationRule struct {
+	ID          int       `json:"id" db:"id"`
+	Pattern     string    `json:"pattern" db:"pattern"`
+	RuleType    string    `json:"rule_type" db:"rule_type"` // word, regex
+	Action      string    `json:"action" db:"action"`       // block, mask, flag
+	Description string    `json:"description" db:"description"`
+	Enabled     bool      `json:"enabled" db:"enabled"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SaveModerationRuleRequest 创建/更新审核规则请求
+type SaveModerationRuleRequest struct {
+	Pattern     string `json:"pattern" binding:"required"`
+	RuleType    string `json:"rule_type"`
+	Action      string `json:"action" binding:"required"`
+	Description string `json:"description"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// ModerationFlag 审核队列记录
+type ModerationFlag struct {
+	ID         int        `json:"id" db:"id"`
+	RuleID     *int       `json:"rule_id,omitempty" db:"rule_id"`
+	Source     string     `json:"source" db:"source"` // message, group_message, file_assistant
+	MessageID  int        `json:"message_id" db:"message_id"`
+	SenderID   int        `json:"sender_id" db:"sender_id"`
+	SenderName string     `json:"sender_name"`
+	TargetID   int        `json:"target_id" db:"target_id"` // 接收者ID或群组ID
+	Content    string     `json:"content" db:"content"`
+	Matched    string     `json:"matched" db:"matched"`
+	Status     string     `json:"status" db:"status"`
+	ReviewNote *string    `json:"review_note,omitempty" db:"review_note"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ReviewModerationFlagRequest 审核请求
+type ReviewModerationFlagRequest struct {
+	Decision string `json:"decision" binding:"required"` // approve-通过, remove-移除（撤回消息）
+	Note     string `json:"note"`
+}
+
+// ModerationRepository 内容审核数据仓库
+type ModerationRepository struct {
+	DB *sql.DB
+}
+
+// NewModerationRepository 创建内容审核仓库
+func NewModerationRepository(db *sql.DB) *ModerationRepository {
+	return &ModerationRepository{DB: db}
+}
+
+const moderationRuleColumns = `id, pattern, rule_type, action, description, enabled, created_at, updated_at`
+
+func scanModerationRule(scanner interface{ Scan(...interface{}) error }) (*ModerationRule, error) {
+	rule := &ModerationRule{}
+	err := scanner.Scan(
+		&rule.ID,
+		&rule.Pattern,
+		&rule.RuleType,
+		&rule.Action,
+		&rule.Description,
+		&rule.Enabled,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// ListRules 获取审核规则，enabledOnly 为 true 时只返回启用的规则
+func (r *ModerationRepository) ListRules(enabledOnly bool) ([]ModerationRule, error) {
+	query := `SELECT ` + moderationRuleColumns + ` FROM moderation_rules`
+	if enabledOnly {
+		query += ` WHERE enabled = true`
+	}
+	query += ` ORDER BY id`
+
+	rows, err := r.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []ModerationRule{}
+	for rows.Next() {
+		rule, err := scanModerationRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, nil
+}
+
+// CreateRule 创建审核规则
+func (r *ModerationRepository) CreateRule(rule *ModerationRule) (*ModerationRule, error) {
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO moderation_rules (pattern, rule_type, action, description, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING ` + moderationRuleColumns
+	return scanModerationRule(r.DB.QueryRow(query, rule.Pattern, rule.RuleType, rule.Action, rule.Description, rule.Enabled, now))
+}
+
+// UpdateRule 更新审核规则
+func (r *ModerationRepository) UpdateRule(rule *ModerationRule) (*ModerationRule, error) {
+	query := `
+		UPDATE moderation_rules
+		SET pattern = $1, rule_type = $2, action = $3, description = $4, enabled = $5, updated_at = $6
+		WHERE id = $7
+		RETURNING ` + moderationRuleColumns
+	return scanModerationRule(r.DB.QueryRow(query, rule.Pattern, rule.RuleType, rule.Action, rule.Description, rule.Enabled, time.Now().UTC(), rule.ID))
+}
+
+// DeleteRule 删除审核规则
+func (r *ModerationRepository) DeleteRule(ruleID int) (bool, error) {
+	result, err := r.DB.Exec(`DELETE FROM moderation_rules WHERE id = $1`, ruleID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// CreateFlag 将消息加入审核队列
+func (r *ModerationRepository) CreateFlag(flag *ModerationFlag) error {
+	return r.DB.QueryRow(`
+		INSERT INTO moderation_flags (rule_id, source, message_id, sender_id, target_id, content, matched, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, flag.RuleID, flag.Source, flag.MessageID, flag.SenderID, flag.TargetID, flag.Content, flag.Matched, ModerationFlagPending, time.Now().UTC()).Scan(&flag.ID)
+}
+
+const moderationFlagSelect = `
+	SELECT f.id, f.rule_id, f.source, f.message_id, f.sender_id, COALESCE(NULLIF(u.full_name, ''), u.username, ''),
+	       f.target_id, f.content, f.matched, f.status, f.review_note, f.reviewed_at, f.created_at
+	FROM moderation_flags f
+	LEFT JOIN users u ON u.id = f.sender_id
+`
+
+func scanModerationFlag(scanner interface{ Scan(...interface{}) error }) (*ModerationFlag, error) {
+	flag := &ModerationFlag{}
+	err := scanner.Scan(
+		&flag.ID,
+		&flag.RuleID,
+		&flag.Source,
+		&flag.MessageID,
+		&flag.SenderID,
+		&flag.SenderName,
+		&flag.TargetID,
+		&flag.Content,
+		&flag.Matched,
+		&flag.Status,
+		&flag.ReviewNote,
+		&flag.ReviewedAt,
+		&flag.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
+
+// ListFlags 分页获取审核队列，status 为空时返回全部状态
+func (r *ModerationRepository) ListFlags(status string, limit, offset int) ([]ModerationFlag, int, error) {
+	where := ""
+	args := []interface{}{}
+	if status != "" {
+		where = ` WHERE f.status = $1`
+		args = append(args, status)
+	}
+
+	var total int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM moderation_flags f`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	n := len(args)
+	query := moderationFlagSelect + where + `
+		ORDER BY f.created_at DESC, f.id DESC
+		LIMIT $` + strconv.Itoa(n+1) + ` OFFSET $` + strconv.Itoa(n+2)
+	rows, err := r.DB.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	flags := []ModerationFlag{}
+	for rows.Next() {
+		flag, err := scanModerationFlag(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		flags = append(flags, *flag)
+	}
+	return flags, total, nil
+}
+
+// GetFlag 获取审核队列记录
+func (r *ModerationRepository) GetFlag(flagID int) (*ModerationFlag, error) {
+	return scanModerationFlag(r.DB.QueryRow(moderationFlagSelect+` WHERE f.id = $1`, flagID))
+}
+
+// ReviewFlag 记录审核结果，仅待审核的记录可以审核，返回 false 表示记录已被审核
+func (r *ModerationRepository) ReviewFlag(flagID int, status, note string) (bool, error) {
+	var reviewNote *string
+	if note != "" {
+		reviewNote = &note
+	}
+	result, err := r.DB.Exec(`
+		UPDATE moderation_flags
+		SET status = $1, review_note = $2, reviewed_at = $3
+		WHERE id = $4 AND status = $5
+	`, status, reviewNote, time.Now().UTC(), flagID, ModerationFlagPending)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
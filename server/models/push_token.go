@@ -0,0 +1,177 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 推送令牌限制
+const (
+	MaxPushTokenLength = 4096 // 推送令牌最大长度
+)
+
+// PushToken 设备推送令牌（按登录会话登记）
+type PushToken struct {
+	ID               int       `json:"id" db:"id"`
+	UserID           int       `json:"user_id" db:"user_id"`
+	SessionID        string    `json:"-" db:"session_id"`
+	Provider         string    `json:"provider" db:"provider"` // apns, fcm, xiaomi, huawei, memory
+	Token            string    `json:"token" db:"token"`
+	Platform         string    `json:"platform" db:"platform"` // ios, android, macos, windows
+	Locale           string    `json:"locale" db:"locale"`     // 推送文案语言，如 zh-CN、en-US
+	SessionExpiresAt time.Time `json:"session_expires_at" db:"session_expires_at"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RegisterPushTokenRequest 登记推送令牌请求
+type RegisterPushTokenRequest struct {
+	Provider string `json:"provider" binding:"required"`
+	Token    string `json:"token" binding:"required"`
+	Platform string `json:"platform"`
+	Locale   string `json:"locale"`
+}
+
+// PushTokenRepository 推送令牌数据仓库
+type PushTokenRepository struct {
+	DB *sql.DB
+}
+
+// NewPushTokenRepository 创建推送令牌仓库
+func NewPushTokenRepository(db *sql.DB) *PushTokenRepository {
+	return &PushTokenRepository{DB: db}
+}
+
+const pushTokenColumns = `id, user_id, session_id, provider, token, platform, locale, session_expires_at, created_at, updated_at`
+
+func scanPushToken(scanner interface{ Scan(...interface{}) error }) (*PushToken, error) {
+	t := &PushToken{}
+	err := scanner.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.SessionID,
+		&t.Provider,
+		&t.Token,
+		&t.Platform,
+		&t.Locale,
+		&t.SessionExpiresAt,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Register 登记推送令牌：同一令牌归属最后登记的用户和会话，同一会话同一通道只保留一个令牌
+func (r *PushTokenRepository) Register(token *PushToken) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+
+	// 清理该用户已过期会话的令牌，以及本会话在该通道上的旧令牌（客户端刷新令牌）
+	_, err = tx.Exec(`
+		DELETE FROM push_tokens
+		WHERE user_id = $1
+		  AND (session_expires_at <= $2 OR (session_id = $3 AND provider = $4 AND token <> $5))
+	`, token.UserID, now, token.SessionID, token.Provider, token.Token)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO push_tokens (user_id, session_id, provider, token, platform, locale, session_expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (provider, token) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			session_id = EXCLUDED.session_id,
+			platform = EXCLUDED.platform,
+			locale = EXCLUDED.locale,
+			session_expires_at = EXCLUDED.session_expires_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING ` + pushTokenColumns
+
+	saved, err := scanPushToken(tx.QueryRow(query,
+		token.UserID, token.SessionID, token.Provider, token.Token,
+		token.Platform, token.Locale, token.SessionExpiresAt, now,
+	))
+	if err != nil {
+		return err
+	}
+	*token = *saved
+
+	return tx.Commit()
+}
+
+// DeleteBySession 删除某个登录会话的所有推送令牌（退出登录）
+func (r *PushTokenRepository) DeleteBySession(userID int, sessionID string) (int64, error) {
+	result, err := r.DB.Exec(`DELETE FROM push_tokens WHERE user_id = $1 AND session_id = $2`, userID, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteByUser 删除用户的所有推送令牌（强制下线）
+func (r *PushTokenRepository) DeleteByUser(userID int) error {
+	_, err := r.DB.Exec(`DELETE FROM push_tokens WHERE user_id = $1`, userID)
+	return err
+}
+
+// DeleteByToken 删除失效的推送令牌
+func (r *PushTokenRepository) DeleteByToken(provider, token string) error {
+	_, err := r.DB.Exec(`DELETE FROM push_tokens WHERE provider = $1 AND token = $2`, provider, token)
+	return err
+}
+
+// ListActiveTokens 获取用户未过期会话的推送令牌
+func (r *PushTokenRepository) ListActiveTokens(userID int) ([]PushToken, error) {
+	query := `SELECT ` + pushTokenColumns + `
+		FROM push_tokens
+		WHERE user_id = $1 AND session_expires_at > $2
+		ORDER BY updated_at DESC
+	`
+
+	rows, err := r.DB.Query(query, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []PushToken{}
+	for rows.Next() {
+		t, err := scanPushToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// IsConversationMuted 用户是否对该会话开启了免打扰（会话设置的免打扰，群聊还包括群成员免打扰）
+func (r *PushTokenRepository) IsConversationMuted(userID int, conversationType string, targetID int) (bool, error) {
+	var muted bool
+	err := r.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM conversation_settings
+			WHERE user_id = $1 AND conversation_type = $2 AND target_id = $3 AND muted_until > $4
+		)
+	`, userID, conversationType, targetID, time.Now().UTC()).Scan(&muted)
+	if err != nil || muted || conversationType != ConversationTypeGroup {
+		return muted, err
+	}
+
+	err = r.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM group_members
+			WHERE group_id = $1 AND user_id = $2 AND do_not_disturb = true
+		)
+	`, targetID, userID).Scan(&muted)
+	return muted, err
+}
@@ -0,0 +1,315 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 快捷回复模板范围
+const (
+	QuickReplyScopePersonal     = "personal"     // 个人模板
+	QuickReplyScopeGroup        = "group"        // 群组共享模板
+	QuickReplyScopeOrganization = "organization" // 组织共享模板
+)
+
+// 快捷回复限制
+const (
+	MaxQuickReplyTitleLength   = 100  // 标题最大长度（字符）
+	MaxQuickReplyContentLength = 2000 // 内容最大长度（字符）
+	MaxQuickReplyAttachments   = 9    // 单个模板最多附件数
+	MaxPersonalQuickReplies    = 200  // 每个用户最多个人模板数
+)
+
+// ErrQuickReplyLimitReached 个人模板数量已达上限
+var ErrQuickReplyLimitReached = errors.New("个人快捷回复数量已达上限")
+
+// QuickReply 快捷回复模板
+type QuickReply struct {
+	ID          int                    `json:"id" db:"id"`
+	Scope       string                 `json:"scope" db:"scope"` // personal, group, organization
+	OwnerID     *int                   `json:"owner_id,omitempty" db:"owner_id"`
+	GroupID     *int                   `json:"group_id,omitempty" db:"group_id"`
+	Title       string                 `json:"title" db:"title"`
+	Content     string                 `json:"content" db:"content"`
+	CreatedBy   *int                   `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at" db:"updated_at"`
+	Attachments []QuickReplyAttachment `json:"attachments"`
+}
+
+// QuickReplyAttachment 快捷回复模板附件
+type QuickReplyAttachment struct {
+	MessageType string `json:"message_type"` // image, video, file, audio
+	URL         string `json:"url"`
+	ObjectKey   string `json:"-"`
+	FileName    string `json:"file_name"`
+	FileSize    int64  `json:"file_size"`
+}
+
+// QuickReplyAttachmentInput 模板附件请求（url 必须是已上传到OSS的文件）
+type QuickReplyAttachmentInput struct {
+	MessageType string `json:"message_type" binding:"required"`
+	URL         string `json:"url" binding:"required"`
+	FileName    string `json:"file_name"`
+}
+
+// SaveQuickReplyRequest 创建/更新快捷回复模板请求（更新时字段为空表示不修改）
+type SaveQuickReplyRequest struct {
+	Scope       string                       `json:"scope"`    // 创建时使用：personal（默认）或 group
+	GroupID     int                          `json:"group_id"` // 创建群组模板时必填
+	Title       *string                      `json:"title"`
+	Content     *string                      `json:"content"`
+	Attachments *[]QuickReplyAttachmentInput `json:"attachments"`
+}
+
+// RenderQuickReplyRequest 渲染快捷回复模板请求
+type RenderQuickReplyRequest struct {
+	ReceiverID int `json:"receiver_id"` // 私聊接收者，用于填充 recipient_* 占位符
+	GroupID    int `json:"group_id"`    // 群聊时的群组，用于填充 group_name 占位符
+}
+
+// RenderedQuickReply 渲染后的快捷回复
+type RenderedQuickReply struct {
+	ID          int                    `json:"id"`
+	Title       string                 `json:"title"`
+	Content     string                 `json:"content"`
+	Attachments []QuickReplyAttachment `json:"attachments"`
+	Unresolved  []string               `json:"unresolved,omitempty"` // 无法填充的占位符
+}
+
+// QuickReplyRepository 快捷回复模板数据仓库
+type QuickReplyRepository struct {
+	DB *sql.DB
+}
+
+// NewQuickReplyRepository 创建快捷回复模板仓库
+func NewQuickReplyRepository(db *sql.DB) *QuickReplyRepository {
+	return &QuickReplyRepository{DB: db}
+}
+
+const quickReplyColumns = `id, scope, owner_id, group_id, title, content, created_by, created_at, updated_at`
+
+func scanQuickReply(scanner interface{ Scan(...interface{}) error }) (*QuickReply, error) {
+	reply := &QuickReply{}
+	err := scanner.Scan(
+		&reply.ID,
+		&reply.Scope,
+		&reply.OwnerID,
+		&reply.GroupID,
+		&reply.Title,
+		&reply.Content,
+		&reply.CreatedBy,
+		&reply.CreatedAt,
+		&reply.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// GetByID 获取模板（包含附件）
+func (r *QuickReplyRepository) GetByID(id int) (*QuickReply, error) {
+	reply, err := scanQuickReply(r.DB.QueryRow(`SELECT `+quickReplyColumns+` FROM quick_replies WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+	replies := []QuickReply{*reply}
+	if err := r.loadAttachments(replies); err != nil {
+		return nil, err
+	}
+	return &replies[0], nil
+}
+
+// ListAvailable 获取用户可用的模板：个人模板、所在群组的群组模板和组织模板
+// scope 不为空时只返回该范围的模板，groupID 大于 0 时群组模板只返回该群组的
+func (r *QuickReplyRepository) ListAvailable(userID int, scope string, groupID int, keyword string) ([]QuickReply, error) {
+	query := `
+		SELECT ` + quickReplyColumns + ` FROM quick_replies
+		WHERE ((scope = 'personal' AND owner_id = $1)
+		    OR (scope = 'group' AND group_id IN (
+		        SELECT group_id FROM group_members WHERE user_id = $1 AND approval_status = 'approved'))
+		    OR scope = 'organization')`
+	args := []interface{}{userID}
+	if scope != "" {
+		args = append(args, scope)
+		query += ` AND scope = $` + strconv.Itoa(len(args))
+	}
+	if groupID > 0 {
+		args = append(args, groupID)
+		query += ` AND (scope <> 'group' OR group_id = $` + strconv.Itoa(len(args)) + `)`
+	}
+	if keyword != "" {
+		args = append(args, "%"+keyword+"%")
+		query += ` AND (title ILIKE $` + strconv.Itoa(len(args)) + ` OR content ILIKE $` + strconv.Itoa(len(args)) + `)`
+	}
+	query += ` ORDER BY CASE scope WHEN 'personal' THEN 0 WHEN 'group' THEN 1 ELSE 2 END, updated_at DESC, id DESC`
+	return r.list(query, args...)
+}
+
+// ListOrganization 获取组织模板（管理后台）
+func (r *QuickReplyRepository) ListOrganization() ([]QuickReply, error) {
+	return r.list(`SELECT ` + quickReplyColumns + ` FROM quick_replies WHERE scope = 'organization' ORDER BY updated_at DESC, id DESC`)
+}
+
+func (r *QuickReplyRepository) list(query string, args ...interface{}) ([]QuickReply, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replies := []QuickReply{}
+	for rows.Next() {
+		reply, err := scanQuickReply(rows)
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, *reply)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadAttachments(replies); err != nil {
+		return nil, err
+	}
+	return replies, nil
+}
+
+// Create 创建模板及其附件，个人模板数量达到上限时返回 ErrQuickReplyLimitReached
+func (r *QuickReplyRepository) Create(reply *QuickReply) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if reply.Scope == QuickReplyScopePersonal && reply.OwnerID != nil {
+		if err := lockPersonalQuickReplyLimit(tx, *reply.OwnerID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	err = tx.QueryRow(`
+		INSERT INTO quick_replies (scope, owner_id, group_id, title, content, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING id, created_at, updated_at
+	`, reply.Scope, reply.OwnerID, reply.GroupID, reply.Title, reply.Content, reply.CreatedBy, now).Scan(&reply.ID, &reply.CreatedAt, &reply.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceQuickReplyAttachments(tx, reply.ID, reply.Attachments); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockPersonalQuickReplyLimit 锁定用户后检查个人模板数量，避免并发创建超出上限
+func lockPersonalQuickReplyLimit(tx *sql.Tx, userID int) error {
+	if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return err
+	}
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM quick_replies WHERE scope = 'personal' AND owner_id = $1`, userID).Scan(&count); err != nil {
+		return err
+	}
+	if count >= MaxPersonalQuickReplies {
+		return ErrQuickReplyLimitReached
+	}
+	return nil
+}
+
+// Update 更新模板标题、内容和附件
+func (r *QuickReplyRepository) Update(reply *QuickReply) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		UPDATE quick_replies SET title = $1, content = $2, updated_at = $3
+		WHERE id = $4
+		RETURNING updated_at
+	`, reply.Title, reply.Content, time.Now().UTC(), reply.ID).Scan(&reply.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceQuickReplyAttachments(tx, reply.ID, reply.Attachments); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete 删除模板
+func (r *QuickReplyRepository) Delete(id int) (bool, error) {
+	result, err := r.DB.Exec(`DELETE FROM quick_replies WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// replaceQuickReplyAttachments 重新设置模板附件
+func replaceQuickReplyAttachments(tx *sql.Tx, replyID int, attachments []QuickReplyAttachment) error {
+	if _, err := tx.Exec(`DELETE FROM quick_reply_attachments WHERE quick_reply_id = $1`, replyID); err != nil {
+		return err
+	}
+	for i, a := range attachments {
+		if _, err := tx.Exec(`
+			INSERT INTO quick_reply_attachments (quick_reply_id, message_type, url, object_key, file_name, file_size, sort_order)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, replyID, a.MessageType, a.URL, a.ObjectKey, a.FileName, a.FileSize, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadAttachments 批量加载模板附件
+func (r *QuickReplyRepository) loadAttachments(replies []QuickReply) error {
+	if len(replies) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(replies))
+	placeholders := make([]string, 0, len(replies))
+	args := make([]interface{}, 0, len(replies))
+	for i := range replies {
+		replies[i].Attachments = []QuickReplyAttachment{}
+		index[replies[i].ID] = i
+		args = append(args, replies[i].ID)
+		placeholders = append(placeholders, "$"+strconv.Itoa(len(args)))
+	}
+
+	rows, err := r.DB.Query(`
+		SELECT quick_reply_id, message_type, url, object_key, file_name, file_size
+		FROM quick_reply_attachments
+		WHERE quick_reply_id IN (`+strings.Join(placeholders, ",")+`)
+		ORDER BY quick_reply_id, sort_order
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var replyID int
+		var a QuickReplyAttachment
+		if err := rows.Scan(&replyID, &a.MessageType, &a.URL, &a.ObjectKey, &a.FileName, &a.FileSize); err != nil {
+			return err
+		}
+		if i, ok := index[replyID]; ok {
+			replies[i].Attachments = append(replies[i].Attachments, a)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,465 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 保留策略范围
+const (
+	RetentionScopeGlobal = "global" // 全局默认策略
+	RetentionScopeGroup  = "group"  // 群组策略
+	RetentionScopeUser   = "user"   // 私聊策略
+)
+
+// MaxRetentionDays 保留天数上限
+const MaxRetentionDays = 36500
+
+// RetentionPolicy 消息保留策略
+type RetentionPolicy struct {
+	ID            int       `json:"id" db:"id"`
+	Scope         string    `json:"scope" db:"scope"`                   // global, group, user
+	TargetID      int       `json:"target_id" db:"target_id"`           // 群组ID；私聊为较小的用户ID；全局为0
+	PeerID        int       `json:"peer_id" db:"peer_id"`               // 私聊为较大的用户ID
+	RetentionDays *int      `json:"retention_days" db:"retention_days"` // 为空表示永久保留
+	LegalHold     bool      `json:"legal_hold" db:"legal_hold"`
+	Note          string    `json:"note" db:"note"`
+	UpdatedBy     *int      `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Purges 策略是否会清理消息（设置了保留天数且未开启法律保全）
+func (p *RetentionPolicy) Purges() bool {
+	return p.RetentionDays != nil && !p.LegalHold
+}
+
+// SaveRetentionPolicyRequest 保存保留策略请求（同一范围已有策略时覆盖）
+type SaveRetentionPolicyRequest struct {
+	Scope         string `json:"scope" binding:"required"`
+	GroupID       int    `json:"group_id"`       // scope=group 时必填
+	UserID        int    `json:"user_id"`        // scope=user 时必填，私聊双方之一
+	PeerUserID    int    `json:"peer_user_id"`   // scope=user 时必填，私聊双方之一
+	RetentionDays *int   `json:"retention_days"` // 为空表示永久保留
+	LegalHold     bool   `json:"legal_hold"`
+	Note          string `json:"note"`
+}
+
+// ToPolicy 校验请求并转换为策略（私聊双方按用户ID排序）
+func (req *SaveRetentionPolicyRequest) ToPolicy() (*RetentionPolicy, error) {
+	policy := &RetentionPolicy{
+		Scope:         req.Scope,
+		RetentionDays: req.RetentionDays,
+		LegalHold:     req.LegalHold,
+		Note:          strings.TrimSpace(req.Note),
+	}
+
+	switch req.Scope {
+	case RetentionScopeGlobal:
+	case RetentionScopeGroup:
+		if req.GroupID <= 0 {
+			return nil, errors.New("请指定群组")
+		}
+		policy.TargetID = req.GroupID
+	case RetentionScopeUser:
+		if req.UserID <= 0 || req.PeerUserID <= 0 || req.UserID == req.PeerUserID {
+			return nil, errors.New("请指定私聊双方")
+		}
+		policy.TargetID, policy.PeerID = RetentionUserPair(req.UserID, req.PeerUserID)
+	default:
+		return nil, errors.New("无效的策略范围")
+	}
+
+	if policy.RetentionDays != nil && (*policy.RetentionDays < 1 || *policy.RetentionDays > MaxRetentionDays) {
+		return nil, errors.New("保留天数必须在 1 到 " + strconv.Itoa(MaxRetentionDays) + " 之间")
+	}
+	if len([]rune(policy.Note)) > 500 {
+		return nil, errors.New("备注不能超过500个字符")
+	}
+	return policy, nil
+}
+
+// RetentionUserPair 私聊双方按用户ID排序
+func RetentionUserPair(userID, peerID int) (int, int) {
+	if userID > peerID {
+		return peerID, userID
+	}
+	return userID, peerID
+}
+
+// EffectiveRetention 会话实际生效的保留策略
+type EffectiveRetention struct {
+	Scope         string `json:"scope"`          // 生效策略的范围，未配置任何策略时为空
+	RetentionDays *int   `json:"retention_days"` // 为空表示永久保留
+	LegalHold     bool   `json:"legal_hold"`
+}
+
+// RetentionPurgeLog 清理审计日志
+type RetentionPurgeLog struct {
+	ID                int       `json:"id" db:"id"`
+	PolicyID          *int      `json:"policy_id" db:"policy_id"`
+	ConversationType  string    `json:"conversation_type" db:"conversation_type"`
+	TargetID          int       `json:"target_id" db:"target_id"`
+	PeerID            int       `json:"peer_id" db:"peer_id"`
+	RetentionDays     int       `json:"retention_days" db:"retention_days"`
+	Cutoff            time.Time `json:"cutoff" db:"cutoff"`
+	MessageCount      int       `json:"message_count" db:"message_count"`
+	ObjectCount       int       `json:"object_count" db:"object_count"`
+	ObjectFailedCount int       `json:"object_failed_count" db:"object_failed_count"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+}
+
+// PurgedMessage 被清理的消息（用于删除 OSS 文件和通知客户端）
+type PurgedMessage struct {
+	ID          int
+	TargetID    int // 群组ID；私聊为较小的用户ID；文件助手为用户ID
+	PeerID      int // 私聊为较大的用户ID
+	MessageType string
+	Content     string
+}
+
+// RetentionRepository 保留策略数据仓库
+type RetentionRepository struct {
+	DB *sql.DB
+}
+
+// NewRetentionRepository 创建保留策略仓库
+func NewRetentionRepository(db *sql.DB) *RetentionRepository {
+	return &RetentionRepository{DB: db}
+}
+
+const retentionPolicyColumns = `id, scope, target_id, peer_id, retention_days, legal_hold, note, updated_by, created_at, updated_at`
+
+func scanRetentionPolicy(scanner interface{ Scan(...interface{}) error }) (*RetentionPolicy, error) {
+	p := &RetentionPolicy{}
+	var retentionDays, updatedBy sql.NullInt64
+	err := scanner.Scan(
+		&p.ID,
+		&p.Scope,
+		&p.TargetID,
+		&p.PeerID,
+		&retentionDays,
+		&p.LegalHold,
+		&p.Note,
+		&updatedBy,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if retentionDays.Valid {
+		days := int(retentionDays.Int64)
+		p.RetentionDays = &days
+	}
+	if updatedBy.Valid {
+		id := int(updatedBy.Int64)
+		p.UpdatedBy = &id
+	}
+	return p, nil
+}
+
+// ListPolicies 获取所有保留策略
+func (r *RetentionRepository) ListPolicies() ([]RetentionPolicy, error) {
+	rows, err := r.DB.Query(`
+		SELECT ` + retentionPolicyColumns + ` FROM retention_policies
+		ORDER BY CASE scope WHEN 'global' THEN 0 WHEN 'group' THEN 1 ELSE 2 END, target_id, peer_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []RetentionPolicy{}
+	for rows.Next() {
+		p, err := scanRetentionPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, *p)
+	}
+	return policies, nil
+}
+
+// GetPolicy 获取指定范围的保留策略
+func (r *RetentionRepository) GetPolicy(scope string, targetID, peerID int) (*RetentionPolicy, error) {
+	return scanRetentionPolicy(r.DB.QueryRow(`
+		SELECT `+retentionPolicyColumns+` FROM retention_policies
+		WHERE scope = $1 AND target_id = $2 AND peer_id = $3
+	`, scope, targetID, peerID))
+}
+
+// SavePolicy 保存保留策略，同一范围已有策略时覆盖
+func (r *RetentionRepository) SavePolicy(p *RetentionPolicy) (*RetentionPolicy, error) {
+	now := time.Now().UTC()
+	return scanRetentionPolicy(r.DB.QueryRow(`
+		INSERT INTO retention_policies (scope, target_id, peer_id, retention_days, legal_hold, note, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (scope, target_id, peer_id) DO UPDATE
+		SET retention_days = EXCLUDED.retention_days, legal_hold = EXCLUDED.legal_hold, note = EXCLUDED.note,
+		    updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		RETURNING `+retentionPolicyColumns,
+		p.Scope, p.TargetID, p.PeerID, p.RetentionDays, p.LegalHold, p.Note, p.UpdatedBy, now))
+}
+
+// DeletePolicy 删除保留策略（删除后该范围回退到全局策略）
+func (r *RetentionRepository) DeletePolicy(policyID int) (bool, error) {
+	result, err := r.DB.Exec(`DELETE FROM retention_policies WHERE id = $1`, policyID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// GetEffectiveRetention 获取会话实际生效的保留策略
+// 全局或会话策略开启法律保全时永久保留；会话策略存在时覆盖全局策略
+func (r *RetentionRepository) GetEffectiveRetention(scope string, targetID, peerID int) (*EffectiveRetention, error) {
+	effective := &EffectiveRetention{}
+
+	global, err := r.GetPolicy(RetentionScopeGlobal, 0, 0)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if global != nil {
+		effective.Scope = global.Scope
+		effective.RetentionDays = global.RetentionDays
+		effective.LegalHold = global.LegalHold
+	}
+
+	if scope != RetentionScopeGlobal {
+		specific, err := r.GetPolicy(scope, targetID, peerID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if specific != nil {
+			effective.Scope = specific.Scope
+			effective.RetentionDays = specific.RetentionDays
+			effective.LegalHold = specific.LegalHold || (global != nil && global.LegalHold)
+		}
+	}
+
+	if effective.LegalHold {
+		effective.RetentionDays = nil
+	}
+	return effective, nil
+}
+
+// PurgeGroupMessages 删除一批早于 cutoff 的群消息
+// groupID > 0 时只清理该群；groupID 为 0 时清理所有未单独配置策略的群
+func (r *RetentionRepository) PurgeGroupMessages(groupID int, cutoff time.Time, limit int) ([]PurgedMessage, error) {
+	if groupID > 0 {
+		return r.purgeBatch("group_messages", `
+			SELECT id, group_id, 0, message_type, content FROM group_messages
+			WHERE created_at < $1 AND group_id = $2
+			ORDER BY id LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		`, cutoff, groupID, limit)
+	}
+	return r.purgeBatch("group_messages", `
+		SELECT m.id, m.group_id, 0, m.message_type, m.content FROM group_messages m
+		WHERE m.created_at < $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM retention_policies p
+			WHERE p.scope = 'group' AND p.target_id = m.group_id
+		  )
+		ORDER BY m.id LIMIT $2
+		FOR UPDATE OF m SKIP LOCKED
+	`, cutoff, limit)
+}
+
+// PurgePrivateMessages 删除一批早于 cutoff 的私聊消息
+// userID、peerID 均大于 0 时只清理这两人之间的私聊；否则清理所有未单独配置策略的私聊
+func (r *RetentionRepository) PurgePrivateMessages(userID, peerID int, cutoff time.Time, limit int) ([]PurgedMessage, error) {
+	if userID > 0 && peerID > 0 {
+		return r.purgeBatch("messages", `
+			SELECT id, LEAST(sender_id, receiver_id), GREATEST(sender_id, receiver_id), message_type, content FROM messages
+			WHERE created_at < $1
+			  AND ((sender_id = $2 AND receiver_id = $3) OR (sender_id = $3 AND receiver_id = $2))
+			ORDER BY id LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		`, cutoff, userID, peerID, limit)
+	}
+	return r.purgeBatch("messages", `
+		SELECT m.id, LEAST(m.sender_id, m.receiver_id), GREATEST(m.sender_id, m.receiver_id), m.message_type, m.content FROM messages m
+		WHERE m.created_at < $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM retention_policies p
+			WHERE p.scope = 'user' AND p.target_id = LEAST(m.sender_id, m.receiver_id) AND p.peer_id = GREATEST(m.sender_id, m.receiver_id)
+		  )
+		ORDER BY m.id LIMIT $2
+		FOR UPDATE OF m SKIP LOCKED
+	`, cutoff, limit)
+}
+
+// PurgeFileAssistantMessages 删除一批早于 cutoff 的文件传输助手消息（只受全局策略约束）
+func (r *RetentionRepository) PurgeFileAssistantMessages(cutoff time.Time, limit int) ([]PurgedMessage, error) {
+	return r.purgeBatch("file_assistant_messages", `
+		SELECT id, user_id, 0, message_type, content FROM file_assistant_messages
+		WHERE created_at < $1
+		ORDER BY id LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, cutoff, limit)
+}
+
+// purgeBatch 锁定并删除一批消息，返回被删除的消息
+// 私聊消息被收藏时保留收藏内容，只解除与原消息的关联；引用消息的投票、任务和加急记录一并删除
+func (r *RetentionRepository) purgeBatch(table, selectQuery string, args ...interface{}) ([]PurgedMessage, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(selectQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	purged := []PurgedMessage{}
+	for rows.Next() {
+		var m PurgedMessage
+		if err := rows.Scan(&m.ID, &m.TargetID, &m.PeerID, &m.MessageType, &m.Content); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		purged = append(purged, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(purged) == 0 {
+		return purged, nil
+	}
+
+	placeholders := make([]string, len(purged))
+	ids := make([]interface{}, len(purged))
+	for i, m := range purged {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		ids[i] = m.ID
+	}
+	in := strings.Join(placeholders, ", ")
+
+	if table == "messages" {
+		if _, err := tx.Exec(`UPDATE favorites SET message_id = NULL WHERE message_id IN (`+in+`)`, ids...); err != nil {
+			return nil, err
+		}
+	}
+	if err := purgeMessageReferences(tx, table, in, ids); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM `+table+` WHERE id IN (`+in+`)`, ids...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
+
+// purgeMessageReferences 删除引用被清理消息的投票、任务和加急记录（message_id 可能指向私聊或群消息，无法使用外键级联）
+func purgeMessageReferences(tx *sql.Tx, table, in string, ids []interface{}) error {
+	var conversationType string
+	switch table {
+	case "messages":
+		conversationType = ConversationTypeUser
+	case "group_messages":
+		conversationType = ConversationTypeGroup
+		// 投票选项和投票记录随投票级联删除
+		if _, err := tx.Exec(`DELETE FROM group_polls WHERE message_id IN (`+in+`)`, ids...); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	args := append(append([]interface{}{}, ids...), conversationType)
+	typeParam := "$" + strconv.Itoa(len(args))
+	// 任务负责人和加急回执随主记录级联删除
+	if _, err := tx.Exec(`DELETE FROM message_tasks WHERE conversation_type = `+typeParam+` AND message_id IN (`+in+`)`, args...); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM urgent_messages WHERE conversation_type = `+typeParam+` AND message_id IN (`+in+`)`, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// IsContentReferenced 检查文件地址是否仍被其他消息或收藏引用（转发的消息共用同一个 OSS 文件）
+func (r *RetentionRepository) IsContentReferenced(content string) (bool, error) {
+	var referenced bool
+	err := r.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM messages WHERE content = $1)
+		    OR EXISTS (SELECT 1 FROM group_messages WHERE content = $1)
+		    OR EXISTS (SELECT 1 FROM file_assistant_messages WHERE content = $1)
+		    OR EXISTS (SELECT 1 FROM favorites WHERE content = $1)
+	`, content).Scan(&referenced)
+	return referenced, err
+}
+
+// CreatePurgeLog 写入清理审计日志
+func (r *RetentionRepository) CreatePurgeLog(log *RetentionPurgeLog) error {
+	return r.DB.QueryRow(`
+		INSERT INTO retention_purge_logs (policy_id, conversation_type, target_id, peer_id, retention_days, cutoff,
+			message_count, object_count, object_failed_count, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`, log.PolicyID, log.ConversationType, log.TargetID, log.PeerID, log.RetentionDays, log.Cutoff.UTC(),
+		log.MessageCount, log.ObjectCount, log.ObjectFailedCount, time.Now().UTC()).Scan(&log.ID, &log.CreatedAt)
+}
+
+// ListPurgeLogs 分页获取清理审计日志
+func (r *RetentionRepository) ListPurgeLogs(conversationType string, targetID, limit, offset int) ([]RetentionPurgeLog, int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	if conversationType != "" {
+		args = append(args, conversationType)
+		conditions = append(conditions, `conversation_type = $`+strconv.Itoa(len(args)))
+	}
+	if targetID > 0 {
+		args = append(args, targetID)
+		conditions = append(conditions, `(target_id = $`+strconv.Itoa(len(args))+` OR peer_id = $`+strconv.Itoa(len(args))+`)`)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	var total int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM retention_purge_logs`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	n := len(args)
+	query := `
+		SELECT id, policy_id, conversation_type, target_id, peer_id, retention_days, cutoff,
+			message_count, object_count, object_failed_count, created_at
+		FROM retention_purge_logs` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $` + strconv.Itoa(n+1) + ` OFFSET $` + strconv.Itoa(n+2)
+	rows, err := r.DB.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	logs := []RetentionPurgeLog{}
+	for rows.Next() {
+		var log RetentionPurgeLog
+		var policyID sql.NullInt64
+		if err := rows.Scan(&log.ID, &policyID, &log.ConversationType, &log.TargetID, &log.PeerID, &log.RetentionDays, &log.Cutoff,
+			&log.MessageCount, &log.ObjectCount, &log.ObjectFailedCount, &log.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if policyID.Valid {
+			id := int(policyID.Int64)
+			log.PolicyID = &id
+		}
+		logs = append(logs, log)
+	}
+	return logs, total, nil
+}
@@ -0,0 +1,471 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MessageTypeRichText 富文本消息（受限 Markdown，服务端规范化为结构化文档）
+const MessageTypeRichText = "rich_text"
+
+// 富文本限制
+const (
+	maxRichTextLen      = 10000 // 原始内容最大长度（字符）
+	maxRichTextBlocks   = 200   // 最多块数
+	maxRichTextURLLen   = 2048  // 链接最大长度
+	maxRichTextLangLen  = 20    // 代码块语言标识最大长度
+	maxRichTextListSize = 100   // 单个列表最多条目数
+)
+
+// 富文本块类型
+const (
+	RichTextBlockParagraph = "paragraph"  // 段落
+	RichTextBlockCode      = "code_block" // 代码块
+	RichTextBlockList      = "list"       // 列表
+)
+
+// 富文本行内元素类型
+const (
+	RichTextInlineText    = "text"    // 普通文本
+	RichTextInlineBold    = "bold"    // 加粗
+	RichTextInlineCode    = "code"    // 行内代码
+	RichTextInlineLink    = "link"    // 链接
+	RichTextInlineMention = "mention" // @提及
+)
+
+// RichTextDocument 富文本规范化文档（存储在消息 content 中）
+type RichTextDocument struct {
+	Blocks []RichTextBlock `json:"blocks"`
+}
+
+// RichTextBlock 富文本块
+type RichTextBlock struct {
+	Type     string             `json:"type"`               // paragraph, code_block, list
+	Inlines  []RichTextInline   `json:"inlines,omitempty"`  // 段落内容
+	Language string             `json:"language,omitempty"` // 代码块语言
+	Code     string             `json:"code,omitempty"`     // 代码块内容（原样保留）
+	Ordered  bool               `json:"ordered,omitempty"`  // 是否有序列表
+	Items    [][]RichTextInline `json:"items,omitempty"`    // 列表条目
+}
+
+// RichTextInline 富文本行内元素
+type RichTextInline struct {
+	Type   string `json:"type"`              // text, bold, code, link, mention
+	Text   string `json:"text"`              // 显示文本
+	URL    string `json:"url,omitempty"`     // 链接地址（仅 link）
+	UserID int    `json:"user_id,omitempty"` // 被提及的用户ID（仅 mention）
+}
+
+var (
+	richTextOrderedItem = regexp.MustCompile(`^\d{1,3}[.)]\s+`)
+	richTextLanguage    = regexp.MustCompile(`^[A-Za-z0-9_+#.-]*$`)
+)
+
+// ParseRichText 解析客户端提交的富文本内容并规范化
+// 支持受限 Markdown（**加粗**、`代码`、```代码块```、- / 1. 列表、[文本](链接)、@[名称](user:ID)），
+// 也接受已规范化的 JSON 文档（例如转发时），两者都会重新清洗
+func ParseRichText(content string) (*RichTextDocument, error) {
+	if utf8.RuneCountInString(content) > maxRichTextLen {
+		return nil, errors.New("富文本内容过长")
+	}
+
+	var doc *RichTextDocument
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "{") {
+		var parsed RichTextDocument
+		if err := json.Unmarshal([]byte(trimmed), &parsed); err == nil && parsed.Blocks != nil {
+			doc = sanitizeRichTextDocument(&parsed)
+		}
+	}
+	if doc == nil {
+		doc = parseRichTextMarkdown(content)
+	}
+
+	if len(doc.Blocks) == 0 {
+		return nil, errors.New("富文本内容不能为空")
+	}
+	if len(doc.Blocks) > maxRichTextBlocks {
+		return nil, errors.New("富文本内容过长")
+	}
+	return doc, nil
+}
+
+// ParseRichTextContent 解析已存储的规范化富文本内容
+func ParseRichTextContent(content string) (*RichTextDocument, error) {
+	var doc RichTextDocument
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// PlainText 生成纯文本（用于会话预览、通知和收藏）
+func (d *RichTextDocument) PlainText() string {
+	lines := make([]string, 0, len(d.Blocks))
+	for _, block := range d.Blocks {
+		switch block.Type {
+		case RichTextBlockCode:
+			lines = append(lines, block.Code)
+		case RichTextBlockList:
+			for i, item := range block.Items {
+				prefix := "- "
+				if block.Ordered {
+					prefix = strconv.Itoa(i+1) + ". "
+				}
+				lines = append(lines, prefix+richTextInlinesPlainText(item))
+			}
+		default:
+			lines = append(lines, richTextInlinesPlainText(block.Inlines))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MentionedUserIDs 返回文档中 @提及 的用户ID（去重）
+func (d *RichTextDocument) MentionedUserIDs() []int {
+	var ids []int
+	seen := make(map[int]bool)
+	collect := func(inlines []RichTextInline) {
+		for _, inline := range inlines {
+			if inline.Type == RichTextInlineMention && !seen[inline.UserID] {
+				seen[inline.UserID] = true
+				ids = append(ids, inline.UserID)
+			}
+		}
+	}
+	for _, block := range d.Blocks {
+		collect(block.Inlines)
+		for _, item := range block.Items {
+			collect(item)
+		}
+	}
+	return ids
+}
+
+// ResolveMentions 用服务端数据重写 @提及 的显示名称：resolve 返回被提及用户的名称，
+// 用户不在会话中时返回 false，该提及降级为普通文本（不再作为@提醒）
+func (d *RichTextDocument) ResolveMentions(resolve func(userID int) (string, bool)) {
+	resolveInlines := func(inlines []RichTextInline) {
+		for i := range inlines {
+			inline := &inlines[i]
+			if inline.Type != RichTextInlineMention {
+				continue
+			}
+			if name, ok := resolve(inline.UserID); ok && name != "" {
+				inline.Text = name
+				continue
+			}
+			*inline = RichTextInline{Type: RichTextInlineText, Text: "@" + inline.Text}
+		}
+	}
+	for i := range d.Blocks {
+		block := &d.Blocks[i]
+		resolveInlines(block.Inlines)
+		for _, item := range block.Items {
+			resolveInlines(item)
+		}
+	}
+}
+
+// MapText 对文档中所有可见文本（行内文本和代码块）应用 fn，用于内容审核打码
+func (d *RichTextDocument) MapText(fn func(string) string) {
+	mapInlines := func(inlines []RichTextInline) {
+		for i := range inlines {
+			inlines[i].Text = fn(inlines[i].Text)
+		}
+	}
+	for i := range d.Blocks {
+		block := &d.Blocks[i]
+		block.Code = fn(block.Code)
+		mapInlines(block.Inlines)
+		for _, item := range block.Items {
+			mapInlines(item)
+		}
+	}
+}
+
+// MessagePlainText 返回消息的纯文本内容，富文本消息返回其纯文本，其余消息返回原内容
+func MessagePlainText(messageType, content string) string {
+	if messageType == MessageTypeRichText {
+		if doc, err := ParseRichTextContent(content); err == nil {
+			return doc.PlainText()
+		}
+	}
+	return content
+}
+
+// StoredPlainText 返回写入 plain_text 列的纯文本，仅富文本消息有值
+func StoredPlainText(messageType, content string) *string {
+	if messageType != MessageTypeRichText {
+		return nil
+	}
+	text := MessagePlainText(messageType, content)
+	return &text
+}
+
+func richTextInlinesPlainText(inlines []RichTextInline) string {
+	var sb strings.Builder
+	for _, inline := range inlines {
+		switch inline.Type {
+		case RichTextInlineMention:
+			sb.WriteString("@" + inline.Text)
+		case RichTextInlineLink:
+			sb.WriteString(inline.Text)
+			if inline.URL != inline.Text {
+				sb.WriteString(" (" + inline.URL + ")")
+			}
+		default:
+			sb.WriteString(inline.Text)
+		}
+	}
+	return sb.String()
+}
+
+// parseRichTextMarkdown 按行解析受限 Markdown
+func parseRichTextMarkdown(content string) *RichTextDocument {
+	doc := &RichTextDocument{Blocks: []RichTextBlock{}}
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var paragraph []string
+	var list *RichTextBlock
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			if inlines := parseRichTextInlines(strings.Join(paragraph, "\n")); len(inlines) > 0 {
+				doc.Blocks = append(doc.Blocks, RichTextBlock{Type: RichTextBlockParagraph, Inlines: inlines})
+			}
+			paragraph = nil
+		}
+	}
+	flushList := func() {
+		if list != nil {
+			doc.Blocks = append(doc.Blocks, *list)
+			list = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		// 代码块：``` 开始，到下一个 ``` 结束（未闭合时延续到末尾）
+		if strings.HasPrefix(trimmed, "```") {
+			flushParagraph()
+			flushList()
+			language := strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+			if len(language) > maxRichTextLangLen || !richTextLanguage.MatchString(language) {
+				language = ""
+			}
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.TrimSpace(lines[i]) == "```" {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			doc.Blocks = append(doc.Blocks, RichTextBlock{Type: RichTextBlockCode, Language: language, Code: strings.Join(code, "\n")})
+			continue
+		}
+
+		if trimmed == "" {
+			flushParagraph()
+			flushList()
+			continue
+		}
+
+		// 列表条目
+		ordered, itemText, isItem := parseRichTextListItem(trimmed)
+		if isItem {
+			flushParagraph()
+			if list != nil && (list.Ordered != ordered || len(list.Items) >= maxRichTextListSize) {
+				flushList()
+			}
+			if list == nil {
+				list = &RichTextBlock{Type: RichTextBlockList, Ordered: ordered}
+			}
+			list.Items = append(list.Items, parseRichTextInlines(itemText))
+			continue
+		}
+
+		flushList()
+		paragraph = append(paragraph, line)
+	}
+	flushParagraph()
+	flushList()
+
+	return doc
+}
+
+func parseRichTextListItem(line string) (ordered bool, text string, ok bool) {
+	for _, marker := range []string{"- ", "* ", "+ "} {
+		if strings.HasPrefix(line, marker) {
+			return false, strings.TrimSpace(line[len(marker):]), true
+		}
+	}
+	if loc := richTextOrderedItem.FindStringIndex(line); loc != nil {
+		return true, strings.TrimSpace(line[loc[1]:]), true
+	}
+	return false, "", false
+}
+
+// parseRichTextInlines 解析行内元素，无法识别的标记按普通文本处理
+func parseRichTextInlines(text string) []RichTextInline {
+	var inlines []RichTextInline
+	appendText := func(s string) {
+		if s == "" {
+			return
+		}
+		if n := len(inlines); n > 0 && inlines[n-1].Type == RichTextInlineText {
+			inlines[n-1].Text += s
+			return
+		}
+		inlines = append(inlines, RichTextInline{Type: RichTextInlineText, Text: s})
+	}
+
+	for len(text) > 0 {
+		switch {
+		case strings.HasPrefix(text, "**"):
+			if end := strings.Index(text[2:], "**"); end > 0 {
+				inlines = append(inlines, RichTextInline{Type: RichTextInlineBold, Text: text[2 : 2+end]})
+				text = text[4+end:]
+				continue
+			}
+		case strings.HasPrefix(text, "`"):
+			if end := strings.Index(text[1:], "`"); end > 0 {
+				inlines = append(inlines, RichTextInline{Type: RichTextInlineCode, Text: text[1 : 1+end]})
+				text = text[2+end:]
+				continue
+			}
+		case strings.HasPrefix(text, "@["):
+			if label, target, rest, ok := parseRichTextBracket(text[1:]); ok && strings.HasPrefix(target, "user:") {
+				if userID, err := strconv.Atoi(strings.TrimPrefix(target, "user:")); err == nil && userID > 0 && label != "" {
+					inlines = append(inlines, RichTextInline{Type: RichTextInlineMention, Text: label, UserID: userID})
+					text = rest
+					continue
+				}
+			}
+		case strings.HasPrefix(text, "["):
+			if label, target, rest, ok := parseRichTextBracket(text); ok {
+				if safeURL, valid := sanitizeRichTextURL(target); valid {
+					if label == "" {
+						label = safeURL
+					}
+					inlines = append(inlines, RichTextInline{Type: RichTextInlineLink, Text: label, URL: safeURL})
+					text = rest
+					continue
+				}
+			}
+		}
+
+		// 普通文本：读到下一个可能的标记为止
+		next := strings.IndexAny(text[1:], "*`@[")
+		if next < 0 {
+			appendText(text)
+			break
+		}
+		appendText(text[:1+next])
+		text = text[1+next:]
+	}
+
+	return inlines
+}
+
+// parseRichTextBracket 解析 [label](target)，返回剩余文本
+func parseRichTextBracket(text string) (label, target, rest string, ok bool) {
+	closeLabel := strings.Index(text, "](")
+	if !strings.HasPrefix(text, "[") || closeLabel < 0 || strings.Contains(text[1:closeLabel], "\n") {
+		return "", "", "", false
+	}
+	closeTarget := strings.Index(text[closeLabel+2:], ")")
+	if closeTarget < 0 {
+		return "", "", "", false
+	}
+	label = strings.TrimSpace(text[1:closeLabel])
+	target = strings.TrimSpace(text[closeLabel+2 : closeLabel+2+closeTarget])
+	return label, target, text[closeLabel+3+closeTarget:], true
+}
+
+// sanitizeRichTextURL 只允许 http、https、mailto 链接
+func sanitizeRichTextURL(raw string) (string, bool) {
+	if raw == "" || len(raw) > maxRichTextURLLen {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "", false
+		}
+	case "mailto":
+	default:
+		return "", false
+	}
+	return u.String(), true
+}
+
+// sanitizeRichTextDocument 清洗客户端提交的结构化文档：丢弃未知类型，非法链接/提及降级为普通文本
+func sanitizeRichTextDocument(doc *RichTextDocument) *RichTextDocument {
+	result := &RichTextDocument{Blocks: []RichTextBlock{}}
+	for _, block := range doc.Blocks {
+		switch block.Type {
+		case RichTextBlockParagraph:
+			if inlines := sanitizeRichTextInlines(block.Inlines); len(inlines) > 0 {
+				result.Blocks = append(result.Blocks, RichTextBlock{Type: RichTextBlockParagraph, Inlines: inlines})
+			}
+		case RichTextBlockCode:
+			language := block.Language
+			if len(language) > maxRichTextLangLen || !richTextLanguage.MatchString(language) {
+				language = ""
+			}
+			result.Blocks = append(result.Blocks, RichTextBlock{Type: RichTextBlockCode, Language: language, Code: block.Code})
+		case RichTextBlockList:
+			list := RichTextBlock{Type: RichTextBlockList, Ordered: block.Ordered}
+			for _, item := range block.Items {
+				if len(list.Items) >= maxRichTextListSize {
+					break
+				}
+				list.Items = append(list.Items, sanitizeRichTextInlines(item))
+			}
+			if len(list.Items) > 0 {
+				result.Blocks = append(result.Blocks, list)
+			}
+		}
+	}
+	return result
+}
+
+func sanitizeRichTextInlines(inlines []RichTextInline) []RichTextInline {
+	var result []RichTextInline
+	for _, inline := range inlines {
+		if inline.Text == "" {
+			continue
+		}
+		clean := RichTextInline{Type: inline.Type, Text: inline.Text}
+		switch inline.Type {
+		case RichTextInlineText, RichTextInlineBold, RichTextInlineCode:
+		case RichTextInlineLink:
+			if safeURL, ok := sanitizeRichTextURL(inline.URL); ok {
+				clean.URL = safeURL
+			} else {
+				clean.Type = RichTextInlineText
+			}
+		case RichTextInlineMention:
+			if inline.UserID > 0 {
+				clean.UserID = inline.UserID
+			} else {
+				clean.Type = RichTextInlineText
+			}
+		default:
+			clean.Type = RichTextInlineText
+		}
+		result = append(result, clean)
+	}
+	return result
+}
@@ -0,0 +1,269 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRichText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []RichTextBlock
+		wantErr bool
+	}{
+		{
+			name:    "加粗、代码和链接",
+			content: "**重要** `make build` [文档](https://example.com/docs)",
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineBold, Text: "重要"},
+				{Type: RichTextInlineText, Text: " "},
+				{Type: RichTextInlineCode, Text: "make build"},
+				{Type: RichTextInlineText, Text: " "},
+				{Type: RichTextInlineLink, Text: "文档", URL: "https://example.com/docs"},
+			}}},
+		},
+		{
+			name:    "javascript链接降级为文本",
+			content: "[点我](javascript:alert(1))",
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineText, Text: "[点我](javascript:alert(1))"},
+			}}},
+		},
+		{
+			name:    "大写JAVASCRIPT链接降级为文本",
+			content: "[x](JaVaScRiPt:alert(1))",
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineText, Text: "[x](JaVaScRiPt:alert(1))"},
+			}}},
+		},
+		{
+			name:    "data链接降级为文本",
+			content: "看 [图](data:text/html;base64,PHNjcmlwdD4=) 吧",
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineText, Text: "看 [图](data:text/html;base64,PHNjcmlwdD4=) 吧"},
+			}}},
+		},
+		{
+			name:    "无主机的http链接降级为文本",
+			content: "[x](http:///path)",
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineText, Text: "[x](http:///path)"},
+			}}},
+		},
+		{
+			name:    "mailto链接",
+			content: "[邮件](mailto:a@example.com)",
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineLink, Text: "邮件", URL: "mailto:a@example.com"},
+			}}},
+		},
+		{
+			name:    "标签内嵌套方括号",
+			content: "[a [b]](https://example.com)",
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineLink, Text: "a [b]", URL: "https://example.com"},
+			}}},
+		},
+		{
+			name:    "外层方括号包裹的危险链接",
+			content: "[[x]](javascript:alert(1))",
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineText, Text: "[[x]](javascript:alert(1))"},
+			}}},
+		},
+		{
+			name:    "空标签使用链接作为文本",
+			content: "[](https://example.com)",
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineLink, Text: "https://example.com", URL: "https://example.com"},
+			}}},
+		},
+		{
+			name:    "@提及",
+			content: "@[张三](user:12) 请看",
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineMention, Text: "张三", UserID: 12},
+				{Type: RichTextInlineText, Text: " 请看"},
+			}}},
+		},
+		{
+			name:    "非法用户ID的提及按文本处理",
+			content: "@[张三](user:abc)",
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineText, Text: "@[张三](user:abc)"},
+			}}},
+		},
+		{
+			name:    "列表和代码块",
+			content: "- 一\n- 二\n\n1. 甲\n```go\nfmt.Println()\n```",
+			want: []RichTextBlock{
+				{Type: RichTextBlockList, Items: [][]RichTextInline{
+					{{Type: RichTextInlineText, Text: "一"}},
+					{{Type: RichTextInlineText, Text: "二"}},
+				}},
+				{Type: RichTextBlockList, Ordered: true, Items: [][]RichTextInline{
+					{{Type: RichTextInlineText, Text: "甲"}},
+				}},
+				{Type: RichTextBlockCode, Language: "go", Code: "fmt.Println()"},
+			},
+		},
+		{
+			name:    "非法代码块语言被清空",
+			content: "```<script>\nx\n```",
+			want:    []RichTextBlock{{Type: RichTextBlockCode, Code: "x"}},
+		},
+		{
+			name:    "结构化文档中的危险链接和未知类型被清洗",
+			content: `{"blocks":[{"type":"paragraph","inlines":[{"type":"link","text":"x","url":"javascript:alert(1)"},{"type":"mention","text":"y","user_id":0},{"type":"html","text":"<b>z</b>"}]},{"type":"iframe"}]}`,
+			want: []RichTextBlock{{Type: RichTextBlockParagraph, Inlines: []RichTextInline{
+				{Type: RichTextInlineText, Text: "x"},
+				{Type: RichTextInlineText, Text: "y"},
+				{Type: RichTextInlineText, Text: "<b>z</b>"},
+			}}},
+		},
+		{
+			name:    "空内容",
+			content: "   \n\n",
+			wantErr: true,
+		},
+		{
+			name:    "内容超长",
+			content: strings.Repeat("字", maxRichTextLen+1),
+			wantErr: true,
+		},
+		{
+			name:    "块数超限",
+			content: strings.Repeat("段落\n\n", maxRichTextBlocks+1),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseRichText(tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("应返回错误，实际得到 %+v", doc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRichText 返回错误: %v", err)
+			}
+			if !reflect.DeepEqual(doc.Blocks, tt.want) {
+				got, _ := json.Marshal(doc.Blocks)
+				want, _ := json.Marshal(tt.want)
+				t.Fatalf("Blocks =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestSanitizeRichTextURL(t *testing.T) {
+	tests := []struct {
+		raw   string
+		valid bool
+	}{
+		{raw: "https://example.com/a?b=c", valid: true},
+		{raw: "HTTP://example.com", valid: true},
+		{raw: "mailto:a@example.com", valid: true},
+		{raw: "javascript:alert(1)", valid: false},
+		{raw: " javascript:alert(1)", valid: false},
+		{raw: "data:text/html,<script>", valid: false},
+		{raw: "vbscript:msgbox", valid: false},
+		{raw: "file:///etc/passwd", valid: false},
+		{raw: "//example.com/a", valid: false},
+		{raw: "/relative", valid: false},
+		{raw: "https://", valid: false},
+		{raw: "", valid: false},
+		{raw: "https://example.com/" + strings.Repeat("a", maxRichTextURLLen), valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if _, valid := sanitizeRichTextURL(tt.raw); valid != tt.valid {
+				t.Fatalf("sanitizeRichTextURL(%q) valid = %v, want %v", tt.raw, valid, tt.valid)
+			}
+		})
+	}
+}
+
+func TestMessagePlainText(t *testing.T) {
+	doc, err := ParseRichText("**标题** 见 [文档](https://example.com)\n@[张三](user:3) 看下\n\n- 一\n- 二\n\n1. 甲\n2. 乙\n```\ncode\n```")
+	if err != nil {
+		t.Fatalf("ParseRichText 返回错误: %v", err)
+	}
+	data, _ := json.Marshal(doc)
+
+	tests := []struct {
+		name        string
+		messageType string
+		content     string
+		want        string
+	}{
+		{
+			name:        "富文本提取纯文本",
+			messageType: MessageTypeRichText,
+			content:     string(data),
+			want:        "标题 见 文档 (https://example.com)\n@张三 看下\n- 一\n- 二\n1. 甲\n2. 乙\ncode",
+		},
+		{
+			name:        "链接文本与地址相同时不重复",
+			messageType: MessageTypeRichText,
+			content:     `{"blocks":[{"type":"paragraph","inlines":[{"type":"link","text":"https://example.com","url":"https://example.com"}]}]}`,
+			want:        "https://example.com",
+		},
+		{
+			name:        "无法解析的富文本返回原内容",
+			messageType: MessageTypeRichText,
+			content:     "not json",
+			want:        "not json",
+		},
+		{
+			name:        "普通文本原样返回",
+			messageType: "text",
+			content:     "**不解析**",
+			want:        "**不解析**",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MessagePlainText(tt.messageType, tt.content); got != tt.want {
+				t.Fatalf("MessagePlainText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := StoredPlainText("text", "hi"); got != nil {
+		t.Errorf("非富文本消息的 plain_text 应为空，实际 %q", *got)
+	}
+}
+
+func TestRichTextResolveMentions(t *testing.T) {
+	doc, err := ParseRichText("@[管理员](user:1) @[伪造名称](user:2) @[外人](user:9)\n- @[管理员](user:1)")
+	if err != nil {
+		t.Fatalf("ParseRichText 返回错误: %v", err)
+	}
+
+	members := map[int]string{1: "张三", 2: "李四"}
+	doc.ResolveMentions(func(userID int) (string, bool) {
+		name, ok := members[userID]
+		return name, ok
+	})
+
+	if want := []int{1, 2}; !reflect.DeepEqual(doc.MentionedUserIDs(), want) {
+		t.Errorf("MentionedUserIDs = %v, want %v", doc.MentionedUserIDs(), want)
+	}
+	if want := "@张三 @李四 @外人\n- @张三"; doc.PlainText() != want {
+		t.Errorf("PlainText = %q, want %q", doc.PlainText(), want)
+	}
+	for _, inline := range doc.Blocks[0].Inlines {
+		if inline.Type == RichTextInlineMention && inline.UserID == 9 {
+			t.Fatal("非成员的提及应降级为普通文本")
+		}
+	}
+}
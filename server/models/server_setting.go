@@ -2,6 +2,9 @@ package models
 
 import (
 	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -111,3 +114,55 @@ func (r *ServerSettingRepository) Upsert(key, value, description string) error {
 	return err
 }
 
+// 消息撤回时限设置
+const (
+	RecallWindowSettingKey     = "message_recall_window_minutes" // 全局撤回时限（分钟）
+	DefaultRecallWindowMinutes = 3                               // 未配置时的默认撤回时限（分钟）
+	MaxGroupRecallWindow       = 1440                            // 群组可设置的最大撤回时限（分钟）
+)
+
+// GroupRecallWindowSettingKey 群组撤回时限设置键
+func GroupRecallWindowSettingKey(groupID int) string {
+	return fmt.Sprintf("group_recall_window_minutes_%d", groupID)
+}
+
+// SetGroupRecallWindowMinutes 设置群组撤回时限（分钟），minutes 为 nil 时删除群组配置、恢复使用全局配置
+func (r *ServerSettingRepository) SetGroupRecallWindowMinutes(groupID int, minutes *int) error {
+	key := GroupRecallWindowSettingKey(groupID)
+	if minutes == nil {
+		_, err := r.DB.Exec(`DELETE FROM server_settings WHERE key = $1`, key)
+		return err
+	}
+	return r.Upsert(key, strconv.Itoa(*minutes), fmt.Sprintf("群组 %d 的消息撤回时限（分钟）", groupID))
+}
+
+// GetRecallWindowMinutes 获取撤回时限（分钟），优先使用群组配置，其次全局配置；0 表示不限制
+func (r *ServerSettingRepository) GetRecallWindowMinutes(groupID int) int {
+	keys := []string{RecallWindowSettingKey}
+	if groupID > 0 {
+		keys = append([]string{GroupRecallWindowSettingKey(groupID)}, keys...)
+	}
+
+	for _, key := range keys {
+		setting, err := r.GetByKey(key)
+		if err != nil {
+			continue
+		}
+		minutes, err := strconv.Atoi(strings.TrimSpace(setting.Value))
+		if err != nil || minutes < 0 {
+			continue
+		}
+		return minutes
+	}
+
+	return DefaultRecallWindowMinutes
+}
+
+// IsWithinRecallWindow 判断消息是否仍在撤回时限内，同时返回生效的时限（分钟）
+func (r *ServerSettingRepository) IsWithinRecallWindow(createdAt time.Time, groupID int) (bool, int) {
+	minutes := r.GetRecallWindowMinutes(groupID)
+	if minutes == 0 {
+		return true, 0
+	}
+	return time.Since(createdAt) <= time.Duration(minutes)*time.Minute, minutes
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 斜杠命令来源
+const (
+	SlashCommandSourceBuiltin = "builtin" // 内置命令
+	SlashCommandSourceBot     = "bot"     // 机器人注册的命令
+)
+
+// BotCommand 机器人注册的斜杠命令
+type BotCommand struct {
+	ID          int       `json:"id" db:"id"`
+	BotID       int       `json:"bot_id" db:"bot_id"`
+	Command     string    `json:"command" db:"command"`
+	Description string    `json:"description" db:"description"`
+	Usage       string    `json:"usage" db:"usage"`
+	CallbackURL string    `json:"callback_url" db:"callback_url"`
+	Secret      string    `json:"-" db:"secret"`
+	MinRole     string    `json:"min_role" db:"min_role"` // member, admin, owner
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SaveBotCommandRequest 注册/更新机器人命令请求
+type SaveBotCommandRequest struct {
+	Command     string `json:"command" binding:"required"`
+	Description string `json:"description"`
+	Usage       string `json:"usage"`
+	CallbackURL string `json:"callback_url" binding:"required"`
+	Secret      string `json:"secret"` // 为空时自动生成（更新时保留原密钥）
+	MinRole     string `json:"min_role"`
+}
+
+// SlashCommandInfo 斜杠命令说明（用于自动补全）
+type SlashCommandInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Usage       string `json:"usage"`
+	Source      string `json:"source"` // builtin, bot
+	BotID       int    `json:"bot_id,omitempty"`
+	BotName     string `json:"bot_name,omitempty"`
+}
+
+// CommandReminder /remind 创建的提醒
+type CommandReminder struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	GroupID   int       `json:"group_id" db:"group_id"`
+	Content   string    `json:"content" db:"content"`
+	RemindAt  time.Time `json:"remind_at" db:"remind_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// SlashCommandRepository 斜杠命令数据仓库
+type SlashCommandRepository struct {
+	DB *sql.DB
+}
+
+// NewSlashCommandRepository 创建斜杠命令仓库
+func NewSlashCommandRepository(db *sql.DB) *SlashCommandRepository {
+	return &SlashCommandRepository{DB: db}
+}
+
+const botCommandColumns = `c.id, c.bot_id, c.command, c.description, c.usage, c.callback_url, c.secret, c.min_role, c.created_at, c.updated_at`
+
+func scanBotCommand(scanner interface{ Scan(...interface{}) error }, extra ...interface{}) (*BotCommand, error) {
+	cmd := &BotCommand{}
+	dest := []interface{}{
+		&cmd.ID,
+		&cmd.BotID,
+		&cmd.Command,
+		&cmd.Description,
+		&cmd.Usage,
+		&cmd.CallbackURL,
+		&cmd.Secret,
+		&cmd.MinRole,
+		&cmd.CreatedAt,
+		&cmd.UpdatedAt,
+	}
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// ListBotCommands 获取机器人注册的所有命令
+func (r *SlashCommandRepository) ListBotCommands(botID int) ([]BotCommand, error) {
+	rows, err := r.DB.Query(`SELECT `+botCommandColumns+` FROM bot_commands c WHERE c.bot_id = $1 ORDER BY c.command`, botID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	commands := []BotCommand{}
+	for rows.Next() {
+		cmd, err := scanBotCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, *cmd)
+	}
+	return commands, nil
+}
+
+// GroupBotCommand 群组中可用的机器人命令（附带机器人显示名称）
+type GroupBotCommand struct {
+	BotCommand
+	BotName string `json:"bot_name"`
+}
+
+// ListGroupBotCommands 获取群组中可用的机器人命令（机器人需是该群已通过审核的成员）
+func (r *SlashCommandRepository) ListGroupBotCommands(groupID int) ([]GroupBotCommand, error) {
+	rows, err := r.DB.Query(`
+		SELECT `+botCommandColumns+`, COALESCE(NULLIF(u.full_name, ''), u.username)
+		FROM bot_commands c
+		JOIN users u ON u.id = c.bot_id AND u.is_bot = true
+		JOIN group_members gm ON gm.user_id = c.bot_id AND gm.group_id = $1 AND gm.approval_status = 'approved'
+		ORDER BY c.command, c.bot_id
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	commands := []GroupBotCommand{}
+	for rows.Next() {
+		var botName string
+		cmd, err := scanBotCommand(rows, &botName)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, GroupBotCommand{BotCommand: *cmd, BotName: botName})
+	}
+	return commands, nil
+}
+
+// GetBotCommand 获取机器人命令
+func (r *SlashCommandRepository) GetBotCommand(botID int, command string) (*BotCommand, error) {
+	return scanBotCommand(r.DB.QueryRow(`SELECT `+botCommandColumns+` FROM bot_commands c WHERE c.bot_id = $1 AND c.command = $2`, botID, command))
+}
+
+// SaveBotCommand 注册机器人命令，同名命令已存在时覆盖
+func (r *SlashCommandRepository) SaveBotCommand(cmd *BotCommand) (*BotCommand, error) {
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO bot_commands AS c (bot_id, command, description, usage, callback_url, secret, min_role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (bot_id, command) DO UPDATE
+		SET description = EXCLUDED.description, usage = EXCLUDED.usage, callback_url = EXCLUDED.callback_url,
+		    secret = EXCLUDED.secret, min_role = EXCLUDED.min_role, updated_at = EXCLUDED.updated_at
+		RETURNING ` + botCommandColumns
+	return scanBotCommand(r.DB.QueryRow(query, cmd.BotID, cmd.Command, cmd.Description, cmd.Usage, cmd.CallbackURL, cmd.Secret, cmd.MinRole, now))
+}
+
+// DeleteBotCommand 删除机器人命令
+func (r *SlashCommandRepository) DeleteBotCommand(commandID int) (bool, error) {
+	result, err := r.DB.Exec(`DELETE FROM bot_commands WHERE id = $1`, commandID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// CreateReminder 创建提醒
+func (r *SlashCommandRepository) CreateReminder(reminder *CommandReminder) error {
+	return r.DB.QueryRow(`
+		INSERT INTO command_reminders (user_id, group_id, content, remind_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, reminder.UserID, reminder.GroupID, reminder.Content, reminder.RemindAt.UTC(), time.Now().UTC()).Scan(&reminder.ID, &reminder.CreatedAt)
+}
+
+// ListDueReminders 获取已到期未送达的提醒
+func (r *SlashCommandRepository) ListDueReminders(limit int) ([]CommandReminder, error) {
+	rows, err := r.DB.Query(`
+		SELECT id, user_id, group_id, content, remind_at, created_at
+		FROM command_reminders
+		WHERE delivered_at IS NULL AND remind_at <= $1
+		ORDER BY remind_at
+		LIMIT $2
+	`, time.Now().UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []CommandReminder{}
+	for rows.Next() {
+		var rem CommandReminder
+		if err := rows.Scan(&rem.ID, &rem.UserID, &rem.GroupID, &rem.Content, &rem.RemindAt, &rem.CreatedAt); err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, rem)
+	}
+	return reminders, nil
+}
+
+// MarkReminderDelivered 标记提醒已送达，返回 false 表示已被其他实例送达
+func (r *SlashCommandRepository) MarkReminderDelivered(reminderID int) (bool, error) {
+	result, err := r.DB.Exec(`
+		UPDATE command_reminders SET delivered_at = $1
+		WHERE id = $2 AND delivered_at IS NULL
+	`, time.Now().UTC(), reminderID)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
@@ -0,0 +1,431 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// MessageTypeSticker 表情消息（content 为表情引用，服务端补全图片信息）
+const MessageTypeSticker = "sticker"
+
+// MaxUserStickers 个人表情数量上限
+const MaxUserStickers = 300
+
+// ErrStickerLimitReached 个人表情数量已达上限
+var ErrStickerLimitReached = errors.New("个人表情数量已达上限")
+
+// StickerPack 表情包模型
+type StickerPack struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	CoverURL    *string   `json:"cover_url,omitempty" db:"cover_url"`
+	SortOrder   int       `json:"sort_order" db:"sort_order"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	Stickers    []Sticker `json:"stickers,omitempty"`
+}
+
+// Sticker 表情模型
+type Sticker struct {
+	ID         int        `json:"id" db:"id"`
+	PackID     *int       `json:"pack_id,omitempty" db:"pack_id"`
+	UploaderID *int       `json:"uploader_id,omitempty" db:"uploader_id"`
+	Name       *string    `json:"name,omitempty" db:"name"`
+	ImageURL   string     `json:"image_url" db:"image_url"`
+	ObjectKey  string     `json:"-" db:"object_key"`
+	Width      int        `json:"width" db:"width"`
+	Height     int        `json:"height" db:"height"`
+	SortOrder  int        `json:"sort_order" db:"sort_order"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// StickerMessageContent 表情消息内容（保存发送时的图片快照，表情被删除后仍可展示）
+type StickerMessageContent struct {
+	StickerID int     `json:"sticker_id"`
+	PackID    *int    `json:"pack_id,omitempty"`
+	Name      *string `json:"name,omitempty"`
+	ImageURL  string  `json:"image_url"`
+	Width     int     `json:"width"`
+	Height    int     `json:"height"`
+}
+
+// CreateStickerPackRequest 创建/更新表情包请求
+type CreateStickerPackRequest struct {
+	Name        string  `json:"name" binding:"required,max=50"`
+	Description *string `json:"description"`
+	CoverURL    *string `json:"cover_url"`
+}
+
+// AddStickerRequest 添加表情请求
+// 管理后台向表情包添加表情时使用 image_url；用户添加个人表情时可传 image_url（新上传）或 sticker_id（收藏已有表情）
+type AddStickerRequest struct {
+	StickerID int     `json:"sticker_id"`
+	ImageURL  string  `json:"image_url"`
+	Name      *string `json:"name"`
+	Width     int     `json:"width"`
+	Height    int     `json:"height"`
+}
+
+// ReorderRequest 排序请求（按数组顺序排列）
+type ReorderRequest struct {
+	IDs []int `json:"ids" binding:"required"`
+}
+
+// StickerRepository 表情数据仓库
+type StickerRepository struct {
+	DB *sql.DB
+}
+
+// NewStickerRepository 创建表情仓库
+func NewStickerRepository(db *sql.DB) *StickerRepository {
+	return &StickerRepository{DB: db}
+}
+
+const stickerColumns = `id, pack_id, uploader_id, name, image_url, object_key, width, height, sort_order, created_at, deleted_at`
+
+func scanSticker(scanner interface{ Scan(...interface{}) error }) (*Sticker, error) {
+	s := &Sticker{}
+	err := scanner.Scan(
+		&s.ID,
+		&s.PackID,
+		&s.UploaderID,
+		&s.Name,
+		&s.ImageURL,
+		&s.ObjectKey,
+		&s.Width,
+		&s.Height,
+		&s.SortOrder,
+		&s.CreatedAt,
+		&s.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *StickerRepository) queryStickers(query string, args ...interface{}) ([]Sticker, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stickers := []Sticker{}
+	for rows.Next() {
+		s, err := scanSticker(rows)
+		if err != nil {
+			return nil, err
+		}
+		stickers = append(stickers, *s)
+	}
+	return stickers, nil
+}
+
+// ListPacks 获取所有未删除的表情包及其表情
+func (r *StickerRepository) ListPacks() ([]StickerPack, error) {
+	rows, err := r.DB.Query(`
+		SELECT id, name, description, cover_url, sort_order, created_at, updated_at
+		FROM sticker_packs
+		WHERE deleted_at IS NULL
+		ORDER BY sort_order, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	packs := []StickerPack{}
+	packIndex := make(map[int]int)
+	for rows.Next() {
+		var pack StickerPack
+		if err := rows.Scan(&pack.ID, &pack.Name, &pack.Description, &pack.CoverURL, &pack.SortOrder, &pack.CreatedAt, &pack.UpdatedAt); err != nil {
+			return nil, err
+		}
+		pack.Stickers = []Sticker{}
+		packIndex[pack.ID] = len(packs)
+		packs = append(packs, pack)
+	}
+	rows.Close()
+
+	stickers, err := r.queryStickers(`
+		SELECT ` + stickerColumns + `
+		FROM stickers
+		WHERE pack_id IS NOT NULL AND deleted_at IS NULL
+		ORDER BY sort_order, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stickers {
+		if i, ok := packIndex[*s.PackID]; ok {
+			packs[i].Stickers = append(packs[i].Stickers, s)
+		}
+	}
+
+	return packs, nil
+}
+
+// GetPack 获取未删除的表情包
+func (r *StickerRepository) GetPack(packID int) (*StickerPack, error) {
+	pack := &StickerPack{}
+	err := r.DB.QueryRow(`
+		SELECT id, name, description, cover_url, sort_order, created_at, updated_at
+		FROM sticker_packs
+		WHERE id = $1 AND deleted_at IS NULL
+	`, packID).Scan(&pack.ID, &pack.Name, &pack.Description, &pack.CoverURL, &pack.SortOrder, &pack.CreatedAt, &pack.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return pack, nil
+}
+
+// CreatePack 创建表情包（排在最后）
+func (r *StickerRepository) CreatePack(req *CreateStickerPackRequest) (*StickerPack, error) {
+	now := time.Now().UTC()
+	pack := &StickerPack{}
+	err := r.DB.QueryRow(`
+		INSERT INTO sticker_packs (name, description, cover_url, sort_order, created_at, updated_at)
+		VALUES ($1, $2, $3, COALESCE((SELECT MAX(sort_order) + 1 FROM sticker_packs WHERE deleted_at IS NULL), 0), $4, $4)
+		RETURNING id, name, description, cover_url, sort_order, created_at, updated_at
+	`, req.Name, req.Description, req.CoverURL, now).Scan(&pack.ID, &pack.Name, &pack.Description, &pack.CoverURL, &pack.SortOrder, &pack.CreatedAt, &pack.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return pack, nil
+}
+
+// UpdatePack 更新表情包信息
+func (r *StickerRepository) UpdatePack(packID int, req *CreateStickerPackRequest) error {
+	result, err := r.DB.Exec(`
+		UPDATE sticker_packs
+		SET name = $1, description = $2, cover_url = $3, updated_at = $4
+		WHERE id = $5 AND deleted_at IS NULL
+	`, req.Name, req.Description, req.CoverURL, time.Now().UTC(), packID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeletePack 软删除表情包及其中的表情（历史消息仍可通过表情ID解析）
+func (r *StickerRepository) DeletePack(packID int) error {
+	now := time.Now().UTC()
+	result, err := r.DB.Exec(`UPDATE sticker_packs SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`, now, packID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = r.DB.Exec(`UPDATE stickers SET deleted_at = $1 WHERE pack_id = $2 AND deleted_at IS NULL`, now, packID)
+	return err
+}
+
+// ReorderPacks 按给定顺序排列表情包
+func (r *StickerRepository) ReorderPacks(packIDs []int) error {
+	return r.reorder(`UPDATE sticker_packs SET sort_order = $1 WHERE id = $2 AND deleted_at IS NULL`, packIDs)
+}
+
+// AddPackSticker 向表情包添加表情（排在最后）
+func (r *StickerRepository) AddPackSticker(packID int, s *Sticker) error {
+	return r.DB.QueryRow(`
+		INSERT INTO stickers (pack_id, name, image_url, object_key, width, height, sort_order, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE((SELECT MAX(sort_order) + 1 FROM stickers WHERE pack_id = $1 AND deleted_at IS NULL), 0), $7)
+		RETURNING id, sort_order, created_at
+	`, packID, s.Name, s.ImageURL, s.ObjectKey, s.Width, s.Height, time.Now().UTC()).Scan(&s.ID, &s.SortOrder, &s.CreatedAt)
+}
+
+// ReorderPackStickers 按给定顺序排列表情包内的表情
+func (r *StickerRepository) ReorderPackStickers(packID int, stickerIDs []int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range stickerIDs {
+		if _, err := tx.Exec(`UPDATE stickers SET sort_order = $1 WHERE id = $2 AND pack_id = $3 AND deleted_at IS NULL`, i, id, packID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteSticker 软删除表情
+func (r *StickerRepository) DeleteSticker(stickerID int) error {
+	result, err := r.DB.Exec(`UPDATE stickers SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`, time.Now().UTC(), stickerID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetByID 根据ID获取表情（包含已删除的表情，用于解析历史消息）
+func (r *StickerRepository) GetByID(stickerID int) (*Sticker, error) {
+	return scanSticker(r.DB.QueryRow(`SELECT `+stickerColumns+` FROM stickers WHERE id = $1`, stickerID))
+}
+
+// ListUserStickers 获取用户的个人表情
+func (r *StickerRepository) ListUserStickers(userID int) ([]Sticker, error) {
+	return r.queryStickers(`
+		SELECT s.id, s.pack_id, s.uploader_id, s.name, s.image_url, s.object_key, s.width, s.height, us.sort_order, us.created_at, s.deleted_at
+		FROM user_stickers us
+		JOIN stickers s ON s.id = us.sticker_id
+		WHERE us.user_id = $1 AND s.deleted_at IS NULL
+		ORDER BY us.sort_order, us.id
+	`, userID)
+}
+
+// CreateUserSticker 创建用户上传的个人表情并加入用户的个人表情（排在最前）
+// 数量上限在同一事务中检查，超限时不会留下孤立的表情记录
+func (r *StickerRepository) CreateUserSticker(userID int, s *Sticker) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockUserStickerLimit(tx, userID); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO stickers (uploader_id, name, image_url, object_key, width, height, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, userID, s.Name, s.ImageURL, s.ObjectKey, s.Width, s.Height, time.Now().UTC()).Scan(&s.ID, &s.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if err := insertUserSticker(tx, userID, s.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.UploaderID = &userID
+	return nil
+}
+
+// AddUserSticker 将已有表情加入用户的个人表情（排在最前），已存在时不重复添加
+func (r *StickerRepository) AddUserSticker(userID, stickerID int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM user_stickers WHERE user_id = $1 AND sticker_id = $2)`, userID, stickerID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	if err := lockUserStickerLimit(tx, userID); err != nil {
+		return err
+	}
+	if err := insertUserSticker(tx, userID, stickerID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockUserStickerLimit 锁定用户行（串行化同一用户的并发添加）并检查个人表情数量上限
+func lockUserStickerLimit(tx *sql.Tx, userID int) error {
+	if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return err
+	}
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM user_stickers WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		return err
+	}
+	if count >= MaxUserStickers {
+		return ErrStickerLimitReached
+	}
+	return nil
+}
+
+// insertUserSticker 将表情加入用户的个人表情（排在最前）
+func insertUserSticker(tx *sql.Tx, userID, stickerID int) error {
+	_, err := tx.Exec(`
+		INSERT INTO user_stickers (user_id, sticker_id, sort_order, created_at)
+		VALUES ($1, $2, COALESCE((SELECT MIN(sort_order) - 1 FROM user_stickers WHERE user_id = $1), 0), $3)
+		ON CONFLICT (user_id, sticker_id) DO NOTHING
+	`, userID, stickerID, time.Now().UTC())
+	return err
+}
+
+// HasUserSticker 检查表情是否在用户的个人表情中
+func (r *StickerRepository) HasUserSticker(userID, stickerID int) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM user_stickers WHERE user_id = $1 AND sticker_id = $2)`, userID, stickerID).Scan(&exists)
+	return exists, err
+}
+
+// ReorderUserStickers 按给定顺序排列用户的个人表情
+func (r *StickerRepository) ReorderUserStickers(userID int, stickerIDs []int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range stickerIDs {
+		if _, err := tx.Exec(`UPDATE user_stickers SET sort_order = $1 WHERE user_id = $2 AND sticker_id = $3`, i, userID, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveUserSticker 从个人表情中移除（不删除表情本身，历史消息不受影响）
+func (r *StickerRepository) RemoveUserSticker(userID, stickerID int) (bool, error) {
+	result, err := r.DB.Exec(`DELETE FROM user_stickers WHERE user_id = $1 AND sticker_id = $2`, userID, stickerID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *StickerRepository) reorder(query string, ids []int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range ids {
+		if _, err := tx.Exec(query, i, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// NewStickerMessageContent 根据表情生成消息内容快照
+func NewStickerMessageContent(s *Sticker) *StickerMessageContent {
+	return &StickerMessageContent{
+		StickerID: s.ID,
+		PackID:    s.PackID,
+		Name:      s.Name,
+		ImageURL:  s.ImageURL,
+		Width:     s.Width,
+		Height:    s.Height,
+	}
+}
@@ -0,0 +1,353 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// 加急消息限制
+const (
+	MaxUrgentRecipients  = 100 // 单条加急消息最多的接收者数
+	UrgentPreviewMaxRune = 60  // 加急消息预览最大长度
+)
+
+// 加急提醒状态
+const (
+	UrgentEscalationPending = "pending" // 等待中
+	UrgentEscalationSending = "sending" // 发送中
+	UrgentEscalationSent    = "sent"    // 已发送
+	UrgentEscalationFailed  = "failed"  // 发送失败
+	UrgentEscalationSkipped = "skipped" // 已读或无联系方式，无需发送
+)
+
+// 加急提醒方式
+const (
+	UrgentChannelSMS   = "sms"
+	UrgentChannelEmail = "email"
+)
+
+// 加急消息错误
+var (
+	ErrUrgentAlreadyExists = errors.New("该消息已是加急消息")
+	ErrUrgentQuotaExceeded = errors.New("今日加急次数已用完，请明天再试")
+)
+
+// UrgentMessage 加急消息
+type UrgentMessage struct {
+	ID               int       `json:"id" db:"id"`
+	ConversationType string    `json:"conversation_type" db:"conversation_type"` // user, group
+	MessageID        int       `json:"message_id" db:"message_id"`
+	GroupID          int       `json:"group_id,omitempty" db:"group_id"`
+	SenderID         int       `json:"sender_id" db:"sender_id"`
+	SenderName       string    `json:"sender_name"`
+	Preview          string    `json:"preview" db:"preview"`
+	EscalateAt       time.Time `json:"escalate_at" db:"escalate_at"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	ConfirmedCount   int       `json:"confirmed_count"`
+	RecipientCount   int       `json:"recipient_count"`
+}
+
+// UrgentReceipt 加急消息接收回执
+type UrgentReceipt struct {
+	UserID            int        `json:"user_id"`
+	Username          string     `json:"username"`
+	FullName          *string    `json:"full_name,omitempty"`
+	Avatar            string     `json:"avatar"`
+	IsRead            bool       `json:"is_read"`
+	ConfirmedAt       *time.Time `json:"confirmed_at,omitempty"`
+	EscalationStatus  string     `json:"escalation_status"`
+	EscalationChannel *string    `json:"escalation_channel,omitempty"`
+	EscalatedAt       *time.Time `json:"escalated_at,omitempty"`
+}
+
+// UrgentEscalation 待发送的升级提醒
+type UrgentEscalation struct {
+	ReceiptID  int
+	UrgentID   int
+	UserID     int
+	Phone      *string
+	Email      *string
+	SenderName string
+	Preview    string
+}
+
+// UrgentMessageRepository 加急消息数据仓库
+type UrgentMessageRepository struct {
+	DB *sql.DB
+}
+
+// NewUrgentMessageRepository 创建加急消息仓库
+func NewUrgentMessageRepository(db *sql.DB) *UrgentMessageRepository {
+	return &UrgentMessageRepository{DB: db}
+}
+
+// CountSentSince 统计用户在某时间之后发送的加急消息数（用于配额）
+func (r *UrgentMessageRepository) CountSentSince(senderID int, since time.Time) (int, error) {
+	var count int
+	err := r.DB.QueryRow(`SELECT COUNT(*) FROM urgent_messages WHERE sender_id = $1 AND created_at >= $2`, senderID, since).Scan(&count)
+	return count, err
+}
+
+// Create 创建加急消息及接收者回执，发送者在 since 之后的加急数达到 quota 时返回 ErrUrgentQuotaExceeded
+// 锁定发送者后再按配额条件插入，同一发送者的并发加急不会超出配额
+func (r *UrgentMessageRepository) Create(urgent *UrgentMessage, recipientIDs []int, quota int, since time.Time) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, urgent.SenderID); err != nil {
+		return err
+	}
+
+	urgent.CreatedAt = time.Now().UTC()
+	err = tx.QueryRow(`
+		INSERT INTO urgent_messages (conversation_type, message_id, group_id, sender_id, preview, escalate_at, created_at)
+		SELECT $1::VARCHAR, $2::INTEGER, $3::INTEGER, $4::INTEGER, $5::VARCHAR, $6::TIMESTAMP, $7::TIMESTAMP
+		WHERE (SELECT COUNT(*) FROM urgent_messages WHERE sender_id = $4 AND created_at >= $8) < $9
+		ON CONFLICT (conversation_type, message_id) DO NOTHING
+		RETURNING id
+	`, urgent.ConversationType, urgent.MessageID, urgent.GroupID, urgent.SenderID, urgent.Preview, urgent.EscalateAt, urgent.CreatedAt, since, quota).Scan(&urgent.ID)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := tx.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM urgent_messages WHERE conversation_type = $1 AND message_id = $2)
+		`, urgent.ConversationType, urgent.MessageID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrUrgentAlreadyExists
+		}
+		return ErrUrgentQuotaExceeded
+	}
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO urgent_message_receipts (urgent_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (urgent_id, user_id) DO NOTHING
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, userID := range recipientIDs {
+		if _, err := stmt.Exec(urgent.ID, userID); err != nil {
+			return err
+		}
+	}
+	urgent.RecipientCount = len(recipientIDs)
+
+	return tx.Commit()
+}
+
+const urgentMessageColumns = `u.id, u.conversation_type, u.message_id, u.group_id, u.sender_id,
+	COALESCE(NULLIF(s.full_name, ''), s.username, ''), u.preview, u.escalate_at, u.created_at,
+	(SELECT COUNT(*) FROM urgent_message_receipts r WHERE r.urgent_id = u.id AND r.confirmed_at IS NOT NULL),
+	(SELECT COUNT(*) FROM urgent_message_receipts r WHERE r.urgent_id = u.id)`
+
+func scanUrgentMessage(scanner interface{ Scan(...interface{}) error }) (*UrgentMessage, error) {
+	u := &UrgentMessage{}
+	err := scanner.Scan(
+		&u.ID,
+		&u.ConversationType,
+		&u.MessageID,
+		&u.GroupID,
+		&u.SenderID,
+		&u.SenderName,
+		&u.Preview,
+		&u.EscalateAt,
+		&u.CreatedAt,
+		&u.ConfirmedCount,
+		&u.RecipientCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// GetByID 获取加急消息（不存在时返回 sql.ErrNoRows）
+func (r *UrgentMessageRepository) GetByID(id int) (*UrgentMessage, error) {
+	query := `SELECT ` + urgentMessageColumns + `
+		FROM urgent_messages u
+		LEFT JOIN users s ON s.id = u.sender_id
+		WHERE u.id = $1
+	`
+	return scanUrgentMessage(r.DB.QueryRow(query, id))
+}
+
+// ListPendingForUser 获取用户收到的、尚未确认的加急消息（最近的在前）
+func (r *UrgentMessageRepository) ListPendingForUser(userID int, since time.Time) ([]UrgentMessage, error) {
+	query := `SELECT ` + urgentMessageColumns + `
+		FROM urgent_message_receipts mr
+		JOIN urgent_messages u ON u.id = mr.urgent_id
+		LEFT JOIN users s ON s.id = u.sender_id
+		WHERE mr.user_id = $1 AND mr.confirmed_at IS NULL AND u.created_at >= $2
+		ORDER BY u.created_at DESC
+	`
+
+	rows, err := r.DB.Query(query, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []UrgentMessage{}
+	for rows.Next() {
+		u, err := scanUrgentMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *u)
+	}
+	return messages, rows.Err()
+}
+
+// IsRecipient 判断用户是否为加急消息的接收者
+func (r *UrgentMessageRepository) IsRecipient(urgentID, userID int) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM urgent_message_receipts WHERE urgent_id = $1 AND user_id = $2)
+	`, urgentID, userID).Scan(&exists)
+	return exists, err
+}
+
+// Confirm 接收者确认加急消息，返回是否为首次确认
+func (r *UrgentMessageRepository) Confirm(urgentID, userID int) (bool, error) {
+	result, err := r.DB.Exec(`
+		UPDATE urgent_message_receipts
+		SET confirmed_at = $1
+		WHERE urgent_id = $2 AND user_id = $3 AND confirmed_at IS NULL
+	`, time.Now().UTC(), urgentID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ListReceipts 获取加急消息的接收回执（未确认的在前）
+func (r *UrgentMessageRepository) ListReceipts(urgent *UrgentMessage) ([]UrgentReceipt, error) {
+	query := `
+		SELECT mr.user_id, u.username, u.full_name, COALESCE(u.avatar, ''),
+		       ` + urgentReadExpr + `,
+		       mr.confirmed_at, mr.escalation_status, mr.escalation_channel, mr.escalated_at
+		FROM urgent_message_receipts mr
+		JOIN urgent_messages um ON um.id = mr.urgent_id
+		JOIN users u ON u.id = mr.user_id
+		WHERE mr.urgent_id = $1
+		ORDER BY mr.confirmed_at IS NOT NULL, mr.confirmed_at, mr.user_id
+	`
+
+	rows, err := r.DB.Query(query, urgent.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := []UrgentReceipt{}
+	for rows.Next() {
+		var rc UrgentReceipt
+		if err := rows.Scan(
+			&rc.UserID,
+			&rc.Username,
+			&rc.FullName,
+			&rc.Avatar,
+			&rc.IsRead,
+			&rc.ConfirmedAt,
+			&rc.EscalationStatus,
+			&rc.EscalationChannel,
+			&rc.EscalatedAt,
+		); err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, rc)
+	}
+	return receipts, rows.Err()
+}
+
+// urgentReadExpr 接收者是否已读加急消息（mr 为回执，um 为加急消息）
+const urgentReadExpr = `CASE um.conversation_type
+	WHEN 'user' THEN EXISTS(SELECT 1 FROM messages m WHERE m.id = um.message_id AND m.is_read = true)
+	ELSE EXISTS(SELECT 1 FROM group_message_reads gr WHERE gr.group_message_id = um.message_id AND gr.user_id = mr.user_id)
+END`
+
+// ClaimDueEscalations 领取到期且仍未读、未确认的回执（标记为发送中，多实例下不会重复领取）
+// 到期后已读或消息已撤回的回执直接标记为无需发送
+func (r *UrgentMessageRepository) ClaimDueEscalations(now time.Time, limit int) ([]UrgentEscalation, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE urgent_message_receipts mr
+		SET escalation_status = 'skipped'
+		FROM urgent_messages um
+		WHERE um.id = mr.urgent_id AND mr.escalation_status = 'pending' AND um.escalate_at <= $1
+		  AND (mr.confirmed_at IS NOT NULL OR `+urgentReadExpr+` OR CASE um.conversation_type
+		      WHEN 'user' THEN NOT EXISTS(SELECT 1 FROM messages m WHERE m.id = um.message_id AND COALESCE(m.status, '') != 'recalled')
+		      ELSE NOT EXISTS(SELECT 1 FROM group_messages gm WHERE gm.id = um.message_id AND COALESCE(gm.status, '') != 'recalled')
+		  END)
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		UPDATE urgent_message_receipts mr
+		SET escalation_status = 'sending'
+		FROM urgent_messages um, users u, users s
+		WHERE mr.id IN (
+			SELECT r.id FROM urgent_message_receipts r
+			JOIN urgent_messages m ON m.id = r.urgent_id
+			WHERE r.escalation_status = 'pending' AND m.escalate_at <= $1
+			ORDER BY m.escalate_at
+			LIMIT $2
+			FOR UPDATE OF r SKIP LOCKED
+		)
+		  AND um.id = mr.urgent_id AND u.id = mr.user_id AND s.id = um.sender_id
+		RETURNING mr.id, mr.urgent_id, mr.user_id, u.phone, u.email,
+		          COALESCE(NULLIF(s.full_name, ''), s.username), um.preview
+	`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	escalations := []UrgentEscalation{}
+	for rows.Next() {
+		var e UrgentEscalation
+		if err := rows.Scan(&e.ReceiptID, &e.UrgentID, &e.UserID, &e.Phone, &e.Email, &e.SenderName, &e.Preview); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		escalations = append(escalations, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return escalations, tx.Commit()
+}
+
+// FinishEscalation 记录升级提醒的发送结果
+func (r *UrgentMessageRepository) FinishEscalation(receiptID int, status string, channel string) error {
+	var channelValue interface{}
+	if channel != "" {
+		channelValue = channel
+	}
+	_, err := r.DB.Exec(`
+		UPDATE urgent_message_receipts
+		SET escalation_status = $1, escalation_channel = $2, escalated_at = $3
+		WHERE id = $4
+	`, status, channelValue, time.Now().UTC(), receiptID)
+	return err
+}
@@ -421,6 +421,18 @@ func (r *UserRepository) UpdateLastLoginAt(id int) error {
 	return err
 }
 
+// UpdateLastSeenAt 更新最近在线时间（使用UTC时间，WebSocket 断开时调用）
+func (r *UserRepository) UpdateLastSeenAt(id int) error {
+	query := `
+		UPDATE users
+		SET last_seen_at = NOW() AT TIME ZONE 'UTC'
+		WHERE id = $1
+	`
+
+	_, err := r.DB.Exec(query, id)
+	return err
+}
+
 // UpdateProfileRequest 更新个人信息请求
 type UpdateProfileRequest struct {
 	FullName    *string `json:"full_name"`
@@ -33,10 +33,31 @@ func SetupRouter(hub *ws.Hub) *gin.Engine {
 	favoriteCtrl := controllers.NewFavoriteController()
 	favoriteCommonCtrl := controllers.NewFavoriteCommonController()
 	groupCtrl := controllers.NewGroupController(hub)
-	fileAssistantCtrl := controllers.NewFileAssistantController()
+	fileAssistantCtrl := controllers.NewFileAssistantController(hub)
 	callCtrl := controllers.NewCallController(hub)
 	deviceCtrl := controllers.NewDeviceController()
 	appVersionCtrl := controllers.NewAppVersionController()
+	conversationSettingCtrl := controllers.NewConversationSettingController(hub)
+	conversationDraftCtrl := controllers.NewConversationDraftController(hub)
+	conversationExportCtrl := controllers.NewConversationExportController(hub)
+	groupPollCtrl := controllers.NewGroupPollController(hub)
+	stickerCtrl := controllers.NewStickerController()
+	groupMentionCtrl := controllers.NewGroupMentionController(hub)
+	groupReadCtrl := controllers.NewGroupReadController(hub)
+	broadcastListCtrl := controllers.NewBroadcastListController(messageCtrl)
+	moderationCtrl := controllers.NewModerationController(hub)
+	botCtrl := controllers.NewBotController(groupCtrl)
+	eventSubscriptionCtrl := controllers.NewEventSubscriptionController()
+	slashCommandCtrl := controllers.NewSlashCommandController(hub)
+	e2eeCtrl := controllers.NewE2EEController(hub)
+	retentionCtrl := controllers.NewRetentionController(hub)
+	pushCtrl := controllers.NewPushController()
+	emailDigestCtrl := controllers.NewEmailDigestController()
+	urgentCtrl := controllers.NewUrgentMessageController(hub)
+	messageTaskCtrl := controllers.NewMessageTaskController(messageCtrl)
+	autoReplyCtrl := controllers.NewAutoReplyController(hub)
+	keywordReplyCtrl := controllers.NewGroupKeywordReplyController(hub)
+	quickReplyCtrl := controllers.NewQuickReplyController()
 
 	// API路由组
 	api := router.Group("/api")
@@ -67,6 +88,12 @@ func SetupRouter(hub *ws.Hub) *gin.Engine {
 			device.GET("/stats", deviceCtrl.GetDeviceStats)     // 获取设备统计信息（管理用）
 		}
 
+		// Incoming Webhook（外部系统以机器人身份向群组发消息，凭 URL 中的密钥认证）
+		webhooks := api.Group("/webhooks")
+		{
+			webhooks.POST("/:token", botCtrl.PostWebhookMessage) // 通过Webhook发送群消息
+		}
+
 		// 版本更新相关路由（客户端检查更新，不需要认证）
 		version := api.Group("/version")
 		{
@@ -90,7 +117,46 @@ func SetupRouter(hub *ws.Hub) *gin.Engine {
 		// 管理后台内部API（不需要用户认证，但需要管理员密钥）
 		admin := api.Group("/admin")
 		{
-			admin.POST("/force-logout", userCtrl.ForceLogout) // 强制用户下线
+			admin.POST("/force-logout", userCtrl.ForceLogout)                                       // 强制用户下线
+			admin.POST("/exports", conversationExportCtrl.AdminCreateExport)                        // 合规导出指定用户的会话
+			admin.GET("/exports/:id", conversationExportCtrl.AdminGetExport)                        // 获取合规导出任务详情
+			admin.POST("/sticker-packs", stickerCtrl.AdminCreateStickerPack)                        // 创建全局表情包
+			admin.PUT("/sticker-packs/order", stickerCtrl.AdminReorderStickerPacks)                 // 调整全局表情包顺序
+			admin.PUT("/sticker-packs/:id", stickerCtrl.AdminUpdateStickerPack)                     // 更新全局表情包信息
+			admin.DELETE("/sticker-packs/:id", stickerCtrl.AdminDeleteStickerPack)                  // 删除全局表情包（软删除）
+			admin.POST("/sticker-packs/:id/stickers", stickerCtrl.AdminAddPackSticker)              // 向表情包添加表情
+			admin.PUT("/sticker-packs/:id/stickers/order", stickerCtrl.AdminReorderPackStickers)    // 调整表情包内表情顺序
+			admin.DELETE("/stickers/:id", stickerCtrl.AdminDeleteSticker)                           // 删除表情（软删除）
+			admin.GET("/moderation/rules", moderationCtrl.AdminGetRules)                            // 获取内容审核规则
+			admin.POST("/moderation/rules", moderationCtrl.AdminCreateRule)                         // 创建内容审核规则
+			admin.PUT("/moderation/rules/:id", moderationCtrl.AdminUpdateRule)                      // 更新内容审核规则
+			admin.DELETE("/moderation/rules/:id", moderationCtrl.AdminDeleteRule)                   // 删除内容审核规则
+			admin.GET("/moderation/flags", moderationCtrl.AdminGetFlags)                            // 获取内容审核队列
+			admin.POST("/moderation/flags/:id/review", moderationCtrl.AdminReviewFlag)              // 审核队列中的消息
+			admin.POST("/bots", botCtrl.AdminCreateBot)                                             // 创建机器人账号
+			admin.GET("/bots", botCtrl.AdminGetBots)                                                // 获取机器人列表
+			admin.GET("/bots/:id/webhooks", botCtrl.AdminGetWebhooks)                               // 获取机器人的群组Webhook
+			admin.POST("/bots/:id/webhooks", botCtrl.AdminCreateWebhook)                            // 为机器人创建群组Webhook
+			admin.POST("/webhooks/:id/rotate", botCtrl.AdminRotateWebhook)                          // 轮换Webhook密钥
+			admin.DELETE("/webhooks/:id", botCtrl.AdminRevokeWebhook)                               // 吊销Webhook
+			admin.GET("/bots/:id/commands", slashCommandCtrl.AdminGetBotCommands)                   // 获取机器人注册的斜杠命令
+			admin.POST("/bots/:id/commands", slashCommandCtrl.AdminSaveBotCommand)                  // 注册机器人斜杠命令
+			admin.DELETE("/bot-commands/:id", slashCommandCtrl.AdminDeleteBotCommand)               // 删除机器人斜杠命令
+			admin.GET("/event-subscriptions", eventSubscriptionCtrl.AdminGetSubscriptions)          // 获取事件订阅列表
+			admin.POST("/event-subscriptions", eventSubscriptionCtrl.AdminCreateSubscription)       // 创建事件订阅
+			admin.PUT("/event-subscriptions/:id", eventSubscriptionCtrl.AdminUpdateSubscription)    // 更新事件订阅
+			admin.DELETE("/event-subscriptions/:id", eventSubscriptionCtrl.AdminDeleteSubscription) // 删除事件订阅
+			admin.GET("/event-deliveries", eventSubscriptionCtrl.AdminGetDeliveries)                // 查询事件投递记录
+			admin.POST("/event-deliveries/:id/retry", eventSubscriptionCtrl.AdminRetryDelivery)     // 重新投递事件
+			admin.GET("/retention-policies", retentionCtrl.AdminGetPolicies)                        // 获取消息保留策略
+			admin.PUT("/retention-policies", retentionCtrl.AdminSavePolicy)                         // 保存消息保留策略（同范围覆盖）
+			admin.DELETE("/retention-policies/:id", retentionCtrl.AdminDeletePolicy)                // 删除消息保留策略
+			admin.GET("/retention-purges", retentionCtrl.AdminGetPurgeLogs)                         // 查询消息清理审计日志
+			admin.POST("/retention-purges/run", retentionCtrl.AdminRunPurge)                        // 立即执行一次消息清理
+			admin.GET("/quick-replies", quickReplyCtrl.AdminGetQuickReplies)                        // 获取组织快捷回复模板
+			admin.POST("/quick-replies", quickReplyCtrl.AdminCreateQuickReply)                      // 创建组织快捷回复模板
+			admin.PUT("/quick-replies/:id", quickReplyCtrl.AdminUpdateQuickReply)                   // 更新组织快捷回复模板
+			admin.DELETE("/quick-replies/:id", quickReplyCtrl.AdminDeleteQuickReply)                // 删除组织快捷回复模板
 		}
 
 		// 需要认证的路由
@@ -129,6 +195,10 @@ func SetupRouter(hub *ws.Hub) *gin.Engine {
 				user.POST("/send-email-code", userCtrl.SendEmailCode)            // 发送邮箱绑定验证码
 				user.POST("/bind-email", userCtrl.BindEmail)                     // 绑定/更换邮箱
 				user.POST("/batch-online-status", userCtrl.BatchGetOnlineStatus) // 批量获取用户在线状态
+				user.GET("/email-digest", emailDigestCtrl.GetSettings)           // 获取未读消息邮件摘要设置
+				user.PUT("/email-digest", emailDigestCtrl.UpdateSettings)        // 更新邮件摘要设置（退订/发送频率）
+				user.GET("/auto-reply", autoReplyCtrl.GetAutoReply)              // 获取自动回复（外出/忙碌）设置
+				user.PUT("/auto-reply", autoReplyCtrl.UpdateAutoReply)           // 更新自动回复内容和生效时间段
 				user.GET("/:id", userCtrl.GetUserByID)                           // 根据ID查询用户信息（动态路由放最后）
 			}
 
@@ -200,6 +270,7 @@ func SetupRouter(hub *ws.Hub) *gin.Engine {
 				group.POST("/:id/join", groupCtrl.JoinGroup)                                         // 加入群组
 				group.POST("/:id/leave", groupCtrl.LeaveGroup)                                       // 退出群组
 				group.GET("/:id/messages", groupCtrl.GetGroupMessages)                               // 获取群组消息列表
+				group.GET("/:id/messages/:message_id/reads", groupReadCtrl.GetMessageReads)          // 获取群消息已读/未读成员
 				group.POST("/messages", groupCtrl.CreateGroupMessage)                                // 发送群组消息
 				group.POST("/:id/mute", groupCtrl.MuteGroupMember)                                   // 禁言群组成员
 				group.POST("/:id/unmute", groupCtrl.UnmuteGroupMember)                               // 解除群组成员禁言
@@ -209,8 +280,42 @@ func SetupRouter(hub *ws.Hub) *gin.Engine {
 				group.POST("/:id/invite-confirmation", groupCtrl.UpdateGroupInviteConfirmation)      // 更新群组邀请确认状态
 				group.POST("/:id/admin-only-edit-name", groupCtrl.UpdateGroupAdminOnlyEditName)      // 更新群组"仅管理员可修改群名称"状态
 				group.POST("/:id/member-view-permission", groupCtrl.UpdateGroupMemberViewPermission) // 更新群组"群成员查看权限"状态
+				group.PUT("/:id/recall-window", groupCtrl.UpdateGroupRecallWindow)                   // 设置群组消息撤回时限（群主/管理员）
 				group.POST("/:id/approve-member", groupCtrl.ApproveGroupMember)                      // 通过群成员审核
 				group.POST("/:id/reject-member", groupCtrl.RejectGroupMember)                        // 拒绝群成员审核
+				group.POST("/:id/polls", groupPollCtrl.CreatePoll)                                   // 发起群投票
+				group.GET("/:id/commands", slashCommandCtrl.GetGroupCommands)                        // 斜杠命令自动补全
+				group.GET("/:id/tasks", messageTaskCtrl.GetGroupTasks)                               // 获取群组中的任务
+				group.GET("/:id/keyword-replies", keywordReplyCtrl.GetKeywordReplies)                // 获取群关键词自动回复规则
+				group.POST("/:id/keyword-replies", keywordReplyCtrl.CreateKeywordReply)              // 创建群关键词自动回复规则
+				group.PUT("/:id/keyword-replies/:rule_id", keywordReplyCtrl.UpdateKeywordReply)      // 更新群关键词自动回复规则（含启用/停用）
+				group.DELETE("/:id/keyword-replies/:rule_id", keywordReplyCtrl.DeleteKeywordReply)   // 删除群关键词自动回复规则
+			}
+
+			// 群投票相关路由
+			poll := authorized.Group("/polls")
+			{
+				poll.GET("/:id", groupPollCtrl.GetPoll)          // 获取投票详情及统计结果
+				poll.POST("/:id/vote", groupPollCtrl.VotePoll)   // 投票
+				poll.POST("/:id/close", groupPollCtrl.ClosePoll) // 结束投票
+			}
+
+			// "@我的"消息相关路由（跨群组）
+			mention := authorized.Group("/mentions")
+			{
+				mention.GET("", groupMentionCtrl.GetMentions)            // 获取@我的消息列表
+				mention.POST("/read", groupMentionCtrl.MarkMentionsRead) // 标记@消息已读
+			}
+
+			// 群发列表相关路由（一条消息分别发送给多个联系人）
+			broadcastList := authorized.Group("/broadcast-lists")
+			{
+				broadcastList.GET("", broadcastListCtrl.GetBroadcastLists)          // 获取群发列表
+				broadcastList.POST("", broadcastListCtrl.CreateBroadcastList)       // 创建群发列表
+				broadcastList.GET("/:id", broadcastListCtrl.GetBroadcastList)       // 获取群发列表详情
+				broadcastList.PUT("/:id", broadcastListCtrl.UpdateBroadcastList)    // 更新群发列表
+				broadcastList.DELETE("/:id", broadcastListCtrl.DeleteBroadcastList) // 删除群发列表
+				broadcastList.POST("/:id/send", broadcastListCtrl.SendBroadcast)    // 向列表成员群发消息
 			}
 
 			// 文件传输助手相关路由
@@ -222,6 +327,89 @@ func SetupRouter(hub *ws.Hub) *gin.Engine {
 				fileAssistant.POST("/messages/:id/recall", fileAssistantCtrl.RecallMessage) // 撤回文件助手消息
 			}
 
+			// 表情相关路由（全局表情包、个人表情）
+			sticker := authorized.Group("/stickers")
+			{
+				sticker.GET("/packs", stickerCtrl.GetStickerPacks)        // 获取全局表情包列表
+				sticker.GET("/mine", stickerCtrl.GetMyStickers)           // 获取个人表情
+				sticker.POST("/mine", stickerCtrl.AddMySticker)           // 添加个人表情
+				sticker.PUT("/mine/order", stickerCtrl.ReorderMyStickers) // 调整个人表情顺序
+				sticker.DELETE("/mine/:id", stickerCtrl.RemoveMySticker)  // 移除个人表情
+				sticker.GET("/:id", stickerCtrl.GetSticker)               // 获取表情详情（含已删除表情）
+			}
+
+			// 会话设置相关路由（置顶、免打扰、归档、标记未读、隐藏）
+			conversationSetting := authorized.Group("/conversation-settings")
+			{
+				conversationSetting.GET("", conversationSettingCtrl.GetConversationSettings)   // 获取会话设置列表
+				conversationSetting.PUT("", conversationSettingCtrl.UpdateConversationSetting) // 更新会话设置
+				conversationSetting.GET("/retention", retentionCtrl.GetEffectiveRetention)     // 获取会话生效的消息保留策略
+			}
+
+			// 会话草稿相关路由（多端同步）
+			draft := authorized.Group("/drafts")
+			{
+				draft.GET("", conversationDraftCtrl.GetDrafts)      // 获取草稿列表
+				draft.PUT("", conversationDraftCtrl.SaveDraft)      // 保存草稿
+				draft.DELETE("", conversationDraftCtrl.DeleteDraft) // 删除草稿
+			}
+
+			// 会话导出相关路由（异步生成ZIP）
+			export := authorized.Group("/exports")
+			{
+				export.POST("", conversationExportCtrl.CreateExport) // 创建导出任务
+				export.GET("", conversationExportCtrl.ListExports)   // 获取导出任务列表
+				export.GET("/:id", conversationExportCtrl.GetExport) // 获取导出任务详情（含下载链接）
+			}
+
+			// 端到端加密公钥目录相关路由（服务端只分发公钥）
+			e2ee := authorized.Group("/e2ee")
+			{
+				e2ee.PUT("/devices/:device_id", e2eeCtrl.PublishDevice)                // 发布/更新设备密钥包
+				e2ee.DELETE("/devices/:device_id", e2eeCtrl.DeleteDevice)              // 移除加密设备
+				e2ee.POST("/devices/:device_id/prekeys", e2eeCtrl.UploadPrekeys)       // 补充一次性预共享公钥
+				e2ee.GET("/devices/:device_id/prekeys/count", e2eeCtrl.GetPrekeyCount) // 剩余一次性预共享公钥数量
+				e2ee.GET("/users/:id/devices", e2eeCtrl.GetUserDevices)                // 获取用户加密设备及身份公钥
+				e2ee.GET("/users/:id/bundles", e2eeCtrl.GetUserBundles)                // 获取用户密钥包（消费一次性预共享公钥）
+			}
+
+			// 离线推送相关路由（推送令牌按登录会话登记）
+			push := authorized.Group("/push")
+			{
+				push.PUT("/token", pushCtrl.RegisterToken)      // 登记当前会话的设备推送令牌
+				push.DELETE("/token", pushCtrl.UnregisterToken) // 注销当前会话的推送令牌（退出登录）
+			}
+
+			// 加急消息（DING）相关路由
+			urgent := authorized.Group("/urgent")
+			{
+				urgent.GET("/pending", urgentCtrl.GetPending)       // 获取我尚未确认的加急消息
+				urgent.GET("/quota", urgentCtrl.GetQuota)           // 获取我的加急消息配额
+				urgent.POST("/:id/confirm", urgentCtrl.Confirm)     // 确认已收到加急消息
+				urgent.GET("/:id/receipts", urgentCtrl.GetReceipts) // 查看加急消息确认情况（仅发送者）
+			}
+
+			// 消息任务相关路由（消息转任务、负责人、截止时间和状态）
+			task := authorized.Group("/tasks")
+			{
+				task.POST("", messageTaskCtrl.CreateTask)                 // 将消息转为任务
+				task.GET("/mine", messageTaskCtrl.GetMyTasks)             // 获取分配给我的任务
+				task.GET("/created", messageTaskCtrl.GetCreatedTasks)     // 获取我分配出去的任务
+				task.GET("/:id", messageTaskCtrl.GetTask)                 // 获取任务详情
+				task.PUT("/:id", messageTaskCtrl.UpdateTask)              // 修改任务标题、负责人和截止时间（仅创建者）
+				task.PUT("/:id/status", messageTaskCtrl.UpdateTaskStatus) // 修改任务状态（创建者或负责人）
+			}
+
+			// 快捷回复模板相关路由（个人、群组共享和组织模板，支持占位符和附件）
+			quickReply := authorized.Group("/quick-replies")
+			{
+				quickReply.GET("", quickReplyCtrl.GetQuickReplies)              // 获取我可用的快捷回复模板
+				quickReply.POST("", quickReplyCtrl.CreateQuickReply)            // 创建个人或群组快捷回复模板
+				quickReply.PUT("/:id", quickReplyCtrl.UpdateQuickReply)         // 更新快捷回复模板
+				quickReply.DELETE("/:id", quickReplyCtrl.DeleteQuickReply)      // 删除快捷回复模板
+				quickReply.POST("/:id/render", quickReplyCtrl.RenderQuickReply) // 使用用户信息填充模板占位符
+			}
+
 			// 语音/视频通话相关路由
 			call := authorized.Group("/call")
 			{
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+)
+
+// EmailDigestConversation 摘要邮件中的一个会话
+type EmailDigestConversation struct {
+	Title    string   // 对方名称或群名称
+	IsGroup  bool     // 是否为群聊（群聊只统计@我的消息）
+	Count    int      // 未读数
+	Snippets []string // 最近几条消息预览
+}
+
+// EmailDigest 未读消息摘要邮件数据
+type EmailDigest struct {
+	UserName       string
+	Conversations  []EmailDigestConversation
+	MoreCount      int    // 未展示的会话数
+	TotalUnread    int    // 未读总数
+	AppURL         string // 应用链接（可选）
+	UnsubscribeTip string // 退订说明
+}
+
+var emailDigestTemplate = template.Must(template.New("email_digest").Parse(`
+		<html>
+		<body style="font-family: Arial, sans-serif; padding: 20px;">
+			<h2 style="color: #4A90E2;">您有 {{.TotalUnread}} 条未读消息</h2>
+			<p>{{.UserName}}，您好：</p>
+			<p>您离线期间收到了以下消息：</p>
+			{{range .Conversations}}
+			<div style="background-color: #f5f5f5; padding: 12px 15px; border-radius: 5px; margin: 12px 0;">
+				<div style="font-weight: bold; color: #333;">
+					{{.Title}}
+					<span style="color: #4A90E2; font-weight: normal;">{{if .IsGroup}}（{{.Count}} 条@我的消息）{{else}}（{{.Count}} 条未读）{{end}}</span>
+				</div>
+				{{range .Snippets}}
+				<div style="color: #666; font-size: 14px; margin-top: 6px;">{{.}}</div>
+				{{end}}
+			</div>
+			{{end}}
+			{{if gt .MoreCount 0}}
+			<p style="color: #666;">还有 {{.MoreCount}} 个会话有未读消息。</p>
+			{{end}}
+			{{if .AppURL}}
+			<p><a href="{{.AppURL}}" style="color: #4A90E2;">立即查看</a></p>
+			{{end}}
+			<hr style="border: none; border-top: 1px solid #eee; margin: 20px 0;">
+			<p style="color: #999; font-size: 12px;">此邮件由系统自动发送，请勿回复。{{.UnsubscribeTip}}</p>
+		</body>
+		</html>
+	`))
+
+// RenderEmailDigest 渲染未读消息摘要邮件，返回主题和 HTML 正文（不发送，便于测试）
+func RenderEmailDigest(digest *EmailDigest) (string, string, error) {
+	var buf bytes.Buffer
+	if err := emailDigestTemplate.Execute(&buf, digest); err != nil {
+		return "", "", err
+	}
+	subject := fmt.Sprintf("您有 %d 条未读消息", digest.TotalUnread)
+	return subject, buf.String(), nil
+}
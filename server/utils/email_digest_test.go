@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderEmailDigest(t *testing.T) {
+	tests := []struct {
+		name        string
+		digest      EmailDigest
+		wantSubject string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name: "私聊和群聊会话",
+			digest: EmailDigest{
+				UserName:    "张三",
+				TotalUnread: 5,
+				Conversations: []EmailDigestConversation{
+					{Title: "李四", Count: 3, Snippets: []string{"在吗", "[语音]"}},
+					{Title: "研发群", IsGroup: true, Count: 2, Snippets: []string{"王五: @张三 看一下"}},
+				},
+				UnsubscribeTip: "如需退订请在设置中修改。",
+			},
+			wantSubject: "您有 5 条未读消息",
+			contains:    []string{"张三，您好", "李四", "（3 条未读）", "[语音]", "研发群", "（2 条@我的消息）", "王五: @张三 看一下", "如需退订请在设置中修改。"},
+			notContains: []string{"还有", "立即查看"},
+		},
+		{
+			name: "更多会话和应用链接",
+			digest: EmailDigest{
+				UserName:      "alice",
+				TotalUnread:   12,
+				Conversations: []EmailDigestConversation{{Title: "bob", Count: 1, Snippets: []string{"hi"}}},
+				MoreCount:     4,
+				AppURL:        "https://im.example.com",
+			},
+			wantSubject: "您有 12 条未读消息",
+			contains:    []string{"还有 4 个会话有未读消息", `href="https://im.example.com"`, "立即查看"},
+		},
+		{
+			name: "转义用户输入",
+			digest: EmailDigest{
+				UserName:    `<b>eve</b>`,
+				TotalUnread: 1,
+				Conversations: []EmailDigestConversation{
+					{Title: `<script>alert(1)</script>`, Count: 1, Snippets: []string{`<img src=x onerror="alert(2)">`}},
+				},
+				AppURL: "javascript:alert(3)",
+			},
+			wantSubject: "您有 1 条未读消息",
+			contains:    []string{"&lt;b&gt;eve&lt;/b&gt;", "&lt;script&gt;alert(1)&lt;/script&gt;", "&lt;img src=x onerror=&#34;alert(2)&#34;&gt;"},
+			notContains: []string{"<script>", "<b>eve", "<img", "javascript:alert(3)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, body, err := RenderEmailDigest(&tt.digest)
+			if err != nil {
+				t.Fatalf("RenderEmailDigest() error = %v", err)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(body, s) {
+					t.Errorf("正文缺少 %q", s)
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(body, s) {
+					t.Errorf("正文不应包含 %q", s)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -56,3 +58,8 @@ func ParseToken(tokenString string) (*Claims, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
+// TokenSessionID 由JWT令牌派生登录会话标识（令牌的SHA-256），同一次登录的令牌始终对应同一会话
+func TokenSessionID(tokenString string) string {
+	sum := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(sum[:])
+}
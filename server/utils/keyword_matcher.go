@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// KeywordMatcher 基于 Aho-Corasick 自动机的多关键词匹配器（忽略大小写）
+// 构建后只读，可在多个 goroutine 中并发使用
+type KeywordMatcher struct {
+	nodes   []keywordNode
+	lengths []int // 每个关键词的字符数
+}
+
+type keywordNode struct {
+	next    map[rune]int
+	fail    int
+	outputs []int // 在此节点结束的关键词下标
+}
+
+// KeywordMatch 关键词命中结果
+type KeywordMatch struct {
+	Keyword int // 关键词在构建时传入列表中的下标
+	Start   int // 命中文本在原文中的起始字节位置
+	End     int // 命中文本在原文中的结束字节位置（不含）
+}
+
+// NewKeywordMatcher 根据关键词列表构建匹配器，空关键词会被忽略
+func NewKeywordMatcher(keywords []string) *KeywordMatcher {
+	m := &KeywordMatcher{
+		nodes:   []keywordNode{{next: map[rune]int{}}},
+		lengths: make([]int, len(keywords)),
+	}
+
+	for i, keyword := range keywords {
+		if keyword == "" {
+			continue
+		}
+		m.lengths[i] = utf8.RuneCountInString(keyword)
+		node := 0
+		for _, r := range keyword {
+			r = unicode.ToLower(r)
+			child, ok := m.nodes[node].next[r]
+			if !ok {
+				m.nodes = append(m.nodes, keywordNode{next: map[rune]int{}})
+				child = len(m.nodes) - 1
+				m.nodes[node].next[r] = child
+			}
+			node = child
+		}
+		m.nodes[node].outputs = append(m.nodes[node].outputs, i)
+	}
+
+	// 广度优先构建失败指针，并合并失败链上的输出
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[node].next {
+			fail := m.nodes[node].fail
+			for fail > 0 {
+				if _, ok := m.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if target, ok := m.nodes[fail].next[r]; ok && target != child {
+				m.nodes[child].fail = target
+			}
+			m.nodes[child].outputs = append(m.nodes[child].outputs, m.nodes[m.nodes[child].fail].outputs...)
+			queue = append(queue, child)
+		}
+	}
+
+	return m
+}
+
+// FindAll 返回文本中所有关键词命中（包括重叠命中），按结束位置排序
+func (m *KeywordMatcher) FindAll(text string) []KeywordMatch {
+	if m == nil || len(m.nodes) <= 1 {
+		return nil
+	}
+
+	var matches []KeywordMatch
+	var runeStarts []int // 每个字符的起始字节位置
+	node := 0
+	for i, r := range text {
+		runeStarts = append(runeStarts, i)
+		_, size := utf8.DecodeRuneInString(text[i:])
+		r = unicode.ToLower(r)
+		for node > 0 {
+			if _, ok := m.nodes[node].next[r]; ok {
+				break
+			}
+			node = m.nodes[node].fail
+		}
+		if next, ok := m.nodes[node].next[r]; ok {
+			node = next
+		}
+
+		for _, keyword := range m.nodes[node].outputs {
+			matches = append(matches, KeywordMatch{
+				Keyword: keyword,
+				Start:   runeStarts[len(runeStarts)-m.lengths[keyword]],
+				End:     i + size,
+			})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeywordMatcherFindAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		keywords []string
+		text     string
+		want     []KeywordMatch
+	}{
+		{
+			name:     "重叠命中",
+			keywords: []string{"he", "she", "his", "hers"},
+			text:     "ushers",
+			want: []KeywordMatch{
+				{Keyword: 1, Start: 1, End: 4},
+				{Keyword: 0, Start: 2, End: 4},
+				{Keyword: 3, Start: 2, End: 6},
+			},
+		},
+		{
+			name:     "关键词互为前缀",
+			keywords: []string{"ab", "abc"},
+			text:     "abcd",
+			want: []KeywordMatch{
+				{Keyword: 0, Start: 0, End: 2},
+				{Keyword: 1, Start: 0, End: 3},
+			},
+		},
+		{
+			name:     "同一关键词多次出现",
+			keywords: []string{"aa"},
+			text:     "aaa",
+			want: []KeywordMatch{
+				{Keyword: 0, Start: 0, End: 2},
+				{Keyword: 0, Start: 1, End: 3},
+			},
+		},
+		{
+			name:     "中文按字节位置返回",
+			keywords: []string{"敏感", "感词"},
+			text:     "这是敏感词",
+			want: []KeywordMatch{
+				{Keyword: 0, Start: 6, End: 12},
+				{Keyword: 1, Start: 9, End: 15},
+			},
+		},
+		{
+			name:     "中英文混合",
+			keywords: []string{"赌博"},
+			text:     "a赌博b",
+			want:     []KeywordMatch{{Keyword: 0, Start: 1, End: 7}},
+		},
+		{
+			name:     "忽略大小写",
+			keywords: []string{"SPAM"},
+			text:     "no Spam here",
+			want:     []KeywordMatch{{Keyword: 0, Start: 3, End: 7}},
+		},
+		{
+			name:     "空关键词被忽略",
+			keywords: []string{"", "x"},
+			text:     "xyz",
+			want:     []KeywordMatch{{Keyword: 1, Start: 0, End: 1}},
+		},
+		{
+			name:     "未命中",
+			keywords: []string{"foo"},
+			text:     "fo o",
+			want:     nil,
+		},
+		{
+			name:     "没有关键词",
+			keywords: nil,
+			text:     "anything",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewKeywordMatcher(tt.keywords).FindAll(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("FindAll(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeywordMatcherNil(t *testing.T) {
+	var m *KeywordMatcher
+	if got := m.FindAll("text"); got != nil {
+		t.Fatalf("nil 匹配器应返回空结果，实际 %+v", got)
+	}
+}
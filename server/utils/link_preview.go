@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// 链接预览抓取限制
+const (
+	linkPreviewTimeout      = 5 * time.Second  // 单次抓取总超时
+	linkPreviewMaxBodySize  = 512 * 1024       // 最多读取的HTML字节数
+	linkPreviewMaxRedirects = 3                // 最多跟随的重定向次数
+	linkPreviewCacheTTL     = 24 * time.Hour   // 成功结果缓存时间
+	linkPreviewMissTTL      = 10 * time.Minute // 失败结果缓存时间（避免反复抓取无效链接）
+	linkPreviewMaxTextLen   = 300              // 标题/描述最大长度（字符）
+)
+
+// LinkPreview 链接预览元数据
+type LinkPreview struct {
+	URL         string `json:"url"`                   // 原始链接
+	Title       string `json:"title,omitempty"`       // 标题
+	Description string `json:"description,omitempty"` // 描述
+	Image       string `json:"image,omitempty"`       // 预览图片URL
+	SiteName    string `json:"site_name,omitempty"`   // 站点名称
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'，。！？、）】]+`)
+
+// ErrLinkPreviewBlocked 目标地址不允许访问（内网、回环等）
+var ErrLinkPreviewBlocked = errors.New("目标地址不允许访问")
+
+// linkPreviewClient 用于抓取链接预览的HTTP客户端（连接时校验IP，防止SSRF）
+var linkPreviewClient = &http.Client{
+	Timeout: linkPreviewTimeout,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: linkPreviewTimeout,
+			Control: linkPreviewDialControl,
+		}).DialContext,
+		TLSHandshakeTimeout:   linkPreviewTimeout,
+		ResponseHeaderTimeout: linkPreviewTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= linkPreviewMaxRedirects {
+			return errors.New("重定向次数过多")
+		}
+		return validatePreviewURL(req.URL)
+	},
+}
+
+// linkPreviewDialControl 在建立连接前校验DNS解析后的目标IP（覆盖重定向和DNS重绑定）
+func linkPreviewDialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return ErrLinkPreviewBlocked
+	}
+	return nil
+}
+
+// ExtractFirstURL 提取文本中的第一个链接
+func ExtractFirstURL(text string) string {
+	return strings.TrimRight(urlPattern.FindString(text), ".,;:!?)")
+}
+
+// GetCachedLinkPreview 从Redis读取缓存的链接预览
+// found 表示缓存是否存在；缓存的失败结果返回 found=true 且 preview 为 nil
+func GetCachedLinkPreview(rawURL string) (preview *LinkPreview, found bool) {
+	if RedisClient == nil {
+		return nil, false
+	}
+
+	value, err := RedisClient.Get(ctx, linkPreviewCacheKey(rawURL)).Result()
+	if err != nil {
+		return nil, false
+	}
+	if value == "" {
+		return nil, true
+	}
+
+	preview = &LinkPreview{}
+	if err := json.Unmarshal([]byte(value), preview); err != nil {
+		return nil, false
+	}
+	return preview, true
+}
+
+// FetchLinkPreview 抓取链接预览（优先读取缓存，结果写入缓存）
+func FetchLinkPreview(rawURL string) (*LinkPreview, error) {
+	if preview, found := GetCachedLinkPreview(rawURL); found {
+		if preview == nil {
+			return nil, errors.New("链接无可用预览")
+		}
+		return preview, nil
+	}
+
+	preview, err := fetchLinkPreview(rawURL)
+	if RedisClient != nil {
+		key := linkPreviewCacheKey(rawURL)
+		if err != nil || preview == nil {
+			RedisClient.Set(ctx, key, "", linkPreviewMissTTL)
+		} else if data, marshalErr := json.Marshal(preview); marshalErr == nil {
+			RedisClient.Set(ctx, key, string(data), linkPreviewCacheTTL)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if preview == nil {
+		return nil, errors.New("链接无可用预览")
+	}
+	return preview, nil
+}
+
+// fetchLinkPreview 实际抓取并解析页面的 OpenGraph / Twitter 元数据
+func fetchLinkPreview(rawURL string) (*LinkPreview, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePreviewURL(parsed); err != nil {
+		return nil, err
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), linkPreviewTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; YouduLinkPreview/1.0)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := linkPreviewClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("响应状态码异常: %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("不支持的内容类型: %s", mediaType)
+	}
+
+	preview := parseLinkPreview(io.LimitReader(resp.Body, linkPreviewMaxBodySize), resp.Request.URL)
+	preview.URL = rawURL
+	if preview.Title == "" && preview.Description == "" && preview.Image == "" {
+		return nil, nil
+	}
+	return preview, nil
+}
+
+// parseLinkPreview 解析HTML头部的元数据，og: 优先，其次 twitter:，最后 <title> 和 description
+func parseLinkPreview(body io.Reader, baseURL *url.URL) *LinkPreview {
+	meta := map[string]string{}
+	var title string
+
+	tokenizer := html.NewTokenizer(body)
+	inTitle := false
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+
+		token := tokenizer.Token()
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch token.Data {
+			case "meta":
+				var key, content string
+				for _, attr := range token.Attr {
+					switch strings.ToLower(attr.Key) {
+					case "property", "name":
+						key = strings.ToLower(strings.TrimSpace(attr.Val))
+					case "content":
+						content = strings.TrimSpace(attr.Val)
+					}
+				}
+				if key != "" && content != "" {
+					if _, exists := meta[key]; !exists {
+						meta[key] = content
+					}
+				}
+			case "title":
+				inTitle = true
+			case "body":
+				// 元数据都在 <head> 中，进入 <body> 后无需继续解析
+				return buildLinkPreview(meta, title, baseURL)
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.TrimSpace(token.Data)
+			}
+		case html.EndTagToken:
+			if token.Data == "title" {
+				inTitle = false
+			} else if token.Data == "head" {
+				return buildLinkPreview(meta, title, baseURL)
+			}
+		}
+	}
+
+	return buildLinkPreview(meta, title, baseURL)
+}
+
+func buildLinkPreview(meta map[string]string, title string, baseURL *url.URL) *LinkPreview {
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := meta[key]; value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	preview := &LinkPreview{
+		Title:       truncateRunes(first("og:title", "twitter:title"), linkPreviewMaxTextLen),
+		Description: truncateRunes(first("og:description", "twitter:description", "description"), linkPreviewMaxTextLen),
+		SiteName:    truncateRunes(first("og:site_name", "application-name"), linkPreviewMaxTextLen),
+	}
+	if preview.Title == "" {
+		preview.Title = truncateRunes(title, linkPreviewMaxTextLen)
+	}
+
+	// 图片地址可能是相对路径，按最终页面地址解析，且只保留 http(s) 链接
+	if image := first("og:image:secure_url", "og:image", "twitter:image", "twitter:image:src"); image != "" {
+		if imageURL, err := baseURL.Parse(image); err == nil && (imageURL.Scheme == "http" || imageURL.Scheme == "https") {
+			preview.Image = imageURL.String()
+		}
+	}
+
+	return preview
+}
+
+// validatePreviewURL 校验链接协议、端口和主机，禁止访问内网地址
+func validatePreviewURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrLinkPreviewBlocked
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		return ErrLinkPreviewBlocked
+	}
+
+	host := u.Hostname()
+	if host == "" || strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".local") {
+		return ErrLinkPreviewBlocked
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return ErrLinkPreviewBlocked
+	}
+	return nil
+}
+
+// isPublicIP 判断是否为公网IP（排除回环、内网、链路本地、组播等地址）
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		// 100.64.0.0/10 运营商级NAT、0.0.0.0/8
+		if ip4[0] == 0 || (ip4[0] == 100 && ip4[1]&0xC0 == 64) {
+			return false
+		}
+	}
+	return true
+}
+
+func linkPreviewCacheKey(rawURL string) string {
+	sum := sha1.Sum([]byte(rawURL))
+	return "link_preview:" + hex.EncodeToString(sum[:])
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "..."
+}
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestValidatePreviewURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawURL  string
+		blocked bool
+	}{
+		{name: "公网https", rawURL: "https://example.com/article", blocked: false},
+		{name: "公网IP", rawURL: "http://93.184.216.34/", blocked: false},
+		{name: "显式默认端口", rawURL: "https://example.com:443/", blocked: false},
+		{name: "非http协议", rawURL: "ftp://example.com/file", blocked: true},
+		{name: "javascript协议", rawURL: "javascript:alert(1)", blocked: true},
+		{name: "非标准端口", rawURL: "http://example.com:8080/", blocked: true},
+		{name: "localhost", rawURL: "http://localhost/", blocked: true},
+		{name: "大写localhost", rawURL: "http://LOCALHOST/", blocked: true},
+		{name: ".local域名", rawURL: "http://printer.local/", blocked: true},
+		{name: "IPv4回环", rawURL: "http://127.0.0.1/", blocked: true},
+		{name: "IPv6回环", rawURL: "http://[::1]/", blocked: true},
+		{name: "IPv4映射的回环", rawURL: "http://[::ffff:127.0.0.1]/", blocked: true},
+		{name: "10网段", rawURL: "http://10.1.2.3/", blocked: true},
+		{name: "172.16网段", rawURL: "http://172.16.0.1/", blocked: true},
+		{name: "192.168网段", rawURL: "http://192.168.1.1/", blocked: true},
+		{name: "链路本地（云元数据）", rawURL: "http://169.254.169.254/latest/meta-data/", blocked: true},
+		{name: "IPv6链路本地", rawURL: "http://[fe80::1]/", blocked: true},
+		{name: "IPv6 ULA", rawURL: "http://[fd12:3456:789a::1]/", blocked: true},
+		{name: "运营商级NAT", rawURL: "http://100.64.0.1/", blocked: true},
+		{name: "0.0.0.0", rawURL: "http://0.0.0.0/", blocked: true},
+		{name: "空主机", rawURL: "http:///path", blocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.rawURL)
+			if err != nil {
+				t.Fatalf("url.Parse(%q) 失败: %v", tt.rawURL, err)
+			}
+			err = validatePreviewURL(u)
+			if blocked := errors.Is(err, ErrLinkPreviewBlocked); blocked != tt.blocked {
+				t.Fatalf("validatePreviewURL(%q) = %v, blocked want %v", tt.rawURL, err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestLinkPreviewDialControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		blocked bool
+	}{
+		{name: "公网IPv4", address: "93.184.216.34:443", blocked: false},
+		{name: "公网IPv6", address: "[2606:2800:220:1:248:1893:25c8:1946]:443", blocked: false},
+		{name: "IPv4回环", address: "127.0.0.1:80", blocked: true},
+		{name: "IPv6回环", address: "[::1]:80", blocked: true},
+		{name: "RFC1918 10网段", address: "10.0.0.5:80", blocked: true},
+		{name: "RFC1918 172.16网段", address: "172.31.255.255:443", blocked: true},
+		{name: "RFC1918 192.168网段", address: "192.168.0.10:443", blocked: true},
+		{name: "链路本地", address: "169.254.169.254:80", blocked: true},
+		{name: "IPv6链路本地", address: "[fe80::abcd]:80", blocked: true},
+		{name: "IPv6 ULA", address: "[fc00::1]:443", blocked: true},
+		{name: "组播", address: "224.0.0.1:80", blocked: true},
+		{name: "未解析的主机名", address: "example.com:80", blocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := linkPreviewDialControl("tcp", tt.address, nil)
+			if blocked := errors.Is(err, ErrLinkPreviewBlocked); blocked != tt.blocked {
+				t.Fatalf("linkPreviewDialControl(%q) = %v, blocked want %v", tt.address, err, tt.blocked)
+			}
+		})
+	}
+
+	if err := linkPreviewDialControl("tcp", "missing-port", nil); err == nil {
+		t.Fatal("地址缺少端口时应返回错误")
+	}
+}
+
+func TestLinkPreviewClientBlocksPrivateTargets(t *testing.T) {
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<title>internal</title>"))
+	}))
+	defer server.Close()
+
+	// 模拟域名解析到回环地址（DNS重绑定）：URL校验通过后，拨号阶段仍需拦截
+	resp, err := linkPreviewClient.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("访问回环地址的请求应被拦截")
+	}
+	if !errors.Is(err, ErrLinkPreviewBlocked) {
+		t.Fatalf("错误应为 ErrLinkPreviewBlocked，实际: %v", err)
+	}
+	if reached {
+		t.Fatal("请求不应到达内网服务")
+	}
+}
+
+func TestLinkPreviewRedirectCheck(t *testing.T) {
+	origin, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+
+	tests := []struct {
+		name    string
+		target  string
+		via     int
+		wantErr bool
+	}{
+		{name: "重定向到公网", target: "https://www.example.com/", via: 1, wantErr: false},
+		{name: "重定向到回环", target: "http://127.0.0.1/admin", via: 1, wantErr: true},
+		{name: "重定向到内网", target: "http://192.168.1.1/", via: 1, wantErr: true},
+		{name: "重定向到元数据地址", target: "http://169.254.169.254/latest/meta-data/", via: 1, wantErr: true},
+		{name: "重定向到IPv6 ULA", target: "http://[fd00::1]/", via: 1, wantErr: true},
+		{name: "重定向到localhost", target: "http://localhost/", via: 1, wantErr: true},
+		{name: "重定向到非http协议", target: "file:///etc/passwd", via: 1, wantErr: true},
+		{name: "重定向次数过多", target: "https://www.example.com/", via: linkPreviewMaxRedirects, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.target, nil)
+			if err != nil {
+				t.Fatalf("构造请求失败: %v", err)
+			}
+			via := make([]*http.Request, tt.via)
+			for i := range via {
+				via[i] = origin
+			}
+			err = linkPreviewClient.CheckRedirect(req, via)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckRedirect(%q) = %v, wantErr %v", tt.target, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{ip: "8.8.8.8", public: true},
+		{ip: "2001:4860:4860::8888", public: true},
+		{ip: "127.0.0.1", public: false},
+		{ip: "10.255.255.255", public: false},
+		{ip: "172.16.0.0", public: false},
+		{ip: "192.168.255.1", public: false},
+		{ip: "169.254.0.1", public: false},
+		{ip: "100.127.255.255", public: false},
+		{ip: "0.1.2.3", public: false},
+		{ip: "::", public: false},
+		{ip: "fd00::1", public: false},
+		{ip: "ff02::1", public: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.public {
+				t.Fatalf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.public)
+			}
+		})
+	}
+}
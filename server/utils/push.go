@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"youdu-server/config"
+)
+
+// 推送通道名称
+const (
+	PushProviderAPNs   = "apns"
+	PushProviderFCM    = "fcm"
+	PushProviderXiaomi = "xiaomi"
+	PushProviderHuawei = "huawei"
+	PushProviderMemory = "memory"
+)
+
+// ErrPushTokenInvalid 推送令牌已失效（应用被卸载或令牌被注销），调用方应删除该令牌
+var ErrPushTokenInvalid = errors.New("推送令牌已失效")
+
+// PushNotification 推送内容
+type PushNotification struct {
+	Title       string            // 通知标题
+	Body        string            // 通知正文
+	CollapseKey string            // 折叠键：同一会话的多条通知只保留最新一条
+	Data        map[string]string // 透传给客户端的附加数据
+}
+
+// PushProvider 推送通道
+type PushProvider interface {
+	Name() string
+	Send(ctx context.Context, token string, notification *PushNotification) error
+}
+
+var (
+	pushProviders   = make(map[string]PushProvider)
+	pushProvidersMu sync.RWMutex
+)
+
+// pushHTTPTimeout 推送通道 HTTP 请求超时
+const pushHTTPTimeout = 10 * time.Second
+
+// RegisterPushProvider 注册推送通道（同名通道会被替换）
+func RegisterPushProvider(provider PushProvider) {
+	pushProvidersMu.Lock()
+	defer pushProvidersMu.Unlock()
+	pushProviders[provider.Name()] = provider
+}
+
+// GetPushProvider 获取推送通道，未注册时返回 nil
+func GetPushProvider(name string) PushProvider {
+	pushProvidersMu.RLock()
+	defer pushProvidersMu.RUnlock()
+	return pushProviders[name]
+}
+
+// PushEnabled 是否注册了任一推送通道
+func PushEnabled() bool {
+	pushProvidersMu.RLock()
+	defer pushProvidersMu.RUnlock()
+	return len(pushProviders) > 0
+}
+
+// ValidPushProviderName 是否为支持的推送通道名称
+func ValidPushProviderName(name string) bool {
+	switch name {
+	case PushProviderAPNs, PushProviderFCM, PushProviderXiaomi, PushProviderHuawei, PushProviderMemory:
+		return true
+	}
+	return false
+}
+
+// InitPushProviders 根据配置注册推送通道
+func InitPushProviders() {
+	cfg := config.AppConfig
+
+	if cfg.APNsKeyFile != "" && cfg.APNsKeyID != "" && cfg.APNsTeamID != "" && cfg.APNsTopic != "" {
+		provider, err := NewAPNsProvider(cfg.APNsKeyFile, cfg.APNsKeyID, cfg.APNsTeamID, cfg.APNsTopic, cfg.APNsProduction)
+		if err != nil {
+			LogError("❌ [推送] APNs 初始化失败: %v", err)
+		} else {
+			RegisterPushProvider(provider)
+			LogInfo("✅ [推送] APNs 已启用 (production=%v)", cfg.APNsProduction)
+		}
+	}
+
+	if cfg.FCMCredentialsFile != "" {
+		provider, err := NewFCMProvider(cfg.FCMCredentialsFile)
+		if err != nil {
+			LogError("❌ [推送] FCM 初始化失败: %v", err)
+		} else {
+			RegisterPushProvider(provider)
+			LogInfo("✅ [推送] FCM 已启用")
+		}
+	}
+
+	if cfg.XiaomiPushAppSecret != "" && cfg.XiaomiPushPackage != "" {
+		RegisterPushProvider(NewXiaomiPushProvider(cfg.XiaomiPushAppSecret, cfg.XiaomiPushPackage))
+		LogInfo("✅ [推送] 小米推送已启用")
+	}
+
+	if cfg.HuaweiPushAppID != "" && cfg.HuaweiPushAppSecret != "" {
+		RegisterPushProvider(NewHuaweiPushProvider(cfg.HuaweiPushAppID, cfg.HuaweiPushAppSecret))
+		LogInfo("✅ [推送] 华为推送已启用")
+	}
+
+	if cfg.PushMemoryProvider {
+		RegisterPushProvider(NewInMemoryPushProvider())
+		LogInfo("✅ [推送] 内存推送通道已启用（仅用于测试）")
+	}
+}
+
+// SentPush 内存推送通道记录的一次推送
+type SentPush struct {
+	Token        string
+	Notification PushNotification
+	SentAt       time.Time
+}
+
+// InMemoryPushProvider 内存推送通道：只记录推送内容，不真正发送，用于测试
+type InMemoryPushProvider struct {
+	mu            sync.Mutex
+	sent          []SentPush
+	invalidTokens map[string]bool
+}
+
+// NewInMemoryPushProvider 创建内存推送通道
+func NewInMemoryPushProvider() *InMemoryPushProvider {
+	return &InMemoryPushProvider{invalidTokens: make(map[string]bool)}
+}
+
+// Name 通道名称
+func (p *InMemoryPushProvider) Name() string {
+	return PushProviderMemory
+}
+
+// Send 记录推送；被标记为失效的令牌返回 ErrPushTokenInvalid
+func (p *InMemoryPushProvider) Send(ctx context.Context, token string, notification *PushNotification) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.invalidTokens[token] {
+		return ErrPushTokenInvalid
+	}
+	p.sent = append(p.sent, SentPush{Token: token, Notification: *notification, SentAt: time.Now().UTC()})
+	return nil
+}
+
+// MarkInvalid 将令牌标记为失效，模拟应用被卸载
+func (p *InMemoryPushProvider) MarkInvalid(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.invalidTokens[token] = true
+}
+
+// Sent 返回已记录的推送副本
+func (p *InMemoryPushProvider) Sent() []SentPush {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sent := make([]SentPush, len(p.sent))
+	copy(sent, p.sent)
+	return sent
+}
+
+// Reset 清空已记录的推送
+func (p *InMemoryPushProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent = nil
+	p.invalidTokens = make(map[string]bool)
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	apnsProductionHost = "https://api.push.apple.com"
+	apnsSandboxHost    = "https://api.sandbox.push.apple.com"
+	// APNs 要求鉴权令牌在 20~60 分钟内刷新
+	apnsTokenRefresh = 50 * time.Minute
+)
+
+// APNsProvider 苹果推送通道（基于 .p8 密钥的令牌鉴权，HTTP/2）
+type APNsProvider struct {
+	key    *ecdsa.PrivateKey
+	keyID  string
+	teamID string
+	topic  string
+	host   string
+	client *http.Client
+
+	mu         sync.Mutex
+	authToken  string
+	authIssued time.Time
+}
+
+// NewAPNsProvider 创建 APNs 推送通道
+func NewAPNsProvider(keyFile, keyID, teamID, topic string, production bool) (*APNsProvider, error) {
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取 APNs 密钥失败: %v", err)
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("解析 APNs 密钥失败: %v", err)
+	}
+
+	host := apnsSandboxHost
+	if production {
+		host = apnsProductionHost
+	}
+
+	return &APNsProvider{
+		key:    key,
+		keyID:  keyID,
+		teamID: teamID,
+		topic:  topic,
+		host:   host,
+		client: &http.Client{Timeout: pushHTTPTimeout},
+	}, nil
+}
+
+// Name 通道名称
+func (p *APNsProvider) Name() string {
+	return PushProviderAPNs
+}
+
+// bearerToken 获取（必要时刷新）APNs 鉴权令牌
+func (p *APNsProvider) bearerToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.authToken != "" && time.Since(p.authIssued) < apnsTokenRefresh {
+		return p.authToken, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": p.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = p.keyID
+
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		return "", err
+	}
+	p.authToken = signed
+	p.authIssued = now
+	return signed, nil
+}
+
+// Send 发送推送
+func (p *APNsProvider) Send(ctx context.Context, token string, notification *PushNotification) error {
+	aps := map[string]interface{}{
+		"alert": map[string]string{
+			"title": notification.Title,
+			"body":  notification.Body,
+		},
+		"sound":     "default",
+		"thread-id": notification.CollapseKey,
+	}
+
+	payload := map[string]interface{}{"aps": aps}
+	for k, v := range notification.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	authToken, err := p.bearerToken()
+	if err != nil {
+		return fmt.Errorf("生成 APNs 鉴权令牌失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.host+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+authToken)
+	req.Header.Set("apns-topic", p.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	if notification.CollapseKey != "" {
+		req.Header.Set("apns-collapse-id", notification.CollapseKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送 APNs 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(resp.Body)
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	json.Unmarshal(respBody, &result)
+
+	// 410 表示令牌已不再有效；BadDeviceToken 表示令牌与环境不匹配或格式错误
+	if resp.StatusCode == http.StatusGone || result.Reason == "BadDeviceToken" || result.Reason == "Unregistered" {
+		return ErrPushTokenInvalid
+	}
+	return fmt.Errorf("APNs 返回错误: status=%d, reason=%s", resp.StatusCode, result.Reason)
+}
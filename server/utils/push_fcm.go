@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// fcmServiceAccount 服务账号 JSON 中用到的字段
+type fcmServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	PrivateKey  string `json:"private_key"`
+	ClientEmail string `json:"client_email"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCMProvider Firebase 推送通道（HTTP v1 API）
+type FCMProvider struct {
+	projectID   string
+	clientEmail string
+	tokenURI    string
+	key         *rsa.PrivateKey
+	client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMProvider 根据服务账号文件创建 FCM 推送通道
+func NewFCMProvider(credentialsFile string) (*FCMProvider, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取 FCM 服务账号失败: %v", err)
+	}
+
+	var account fcmServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("解析 FCM 服务账号失败: %v", err)
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("FCM 服务账号缺少 project_id、client_email 或 private_key")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("解析 FCM 私钥失败: %v", err)
+	}
+
+	return &FCMProvider{
+		projectID:   account.ProjectID,
+		clientEmail: account.ClientEmail,
+		tokenURI:    account.TokenURI,
+		key:         key,
+		client:      &http.Client{Timeout: pushHTTPTimeout},
+	}, nil
+}
+
+// Name 通道名称
+func (p *FCMProvider) Name() string {
+	return PushProviderFCM
+}
+
+// getAccessToken 用服务账号签发的 JWT 换取 OAuth2 访问令牌（过期前 1 分钟刷新）
+func (p *FCMProvider) getAccessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken != "" && time.Now().Before(p.expiresAt.Add(-time.Minute)) {
+		return p.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   p.clientEmail,
+		"scope": fcmScope,
+		"aud":   p.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(p.key)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK || result.AccessToken == "" {
+		return "", fmt.Errorf("获取 FCM 访问令牌失败: status=%d", resp.StatusCode)
+	}
+
+	p.accessToken = result.AccessToken
+	p.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return p.accessToken, nil
+}
+
+// Send 发送推送
+func (p *FCMProvider) Send(ctx context.Context, token string, notification *PushNotification) error {
+	message := map[string]interface{}{
+		"token": token,
+		"notification": map[string]string{
+			"title": notification.Title,
+			"body":  notification.Body,
+		},
+		"android": map[string]interface{}{
+			"priority":     "high",
+			"collapse_key": notification.CollapseKey,
+			"notification": map[string]string{"tag": notification.CollapseKey},
+		},
+		"apns": map[string]interface{}{
+			"headers": map[string]string{"apns-collapse-id": notification.CollapseKey},
+		},
+	}
+	if len(notification.Data) > 0 {
+		message["data"] = notification.Data
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"message": message})
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := p.getAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", p.projectID)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送 FCM 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(resp.Body)
+	// 令牌已注销或不存在时返回 404 / UNREGISTERED
+	if resp.StatusCode == http.StatusNotFound || strings.Contains(string(respBody), "UNREGISTERED") {
+		return ErrPushTokenInvalid
+	}
+	return fmt.Errorf("FCM 返回错误: status=%d, body=%s", resp.StatusCode, string(respBody))
+}
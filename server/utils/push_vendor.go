@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// XiaomiPushProvider 小米推送通道
+type XiaomiPushProvider struct {
+	appSecret   string
+	packageName string
+	client      *http.Client
+}
+
+// NewXiaomiPushProvider 创建小米推送通道
+func NewXiaomiPushProvider(appSecret, packageName string) *XiaomiPushProvider {
+	return &XiaomiPushProvider{
+		appSecret:   appSecret,
+		packageName: packageName,
+		client:      &http.Client{Timeout: pushHTTPTimeout},
+	}
+}
+
+// Name 通道名称
+func (p *XiaomiPushProvider) Name() string {
+	return PushProviderXiaomi
+}
+
+// Send 发送推送（同一 notify_id 的通知会覆盖，实现按会话折叠）
+func (p *XiaomiPushProvider) Send(ctx context.Context, token string, notification *PushNotification) error {
+	form := url.Values{}
+	form.Set("registration_id", token)
+	form.Set("restricted_package_name", p.packageName)
+	form.Set("title", notification.Title)
+	form.Set("description", notification.Body)
+	form.Set("pass_through", "0")
+	form.Set("notify_type", "-1")
+	if notification.CollapseKey != "" {
+		form.Set("notify_id", strconv.Itoa(collapseKeyNotifyID(notification.CollapseKey)))
+	}
+	for k, v := range notification.Data {
+		form.Set("extra."+k, v)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.xmpush.xiaomi.com/v3/message/regid", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "key="+p.appSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送小米推送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Result string `json:"result"`
+		Code   int    `json:"code"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("解析小米推送响应失败: %v", err)
+	}
+	if result.Code == 0 {
+		return nil
+	}
+	// 20301 表示 regId 无效（应用已卸载或 regId 过期）
+	if result.Code == 20301 {
+		return ErrPushTokenInvalid
+	}
+	return fmt.Errorf("小米推送返回错误: code=%d, reason=%s", result.Code, result.Reason)
+}
+
+// HuaweiPushProvider 华为推送通道
+type HuaweiPushProvider struct {
+	appID     string
+	appSecret string
+	client    *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewHuaweiPushProvider 创建华为推送通道
+func NewHuaweiPushProvider(appID, appSecret string) *HuaweiPushProvider {
+	return &HuaweiPushProvider{
+		appID:     appID,
+		appSecret: appSecret,
+		client:    &http.Client{Timeout: pushHTTPTimeout},
+	}
+}
+
+// Name 通道名称
+func (p *HuaweiPushProvider) Name() string {
+	return PushProviderHuawei
+}
+
+// getAccessToken 使用客户端凭证获取访问令牌（过期前 1 分钟刷新）
+func (p *HuaweiPushProvider) getAccessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken != "" && time.Now().Before(p.expiresAt.Add(-time.Minute)) {
+		return p.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", p.appID)
+	form.Set("client_secret", p.appSecret)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://oauth-login.cloud.huawei.com/oauth2/v3/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK || result.AccessToken == "" {
+		return "", fmt.Errorf("获取华为推送访问令牌失败: status=%d", resp.StatusCode)
+	}
+
+	p.accessToken = result.AccessToken
+	p.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return p.accessToken, nil
+}
+
+// Send 发送推送（同一 tag 的通知会覆盖，实现按会话折叠）
+func (p *HuaweiPushProvider) Send(ctx context.Context, token string, notification *PushNotification) error {
+	androidNotification := map[string]interface{}{
+		"title":        notification.Title,
+		"body":         notification.Body,
+		"click_action": map[string]interface{}{"type": 3},
+	}
+	if notification.CollapseKey != "" {
+		androidNotification["tag"] = notification.CollapseKey
+	}
+
+	message := map[string]interface{}{
+		"token": []string{token},
+		"android": map[string]interface{}{
+			"urgency":      "HIGH",
+			"notification": androidNotification,
+		},
+	}
+	if len(notification.Data) > 0 {
+		data, _ := json.Marshal(notification.Data)
+		message["data"] = string(data)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"message": message})
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := p.getAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("https://push-api.cloud.huawei.com/v1/%s/messages:send", p.appID)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送华为推送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("解析华为推送响应失败: %v", err)
+	}
+	switch result.Code {
+	case "80000000":
+		return nil
+	case "80300007":
+		// 所有令牌均无效
+		return ErrPushTokenInvalid
+	}
+	return fmt.Errorf("华为推送返回错误: code=%s, msg=%s", result.Code, result.Msg)
+}
+
+// collapseKeyNotifyID 将折叠键映射为厂商通道要求的整数通知ID
+func collapseKeyNotifyID(collapseKey string) int {
+	h := fnv.New32a()
+	h.Write([]byte(collapseKey))
+	return int(h.Sum32() & 0x7fffffff)
+}
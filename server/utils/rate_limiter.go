@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter 固定窗口限流器（进程内），每个 key 在一个窗口内最多允许 limit 次
+type RateLimiter struct {
+	limit   int
+	window  time.Duration
+	mu      sync.Mutex
+	buckets map[string]*rateLimitBucket
+}
+
+type rateLimitBucket struct {
+	count   int
+	resetAt time.Time
+}
+
+// NewRateLimiter 创建限流器
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		buckets: make(map[string]*rateLimitBucket),
+	}
+}
+
+// Allow 判断 key 是否允许本次请求，不允许时返回距窗口重置的等待时间
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[key]
+	if !ok || !now.Before(bucket.resetAt) {
+		// 顺带清理过期的窗口，避免 map 无限增长
+		if len(l.buckets) > 1024 {
+			for k, b := range l.buckets {
+				if !now.Before(b.resetAt) {
+					delete(l.buckets, k)
+				}
+			}
+		}
+		l.buckets[key] = &rateLimitBucket{count: 1, resetAt: now.Add(l.window)}
+		return true, 0
+	}
+
+	if bucket.count >= l.limit {
+		return false, bucket.resetAt.Sub(now)
+	}
+	bucket.count++
+	return true, 0
+}
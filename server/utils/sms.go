@@ -30,6 +30,22 @@ var DefaultSMSConfig = SMSConfig{
 	APIURL:   "https://106.ihuyi.com/webservice/sms.php",
 }
 
+// SMSProvider 短信发送通道（测试时可替换为假实现，不真正发送短信）
+type SMSProvider interface {
+	SendSMS(phone, content string) error
+}
+
+// ihuyiSMSProvider 互亿无线短信通道
+type ihuyiSMSProvider struct{}
+
+// SendSMS 通过互亿无线发送短信
+func (ihuyiSMSProvider) SendSMS(phone, content string) error {
+	return SendSMS(phone, content)
+}
+
+// DefaultSMSProvider 默认短信通道
+var DefaultSMSProvider SMSProvider = ihuyiSMSProvider{}
+
 // SendLoginSMS 发送登录验证码短信
 // phone: 手机号
 // code: 6位数字验证码